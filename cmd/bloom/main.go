@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/fs"
 	"log"
@@ -10,9 +11,16 @@ import (
 	"os/signal"
 	"syscall"
 	"time"
+	_ "time/tzdata" // so ?tz= works on hosts without zoneinfo
+
+	"github.com/go-chi/chi/v5"
 
 	"github.com/walidabualafia/bloom/internal/api"
+	"github.com/walidabualafia/bloom/internal/api/middleware"
 	"github.com/walidabualafia/bloom/internal/config"
+	"github.com/walidabualafia/bloom/internal/demo"
+	"github.com/walidabualafia/bloom/internal/password"
+	"github.com/walidabualafia/bloom/internal/reminder"
 	"github.com/walidabualafia/bloom/internal/store"
 	"github.com/walidabualafia/bloom/web"
 
@@ -39,7 +47,7 @@ func run() error {
 	case "sqlite":
 		db, err = sqlitestore.New(cfg.DatabaseURL)
 	case "postgres":
-		db, err = pgstore.New(cfg.DatabaseURL)
+		db, err = pgstore.NewWithReplica(cfg.DatabaseURL, cfg.DatabaseReadURL)
 	default:
 		return fmt.Errorf("unsupported database driver: %s", cfg.DBDriver)
 	}
@@ -48,6 +56,10 @@ func run() error {
 	}
 	defer db.Close()
 
+	if cfg.DBTiming || cfg.SlowQueryThreshold > 0 {
+		db = store.NewTimed(db, cfg.SlowQueryThreshold)
+	}
+
 	// Run migrations.
 	if err := db.Migrate(context.Background()); err != nil {
 		return fmt.Errorf("run migrations: %w", err)
@@ -55,7 +67,8 @@ func run() error {
 	log.Printf("database ready (%s)", cfg.DBDriver)
 
 	// Build the router.
-	router := api.NewRouter(db, cfg.JWTSecret)
+	events := api.NewEvents(db, cfg)
+	router := api.NewRouter(db, cfg, events)
 
 	// Serve the embedded frontend in production, or skip in development
 	// (Vite dev server handles the frontend).
@@ -66,8 +79,15 @@ func run() error {
 		}
 		fileServer := http.FileServer(http.FS(frontendFS))
 
-		// Serve static files, fall back to index.html for SPA routing.
-		router.Get("/*", func(w http.ResponseWriter, r *http.Request) {
+		static := chi.Router(router)
+		if cfg.SecurityHeaders {
+			static = router.With(middleware.SecurityHeaders(cfg.ContentSecurityPolicy, cfg.ReferrerPolicy))
+		}
+
+		// Serve static files, fall back to index.html for SPA routing. The
+		// file server answers HEAD with the same headers, Content-Length
+		// included, and no body.
+		spa := func(w http.ResponseWriter, r *http.Request) {
 			// Try to serve the file directly.
 			if _, err := fs.Stat(frontendFS, r.URL.Path[1:]); err == nil {
 				fileServer.ServeHTTP(w, r)
@@ -76,7 +96,9 @@ func run() error {
 			// Fall back to index.html for client-side routing.
 			r.URL.Path = "/"
 			fileServer.ServeHTTP(w, r)
-		})
+		}
+		static.Get("/*", spa)
+		static.Head("/*", spa)
 	}
 
 	// Start the HTTP server.
@@ -88,6 +110,46 @@ func run() error {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Dispatch deadline reminders in the background until shutdown.
+	reminderCtx, stopReminders := context.WithCancel(context.Background())
+	remindersDone := make(chan struct{})
+	if cfg.ReminderInterval > 0 {
+		scheduler := reminder.New(db, reminder.LogNotifier, cfg.ReminderInterval, cfg.ReminderLeadTime)
+		go func() {
+			defer close(remindersDone)
+			scheduler.Run(reminderCtx)
+		}()
+	} else {
+		close(remindersDone)
+	}
+	defer func() {
+		stopReminders()
+		<-remindersDone
+	}()
+
+	// In demo mode, wipe and reseed the data now and periodically until
+	// shutdown. The first reset finishes before the server starts listening.
+	demoCtx, stopDemo := context.WithCancel(context.Background())
+	demoDone := make(chan struct{})
+	if cfg.DemoMode {
+		resetter := demo.NewResetter(db, cfg.DemoResetInterval, password.NewHasher(cfg.PasswordPepper))
+		log.Printf("demo mode: all data is deleted every %s", cfg.DemoResetInterval)
+		if err := resetter.Reset(demoCtx); err != nil {
+			stopDemo()
+			return fmt.Errorf("demo reset: %w", err)
+		}
+		go func() {
+			defer close(demoDone)
+			resetter.Run(demoCtx)
+		}()
+	} else {
+		close(demoDone)
+	}
+	defer func() {
+		stopDemo()
+		<-demoDone
+	}()
+
 	// Graceful shutdown on SIGINT/SIGTERM.
 	done := make(chan os.Signal, 1)
 	signal.Notify(done, os.Interrupt, syscall.SIGTERM)
@@ -102,8 +164,17 @@ func run() error {
 	<-done
 	log.Println("shutting down...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
-	return srv.Shutdown(ctx)
+	err = srv.Shutdown(ctx)
+	if errors.Is(err, context.DeadlineExceeded) {
+		log.Printf("shutdown timed out after %s with connections still open; closing them", cfg.ShutdownTimeout)
+		err = srv.Close()
+	}
+	// Queued webhook deliveries get whatever is left of the timeout.
+	if err := events.Close(ctx); err != nil {
+		log.Print(err)
+	}
+	return err
 }
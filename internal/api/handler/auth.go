@@ -1,27 +1,43 @@
 package handler
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
+	"log"
 	"net/http"
+	"time"
 
 	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/api/response"
 	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/password"
 	"github.com/walidabualafia/bloom/internal/store"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 // Auth handles user registration and login.
 type Auth struct {
-	store     store.Store
-	jwtSecret string
+	store        store.Store
+	jwtSecret    string
+	registration bool
+	cookie       AuthCookie
+	passwords    password.Hasher
+}
+
+// AuthCookie configures the optional session cookie. When Enabled, Register
+// and Login also set the token as an HttpOnly, SameSite=Strict cookie that
+// middleware.Auth accepts in place of the Authorization header, so browser
+// clients never have to expose the token to JavaScript. Secure should only
+// be off for plain-HTTP development.
+type AuthCookie struct {
+	Enabled bool
+	Secure  bool
 }
 
-// NewAuth creates a new Auth handler.
-func NewAuth(s store.Store, jwtSecret string) *Auth {
-	return &Auth{store: s, jwtSecret: jwtSecret}
+// NewAuth creates a new Auth handler. If registration is false, Register
+// only accepts the first account on an empty instance; see Register.
+// passwords hashes and verifies passwords.
+func NewAuth(s store.Store, jwtSecret string, registration bool, cookie AuthCookie, passwords password.Hasher) *Auth {
+	return &Auth{store: s, jwtSecret: jwtSecret, registration: registration, cookie: cookie, passwords: passwords}
 }
 
 type registerRequest struct {
@@ -38,9 +54,14 @@ type loginRequest struct {
 type authResponse struct {
 	Token string      `json:"token"`
 	User  *model.User `json:"user"`
+	// CookieSession tells browser clients the session is also held in an
+	// HttpOnly cookie, so they need not store Token themselves.
+	CookieSession bool `json:"cookie_session,omitempty"`
 }
 
-// Register creates a new user account.
+// Register creates a new user account. When registration is disabled it
+// returns 403, unless the instance has no users yet: that first account is
+// let through as an admin so a private instance can be bootstrapped.
 func (h *Auth) Register(w http.ResponseWriter, r *http.Request) {
 	var req registerRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -48,40 +69,69 @@ func (h *Auth) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Username == "" || req.Email == "" || req.Password == "" {
-		writeError(w, http.StatusBadRequest, "username, email, and password are required")
+	bootstrap := false
+	if !h.registration {
+		count, err := h.store.CountUsers(r.Context())
+		if err != nil {
+			writeServerError(w, err, "internal server error")
+			return
+		}
+		if count > 0 {
+			writeError(w, http.StatusForbidden, "registration is disabled")
+			return
+		}
+		bootstrap = true
+	}
+
+	user, ok := newUser(w, req, h.passwords)
+	if !ok {
 		return
 	}
+	user.IsAdmin = bootstrap
 
-	if len(req.Password) < 6 {
-		writeError(w, http.StatusBadRequest, "password must be at least 6 characters")
+	if err := h.store.CreateUser(r.Context(), user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			writeError(w, http.StatusConflict, "username or email already exists")
+			return
+		}
+		writeServerError(w, err, "failed to create user")
 		return
 	}
 
-	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	token, err := middleware.GenerateToken(user.ID, h.jwtSecret)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to hash password")
+		writeServerError(w, err, "failed to generate token")
 		return
 	}
 
-	user := &model.User{
-		Username: req.Username,
-		Email:    req.Email,
-		Password: string(hash),
+	h.setTokenCookie(w, token, middleware.TokenLifetime)
+	writeJSON(w, http.StatusCreated, authResponse{Token: token, User: user, CookieSession: h.cookie.Enabled})
+}
+
+// newUser validates req and returns the user to create with its password
+// hashed by passwords, writing a 400 if req is invalid.
+func newUser(w http.ResponseWriter, req registerRequest, passwords password.Hasher) (*model.User, bool) {
+	if req.Username == "" || req.Email == "" || req.Password == "" {
+		writeError(w, http.StatusBadRequest, "username, email, and password are required")
+		return nil, false
 	}
 
-	if err := h.store.CreateUser(r.Context(), user); err != nil {
-		writeError(w, http.StatusConflict, "username or email already exists")
-		return
+	if len(req.Password) < 6 {
+		writeError(w, http.StatusBadRequest, "password must be at least 6 characters")
+		return nil, false
 	}
 
-	token, err := middleware.GenerateToken(user.ID, h.jwtSecret)
+	hash, err := passwords.Hash(req.Password)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to generate token")
-		return
+		writeServerError(w, err, "failed to hash password")
+		return nil, false
 	}
 
-	writeJSON(w, http.StatusCreated, authResponse{Token: token, User: user})
+	return &model.User{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: hash,
+	}, true
 }
 
 // Login authenticates a user and returns a JWT.
@@ -99,26 +149,35 @@ func (h *Auth) Login(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.store.GetUserByUsername(r.Context(), req.Username)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
+		if errors.Is(err, store.ErrNotFound) {
 			writeError(w, http.StatusUnauthorized, "invalid credentials")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+	if !h.passwords.Verify(user.Password, req.Password) {
 		writeError(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
+	// Failing to record the login only skews the inactive users report, so
+	// it does not stop the user signing in.
+	now := time.Now()
+	if err := h.store.RecordLogin(r.Context(), user.ID, now); err != nil {
+		log.Printf("record login of user %d: %v", user.ID, err)
+	} else {
+		user.LastLoginAt = &now
+	}
 
 	token, err := middleware.GenerateToken(user.ID, h.jwtSecret)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to generate token")
+		writeServerError(w, err, "failed to generate token")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, authResponse{Token: token, User: user})
+	h.setTokenCookie(w, token, middleware.TokenLifetime)
+	writeJSON(w, http.StatusOK, authResponse{Token: token, User: user, CookieSession: h.cookie.Enabled})
 }
 
 // Me returns the currently authenticated user.
@@ -126,8 +185,102 @@ func (h *Auth) Me(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 	user, err := h.store.GetUserByID(r.Context(), userID)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "user not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeUserNotFound, "user not found")
+			return
+		}
+		writeServerError(w, err, "failed to get user")
+		return
+	}
+	writeJSON(w, http.StatusOK, user)
+}
+
+type updateProfileRequest struct {
+	Timezone *string `json:"timezone"`
+	Locale   *string `json:"locale"`
+}
+
+// UpdateMe updates the current user's preferences. Timezone must be an IANA
+// zone name such as Europe/Berlin; it becomes the default zone of
+// GET /todos/due. Either field may be set to "" to clear it.
+func (h *Auth) UpdateMe(w http.ResponseWriter, r *http.Request) {
+	var req updateProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	v := validation{}
+	v.check(req.Timezone == nil || model.ValidTimezone(*req.Timezone), "timezone", "timezone must be an IANA time zone like America/New_York")
+	v.check(req.Locale == nil || model.ValidLocale(*req.Locale), "locale", "locale must be a language tag like en-US")
+	if v.write(w) {
+		return
+	}
+
+	user, err := h.store.GetUserByID(r.Context(), middleware.GetUserID(r.Context()))
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeUserNotFound, "user not found")
+			return
+		}
+		writeServerError(w, err, "failed to get user")
+		return
+	}
+	if req.Timezone != nil {
+		user.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		user.Locale = *req.Locale
+	}
+	if err := h.store.UpdateUser(r.Context(), user, nil); err != nil {
+		writeServerError(w, err, "failed to update user")
 		return
 	}
 	writeJSON(w, http.StatusOK, user)
 }
+
+type validateResponse struct {
+	Valid     bool       `json:"valid"`
+	UserID    int64      `json:"user_id"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// Validate reports that the request's token is valid, with its user and
+// expiry so clients can schedule signing in again. Invalid tokens never get
+// here: the auth middleware rejects them with 401. Unlike Me it does not
+// touch the database, so a token for a deleted user still validates.
+func (h *Auth) Validate(w http.ResponseWriter, r *http.Request) {
+	resp := validateResponse{Valid: true, UserID: middleware.GetUserID(r.Context())}
+	if exp := middleware.GetTokenExpiry(r.Context()); !exp.IsZero() {
+		exp = exp.UTC()
+		resp.ExpiresAt = &exp
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Logout clears the session cookie. Tokens are stateless, so one held
+// elsewhere stays valid until it expires.
+func (h *Auth) Logout(w http.ResponseWriter, r *http.Request) {
+	h.setTokenCookie(w, "", -1)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// setTokenCookie sets the session cookie if cookie auth is enabled. A
+// negative maxAge deletes it.
+func (h *Auth) setTokenCookie(w http.ResponseWriter, token string, maxAge time.Duration) {
+	if !h.cookie.Enabled {
+		return
+	}
+	cookie := &http.Cookie{
+		Name:     middleware.TokenCookie,
+		Value:    token,
+		Path:     "/api",
+		MaxAge:   int(maxAge.Seconds()),
+		Secure:   h.cookie.Secure,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+	}
+	if maxAge < 0 {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
@@ -2,20 +2,38 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
 
 	"github.com/walidabualafia/bloom/internal/api"
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/config"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
 	"github.com/walidabualafia/bloom/internal/store/sqlite"
-	"context"
 )
 
 const testJWTSecret = "test-secret-key"
 
 func setupTestRouter(t *testing.T) http.Handler {
 	t.Helper()
+	return setupTestRouterWithConfig(t, &config.Config{})
+}
+
+// setupTestRouterWithConfig builds a router over a fresh in-memory store.
+// The JWT secret is filled in if cfg leaves it empty.
+func setupTestRouterWithConfig(t *testing.T, cfg *config.Config) http.Handler {
+	t.Helper()
+	if cfg.JWTSecret == "" {
+		cfg.JWTSecret = testJWTSecret
+	}
 	s, err := sqlite.New(":memory:")
 	if err != nil {
 		t.Fatalf("open store: %v", err)
@@ -24,7 +42,16 @@ func setupTestRouter(t *testing.T) http.Handler {
 		t.Fatalf("migrate: %v", err)
 	}
 	t.Cleanup(func() { s.Close() })
-	return api.NewRouter(s, testJWTSecret)
+	return newTestRouter(t, s, cfg)
+}
+
+// newTestRouter builds the API router over s and closes its webhook
+// dispatcher when the test ends.
+func newTestRouter(t *testing.T, s store.Store, cfg *config.Config) http.Handler {
+	t.Helper()
+	events := api.NewEvents(s, cfg)
+	t.Cleanup(func() { events.Close(context.Background()) })
+	return api.NewRouter(s, cfg, events)
 }
 
 func TestRegisterAndLogin(t *testing.T) {
@@ -87,6 +114,34 @@ func TestRegisterAndLogin(t *testing.T) {
 	}
 }
 
+func TestPasswordPepper(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{PasswordPepper: "server-secret", RegistrationDisabled: true})
+	admin := registerUser(t, router, "root", "root@example.com", "password123")
+
+	// Both registration and admin-created accounts can log in.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/admin/users", admin,
+		`{"username":"bob","email":"bob@example.com","password":"password456"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create user: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	for _, login := range []string{
+		`{"username":"root","password":"password123"}`,
+		`{"username":"bob","password":"password456"}`,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("POST", "/api/auth/login", "", login))
+		if rec.Code != http.StatusOK {
+			t.Errorf("login %s: status = %d, want %d", login, rec.Code, http.StatusOK)
+		}
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/auth/login", "", `{"username":"bob","password":"password123"}`))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("wrong password: status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+}
+
 func TestRegisterValidation(t *testing.T) {
 	router := setupTestRouter(t)
 
@@ -114,6 +169,22 @@ func TestRegisterValidation(t *testing.T) {
 	}
 }
 
+func TestRegisterDuplicate(t *testing.T) {
+	router := setupTestRouter(t)
+	registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	for _, body := range []string{
+		`{"username":"alice","email":"other@example.com","password":"password123"}`,
+		`{"username":"other","email":"alice@example.com","password":"password123"}`,
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("POST", "/api/auth/register", "", body))
+		if rec.Code != http.StatusConflict {
+			t.Errorf("register %s: status = %d, want %d", body, rec.Code, http.StatusConflict)
+		}
+	}
+}
+
 func TestLoginInvalidCredentials(t *testing.T) {
 	router := setupTestRouter(t)
 
@@ -139,3 +210,323 @@ func TestProtectedRouteWithoutAuth(t *testing.T) {
 		t.Errorf("status = %d, want %d", rec.Code, http.StatusUnauthorized)
 	}
 }
+
+func TestAuthErrorsAreJSON(t *testing.T) {
+	router := setupTestRouter(t)
+
+	for _, header := range []string{"", "Basic abc", "Bearer not-a-jwt"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/projects", nil)
+		if header != "" {
+			req.Header.Set("Authorization", header)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%q: Content-Type = %q, want application/json", header, ct)
+		}
+		var resp struct{ Error string }
+		if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil || resp.Error == "" {
+			t.Errorf("%q: body is not a JSON error: %v", header, err)
+		}
+	}
+}
+
+func TestJWTSecretRotation(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{
+		JWTSecret:         "new-secret",
+		JWTSecretPrevious: []string{"older-secret", "old-secret"},
+	})
+	newToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	sign := func(secret string) string {
+		token, err := middleware.GenerateToken(1, secret)
+		if err != nil {
+			t.Fatalf("sign token: %v", err)
+		}
+		return token
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"issued with the primary secret", newToken, http.StatusOK},
+		{"signed with a previous secret", sign("old-secret"), http.StatusOK},
+		{"signed with an unknown secret", sign("stolen-secret"), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("GET", "/api/auth/me", tt.token, ""))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+
+	// Once the old secret is retired, its tokens stop working but new ones
+	// don't, since they were only ever signed with the primary.
+	retired := setupTestRouterWithConfig(t, &config.Config{JWTSecret: "new-secret"})
+	registerUser(t, retired, "alice", "alice@example.com", "password123")
+	for token, want := range map[string]int{sign("new-secret"): http.StatusOK, sign("old-secret"): http.StatusUnauthorized} {
+		rec := httptest.NewRecorder()
+		retired.ServeHTTP(rec, authedRequest("GET", "/api/auth/me", token, ""))
+		if rec.Code != want {
+			t.Errorf("after retiring: status = %d, want %d", rec.Code, want)
+		}
+	}
+}
+
+func TestTokenClockSkew(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{JWTLeeway: 30 * time.Second})
+	registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	sign := func(issuedAt time.Time) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"sub": "1",
+			"iat": issuedAt.Unix(),
+			"nbf": issuedAt.Unix(),
+			"exp": issuedAt.Add(time.Hour).Unix(),
+		})
+		signed, err := token.SignedString([]byte(testJWTSecret))
+		if err != nil {
+			t.Fatalf("sign token: %v", err)
+		}
+		return signed
+	}
+
+	tests := []struct {
+		name     string
+		issuedAt time.Time
+		want     int
+	}{
+		{"slightly ahead", time.Now().Add(10 * time.Second), http.StatusOK},
+		{"far in the future", time.Now().Add(time.Hour), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+			req.Header.Set("Authorization", "Bearer "+sign(tt.issuedAt))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestCookieAuth(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{AuthCookie: true})
+
+	body := `{"username":"alice","email":"alice@example.com","password":"password123"}`
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/register", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("register: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var cookie *http.Cookie
+	for _, c := range rec.Result().Cookies() {
+		if c.Name == middleware.TokenCookie {
+			cookie = c
+		}
+	}
+	if cookie == nil || cookie.Value == "" {
+		t.Fatal("register did not set the token cookie")
+	}
+	if !cookie.HttpOnly || cookie.SameSite != http.SameSiteStrictMode {
+		t.Errorf("cookie = %+v, want HttpOnly and SameSite=Strict", cookie)
+	}
+
+	send := func(method, path string, csrf bool) int {
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(`{"name":"P"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(cookie)
+		if csrf {
+			req.Header.Set(middleware.CSRFHeader, "XMLHttpRequest")
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := send(http.MethodGet, "/api/auth/me", false); code != http.StatusOK {
+		t.Errorf("GET with cookie: status = %d, want 200", code)
+	}
+	if code := send(http.MethodPost, "/api/projects", false); code != http.StatusForbidden {
+		t.Errorf("POST without %s: status = %d, want 403", middleware.CSRFHeader, code)
+	}
+	if code := send(http.MethodPost, "/api/projects", true); code != http.StatusCreated {
+		t.Errorf("POST with %s: status = %d, want 201", middleware.CSRFHeader, code)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/auth/logout", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("logout: status = %d", rec.Code)
+	}
+	if cookies := rec.Result().Cookies(); len(cookies) != 1 || cookies[0].MaxAge >= 0 {
+		t.Errorf("logout cookies = %+v, want the token cookie expired", cookies)
+	}
+
+	// Header auth stays the only option when cookie mode is off.
+	headerOnly := setupTestRouterWithConfig(t, &config.Config{})
+	registerUser(t, headerOnly, "alice", "alice@example.com", "password123")
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	headerOnly.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("cookie with cookie auth off: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestValidateToken(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/v1/auth/validate", token, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct {
+		Valid     bool      `json:"valid"`
+		UserID    int64     `json:"user_id"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if !resp.Valid || resp.UserID == 0 {
+		t.Errorf("response = %+v, want valid with a user id", resp)
+	}
+	if want := time.Now().Add(middleware.TokenLifetime); resp.ExpiresAt.Sub(want).Abs() > time.Minute {
+		t.Errorf("expires_at = %v, want about %v", resp.ExpiresAt, want)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/v1/auth/validate", token+"x", ""))
+	if rec.Code != http.StatusUnauthorized {
+		t.Errorf("bad token: status = %d, want 401", rec.Code)
+	}
+}
+
+func TestUpdateMe(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	tests := []struct {
+		body     string
+		wantCode int
+	}{
+		{`{"timezone":"Europe/Berlin","locale":"de-DE"}`, http.StatusOK},
+		{`{"timezone":"Mars/Olympus"}`, http.StatusBadRequest},
+		{`{"timezone":"Local"}`, http.StatusBadRequest},
+		{`{"locale":"not a locale"}`, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest(http.MethodPut, "/api/auth/me", token, tt.body))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.body, rec.Code, tt.wantCode)
+		}
+	}
+
+	// Rejected updates leave the stored preferences alone.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest(http.MethodGet, "/api/auth/me", token, ""))
+	var me model.User
+	json.NewDecoder(rec.Body).Decode(&me)
+	if me.Timezone != "Europe/Berlin" || me.Locale != "de-DE" {
+		t.Errorf("timezone, locale = %q, %q; want Europe/Berlin, de-DE", me.Timezone, me.Locale)
+	}
+}
+
+func TestMeta(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{
+		JWTSecret:       "do-not-leak",
+		AuthCookie:      true,
+		MaintenanceMode: true,
+		TodoMaxTitle:    100,
+	})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/meta", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.Contains(cc, "max-age") {
+		t.Errorf("Cache-Control = %q, want a max-age", cc)
+	}
+	if strings.Contains(rec.Body.String(), "do-not-leak") {
+		t.Error("response includes the JWT secret")
+	}
+
+	var meta struct {
+		ServerTime  time.Time `json:"server_time"`
+		Maintenance bool      `json:"maintenance"`
+		Features    struct {
+			CookieAuth   bool `json:"cookie_auth"`
+			TodoMaxTitle int  `json:"todo_max_title_length"`
+		} `json:"features"`
+	}
+	json.NewDecoder(rec.Body).Decode(&meta)
+	if d := time.Since(meta.ServerTime); d < -time.Minute || d > time.Minute {
+		t.Errorf("server_time = %v, want about now", meta.ServerTime)
+	}
+	if !meta.Maintenance || !meta.Features.CookieAuth || meta.Features.TodoMaxTitle != 100 {
+		t.Errorf("meta = %+v", meta)
+	}
+}
+
+func TestAPIVersionPrefix(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	tests := []struct {
+		path           string
+		wantDeprecated bool
+	}{
+		{"/api/v1/auth/me", false},
+		{"/api/auth/me", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest(http.MethodGet, tt.path, token, ""))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, want 200", rec.Code)
+			}
+			if got := rec.Header().Get("Deprecation") == "true"; got != tt.wantDeprecated {
+				t.Errorf("deprecated = %v, want %v", got, tt.wantDeprecated)
+			}
+		})
+	}
+}
+
+func TestAPISecurityHeaders(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.Config
+		want bool
+	}{
+		{"off", config.Config{}, false},
+		{"frontend only", config.Config{SecurityHeaders: true, ContentSecurityPolicy: "default-src 'self'"}, false},
+		{"api too", config.Config{SecurityHeaders: true, SecurityHeadersAPI: true, ContentSecurityPolicy: "default-src 'self'"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := setupTestRouterWithConfig(t, &tt.cfg)
+			token := registerUser(t, router, "alice", "alice@example.com", "password123")
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest(http.MethodGet, "/api/v1/auth/me", token, ""))
+			got := rec.Header().Get("Content-Security-Policy") == "default-src 'self'" &&
+				rec.Header().Get("X-Content-Type-Options") == "nosniff"
+			if got != tt.want {
+				t.Errorf("security headers = %v, want %v (headers %v)", got, tt.want, rec.Header())
+			}
+		})
+	}
+}
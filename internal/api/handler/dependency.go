@@ -0,0 +1,267 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/api/response"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+type addDependencyRequest struct {
+	DependsOnID int64 `json:"depends_on_id"`
+}
+
+// ListDependencies returns the todos a todo directly depends on (members
+// only).
+func (h *Todo) ListDependencies(w http.ResponseWriter, r *http.Request) {
+	todo, role, ok := h.loadTodoWithRole(w, r)
+	if !ok {
+		return
+	}
+	if role == "" {
+		writeError(w, http.StatusForbidden, "you do not have access to this todo")
+		return
+	}
+
+	deps, err := h.store.ListTodoDependencies(r.Context(), todo.ID)
+	if err != nil {
+		writeServerError(w, err, "failed to list dependencies")
+		return
+	}
+	writeJSON(w, http.StatusOK, deps)
+}
+
+// todoGraph is a project's todos and the dependencies between them, shaped
+// for drawing. HasCycle is set if any todo is flagged InCycle.
+type todoGraph struct {
+	Nodes    []graphNode            `json:"nodes"`
+	Edges    []model.TodoDependency `json:"edges"`
+	HasCycle bool                   `json:"has_cycle"`
+}
+
+type graphNode struct {
+	ID       int64  `json:"id"`
+	Title    string `json:"title"`
+	Status   string `json:"status"`
+	Priority string `json:"priority"`
+	InCycle  bool   `json:"in_cycle"`
+}
+
+// Graph returns a project's todos as nodes and their dependencies as edges
+// from a todo to the todo it depends on (members only). Adding dependencies
+// rejects cycles, but any that exist anyway, such as from older data, are
+// flagged rather than followed.
+func (h *Todo) Graph(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+	if !h.requireProject(w, r, projectID) {
+		return
+	}
+	isMember, err := h.store.IsProjectMember(r.Context(), projectID, middleware.GetUserID(r.Context()))
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, "you do not have access to this project")
+		return
+	}
+
+	todos, err := h.store.ListTodosByProject(r.Context(), projectID, store.TodoListParams{})
+	if err != nil {
+		writeServerError(w, err, "failed to list todos")
+		return
+	}
+	edges, err := h.store.ListProjectDependencies(r.Context(), projectID)
+	if err != nil {
+		writeServerError(w, err, "failed to list dependencies")
+		return
+	}
+
+	cyclic := cyclicTodos(edges)
+	graph := todoGraph{Nodes: make([]graphNode, len(todos)), Edges: edges, HasCycle: len(cyclic) > 0}
+	for i, t := range todos {
+		graph.Nodes[i] = graphNode{ID: t.ID, Title: t.Title, Status: t.Status, Priority: t.Priority, InCycle: cyclic[t.ID]}
+	}
+	writeJSON(w, http.StatusOK, graph)
+}
+
+// cyclicTodos returns the todos that lie on a dependency cycle. It finds the
+// strongly connected components with Tarjan's algorithm, visiting each todo
+// and edge once: a todo is on a cycle if its component has more than one
+// todo or it depends on itself.
+func cyclicTodos(edges []model.TodoDependency) map[int64]bool {
+	next := map[int64][]int64{}
+	for _, e := range edges {
+		next[e.TodoID] = append(next[e.TodoID], e.DependsOnID)
+	}
+
+	cyclic := map[int64]bool{}
+	index := map[int64]int{}
+	low := map[int64]int{}
+	onStack := map[int64]bool{}
+	var stack []int64
+	var visit func(id int64)
+	visit = func(id int64) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, dep := range next[id] {
+			if _, seen := index[dep]; !seen {
+				visit(dep)
+				low[id] = min(low[id], low[dep])
+			} else if onStack[dep] {
+				low[id] = min(low[id], index[dep])
+			}
+			if dep == id {
+				cyclic[id] = true
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+		// id is the root of a component; pop it off the stack.
+		i := len(stack) - 1
+		for stack[i] != id {
+			i--
+		}
+		if len(stack)-i > 1 {
+			for _, member := range stack[i:] {
+				cyclic[member] = true
+			}
+		}
+		for _, member := range stack[i:] {
+			onStack[member] = false
+		}
+		stack = stack[:i]
+	}
+	for _, e := range edges {
+		if _, seen := index[e.TodoID]; !seen {
+			visit(e.TodoID)
+		}
+	}
+	return cyclic
+}
+
+// AddDependency records that a todo depends on another todo in the same
+// project (owner or editor only). It returns 409 if the dependency would
+// create a cycle.
+func (h *Todo) AddDependency(w http.ResponseWriter, r *http.Request) {
+	todo, role, ok := h.loadTodoWithRole(w, r)
+	if !ok {
+		return
+	}
+	if !canEditDependencies(w, role) {
+		return
+	}
+
+	var req addDependencyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	v := validation{}
+	v.check(req.DependsOnID != 0, "depends_on_id", "depends_on_id is required")
+	v.check(req.DependsOnID != todo.ID, "depends_on_id", "a todo cannot depend on itself")
+	if v.write(w) {
+		return
+	}
+
+	dep, err := h.store.GetTodo(r.Context(), req.DependsOnID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "dependency not found")
+			return
+		}
+		writeServerError(w, err, "failed to get todo")
+		return
+	}
+	if dep.ProjectID != todo.ProjectID {
+		v.check(false, "depends_on_id", "dependency must be in the same project")
+		v.write(w)
+		return
+	}
+
+	if err := h.store.AddTodoDependency(r.Context(), todo.ID, dep.ID); err != nil {
+		if errors.Is(err, store.ErrDependencyCycle) {
+			writeError(w, http.StatusConflict, "dependency would create a cycle")
+			return
+		}
+		writeServerError(w, err, "failed to add dependency")
+		return
+	}
+	writeJSON(w, http.StatusCreated, model.TodoDependency{TodoID: todo.ID, DependsOnID: dep.ID})
+}
+
+// RemoveDependency deletes a dependency (owner or editor only). Removing a
+// dependency that does not exist succeeds.
+func (h *Todo) RemoveDependency(w http.ResponseWriter, r *http.Request) {
+	dependsOnID, err := strconv.ParseInt(chi.URLParam(r, "dependsOnID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid dependency id")
+		return
+	}
+	todo, role, ok := h.loadTodoWithRole(w, r)
+	if !ok {
+		return
+	}
+	if !canEditDependencies(w, role) {
+		return
+	}
+
+	if err := h.store.RemoveTodoDependency(r.Context(), todo.ID, dependsOnID); err != nil {
+		writeServerError(w, err, "failed to remove dependency")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// loadTodoWithRole loads the todo named in the URL and the caller's role in
+// its project, which is empty for non-members.
+func (h *Todo) loadTodoWithRole(w http.ResponseWriter, r *http.Request) (*model.Todo, string, bool) {
+	todoID, err := strconv.ParseInt(chi.URLParam(r, "todoID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid todo id")
+		return nil, "", false
+	}
+
+	todo, err := h.store.GetTodo(r.Context(), todoID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeTodoNotFound, "todo not found")
+			return nil, "", false
+		}
+		writeServerError(w, err, "failed to get todo")
+		return nil, "", false
+	}
+
+	role, err := h.store.GetMemberRole(r.Context(), todo.ProjectID, middleware.GetUserID(r.Context()))
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return nil, "", false
+	}
+	return todo, role, true
+}
+
+func canEditDependencies(w http.ResponseWriter, role string) bool {
+	switch role {
+	case "":
+		writeError(w, http.StatusForbidden, "you do not have access to this todo")
+		return false
+	case "viewer":
+		writeError(w, http.StatusForbidden, "viewers cannot edit todos")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/walidabualafia/bloom/internal/model"
+)
+
+func TestCyclicTodos(t *testing.T) {
+	tests := []struct {
+		name  string
+		edges [][2]int64
+		want  []int64
+	}{
+		{"none", nil, nil},
+		{"chain", [][2]int64{{1, 2}, {2, 3}}, nil},
+		{"diamond", [][2]int64{{1, 2}, {1, 3}, {2, 4}, {3, 4}}, nil},
+		{"self", [][2]int64{{1, 1}, {1, 2}}, []int64{1}},
+		{"pair", [][2]int64{{1, 2}, {2, 1}, {2, 3}}, []int64{1, 2}},
+		{"loop with tail", [][2]int64{{1, 2}, {2, 3}, {3, 4}, {4, 2}, {5, 1}}, []int64{2, 3, 4}},
+		{"two loops", [][2]int64{{1, 2}, {2, 1}, {3, 4}, {4, 3}, {2, 3}}, []int64{1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		edges := make([]model.TodoDependency, len(tt.edges))
+		for i, e := range tt.edges {
+			edges[i] = model.TodoDependency{TodoID: e[0], DependsOnID: e[1]}
+		}
+		got := slices.Sorted(maps.Keys(cyclicTodos(edges)))
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%s: cyclic = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
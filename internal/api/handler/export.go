@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/api/response"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+// Export returns all of the caller's data as a downloadable JSON bundle.
+func (h *User) Export(w http.ResponseWriter, r *http.Request) {
+	h.writeExport(w, r, middleware.GetUserID(r.Context()))
+}
+
+// AdminExport returns all of a user's data as a downloadable JSON bundle
+// (admin only).
+func (h *User) AdminExport(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	h.writeExport(w, r, userID)
+}
+
+// writeExport streams the export bundle for userID:
+//
+//	{"version": 1, "exported_at": ..., "user": {...}, "projects": [...], "memberships": [...], "todos": [...]}
+//
+// version is model.ExportVersion. projects holds the projects the user owns
+// and todos the todos within them; see Project.Import for loading them into
+// another instance.
+//
+// Todos are written project by project so the whole bundle is never held in
+// memory. Once streaming has started, errors can no longer change the status
+// code, so they are logged and the response is cut short. If the client goes
+// away, streaming stops at the next todo without querying further projects.
+func (h *User) writeExport(w http.ResponseWriter, r *http.Request, userID int64) {
+	ctx := r.Context()
+
+	user, err := h.store.GetUserByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeUserNotFound, "user not found")
+			return
+		}
+		writeServerError(w, err, "failed to get user")
+		return
+	}
+
+	projects, err := h.store.ListProjectsByUser(ctx, userID)
+	if err != nil {
+		writeServerError(w, err, "failed to list projects")
+		return
+	}
+	owned := []model.Project{}
+	for _, p := range projects {
+		if p.OwnerID == userID {
+			owned = append(owned, p)
+		}
+	}
+
+	memberships, err := h.store.ListMembershipsByUser(ctx, userID)
+	if err != nil {
+		writeServerError(w, err, "failed to list memberships")
+		return
+	}
+
+	filename := fmt.Sprintf("bloom-export-%s-%s.json", user.Username, time.Now().UTC().Format("20060102"))
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		// The bundle is streamed, so there is no Content-Length to report;
+		// skip building it.
+		return
+	}
+
+	enc := json.NewEncoder(w)
+	write := func(s string) bool {
+		_, err := w.Write([]byte(s))
+		return err == nil
+	}
+	encode := func(v any) bool {
+		return enc.Encode(v) == nil
+	}
+
+	ok := write(`{"version":`) && encode(model.ExportVersion) &&
+		write(`,"exported_at":`) && encode(time.Now().UTC()) &&
+		write(`,"user":`) && encode(user) &&
+		write(`,"projects":`) && encode(owned) &&
+		write(`,"memberships":`) && encode(memberships) &&
+		write(`,"todos":[`)
+	if !ok {
+		return
+	}
+
+	first := true
+	for _, p := range owned {
+		if ctx.Err() != nil {
+			return
+		}
+		todos, err := h.store.ListTodosByProject(ctx, p.ID, store.TodoListParams{})
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("export user %d: list todos for project %d: %v", userID, p.ID, err)
+			}
+			return
+		}
+		for _, t := range todos {
+			if ctx.Err() != nil {
+				return
+			}
+			if !first && !write(",") {
+				return
+			}
+			first = false
+			if !encode(t) {
+				return
+			}
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+	}
+	write("]}")
+}
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+// Caps on the size of a project import.
+const (
+	maxImportProjects = 100
+	maxImportTodos    = 10000
+)
+
+// utcTime returns a copy of t in UTC, or nil if t is nil.
+func utcTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	u := t.UTC()
+	return &u
+}
+
+// Import recreates the projects in an export bundle, such as one from
+// another bloom instance, under the caller's ownership. See
+// model.ProjectImport for the format. Everything is created in one
+// transaction; todos and members that cannot be linked to an imported
+// project or a local user are skipped and listed in the response.
+func (h *Project) Import(w http.ResponseWriter, r *http.Request) {
+	var bundle model.ProjectImport
+	if err := json.NewDecoder(r.Body).Decode(&bundle); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	v := validation{}
+	v.check(bundle.Version == model.ExportVersion, "version", fmt.Sprintf("unsupported export version; want %d", model.ExportVersion))
+	v.check(len(bundle.Projects) > 0, "projects", "at least one project is required")
+	v.check(len(bundle.Projects) <= maxImportProjects, "projects", fmt.Sprintf("an import can have at most %d projects", maxImportProjects))
+	v.check(len(bundle.Todos) <= maxImportTodos, "todos", fmt.Sprintf("an import can have at most %d todos", maxImportTodos))
+	if v.write(w) {
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	caller, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+
+	// Index the imports by the bundle's project IDs, which are only used to
+	// link todos and members and are never written.
+	imports := make([]store.ProjectImport, len(bundle.Projects))
+	byID := make(map[int64]*store.ProjectImport, len(bundle.Projects))
+	for i, p := range bundle.Projects {
+		field := fmt.Sprintf("projects[%d]", i)
+		_, dup := byID[p.ID]
+		v.check(!dup, field, "duplicate project id")
+		v.check(p.Name != "", field, "name is required")
+		v.check(p.Color == "" || model.ValidColor(p.Color), field, colorFormatError)
+		v.check(model.ValidStatusList(p.Statuses), field, statusesError)
+		v.check(p.DefaultMemberRole == "" || model.ValidAssignableRole(p.DefaultMemberRole), field, roleError)
+		imports[i].Project = &model.Project{
+			Name:              p.Name,
+			Description:       p.Description,
+			Color:             p.Color,
+			IsTemplate:        p.IsTemplate,
+			Statuses:          statusList((*[]string)(&p.Statuses)),
+			HideCompleted:     p.HideCompleted,
+			DefaultMemberRole: p.DefaultMemberRole,
+			EndDate:           utcTime(p.EndDate),
+			OwnerID:           userID,
+		}
+		byID[p.ID] = &imports[i]
+	}
+
+	result := model.ImportResult{Skipped: []model.ImportSkip{}}
+	skip := func(kind, ref, reason string) {
+		result.Skipped = append(result.Skipped, model.ImportSkip{Kind: kind, Ref: ref, Reason: reason})
+	}
+
+	for i, t := range bundle.Todos {
+		imp, ok := byID[t.ProjectID]
+		if !ok {
+			skip("todo", formatID(t.ID), fmt.Sprintf("project %d is not in the import", t.ProjectID))
+			continue
+		}
+		if h.maxTodos > 0 && len(imp.Todos) >= h.maxTodos {
+			skip("todo", formatID(t.ID), fmt.Sprintf("a project can have at most %s", countNoun(h.maxTodos, "todo")))
+			continue
+		}
+		if t.Status == "" {
+			t.Status = model.StatusPending
+		}
+		if t.Priority == "" {
+			t.Priority = model.PriorityMedium
+		}
+		field := fmt.Sprintf("todos[%d]", i)
+		v.check(t.Title != "", field, "title is required")
+		v.check(utf8.RuneCountInString(t.Title) <= defaultMaxTitle, field, fmt.Sprintf("title must be at most %d characters", defaultMaxTitle))
+		v.check(utf8.RuneCountInString(t.Description) <= defaultMaxDescription, field,
+			fmt.Sprintf("description must be at most %d characters", defaultMaxDescription))
+		v.check(imp.Project.AllowsStatus(t.Status), field, statusError(imp.Project))
+		v.check(model.ValidPriority(t.Priority), field, "priority must be 'low', 'medium', or 'high'")
+		v.check(t.PriorityRank == nil || model.ValidPriorityRank(*t.PriorityRank), field, priorityRankError)
+		v.check(t.ReminderOffset == nil || (*t.ReminderOffset >= 0 && *t.ReminderOffset <= model.Duration(maxReminderOffset)),
+			field, reminderOffsetError)
+		v.check(t.Estimate == nil || model.ValidEstimate(*t.Estimate), field, estimateError)
+		v.check(t.Deadline == nil || imp.Project.AllowsDeadline(*t.Deadline), field, "deadline must not be after the project's end date")
+		// Copy only the todo's own content: ids, authorship, assignees and
+		// timestamps belong to the source instance.
+		imp.Todos = append(imp.Todos, model.Todo{
+			Title:          t.Title,
+			Description:    t.Description,
+			Status:         t.Status,
+			Priority:       t.Priority,
+			PriorityRank:   t.PriorityRank,
+			Deadline:       utcTime(t.Deadline),
+			ReminderOffset: t.ReminderOffset,
+			Estimate:       t.Estimate,
+		})
+	}
+
+	for i, m := range bundle.Members {
+		v.check(model.ValidAssignableRole(m.Role), fmt.Sprintf("members[%d]", i), roleError)
+	}
+	if v.write(w) {
+		return
+	}
+	if !h.checkProjectLimit(w, r, userID, len(imports)) {
+		return
+	}
+
+	type membership struct{ projectID, userID int64 }
+	added := map[membership]bool{}
+	for _, m := range bundle.Members {
+		imp, ok := byID[m.ProjectID]
+		if !ok {
+			skip("member", m.Email, fmt.Sprintf("project %d is not in the import", m.ProjectID))
+			continue
+		}
+		if strings.EqualFold(m.Email, caller.Email) {
+			skip("member", m.Email, "you own the imported project")
+			continue
+		}
+		user, err := h.store.GetUserByEmail(r.Context(), m.Email)
+		if err != nil {
+			if errors.Is(err, store.ErrNotFound) {
+				skip("member", m.Email, "no user with this email")
+				continue
+			}
+			writeServerError(w, err, "internal server error")
+			return
+		}
+		if added[membership{m.ProjectID, user.ID}] {
+			skip("member", m.Email, "already listed for this project")
+			continue
+		}
+		if h.maxMembers > 0 && len(imp.Members) >= h.maxMembers {
+			skip("member", m.Email, fmt.Sprintf("a project can have at most %s", countNoun(h.maxMembers, "member")))
+			continue
+		}
+		added[membership{m.ProjectID, user.ID}] = true
+		imp.Members = append(imp.Members, model.ProjectMember{UserID: user.ID, Role: m.Role})
+	}
+
+	if err := h.store.ImportProjects(r.Context(), imports); err != nil {
+		writeServerError(w, err, "failed to import projects")
+		return
+	}
+
+	result.Projects = make([]model.Project, len(imports))
+	for i, imp := range imports {
+		result.Projects[i] = *imp.Project
+		result.Todos += len(imp.Todos)
+		result.Members += len(imp.Members)
+	}
+	writeJSON(w, http.StatusCreated, result)
+}
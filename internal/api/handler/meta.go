@@ -0,0 +1,64 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/featureflag"
+)
+
+// metaMaxAge is how long clients and proxies may cache GET /meta, in
+// seconds. The flags rarely change and a minute of staleness in
+// server_time is harmless for day-granularity deadline maths.
+const metaMaxAge = "60"
+
+// Meta serves public information about the server that clients use to adapt
+// their UI.
+type Meta struct {
+	features    MetaFeatures
+	maintenance *middleware.Maintenance
+	flags       *featureflag.Flags
+}
+
+// MetaFeatures is the curated set of configuration exposed by GET /meta.
+// Only add settings that are safe for anyone to see; never secrets, URLs or
+// anything that helps fingerprint the deployment.
+type MetaFeatures struct {
+	RegistrationEnabled         bool `json:"registration_enabled"`
+	CookieAuth                  bool `json:"cookie_auth"`
+	BlockIncompleteDependencies bool `json:"block_incomplete_dependencies"`
+	Reminders                   bool `json:"reminders"`
+	MaxProjectsPerUser          int  `json:"max_projects_per_user"` // 0 means unlimited
+	MaxProjectMembers           int  `json:"max_project_members"`   // 0 means unlimited
+	MaxTodosPerProject          int  `json:"max_todos_per_project"` // 0 means unlimited
+	TodoMaxTitle                int  `json:"todo_max_title_length"`
+	TodoMaxDescription          int  `json:"todo_max_description_length"`
+	MaxPageSize                 int  `json:"max_page_size"`
+	DemoMode                    bool `json:"demo_mode"` // data is wiped periodically
+}
+
+type metaResponse struct {
+	ServerTime  time.Time    `json:"server_time"`
+	Maintenance bool         `json:"maintenance"`
+	Features    MetaFeatures `json:"features"`
+	// Flags are the public feature flags; see featureflag.Flags.Public.
+	Flags map[string]bool `json:"flags"`
+}
+
+// NewMeta creates a Meta handler.
+func NewMeta(features MetaFeatures, maintenance *middleware.Maintenance, flags *featureflag.Flags) *Meta {
+	return &Meta{features: features, maintenance: maintenance, flags: flags}
+}
+
+// Get returns the server's current UTC time, whether maintenance mode is on,
+// the enabled features and limits, and the public feature flags.
+func (h *Meta) Get(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age="+metaMaxAge)
+	writeJSON(w, http.StatusOK, metaResponse{
+		ServerTime:  time.Now().UTC().Truncate(time.Second),
+		Maintenance: h.maintenance.Enabled(),
+		Features:    h.features,
+		Flags:       h.flags.Public(r.Context()),
+	})
+}
@@ -0,0 +1,22 @@
+package handler
+
+import (
+	_ "embed"
+	"net/http"
+	"strconv"
+)
+
+// openAPISpec is the OpenAPI 3 description of the API. It is maintained by
+// hand; TestOpenAPICoversRoutes fails when it and the router disagree.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// OpenAPI serves the API's OpenAPI 3 document, for client generators and
+// API explorers.
+func OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(openAPISpec)))
+	w.Header().Set("Cache-Control", "public, max-age="+metaMaxAge)
+	w.Write(openAPISpec) //nolint:errcheck
+}
@@ -1,32 +1,97 @@
 package handler
 
 import (
-	"database/sql"
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/api/response"
+	"github.com/walidabualafia/bloom/internal/markdown"
 	"github.com/walidabualafia/bloom/internal/model"
 	"github.com/walidabualafia/bloom/internal/store"
 )
 
 // Project handles project CRUD and member management.
 type Project struct {
-	store store.Store
+	store       store.Store
+	maxProjects int // per non-admin owner; 0 means unlimited
+	maxMembers  int // per project, excluding the owner; 0 means unlimited
+	maxTodos    int // per project; 0 means unlimited
+	// allowPrivateWebhooks accepts webhook URLs on loopback and private
+	// hosts.
+	allowPrivateWebhooks bool
 }
 
-// NewProject creates a new Project handler.
-func NewProject(s store.Store) *Project {
-	return &Project{store: s}
+// NewProject creates a new Project handler. maxProjects caps how many
+// projects a non-admin user may own, maxMembers how many members a project
+// may have and maxTodos how many todos an imported project may start with;
+// 0 disables any of the caps. allowPrivateWebhooks lets webhooks target
+// the server's own network.
+func NewProject(s store.Store, maxProjects, maxMembers, maxTodos int, allowPrivateWebhooks bool) *Project {
+	return &Project{
+		store:                s,
+		maxProjects:          maxProjects,
+		maxMembers:           maxMembers,
+		maxTodos:             maxTodos,
+		allowPrivateWebhooks: allowPrivateWebhooks,
+	}
 }
 
 type createProjectRequest struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
+	Name              string             `json:"name"`
+	Description       string             `json:"description"`
+	Color             *string            `json:"color"`
+	IsTemplate        *bool              `json:"is_template"`
+	Statuses          optional[[]string] `json:"statuses"` // null or [] means the default workflow
+	HideCompleted     *bool              `json:"hide_completed"`
+	DefaultMemberRole *string            `json:"default_member_role"`
+	EndDate           optional[string]   `json:"end_date"` // RFC3339; null or "" means none
+}
+
+type fromTemplateRequest struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+	Color       *string `json:"color"`
+}
+
+const (
+	colorFormatError = "color must be a hex code like #RRGGBB"
+	roleError        = "role must be 'viewer' or 'editor'"
+	endDateError     = "end_date must be in RFC3339 format"
+)
+
+var statusesError = fmt.Sprintf("statuses must be at most %d distinct lowercase names (letters, digits, _) "+
+	"including 'pending' and 'completed'", model.MaxProjectStatuses)
+
+// parseEndDate parses an optional RFC3339 end date into UTC, recording an
+// error in v if it is malformed. Nil or "" means no end date.
+func parseEndDate(v validation, s *string) *time.Time {
+	if s == nil || *s == "" {
+		return nil
+	}
+	t, err := time.Parse(time.RFC3339, *s)
+	if err != nil {
+		v.check(false, "end_date", endDateError)
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+// projectResponse adds the rendered description for ?render=html.
+type projectResponse struct {
+	*model.Project
+	DescriptionHTML string `json:"description_html,omitempty"`
 }
 
 type addMemberRequest struct {
@@ -39,12 +104,9 @@ func (h *Project) List(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 	projects, err := h.store.ListProjectsByUser(r.Context(), userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list projects")
+		writeServerError(w, err, "failed to list projects")
 		return
 	}
-	if projects == nil {
-		projects = []model.Project{}
-	}
 	writeJSON(w, http.StatusOK, projects)
 }
 
@@ -55,24 +117,190 @@ func (h *Project) Create(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	if req.Name == "" {
-		writeError(w, http.StatusBadRequest, "name is required")
+	v := validation{}
+	v.check(req.Name != "", "name", "name is required")
+	v.check(req.Color == nil || model.ValidColor(*req.Color), "color", colorFormatError)
+	v.check(req.Statuses.Value == nil || model.ValidStatusList(*req.Statuses.Value), "statuses", statusesError)
+	v.check(req.DefaultMemberRole == nil || model.ValidAssignableRole(*req.DefaultMemberRole), "default_member_role", roleError)
+	endDate := parseEndDate(v, req.EndDate.Value)
+	if v.write(w) {
 		return
 	}
 
 	userID := middleware.GetUserID(r.Context())
+	if !h.checkProjectLimit(w, r, userID, 1) {
+		return
+	}
+
 	project := &model.Project{
 		Name:        req.Name,
 		Description: req.Description,
 		OwnerID:     userID,
+		Statuses:    statusList(req.Statuses.Value),
+		EndDate:     endDate,
+	}
+	if req.Color != nil {
+		project.Color = *req.Color
+	}
+	if req.IsTemplate != nil {
+		project.IsTemplate = *req.IsTemplate
+	}
+	if req.HideCompleted != nil {
+		project.HideCompleted = *req.HideCompleted
+	}
+	if req.DefaultMemberRole != nil {
+		project.DefaultMemberRole = *req.DefaultMemberRole
 	}
 
 	if err := h.store.CreateProject(r.Context(), project); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create project")
+		writeServerError(w, err, "failed to create project")
+		return
+	}
+
+	writeCreated(w, resourceURL("projects", project.ID), project)
+}
+
+// ListTemplates returns the template projects accessible to the user.
+func (h *Project) ListTemplates(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	templates, err := h.store.ListTemplatesByUser(r.Context(), userID)
+	if err != nil {
+		writeServerError(w, err, "failed to list templates")
+		return
+	}
+	writeJSON(w, http.StatusOK, templates)
+}
+
+// CreateFromTemplate creates a project owned by the user with a copy of a
+// template's todos. Any member of the template may use it. Name,
+// description and color default to the template's.
+func (h *Project) CreateFromTemplate(w http.ResponseWriter, r *http.Request) {
+	templateID, err := strconv.ParseInt(chi.URLParam(r, "templateID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid template id")
+		return
+	}
+
+	var req fromTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	v := validation{}
+	v.check(req.Color == nil || model.ValidColor(*req.Color), "color", colorFormatError)
+	if v.write(w) {
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	isMember, err := h.store.IsProjectMember(r.Context(), templateID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, "you do not have access to this template")
+		return
+	}
+
+	template, err := h.store.GetProject(r.Context(), templateID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "template not found")
+			return
+		}
+		writeServerError(w, err, "failed to get template")
+		return
+	}
+	if !template.IsTemplate {
+		writeError(w, http.StatusBadRequest, "project is not a template")
+		return
+	}
+
+	if !h.checkProjectLimit(w, r, userID, 1) {
+		return
+	}
+
+	project := &model.Project{
+		Name:              template.Name,
+		Description:       template.Description,
+		Color:             template.Color,
+		Statuses:          template.Statuses,
+		HideCompleted:     template.HideCompleted,
+		DefaultMemberRole: template.DefaultMemberRole,
+		OwnerID:           userID,
+	}
+	if req.Name != "" {
+		project.Name = req.Name
+	}
+	if req.Description != nil {
+		project.Description = *req.Description
+	}
+	if req.Color != nil {
+		project.Color = *req.Color
+	}
+
+	if err := h.store.CreateProjectFromTemplate(r.Context(), templateID, project); err != nil {
+		writeServerError(w, err, "failed to create project")
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, project)
+	writeCreated(w, resourceURL("projects", project.ID), project)
+}
+
+// statusList converts a requested workflow to a model.StatusList, mapping
+// null and [] to nil so the project uses the defaults.
+func statusList(statuses *[]string) model.StatusList {
+	if statuses == nil || len(*statuses) == 0 {
+		return nil
+	}
+	return model.StatusList(*statuses)
+}
+
+// checkProjectLimit reports whether the user may own n more projects,
+// writing a 403 if that would take a non-admin past the configured cap.
+func (h *Project) checkProjectLimit(w http.ResponseWriter, r *http.Request, userID int64, n int) bool {
+	if h.maxProjects <= 0 {
+		return true
+	}
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return false
+	}
+	if user.IsAdmin {
+		return true
+	}
+	count, err := h.store.CountProjectsByOwner(r.Context(), userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return false
+	}
+	if count+n > h.maxProjects {
+		writeError(w, http.StatusForbidden, fmt.Sprintf("project limit reached: you can own at most %s", countNoun(h.maxProjects, "project")))
+		return false
+	}
+	return true
+}
+
+// checkMemberLimit reports whether n more members fit in the project,
+// writing a 409 if they would take it past the configured cap. Pending
+// invites hold a place, so redeeming one never exceeds the cap.
+func (h *Project) checkMemberLimit(w http.ResponseWriter, r *http.Request, projectID int64, n int) bool {
+	if h.maxMembers <= 0 {
+		return true
+	}
+	count, err := h.store.CountProjectMembers(r.Context(), projectID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return false
+	}
+	if count+n > h.maxMembers {
+		writeError(w, http.StatusConflict, fmt.Sprintf("member limit reached: a project can have at most %s, including pending invites",
+			countNoun(h.maxMembers, "member")))
+		return false
+	}
+	return true
 }
 
 // Get returns a single project by ID (must be a member).
@@ -82,11 +310,16 @@ func (h *Project) Get(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid project id")
 		return
 	}
+	renderHTML, err := wantHTML(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	userID := middleware.GetUserID(r.Context())
 	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return
 	}
 	if !isMember {
@@ -96,15 +329,120 @@ func (h *Project) Get(w http.ResponseWriter, r *http.Request) {
 
 	project, err := h.store.GetProject(r.Context(), projectID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeError(w, http.StatusNotFound, "project not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "failed to get project")
+		writeServerError(w, err, "failed to get project")
 		return
 	}
 
-	writeJSON(w, http.StatusOK, project)
+	resp := projectResponse{Project: project}
+	if renderHTML {
+		resp.DescriptionHTML = markdown.Render(project.Description)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// Favorite stars a project for the current user (must be a member).
+func (h *Project) Favorite(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, true)
+}
+
+// Unfavorite removes the current user's star from a project.
+func (h *Project) Unfavorite(w http.ResponseWriter, r *http.Request) {
+	h.setFavorite(w, r, false)
+}
+
+func (h *Project) setFavorite(w http.ResponseWriter, r *http.Request, favorite bool) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, "you do not have access to this project")
+		return
+	}
+
+	if err := h.store.SetProjectFavorite(r.Context(), userID, projectID, favorite); err != nil {
+		writeServerError(w, err, "failed to update favorite")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// maxRoleLookup caps how many projects one Roles request may ask about.
+const maxRoleLookup = 100
+
+type rolesRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// Roles returns the current user's role in each requested project as a map
+// from project id to role. Projects the user cannot access are left out.
+func (h *Project) Roles(w http.ResponseWriter, r *http.Request) {
+	var req rolesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.IDs) > maxRoleLookup {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d projects can be looked up at once", maxRoleLookup))
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	roles, err := h.store.GetMemberRoles(r.Context(), userID, req.IDs)
+	if err != nil {
+		writeServerError(w, err, "failed to get roles")
+		return
+	}
+	writeJSON(w, http.StatusOK, roles)
+}
+
+// maxProjectReorder caps how many projects one Reorder request may list.
+const maxProjectReorder = 1000
+
+type reorderRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// Reorder saves the current user's preferred project order: the listed
+// projects are shown first, in that order, and the rest after them, most
+// recently updated first. Favorites stay on top either way. Projects the
+// user cannot access are ignored.
+func (h *Project) Reorder(w http.ResponseWriter, r *http.Request) {
+	var req reorderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	v := validation{}
+	v.check(len(req.IDs) <= maxProjectReorder, "ids", fmt.Sprintf("at most %d projects can be ordered", maxProjectReorder))
+	seen := make(map[int64]bool, len(req.IDs))
+	for _, id := range req.IDs {
+		v.check(!seen[id], "ids", fmt.Sprintf("project %d is listed more than once", id))
+		seen[id] = true
+	}
+	if v.write(w) {
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if err := h.store.SetProjectOrder(r.Context(), userID, req.IDs); err != nil {
+		writeServerError(w, err, "failed to reorder projects")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // GetRole returns the current user's role in a project.
@@ -118,7 +456,7 @@ func (h *Project) GetRole(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 	role, err := h.store.GetMemberRole(r.Context(), projectID, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return
 	}
 	if role == "" {
@@ -129,6 +467,38 @@ func (h *Project) GetRole(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]string{"role": role})
 }
 
+// Stats returns todo counts and effort estimate totals for a project (must
+// be a member). See store.ProjectStats.
+func (h *Project) Stats(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, "you do not have access to this project")
+		return
+	}
+
+	stats, err := h.store.GetProjectStats(r.Context(), projectID)
+	if err != nil {
+		writeServerError(w, err, "failed to get project stats")
+		return
+	}
+	if stats.TodosByPriority, err = h.store.CountTodosByPriority(r.Context(), projectID); err != nil {
+		writeServerError(w, err, "failed to get project stats")
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
 // Update modifies a project (owner only).
 func (h *Project) Update(w http.ResponseWriter, r *http.Request) {
 	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
@@ -139,11 +509,11 @@ func (h *Project) Update(w http.ResponseWriter, r *http.Request) {
 
 	project, err := h.store.GetProject(r.Context(), projectID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeError(w, http.StatusNotFound, "project not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "failed to get project")
+		writeServerError(w, err, "failed to get project")
 		return
 	}
 
@@ -159,13 +529,42 @@ func (h *Project) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	v := validation{}
+	v.check(req.Color == nil || model.ValidColor(*req.Color), "color", colorFormatError)
+	v.check(req.Statuses.Value == nil || model.ValidStatusList(*req.Statuses.Value), "statuses", statusesError)
+	v.check(req.DefaultMemberRole == nil || model.ValidAssignableRole(*req.DefaultMemberRole), "default_member_role", roleError)
+	endDate := parseEndDate(v, req.EndDate.Value)
+	if v.write(w) {
+		return
+	}
+
 	if req.Name != "" {
 		project.Name = req.Name
 	}
 	project.Description = req.Description
+	if req.Color != nil {
+		project.Color = *req.Color
+	}
+	if req.IsTemplate != nil {
+		project.IsTemplate = *req.IsTemplate
+	}
+	if req.HideCompleted != nil {
+		project.HideCompleted = *req.HideCompleted
+	}
+	if req.DefaultMemberRole != nil {
+		project.DefaultMemberRole = *req.DefaultMemberRole
+	}
+	// Todos keep a status the new workflow drops, or a deadline after the
+	// new end date, until they are next updated.
+	if req.Statuses.Set {
+		project.Statuses = statusList(req.Statuses.Value)
+	}
+	if req.EndDate.Set {
+		project.EndDate = endDate
+	}
 
 	if err := h.store.UpdateProject(r.Context(), project); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to update project")
+		writeServerError(w, err, "failed to update project")
 		return
 	}
 
@@ -182,11 +581,11 @@ func (h *Project) Delete(w http.ResponseWriter, r *http.Request) {
 
 	project, err := h.store.GetProject(r.Context(), projectID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeError(w, http.StatusNotFound, "project not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "failed to get project")
+		writeServerError(w, err, "failed to get project")
 		return
 	}
 
@@ -197,7 +596,7 @@ func (h *Project) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.store.DeleteProject(r.Context(), projectID); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to delete project")
+		writeServerError(w, err, "failed to delete project")
 		return
 	}
 
@@ -215,7 +614,7 @@ func (h *Project) ListMembers(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return
 	}
 	if !isMember {
@@ -225,15 +624,50 @@ func (h *Project) ListMembers(w http.ResponseWriter, r *http.Request) {
 
 	members, err := h.store.ListProjectMembers(r.Context(), projectID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list members")
+		writeServerError(w, err, "failed to list members")
 		return
 	}
-	if members == nil {
-		members = []model.ProjectMember{}
-	}
 	writeJSON(w, http.StatusOK, members)
 }
 
+// GetMember returns one member's role and username, including the owner's.
+// Any member of the project may look up any other; it returns 404 if the
+// requested user has no access.
+func (h *Project) GetMember(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+	memberID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, "you do not have access to this project")
+		return
+	}
+
+	member, err := h.store.GetProjectMember(r.Context(), projectID, memberID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "member not found")
+			return
+		}
+		writeServerError(w, err, "failed to get member")
+		return
+	}
+	writeJSON(w, http.StatusOK, member)
+}
+
 // AddMember adds a user to a project (owner only).
 func (h *Project) AddMember(w http.ResponseWriter, r *http.Request) {
 	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
@@ -244,7 +678,11 @@ func (h *Project) AddMember(w http.ResponseWriter, r *http.Request) {
 
 	project, err := h.store.GetProject(r.Context(), projectID)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "project not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
+			return
+		}
+		writeServerError(w, err, "failed to get project")
 		return
 	}
 
@@ -264,16 +702,24 @@ func (h *Project) AddMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if req.Role == "" {
-		req.Role = "viewer"
+		req.Role = project.DefaultMemberRole
 	}
-	if req.Role != "viewer" && req.Role != "editor" {
-		writeError(w, http.StatusBadRequest, "role must be 'viewer' or 'editor'")
+	if req.Role == model.RoleOwner {
+		writeError(w, http.StatusBadRequest, "ownership cannot be assigned through members; transfer the project instead")
+		return
+	}
+	if !model.ValidAssignableRole(req.Role) {
+		writeError(w, http.StatusBadRequest, roleError)
 		return
 	}
 
 	targetUser, err := h.store.GetUserByUsername(r.Context(), req.Username)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "user not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeUserNotFound, "user not found")
+			return
+		}
+		writeServerError(w, err, "failed to get user")
 		return
 	}
 
@@ -282,12 +728,24 @@ func (h *Project) AddMember(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.maxMembers > 0 {
+		role, err := h.store.GetMemberRole(r.Context(), projectID, targetUser.ID)
+		if err != nil {
+			writeServerError(w, err, "internal server error")
+			return
+		}
+		// Changing an existing member's role does not add a member.
+		if role == "" && !h.checkMemberLimit(w, r, projectID, 1) {
+			return
+		}
+	}
+
 	if err := h.store.AddProjectMember(r.Context(), projectID, targetUser.ID, req.Role); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to add member")
+		writeServerError(w, err, "failed to add member")
 		return
 	}
 
-	writeJSON(w, http.StatusCreated, model.ProjectMember{
+	writeCreated(w, resourceURL("projects", projectID, "members", targetUser.ID), model.ProjectMember{
 		ProjectID: projectID,
 		UserID:    targetUser.ID,
 		Username:  targetUser.Username,
@@ -305,7 +763,11 @@ func (h *Project) RemoveMember(w http.ResponseWriter, r *http.Request) {
 
 	project, err := h.store.GetProject(r.Context(), projectID)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "project not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
+			return
+		}
+		writeServerError(w, err, "failed to get project")
 		return
 	}
 
@@ -322,9 +784,176 @@ func (h *Project) RemoveMember(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.store.RemoveProjectMember(r.Context(), projectID, memberID); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to remove member")
+		writeServerError(w, err, "failed to remove member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type createInviteRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// CreateInvite invites someone who has not registered yet to a project
+// (owner only). The invite turns into a membership when a user registers
+// with the invited email.
+func (h *Project) CreateInvite(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+
+	project, err := h.store.GetProject(r.Context(), projectID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
+			return
+		}
+		writeServerError(w, err, "failed to get project")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if project.OwnerID != userID {
+		writeError(w, http.StatusForbidden, "only the owner can invite members")
+		return
+	}
+
+	var req createInviteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	if req.Email == "" || !strings.Contains(req.Email, "@") {
+		writeError(w, http.StatusBadRequest, "a valid email is required")
+		return
+	}
+	if req.Role == "" {
+		req.Role = project.DefaultMemberRole
+	}
+	if !model.ValidAssignableRole(req.Role) {
+		writeError(w, http.StatusBadRequest, roleError)
+		return
+	}
+
+	if !h.checkMemberLimit(w, r, projectID, 1) {
+		return
+	}
+	if _, err := h.store.GetUserByEmail(r.Context(), req.Email); err == nil {
+		writeError(w, http.StatusConflict, "a user with this email already exists; add them as a member instead")
+		return
+	} else if !errors.Is(err, store.ErrNotFound) {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+
+	token, err := newInviteToken()
+	if err != nil {
+		writeServerError(w, err, "failed to generate invite token")
+		return
+	}
+
+	invite := &model.ProjectInvite{
+		ProjectID: projectID,
+		Email:     req.Email,
+		Role:      req.Role,
+		Token:     token,
+	}
+	if err := h.store.CreateProjectInvite(r.Context(), invite); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			writeError(w, http.StatusConflict, "an invite for this email is already pending")
+			return
+		}
+		writeServerError(w, err, "failed to create invite")
+		return
+	}
+
+	writeCreated(w, resourceURL("projects", projectID, "invites", invite.ID), invite)
+}
+
+// ListInvites returns a project's pending invites (owner only).
+func (h *Project) ListInvites(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+
+	project, err := h.store.GetProject(r.Context(), projectID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
+			return
+		}
+		writeServerError(w, err, "failed to get project")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if project.OwnerID != userID {
+		writeError(w, http.StatusForbidden, "only the owner can view invites")
+		return
+	}
+
+	invites, err := h.store.ListProjectInvites(r.Context(), projectID)
+	if err != nil {
+		writeServerError(w, err, "failed to list invites")
+		return
+	}
+	writeJSON(w, http.StatusOK, invites)
+}
+
+// RevokeInvite deletes a pending invite (owner only).
+func (h *Project) RevokeInvite(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+
+	project, err := h.store.GetProject(r.Context(), projectID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
+			return
+		}
+		writeServerError(w, err, "failed to get project")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if project.OwnerID != userID {
+		writeError(w, http.StatusForbidden, "only the owner can revoke invites")
+		return
+	}
+
+	inviteID, err := strconv.ParseInt(chi.URLParam(r, "inviteID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid invite id")
+		return
+	}
+
+	if err := h.store.DeleteProjectInvite(r.Context(), projectID, inviteID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "invite not found")
+			return
+		}
+		writeServerError(w, err, "failed to revoke invite")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// newInviteToken returns a random hex token identifying an invite.
+func newInviteToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -6,7 +6,11 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/config"
 )
 
 // helper to register a user and return a JWT token.
@@ -112,3 +116,952 @@ func TestProjectAccessControl(t *testing.T) {
 		t.Errorf("bob delete: status = %d, want %d", rec.Code, http.StatusForbidden)
 	}
 }
+
+func TestProjectColor(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", token, `{"name":"Bad","color":"red"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid color: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", token, `{"name":"Good","color":"#1a2B3c"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var project struct {
+		ID    int64  `json:"id"`
+		Color string `json:"color"`
+	}
+	json.NewDecoder(rec.Body).Decode(&project)
+
+	// Updating without a color keeps the existing one.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/projects/%d", project.ID), token, `{"name":"Renamed"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d", project.ID), token, ""))
+	json.NewDecoder(rec.Body).Decode(&project)
+	if project.Color != "#1a2B3c" {
+		t.Errorf("color = %q, want #1a2B3c", project.Color)
+	}
+}
+
+func TestAddMemberRejectsOwnerRole(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	registerUser(t, router, "bob", "bob@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", aliceToken, `{"name":"Shared"}`))
+	var project struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&project)
+
+	tests := []struct {
+		name string
+		role string
+		want int
+	}{
+		{"owner", "owner", http.StatusBadRequest},
+		{"unknown", "admin", http.StatusBadRequest},
+		{"editor", "editor", http.StatusCreated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"username":"bob","role":%q}`, tt.role)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/members", project.ID), aliceToken, body))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d, body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+}
+
+func TestInviteConvertsOnRegistration(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", aliceToken, `{"name":"Shared"}`))
+	var project struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&project)
+
+	invitesPath := fmt.Sprintf("/api/projects/%d/invites", project.ID)
+
+	// Inviting an existing account is refused.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", invitesPath, aliceToken, `{"email":"alice@example.com"}`))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("invite existing user: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", invitesPath, aliceToken, `{"email":"Carol@Example.com","role":"editor"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("invite: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var invite struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&invite)
+	if want := fmt.Sprintf("/api/v1/projects/%d/invites/%d", project.ID, invite.ID); rec.Header().Get("Location") != want {
+		t.Errorf("invite: Location = %q, want %q", rec.Header().Get("Location"), want)
+	}
+
+	carolToken := registerUser(t, router, "carol", "carol@example.com", "password123")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/role", project.ID), carolToken, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("role: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var role struct{ Role string }
+	json.NewDecoder(rec.Body).Decode(&role)
+	if role.Role != "editor" {
+		t.Errorf("role = %q, want editor", role.Role)
+	}
+
+	// The invite is consumed.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", invitesPath, aliceToken, ""))
+	var invites []struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&invites)
+	if len(invites) != 0 {
+		t.Errorf("got %d pending invites, want 0", len(invites))
+	}
+}
+
+func TestProjectLimit(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{MaxProjectsPerUser: 1})
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", token, `{"name":"First"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("first: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", token, `{"name":"Second"}`))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("second: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestProjectMemberLimit(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{MaxProjectMembers: 1})
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	registerUser(t, router, "bob", "bob@example.com", "password123")
+	registerUser(t, router, "carol", "carol@example.com", "password123")
+	projectID := createProject(t, router, token, "Team")
+	membersPath := fmt.Sprintf("/api/projects/%d/members", projectID)
+
+	addMember(t, router, token, projectID, "bob", "viewer")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", membersPath, token, `{"username":"carol"}`))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "at most 1 member,") {
+		t.Errorf("add past limit: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	// Changing an existing member's role is not an addition.
+	addMember(t, router, token, projectID, "bob", "editor")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/invites", projectID), token, `{"email":"dave@example.com"}`))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("invite past limit: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", membersPath+"/2", token, ""))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("remove bob: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	addMember(t, router, token, projectID, "carol", "viewer")
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", membersPath+"/3", token, ""))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("remove carol: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	// A pending invite holds the only place, so neither a second invite nor
+	// a direct addition fits, and redeeming it stays within the cap.
+	invitesPath := fmt.Sprintf("/api/projects/%d/invites", projectID)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", invitesPath, token, `{"email":"dave@example.com"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("invite dave: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", invitesPath, token, `{"email":"erin@example.com"}`))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("second invite: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", membersPath, token, `{"username":"bob"}`))
+	if rec.Code != http.StatusConflict {
+		t.Errorf("add with invite pending: status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+
+	registerUser(t, router, "dave", "dave@example.com", "password123")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", membersPath, token, ""))
+	var members []struct{ Username string }
+	json.NewDecoder(rec.Body).Decode(&members)
+	if len(members) != 1 || members[0].Username != "dave" {
+		t.Errorf("members = %+v, want only dave", members)
+	}
+}
+
+func TestReorderProjects(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	ids := map[string]int64{}
+	for _, name := range []string{"A", "B", "C"} {
+		ids[name] = createProject(t, router, token, name)
+	}
+
+	names := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", "/api/projects", token, ""))
+		var projects []struct{ Name string }
+		json.NewDecoder(rec.Body).Decode(&projects)
+		var out []string
+		for _, p := range projects {
+			out = append(out, p.Name)
+		}
+		return strings.Join(out, ",")
+	}
+	reorder := func(body string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("POST", "/api/projects/reorder", token, body))
+		return rec.Code
+	}
+
+	if code := reorder(fmt.Sprintf(`{"ids":[%d,%d,9999]}`, ids["A"], ids["C"])); code != http.StatusNoContent {
+		t.Fatalf("reorder: status = %d", code)
+	}
+	if got := names(); got != "A,C,B" {
+		t.Errorf("after reorder: %s, want A,C,B (unordered projects last)", got)
+	}
+
+	// Favorites stay on top, and new projects are appended.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/favorite", ids["B"]), token, ""))
+	createProject(t, router, token, "D")
+	if got := names(); got != "B,A,C,D" {
+		t.Errorf("after favorite and create: %s, want B,A,C,D", got)
+	}
+
+	if code := reorder(fmt.Sprintf(`{"ids":[%d,%d]}`, ids["A"], ids["A"])); code != http.StatusBadRequest {
+		t.Errorf("duplicate ids: status = %d, want %d", code, http.StatusBadRequest)
+	}
+}
+
+func TestMaintenanceModeBlocksWrites(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{MaintenanceMode: true})
+
+	// Auth routes stay available.
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", token, `{"name":"Blocked"}`))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("create: status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Error("expected Retry-After header")
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/projects", token, ""))
+	if rec.Code != http.StatusOK {
+		t.Errorf("list: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", "/api/auth/me", token, `{"username":"alicia"}`))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("update me: status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+func TestExportAndImportTemplate(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+
+	projectID := createProject(t, router, aliceToken, "Launch")
+	addMember(t, router, aliceToken, projectID, "bob", "editor")
+	createTodo(t, router, aliceToken, projectID,
+		`{"title":"Write docs","description":"secret plans","priority":"high","priority_rank":1,
+		  "deadline":"2030-01-01T00:00:00Z","assignee_ids":[2]}`)
+	createTodo(t, router, aliceToken, projectID, `{"title":"Ship it"}`)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/export-template", projectID), aliceToken, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	exported := rec.Body.String()
+	for _, leak := range []string{"secret plans", "2030", "assignee", "bob", "description", "id\"", "owner"} {
+		if strings.Contains(exported, leak) {
+			t.Errorf("template contains %q: %s", leak, exported)
+		}
+	}
+	want := `{"version":1,"name":"Launch","color":"","todos":[` +
+		`{"title":"Write docs","priority":"high","priority_rank":1},` +
+		`{"title":"Ship it","priority":"medium","priority_rank":null}]}`
+	if strings.TrimSpace(exported) != want {
+		t.Errorf("template = %s, want %s", exported, want)
+	}
+
+	// Anyone can import it as a new project of their own.
+	carolToken := registerUser(t, router, "carol", "carol@example.com", "password123")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/templates/import", carolToken, exported))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("import: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var project struct {
+		ID      int64
+		Name    string
+		OwnerID int64 `json:"owner_id"`
+	}
+	json.NewDecoder(rec.Body).Decode(&project)
+	if project.Name != "Launch" || project.OwnerID != 3 {
+		t.Errorf("project = %+v, want carol's Launch", project)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos?sort=priority", project.ID), carolToken, ""))
+	var todos []struct{ Title, Priority, Status string }
+	json.NewDecoder(rec.Body).Decode(&todos)
+	if len(todos) != 2 || todos[0].Title != "Write docs" || todos[0].Priority != "high" || todos[1].Status != "pending" {
+		t.Errorf("todos = %+v", todos)
+	}
+
+	// Non-members cannot export, and unknown versions are rejected.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/export-template", projectID), carolToken, ""))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("export as non-member: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/templates/import", bobToken, `{"version":2,"name":"X","todos":[{"title":""}]}`))
+	var resp struct{ Errors map[string]string }
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusBadRequest || resp.Errors["version"] == "" || resp.Errors["todos[0]"] == "" {
+		t.Errorf("bad import: status = %d, errors = %v", rec.Code, resp.Errors)
+	}
+}
+
+func TestImportProjects(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+	registerUser(t, router, "carol", "carol@example.com", "password123")
+
+	projectID := createProject(t, router, aliceToken, "Move me")
+	createTodo(t, router, aliceToken, projectID,
+		`{"title":"Write docs","description":"details","priority":"high","deadline":"2030-01-01T00:00:00Z"}`)
+	doneID := createTodo(t, router, aliceToken, projectID, `{"title":"Done already"}`)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", doneID), aliceToken, `{"status":"completed"}`))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/users/me/export", aliceToken, ""))
+	var bundle map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&bundle); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if bundle["version"] != float64(1) {
+		t.Fatalf("export version = %v, want 1", bundle["version"])
+	}
+	// Add collaborators by hand, plus a todo whose project is missing.
+	bundle["members"] = []map[string]any{
+		{"project_id": projectID, "email": "CAROL@example.com", "role": "editor"},
+		{"project_id": projectID, "email": "nobody@example.com", "role": "viewer"},
+		{"project_id": projectID, "email": "bob@example.com", "role": "viewer"},
+	}
+	bundle["todos"] = append(bundle["todos"].([]any), map[string]any{"id": 99, "project_id": 12345, "title": "Stray"})
+	body, _ := json.Marshal(bundle)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects/import", bobToken, string(body)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("import: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var result struct {
+		Projects []struct {
+			ID      int64
+			Name    string
+			OwnerID int64 `json:"owner_id"`
+		}
+		Todos   int `json:"todos_created"`
+		Members int `json:"members_added"`
+		Skipped []struct{ Kind, Ref, Reason string }
+	}
+	json.NewDecoder(rec.Body).Decode(&result)
+	if len(result.Projects) != 1 || result.Projects[0].Name != "Move me" || result.Projects[0].OwnerID != 2 ||
+		result.Projects[0].ID == projectID {
+		t.Fatalf("projects = %+v, want a new project owned by bob", result.Projects)
+	}
+	if result.Todos != 2 || result.Members != 1 || len(result.Skipped) != 3 {
+		t.Errorf("created %d todos, %d members, skipped %+v; want 2, 1 and 3 skips",
+			result.Todos, result.Members, result.Skipped)
+	}
+
+	newID := result.Projects[0].ID
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos?sort=priority", newID), bobToken, ""))
+	var todos []struct {
+		Title, Description, Status string
+		Deadline                   *string
+		CreatedBy                  int64 `json:"created_by"`
+	}
+	json.NewDecoder(rec.Body).Decode(&todos)
+	if len(todos) != 2 || todos[0].Title != "Write docs" || todos[0].Description != "details" ||
+		todos[0].Deadline == nil || todos[0].CreatedBy != 2 || todos[1].Status != "completed" {
+		t.Errorf("imported todos = %+v", todos)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/members", newID), bobToken, ""))
+	if !strings.Contains(rec.Body.String(), `"carol"`) {
+		t.Errorf("members = %s, want carol", rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects/import", bobToken,
+		`{"version":2,"projects":[{"id":1,"name":""}],"todos":[{"project_id":1,"title":"x","status":"bogus"}]}`))
+	var resp struct{ Errors map[string]string }
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusBadRequest || resp.Errors["version"] == "" {
+		t.Errorf("bad version: status = %d, errors = %v", rec.Code, resp.Errors)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects/import", bobToken,
+		`{"version":1,"projects":[{"id":1,"name":""}],"todos":[{"project_id":1,"title":"x","status":"bogus"}]}`))
+	resp.Errors = nil
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusBadRequest || resp.Errors["projects[0]"] == "" || resp.Errors["todos[0]"] == "" {
+		t.Errorf("bad import: status = %d, errors = %v", rec.Code, resp.Errors)
+	}
+}
+
+func TestCreateProjectFromTemplate(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+	carolToken := registerUser(t, router, "carol", "carol@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", aliceToken,
+		`{"name":"Client onboarding","color":"#336699","is_template":true}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create template: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var template struct {
+		ID         int64
+		IsTemplate bool `json:"is_template"`
+	}
+	json.NewDecoder(rec.Body).Decode(&template)
+	if !template.IsTemplate {
+		t.Fatal("is_template = false, want true")
+	}
+	createTodo(t, router, aliceToken, template.ID, `{"title":"Kickoff call","status":"completed","priority":"high"}`)
+	createTodo(t, router, aliceToken, template.ID, `{"title":"Send contract"}`)
+	addMember(t, router, aliceToken, template.ID, "bob", "viewer")
+	plainID := createProject(t, router, aliceToken, "Not a template")
+
+	// Templates are listed separately.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/projects/templates", bobToken, ""))
+	var templates []struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&templates)
+	if len(templates) != 1 || templates[0].ID != template.ID {
+		t.Errorf("templates = %+v, want only %d", templates, template.ID)
+	}
+
+	// A member of the template can instantiate it.
+	path := fmt.Sprintf("/api/projects/from-template/%d", template.ID)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", path, bobToken, `{"name":"Acme onboarding"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("from template: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var project struct {
+		ID         int64
+		Name       string
+		Color      string
+		OwnerID    int64 `json:"owner_id"`
+		IsTemplate bool  `json:"is_template"`
+	}
+	json.NewDecoder(rec.Body).Decode(&project)
+	if project.Name != "Acme onboarding" || project.Color != "#336699" || project.OwnerID != 2 || project.IsTemplate {
+		t.Errorf("project = %+v, want bob's non-template Acme onboarding with the template color", project)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos", project.ID), bobToken, ""))
+	var todos []struct{ Title, Status string }
+	json.NewDecoder(rec.Body).Decode(&todos)
+	if len(todos) != 2 {
+		t.Fatalf("got %d todos, want 2", len(todos))
+	}
+	for _, todo := range todos {
+		if todo.Status != "pending" {
+			t.Errorf("todo %q status = %q, want pending", todo.Title, todo.Status)
+		}
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		id    int64
+		want  int
+	}{
+		{"non-member", carolToken, template.ID, http.StatusForbidden},
+		{"not a template", aliceToken, plainID, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/from-template/%d", tt.id), tt.token, ""))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestFavoriteProjects(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+
+	first := createProject(t, router, aliceToken, "First")
+	second := createProject(t, router, aliceToken, "Second")
+	createProject(t, router, aliceToken, "Third")
+	addMember(t, router, aliceToken, first, "bob", "viewer")
+
+	listIDs := func(token string) []int64 {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", "/api/projects", token, ""))
+		var projects []struct {
+			ID        int64
+			Favorited bool
+		}
+		json.NewDecoder(rec.Body).Decode(&projects)
+		var ids []int64
+		for _, p := range projects {
+			if p.Favorited {
+				ids = append(ids, -p.ID) // negative marks a favorite
+			} else {
+				ids = append(ids, p.ID)
+			}
+		}
+		return ids
+	}
+	favorite := func(token, method string, projectID int64) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest(method, fmt.Sprintf("/api/projects/%d/favorite", projectID), token, ""))
+		return rec.Code
+	}
+
+	// Projects created in the same second share updated_at, so compare the
+	// favorites as a group rather than relying on the order of the rest.
+	if code := favorite(aliceToken, "POST", first); code != http.StatusNoContent {
+		t.Fatalf("favorite: status = %d", code)
+	}
+	favorite(aliceToken, "POST", first) // idempotent
+	ids := listIDs(aliceToken)
+	if len(ids) != 3 || ids[0] != -first {
+		t.Errorf("alice's projects = %v, want %d favorited first", ids, first)
+	}
+
+	// Favorites are per user.
+	if ids := listIDs(bobToken); len(ids) != 1 || ids[0] != first {
+		t.Errorf("bob's projects = %v, want [%d] not favorited", ids, first)
+	}
+
+	if code := favorite(bobToken, "POST", second); code != http.StatusForbidden {
+		t.Errorf("favorite without access: status = %d, want 403", code)
+	}
+
+	if code := favorite(aliceToken, "DELETE", first); code != http.StatusNoContent {
+		t.Fatalf("unfavorite: status = %d", code)
+	}
+	for _, id := range listIDs(aliceToken) {
+		if id < 0 {
+			t.Errorf("project %d still favorited", -id)
+		}
+	}
+
+	// Losing access drops the favorite.
+	favorite(bobToken, "POST", first)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", fmt.Sprintf("/api/projects/%d/members/2", first), aliceToken, ""))
+	addMember(t, router, aliceToken, first, "bob", "viewer")
+	if ids := listIDs(bobToken); len(ids) != 1 || ids[0] != first {
+		t.Errorf("bob's projects after re-adding = %v, want [%d] not favorited", ids, first)
+	}
+}
+
+func TestGetProjectMember(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+	carolToken := registerUser(t, router, "carol", "carol@example.com", "password123")
+
+	projectID := createProject(t, router, aliceToken, "Team")
+	addMember(t, router, aliceToken, projectID, "bob", "viewer")
+
+	tests := []struct {
+		name     string
+		token    string
+		userID   int64
+		want     int
+		wantRole string
+	}{
+		{"member looks up owner", bobToken, 1, http.StatusOK, "owner"},
+		{"owner looks up member", aliceToken, 2, http.StatusOK, "viewer"},
+		{"non-member target", aliceToken, 3, http.StatusNotFound, ""},
+		{"outsider", carolToken, 1, http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/members/%d", projectID, tt.userID), tt.token, ""))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d, body = %s", rec.Code, tt.want, rec.Body.String())
+			}
+			if tt.wantRole == "" {
+				return
+			}
+			var member struct {
+				UserID   int64 `json:"user_id"`
+				Username string
+				Role     string
+			}
+			json.NewDecoder(rec.Body).Decode(&member)
+			if member.UserID != tt.userID || member.Role != tt.wantRole || member.Username == "" {
+				t.Errorf("member = %+v, want user %d with role %s", member, tt.userID, tt.wantRole)
+			}
+		})
+	}
+}
+
+func TestGetProjectRenderHTML(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", token, `{"name":"Docs","description":"**Goal** <script>x</script>"}`))
+	var created struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&created)
+
+	tests := []struct {
+		query    string
+		want     int
+		wantHTML string
+	}{
+		{"", http.StatusOK, ""},
+		{"?render=html", http.StatusOK, "<p><strong>Goal</strong> &lt;script&gt;x&lt;/script&gt;</p>\n"},
+		{"?render=pdf", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d%s", created.ID, tt.query), token, ""))
+			if rec.Code != tt.want {
+				t.Fatalf("status = %d, want %d", rec.Code, tt.want)
+			}
+			var project struct {
+				Description     string
+				DescriptionHTML string `json:"description_html"`
+			}
+			json.NewDecoder(rec.Body).Decode(&project)
+			if project.DescriptionHTML != tt.wantHTML {
+				t.Errorf("description_html = %q, want %q", project.DescriptionHTML, tt.wantHTML)
+			}
+			if tt.want == http.StatusOK && project.Description != "**Goal** <script>x</script>" {
+				t.Errorf("description = %q, want the raw Markdown", project.Description)
+			}
+		})
+	}
+}
+
+func TestProjectRoles(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+
+	owned := createProject(t, router, bobToken, "Bob's")
+	shared := createProject(t, router, aliceToken, "Shared")
+	private := createProject(t, router, aliceToken, "Private")
+	addMember(t, router, aliceToken, shared, "bob", "editor")
+
+	body := fmt.Sprintf(`{"ids":[%d,%d,%d,9999]}`, owned, shared, private)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects/roles", bobToken, body))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var roles map[string]string
+	json.NewDecoder(rec.Body).Decode(&roles)
+	want := map[string]string{fmt.Sprint(owned): "owner", fmt.Sprint(shared): "editor"}
+	if fmt.Sprint(roles) != fmt.Sprint(want) {
+		t.Errorf("roles = %v, want %v", roles, want)
+	}
+}
+
+func TestProjectWebhooks(t *testing.T) {
+	// The test receiver listens on loopback.
+	router := setupTestRouterWithConfig(t, &config.Config{WebhookAllowPrivateNetworks: true})
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+	projectID := createProject(t, router, aliceToken, "Hooked")
+	addMember(t, router, aliceToken, projectID, "bob", "editor")
+	path := fmt.Sprintf("/api/projects/%d/webhooks", projectID)
+
+	events := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events <- r.Header.Get("X-Bloom-Event")
+	}))
+	defer srv.Close()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", path, bobToken, fmt.Sprintf(`{"url":%q,"events":["todo.created"]}`, srv.URL)))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("create as editor: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", path, aliceToken, `{"url":"ftp://example.com","events":["todo.nope"]}`))
+	var resp struct{ Errors map[string]string }
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if rec.Code != http.StatusBadRequest || resp.Errors["url"] == "" || resp.Errors["events"] == "" {
+		t.Errorf("invalid create: status = %d, errors = %v", rec.Code, resp.Errors)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", path, aliceToken, fmt.Sprintf(`{"url":%q,"events":["todo.created"]}`, srv.URL)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var hook struct {
+		ID     int64
+		Secret string
+	}
+	json.NewDecoder(rec.Body).Decode(&hook)
+	if len(hook.Secret) != 64 {
+		t.Errorf("generated secret = %q, want 64 hex characters", hook.Secret)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", path, aliceToken, ""))
+	if strings.Contains(rec.Body.String(), hook.Secret) {
+		t.Errorf("list leaks the secret: %s", rec.Body.String())
+	}
+
+	todoID := createTodo(t, router, bobToken, projectID, `{"title":"Ping"}`)
+	select {
+	case event := <-events:
+		if event != "todo.created" {
+			t.Errorf("event = %q, want todo.created", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not called")
+	}
+
+	hookPath := fmt.Sprintf("%s/%d", path, hook.ID)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", hookPath, aliceToken, `{"events":["todo.deleted"]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", fmt.Sprintf("/api/todos/%d", todoID), bobToken, ""))
+	select {
+	case event := <-events:
+		if event != "todo.deleted" {
+			t.Errorf("event = %q, want todo.deleted", event)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("webhook not called on delete")
+	}
+
+	// Clearing completed todos reports each one, and nothing for the rest.
+	createTodo(t, router, bobToken, projectID, `{"title":"Done","status":"completed"}`)
+	createTodo(t, router, bobToken, projectID, `{"title":"Also done","status":"completed"}`)
+	createTodo(t, router, bobToken, projectID, `{"title":"Open"}`)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", fmt.Sprintf("/api/projects/%d/todos/completed", projectID), bobToken, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("delete completed: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case event := <-events:
+			if event != "todo.deleted" {
+				t.Errorf("event = %q, want todo.deleted", event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("webhook called %d times on delete completed, want 2", i)
+		}
+	}
+	select {
+	case event := <-events:
+		t.Errorf("unexpected %s event after delete completed", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("DELETE", hookPath, aliceToken, ""))
+		if rec.Code != want {
+			t.Errorf("delete: status = %d, want %d", rec.Code, want)
+		}
+	}
+}
+
+func TestWebhookRejectsPrivateURLs(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Hooked")
+	path := fmt.Sprintf("/api/projects/%d/webhooks", projectID)
+
+	for _, url := range []string{
+		"http://127.0.0.1:8080/hook",
+		"http://localhost/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://10.0.0.5/hook",
+		"http://192.168.1.1/hook",
+		"http://[::1]/hook",
+		"http://0.0.0.0/hook",
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("POST", path, token, fmt.Sprintf(`{"url":%q,"events":["todo.created"]}`, url)))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want %d", url, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", path, token, `{"url":"https://hooks.example.com/bloom","events":["todo.created"]}`))
+	if rec.Code != http.StatusCreated {
+		t.Errorf("public URL: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestCreateSetsLocation(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	registerUser(t, router, "bob", "bob@example.com", "password123")
+	projectID := createProject(t, router, token, "P")
+
+	tests := []struct {
+		name, path, body string
+	}{
+		{"project", "/api/projects", `{"name":"Q"}`},
+		{"todo", fmt.Sprintf("/api/projects/%d/todos", projectID), `{"title":"T"}`},
+		{"member", fmt.Sprintf("/api/projects/%d/members", projectID), `{"username":"bob","role":"viewer"}`},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("POST", tt.path, token, tt.body))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create %s: status = %d, body = %s", tt.name, rec.Code, rec.Body.String())
+		}
+		location := rec.Header().Get("Location")
+		if !strings.HasPrefix(location, "/api/v1/") {
+			t.Fatalf("create %s: Location = %q, want a /api/v1 URL", tt.name, location)
+		}
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", location, token, ""))
+		if rec.Code != http.StatusOK {
+			t.Errorf("GET %s: status = %d, want 200", location, rec.Code)
+		}
+	}
+}
+
+func TestDefaultMemberRole(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	registerUser(t, router, "bob", "bob@example.com", "password123")
+	registerUser(t, router, "carol", "carol@example.com", "password123")
+	projectID := createProject(t, router, token, "P")
+	path := fmt.Sprintf("/api/projects/%d", projectID)
+
+	addMember := func(username string) string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("POST", path+"/members", token, fmt.Sprintf(`{"username":%q}`, username)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("add %s: status = %d, body = %s", username, rec.Code, rec.Body.String())
+		}
+		var member struct{ Role string }
+		json.NewDecoder(rec.Body).Decode(&member)
+		return member.Role
+	}
+	if role := addMember("bob"); role != "viewer" {
+		t.Errorf("default role = %q, want viewer", role)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", path, token, `{"default_member_role":"owner"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("set owner: status = %d, want 400", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", path, token, `{"default_member_role":"editor"}`))
+	var project struct {
+		DefaultMemberRole string `json:"default_member_role"`
+	}
+	json.NewDecoder(rec.Body).Decode(&project)
+	if rec.Code != http.StatusOK || project.DefaultMemberRole != "editor" {
+		t.Fatalf("set editor: status = %d, default_member_role = %q", rec.Code, project.DefaultMemberRole)
+	}
+	if role := addMember("carol"); role != "editor" {
+		t.Errorf("role = %q, want the project's default editor", role)
+	}
+}
+
+func TestErrorCodes(t *testing.T) {
+	router := setupTestRouter(t)
+	alice := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bob := registerUser(t, router, "bob", "bob@example.com", "password123")
+	projectID := createProject(t, router, alice, "P")
+
+	tests := []struct {
+		name, method, path, token, body string
+		status                          int
+		code                            string
+	}{
+		{"missing project", "PUT", "/api/projects/9999", alice, `{"name":"Q"}`, http.StatusNotFound, "PROJECT_NOT_FOUND"},
+		{"missing todo", "GET", "/api/todos/9999", alice, "", http.StatusNotFound, "TODO_NOT_FOUND"},
+		{"missing member", "POST", fmt.Sprintf("/api/projects/%d/members", projectID), alice, `{"username":"nobody"}`,
+			http.StatusNotFound, "USER_NOT_FOUND"},
+		{"not a member", "GET", fmt.Sprintf("/api/projects/%d", projectID), bob, "", http.StatusForbidden, "FORBIDDEN"},
+		{"invalid input", "POST", "/api/projects", alice, `{"name":""}`, http.StatusBadRequest, "VALIDATION_FAILED"},
+		{"bad id", "GET", "/api/projects/abc", alice, "", http.StatusBadRequest, "BAD_REQUEST"},
+		{"no token", "GET", "/api/projects", "", "", http.StatusUnauthorized, "UNAUTHORIZED"},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest(tt.method, tt.path, tt.token, tt.body))
+		var resp struct{ Error, Code string }
+		json.NewDecoder(rec.Body).Decode(&resp)
+		if rec.Code != tt.status || resp.Code != tt.code || resp.Error == "" {
+			t.Errorf("%s: status = %d, code = %q, error = %q; want %d, %q and a message",
+				tt.name, rec.Code, resp.Code, resp.Error, tt.status, tt.code)
+		}
+	}
+}
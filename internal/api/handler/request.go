@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Pagination holds the page-size limits shared by paginated handlers.
+type Pagination struct {
+	DefaultLimit int // used when ?limit is absent
+	MaxLimit     int // larger requests are clamped to this
+}
+
+// Fallbacks used when a Pagination field is left zero.
+const (
+	defaultPageLimit = 10
+	maxPageLimit     = 50
+)
+
+// page is the effective limit and offset for one request.
+type page struct {
+	Limit  int
+	Offset int
+}
+
+// parsePagination reads ?limit and ?offset, applying the default limit and
+// clamping requests above the maximum. Malformed or negative values are
+// rejected.
+func parsePagination(r *http.Request, cfg Pagination) (page, error) {
+	if cfg.DefaultLimit < 1 {
+		cfg.DefaultLimit = defaultPageLimit
+	}
+	if cfg.MaxLimit < 1 {
+		cfg.MaxLimit = maxPageLimit
+	}
+	limit, err := queryInt(r, "limit", cfg.DefaultLimit)
+	if err != nil || limit < 1 {
+		return page{}, errors.New("limit must be a positive integer")
+	}
+	if limit > cfg.MaxLimit {
+		limit = cfg.MaxLimit
+	}
+	offset, err := queryInt(r, "offset", 0)
+	if err != nil || offset < 0 {
+		return page{}, errors.New("offset must be a non-negative integer")
+	}
+	return page{Limit: limit, Offset: offset}, nil
+}
+
+// writePageHeaders reports the effective page so clients can tell when their
+// requested limit was clamped.
+func writePageHeaders(w http.ResponseWriter, p page) {
+	w.Header().Set("X-Page-Limit", strconv.Itoa(p.Limit))
+	w.Header().Set("X-Page-Offset", strconv.Itoa(p.Offset))
+}
+
+// The query helpers below parse one query parameter each. An absent or
+// empty parameter yields the fallback or nil; a malformed one yields an
+// error naming the parameter, meant to be sent as is with a 400.
+
+// queryInt parses an integer query parameter, returning fallback when it is
+// absent.
+func queryInt(r *http.Request, key string, fallback int) (int, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+	return n, nil
+}
+
+// queryInt64 parses an int64 query parameter, such as an id, returning 0
+// when it is absent.
+func queryInt64(r *http.Request, key string) (int64, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer", key)
+	}
+	return n, nil
+}
+
+// queryBool parses a boolean query parameter (true, false, 1, 0 and the
+// other forms strconv.ParseBool accepts), returning nil when it is absent.
+func queryBool(r *http.Request, key string) (*bool, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be true or false", key)
+	}
+	return &b, nil
+}
+
+// queryTime parses an RFC3339 time query parameter, returning nil when it
+// is absent.
+func queryTime(r *http.Request, key string) (*time.Time, error) {
+	v := r.URL.Query().Get(key)
+	if v == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be in RFC3339 format", key)
+	}
+	return &t, nil
+}
+
+// wantHTML reports whether the request asked for ?render=html. Any other
+// non-empty value is rejected.
+func wantHTML(r *http.Request) (bool, error) {
+	switch r.URL.Query().Get("render") {
+	case "":
+		return false, nil
+	case "html":
+		return true, nil
+	}
+	return false, errors.New("render must be 'html'")
+}
+
+// optional is a JSON field that distinguishes "absent" from "null", so
+// update requests can clear a nullable value by sending null.
+type optional[T any] struct {
+	Set   bool
+	Value *T
+}
+
+func (o *optional[T]) UnmarshalJSON(data []byte) error {
+	o.Set = true
+	if string(data) == "null" {
+		o.Value = nil
+		return nil
+	}
+	return json.Unmarshal(data, &o.Value)
+}
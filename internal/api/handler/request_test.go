@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestQueryInt(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int
+		wantErr string
+	}{
+		{"", 7, ""},
+		{"?n=", 7, ""},
+		{"?n=42", 42, ""},
+		{"?n=-3", -3, ""},
+		{"?n=abc", 0, "n must be an integer"},
+		{"?n=1.5", 0, "n must be an integer"},
+	}
+	for _, tt := range tests {
+		got, err := queryInt(httptest.NewRequest("GET", "/"+tt.query, nil), "n", 7)
+		if got != tt.want || errString(err) != tt.wantErr {
+			t.Errorf("%q: got %d, %v; want %d, %q", tt.query, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestQueryInt64(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    int64
+		wantErr string
+	}{
+		{"", 0, ""},
+		{"?id=9007199254740993", 9007199254740993, ""},
+		{"?id=x", 0, "id must be an integer"},
+	}
+	for _, tt := range tests {
+		got, err := queryInt64(httptest.NewRequest("GET", "/"+tt.query, nil), "id")
+		if got != tt.want || errString(err) != tt.wantErr {
+			t.Errorf("%q: got %d, %v; want %d, %q", tt.query, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestQueryBool(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    *bool
+		wantErr string
+	}{
+		{"", nil, ""},
+		{"?b=true", ptr(true), ""},
+		{"?b=0", ptr(false), ""},
+		{"?b=yes", nil, "b must be true or false"},
+	}
+	for _, tt := range tests {
+		got, err := queryBool(httptest.NewRequest("GET", "/"+tt.query, nil), "b")
+		if (got == nil) != (tt.want == nil) || (got != nil && *got != *tt.want) || errString(err) != tt.wantErr {
+			t.Errorf("%q: got %v, %v; want %v, %q", tt.query, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestQueryTime(t *testing.T) {
+	want := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		query   string
+		want    *time.Time
+		wantErr string
+	}{
+		{"", nil, ""},
+		{"?t=2024-05-01T14:00:00%2B02:00", &want, ""},
+		{"?t=2024-05-01", nil, "t must be in RFC3339 format"},
+	}
+	for _, tt := range tests {
+		got, err := queryTime(httptest.NewRequest("GET", "/"+tt.query, nil), "t")
+		if (got == nil) != (tt.want == nil) || (got != nil && !got.Equal(*tt.want)) || errString(err) != tt.wantErr {
+			t.Errorf("%q: got %v, %v; want %v, %q", tt.query, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func ptr[T any](v T) *T { return &v }
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -2,22 +2,101 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
 	"net/http"
+	"strings"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/api/response"
+	"github.com/walidabualafia/bloom/internal/store"
 )
 
-// errorResponse is a standard error payload.
-type errorResponse struct {
-	Error string `json:"error"`
-}
+// apiPrefix is the canonical prefix of API URLs. Location headers use it
+// even for requests made under the deprecated /api alias.
+const apiPrefix = "/api/v1"
 
-// writeJSON serializes data as JSON and writes it to the response.
+// unavailableRetryAfter is the Retry-After, in seconds, sent when the
+// database cannot be reached.
+const unavailableRetryAfter = "5"
+
+// writeJSON serializes data as JSON and writes it to the response, keeping
+// only the fields the client selected, if any (see middleware.Fields).
 func writeJSON(w http.ResponseWriter, status int, data any) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(data) //nolint:errcheck
+	if fields := middleware.SelectedFields(w); fields != nil {
+		data = selectFields(data, fields)
+	}
+	response.WriteJSON(w, status, data)
 }
 
-// writeError writes a JSON error response.
+// selectFields drops the keys not in fields from data if it encodes as a
+// JSON object, or from each object in it if it encodes as an array. Other
+// values are returned unchanged.
+func selectFields(data any, fields map[string]bool) any {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return data // let WriteJSON report the error
+	}
+	filter := func(raw json.RawMessage) json.RawMessage {
+		var obj map[string]json.RawMessage
+		if json.Unmarshal(raw, &obj) != nil {
+			return raw
+		}
+		maps.DeleteFunc(obj, func(key string, _ json.RawMessage) bool { return !fields[key] })
+		filtered, _ := json.Marshal(obj)
+		return filtered
+	}
+	var items []json.RawMessage
+	if json.Unmarshal(b, &items) == nil {
+		for i := range items {
+			items[i] = filter(items[i])
+		}
+		return items
+	}
+	return filter(b)
+}
+
+// writeError writes a JSON error response with the generic code for status.
 func writeError(w http.ResponseWriter, status int, message string) {
-	writeJSON(w, status, errorResponse{Error: message})
+	response.WriteJSONError(w, status, message)
+}
+
+// writeErrorCode writes a JSON error response with a specific code, one of
+// the response.Code constants.
+func writeErrorCode(w http.ResponseWriter, status int, code, message string) {
+	response.WriteJSONErrorCode(w, status, code, message)
+}
+
+// writeServerError writes the response for a store error the handler does
+// not expect: 503 with a Retry-After header if the database is unavailable,
+// so clients know to retry, and otherwise 500 with message.
+func writeServerError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, store.ErrUnavailable) {
+		log.Printf("database unavailable: %v", err)
+		w.Header().Set("Retry-After", unavailableRetryAfter)
+		writeErrorCode(w, http.StatusServiceUnavailable, response.CodeUnavailable, "database temporarily unavailable; retry shortly")
+		return
+	}
+	writeError(w, http.StatusInternalServerError, message)
+}
+
+// writeCreated writes data as a 201 response with a Location header
+// pointing at the created resource, built by resourceURL.
+func writeCreated(w http.ResponseWriter, location string, data any) {
+	w.Header().Set("Location", location)
+	writeJSON(w, http.StatusCreated, data)
+}
+
+// resourceURL joins path segments, such as "projects" and an id, into the
+// canonical URL of a resource: resourceURL("projects", 3) is
+// /api/v1/projects/3.
+func resourceURL(segments ...any) string {
+	var b strings.Builder
+	b.WriteString(apiPrefix)
+	for _, s := range segments {
+		fmt.Fprintf(&b, "/%v", s)
+	}
+	return b.String()
 }
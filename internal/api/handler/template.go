@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/api/response"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+// maxTemplateTodos caps how many todos an imported template may contain.
+const maxTemplateTodos = 1000
+
+// ExportTemplate returns a project's structure as a model.ProjectTemplate
+// that can be shared and imported elsewhere (must be a member). Only todo
+// titles, priorities and ranks are included; see model.ProjectTemplate.
+func (h *Project) ExportTemplate(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, "you do not have access to this project")
+		return
+	}
+
+	project, err := h.store.GetProject(r.Context(), projectID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
+			return
+		}
+		writeServerError(w, err, "failed to get project")
+		return
+	}
+	todos, err := h.store.ListTodosByProject(r.Context(), projectID, store.TodoListParams{})
+	if err != nil {
+		writeServerError(w, err, "failed to list todos")
+		return
+	}
+	slices.SortFunc(todos, func(a, b model.Todo) int { return cmp.Compare(a.ID, b.ID) })
+
+	// Copy fields one by one rather than embedding model types so new todo
+	// fields never end up in shared templates by accident.
+	tmpl := model.ProjectTemplate{
+		Version: model.TemplateVersion,
+		Name:    project.Name,
+		Color:   project.Color,
+		Todos:   make([]model.TemplateTodo, len(todos)),
+	}
+	for i, t := range todos {
+		tmpl.Todos[i] = model.TemplateTodo{Title: t.Title, Priority: t.Priority, PriorityRank: t.PriorityRank}
+	}
+	writeJSON(w, http.StatusOK, tmpl)
+}
+
+// ImportTemplate creates a project owned by the user from a
+// model.ProjectTemplate, such as one produced by ExportTemplate.
+func (h *Project) ImportTemplate(w http.ResponseWriter, r *http.Request) {
+	var tmpl model.ProjectTemplate
+	if err := json.NewDecoder(r.Body).Decode(&tmpl); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	v := validation{}
+	v.check(tmpl.Version == model.TemplateVersion, "version", fmt.Sprintf("unsupported template version; want %d", model.TemplateVersion))
+	v.check(tmpl.Name != "", "name", "name is required")
+	v.check(tmpl.Color == "" || model.ValidColor(tmpl.Color), "color", colorFormatError)
+	v.check(len(tmpl.Todos) <= maxTemplateTodos, "todos", fmt.Sprintf("a template can have at most %d todos", maxTemplateTodos))
+	v.check(h.maxTodos <= 0 || len(tmpl.Todos) <= h.maxTodos, "todos", fmt.Sprintf("a project can have at most %s", countNoun(h.maxTodos, "todo")))
+	for i := range tmpl.Todos {
+		t := &tmpl.Todos[i]
+		if t.Priority == "" {
+			t.Priority = model.PriorityMedium
+		}
+		field := fmt.Sprintf("todos[%d]", i)
+		v.check(t.Title != "", field, "title is required")
+		v.check(utf8.RuneCountInString(t.Title) <= defaultMaxTitle, field, fmt.Sprintf("title must be at most %d characters", defaultMaxTitle))
+		v.check(model.ValidPriority(t.Priority), field, "priority must be 'low', 'medium', or 'high'")
+		v.check(t.PriorityRank == nil || model.ValidPriorityRank(*t.PriorityRank), field, priorityRankError)
+	}
+	if v.write(w) {
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	if !h.checkProjectLimit(w, r, userID, 1) {
+		return
+	}
+
+	project := &model.Project{Name: tmpl.Name, Color: tmpl.Color, OwnerID: userID}
+	if err := h.store.ImportProjectTemplate(r.Context(), &tmpl, project); err != nil {
+		writeServerError(w, err, "failed to create project")
+		return
+	}
+
+	writeCreated(w, resourceURL("projects", project.ID), project)
+}
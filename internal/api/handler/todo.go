@@ -1,58 +1,171 @@
 package handler
 
 import (
-	"database/sql"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/api/response"
+	"github.com/walidabualafia/bloom/internal/markdown"
 	"github.com/walidabualafia/bloom/internal/model"
 	"github.com/walidabualafia/bloom/internal/store"
+	"github.com/walidabualafia/bloom/internal/webhook"
 )
 
 // Todo handles todo CRUD within projects.
 type Todo struct {
-	store store.Store
+	store      store.Store
+	limits     TodoLimits
+	pagination Pagination
+	// blockOnDependencies rejects completing a todo with incomplete
+	// dependencies.
+	blockOnDependencies bool
+	events              *webhook.Dispatcher
 }
 
-// NewTodo creates a new Todo handler.
-func NewTodo(s store.Store) *Todo {
-	return &Todo{store: s}
+// TodoLimits caps the length, in characters, of todo text fields. Zero
+// fields fall back to the defaults below.
+type TodoLimits struct {
+	MaxTitle       int
+	MaxDescription int
+	// DeadlineMaxPast and DeadlineMaxFuture bound how far from now a
+	// deadline may be set, to catch clients sending garbage dates.
+	DeadlineMaxPast   time.Duration
+	DeadlineMaxFuture time.Duration
+	// MaxPerProject caps how many todos a project may have. Unlike the
+	// other limits, zero means unlimited.
+	MaxPerProject int
+}
+
+const (
+	defaultMaxTitle          = 255 // matches the VARCHAR(255) column on Postgres
+	defaultMaxDescription    = 10000
+	defaultDeadlineMaxPast   = 10 * 365 * 24 * time.Hour
+	defaultDeadlineMaxFuture = 100 * 365 * 24 * time.Hour
+)
+
+// NewTodo creates a new Todo handler. If blockOnDependencies is set, a todo
+// cannot be marked completed while any of its dependencies are incomplete.
+// Creates, updates and deletes are sent to the project's webhooks through
+// events.
+func NewTodo(s store.Store, limits TodoLimits, pagination Pagination, blockOnDependencies bool, events *webhook.Dispatcher) *Todo {
+	if limits.MaxTitle < 1 {
+		limits.MaxTitle = defaultMaxTitle
+	}
+	if limits.MaxDescription < 1 {
+		limits.MaxDescription = defaultMaxDescription
+	}
+	if limits.DeadlineMaxPast <= 0 {
+		limits.DeadlineMaxPast = defaultDeadlineMaxPast
+	}
+	if limits.DeadlineMaxFuture <= 0 {
+		limits.DeadlineMaxFuture = defaultDeadlineMaxFuture
+	}
+	return &Todo{store: s, limits: limits, pagination: pagination, blockOnDependencies: blockOnDependencies, events: events}
 }
 
 type createTodoRequest struct {
-	Title       string  `json:"title"`
-	Description string  `json:"description"`
-	Status      string  `json:"status"`
-	Priority    string  `json:"priority"`
-	Deadline    *string `json:"deadline"`
+	Title        string  `json:"title"`
+	Description  string  `json:"description"`
+	Status       string  `json:"status"`
+	Priority     string  `json:"priority"`
+	PriorityRank *int    `json:"priority_rank"`
+	Deadline     *string `json:"deadline"`
+	// ReminderOffset is a Go duration such as "24h"; see parseReminderOffset.
+	ReminderOffset *string `json:"reminder_offset"`
+	Estimate       *int    `json:"estimate"`
+	AssigneeIDs    []int64 `json:"assignee_ids"`
+	AssigneeID     *int64  `json:"assignee_id"` // shorthand for a single assignee
 }
 
 type updateTodoRequest struct {
-	Title       *string `json:"title"`
-	Description *string `json:"description"`
-	Status      *string `json:"status"`
-	Priority    *string `json:"priority"`
-	Deadline    *string `json:"deadline"`
+	Title          *string           `json:"title"`
+	Description    *string           `json:"description"`
+	Status         *string           `json:"status"`
+	Priority       *string           `json:"priority"`
+	PriorityRank   optional[int]     `json:"priority_rank"` // null clears the rank
+	Deadline       *string           `json:"deadline"`
+	ReminderOffset optional[string]  `json:"reminder_offset"` // null falls back to the server default
+	Estimate       optional[int]     `json:"estimate"`        // null clears the estimate
+	AssigneeIDs    optional[[]int64] `json:"assignee_ids"`    // replaces the set; null or [] unassigns everyone
+	AssigneeID     optional[int64]   `json:"assignee_id"`     // shorthand for a single assignee; null unassigns
+}
+
+// todoGroup is one project's todos in a ListMine response.
+type todoGroup struct {
+	Project *model.Project `json:"project"`
+	Todos   []model.Todo   `json:"todos"`
+}
+
+// todoResponse adds the rendered description for ?render=html and the
+// todo's project for ?expand=project.
+type todoResponse struct {
+	*model.Todo
+	DescriptionHTML string         `json:"description_html,omitempty"`
+	Project         *model.Project `json:"project,omitempty"`
 }
 
-// ListByProject returns all todos for a given project.
+const (
+	assigneeConflictError = "assignee_id and assignee_ids cannot both be set"
+	deadlineFormatError   = "deadline must be in RFC3339 format"
+)
+
+// maxReminderOffset is the furthest ahead of a deadline a reminder can be
+// scheduled.
+const maxReminderOffset = 30 * 24 * time.Hour
+
+var reminderOffsetError = fmt.Sprintf("reminder_offset must be a duration between 0s and %s", maxReminderOffset)
+
+var priorityRankError = fmt.Sprintf("priority_rank must be between %d and %d", model.MinPriorityRank, model.MaxPriorityRank)
+
+var estimateError = fmt.Sprintf("estimate must be between 0 and %d", model.MaxEstimate)
+
+// ListByProject returns all todos for a given project. See
+// parseTodoListParams for the supported query parameters. Completed todos
+// are left out if the project hides them, unless the request passes
+// ?include_completed=true or filters by status; ?include_completed=false
+// hides them in any project. Clients syncing with ?updated_since should
+// include them so they see todos being completed.
 func (h *Todo) ListByProject(w http.ResponseWriter, r *http.Request) {
 	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "invalid project id")
 		return
 	}
+	project, ok := h.loadProject(w, r, projectID)
+	if !ok {
+		return
+	}
+
+	params, err := parseTodoListParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	params.HideCompleted = project.HideCompleted
+	include, err := queryBool(r, "include_completed")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if include != nil {
+		params.HideCompleted = !*include
+	}
 
 	userID := middleware.GetUserID(r.Context())
 	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return
 	}
 	if !isMember {
@@ -60,17 +173,83 @@ func (h *Todo) ListByProject(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	todos, err := h.store.ListTodosByProject(r.Context(), projectID)
-	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list todos")
+	if params.AssigneeID != 0 && !h.requireMember(w, r, projectID, params.AssigneeID) {
 		return
 	}
-	if todos == nil {
-		todos = []model.Todo{}
+
+	todos, err := h.store.ListTodosByProject(r.Context(), projectID, params)
+	if err != nil {
+		writeServerError(w, err, "failed to list todos")
+		return
 	}
 	writeJSON(w, http.StatusOK, todos)
 }
 
+// todoBoard holds a project's todos split into kanban columns by status.
+// Todos in a project's custom statuses are grouped by status under Other.
+type todoBoard struct {
+	Pending    []model.Todo            `json:"pending"`
+	InProgress []model.Todo            `json:"in_progress"`
+	Completed  []model.Todo            `json:"completed"`
+	Other      map[string][]model.Todo `json:"other"`
+}
+
+// Board returns a project's todos grouped by status. Each column keeps the
+// order of the underlying list, so ?sort applies as in ListByProject.
+func (h *Todo) Board(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+	if !h.requireProject(w, r, projectID) {
+		return
+	}
+
+	params, err := parseTodoListParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, "you do not have access to this project")
+		return
+	}
+
+	todos, err := h.store.ListTodosByProject(r.Context(), projectID, params)
+	if err != nil {
+		writeServerError(w, err, "failed to list todos")
+		return
+	}
+
+	board := todoBoard{
+		Pending:    []model.Todo{},
+		InProgress: []model.Todo{},
+		Completed:  []model.Todo{},
+		Other:      map[string][]model.Todo{},
+	}
+	for _, t := range todos {
+		switch t.Status {
+		case model.StatusPending:
+			board.Pending = append(board.Pending, t)
+		case model.StatusInProgress:
+			board.InProgress = append(board.InProgress, t)
+		case model.StatusCompleted:
+			board.Completed = append(board.Completed, t)
+		default:
+			board.Other[t.Status] = append(board.Other[t.Status], t)
+		}
+	}
+	writeJSON(w, http.StatusOK, board)
+}
+
 // Create adds a new todo to a project (owner or editor only).
 func (h *Todo) Create(w http.ResponseWriter, r *http.Request) {
 	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
@@ -78,11 +257,15 @@ func (h *Todo) Create(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid project id")
 		return
 	}
+	project, ok := h.loadProject(w, r, projectID)
+	if !ok {
+		return
+	}
 
 	userID := middleware.GetUserID(r.Context())
 	role, err := h.store.GetMemberRole(r.Context(), projectID, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return
 	}
 	if role == "" {
@@ -99,17 +282,15 @@ func (h *Todo) Create(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
-	if req.Title == "" {
-		writeError(w, http.StatusBadRequest, "title is required")
-		return
-	}
-
 	todo := &model.Todo{
-		ProjectID:   projectID,
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      req.Status,
-		Priority:    req.Priority,
+		ProjectID:    projectID,
+		Title:        req.Title,
+		Description:  req.Description,
+		Status:       req.Status,
+		Priority:     req.Priority,
+		PriorityRank: req.PriorityRank,
+		Estimate:     req.Estimate,
+		CreatedBy:    &userID,
 	}
 
 	// Default values
@@ -120,47 +301,192 @@ func (h *Todo) Create(w http.ResponseWriter, r *http.Request) {
 		todo.Priority = model.PriorityMedium
 	}
 
-	if !model.ValidStatus(todo.Status) {
-		writeError(w, http.StatusBadRequest, "status must be 'pending', 'in_progress', or 'completed'")
+	v := validation{}
+	v.check(todo.Title != "", "title", "title is required")
+	h.checkLengths(v, todo.Title, todo.Description)
+	v.check(project.AllowsStatus(todo.Status), "status", statusError(project))
+	v.check(model.ValidPriority(todo.Priority), "priority", "priority must be 'low', 'medium', or 'high'")
+	v.check(todo.PriorityRank == nil || model.ValidPriorityRank(*todo.PriorityRank), "priority_rank", priorityRankError)
+	v.check(todo.Estimate == nil || model.ValidEstimate(*todo.Estimate), "estimate", estimateError)
+
+	if req.Deadline != nil && *req.Deadline != "" {
+		todo.Deadline = h.parseDeadline(v, *req.Deadline)
+		checkEndDate(v, project, todo.Deadline)
+	}
+	if req.ReminderOffset != nil {
+		todo.ReminderOffset = parseReminderOffset(v, *req.ReminderOffset)
+	}
+
+	v.check(req.AssigneeID == nil || req.AssigneeIDs == nil, "assignee_ids", assigneeConflictError)
+	assigneeIDs := req.AssigneeIDs
+	if req.AssigneeID != nil {
+		assigneeIDs = []int64{*req.AssigneeID}
+	}
+	ids, err := h.checkAssignees(r, v, projectID, assigneeIDs)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
 		return
 	}
-	if !model.ValidPriority(todo.Priority) {
-		writeError(w, http.StatusBadRequest, "priority must be 'low', 'medium', or 'high'")
+	if v.write(w) {
 		return
 	}
-
-	if req.Deadline != nil && *req.Deadline != "" {
-		t, err := time.Parse(time.RFC3339, *req.Deadline)
-		if err != nil {
-			writeError(w, http.StatusBadRequest, "deadline must be in RFC3339 format")
-			return
-		}
-		todo.Deadline = &t
+	for _, id := range ids {
+		todo.Assignees = append(todo.Assignees, model.TodoAssignee{UserID: id})
+	}
+	if !h.checkTodoLimit(w, r, projectID) {
+		return
 	}
 
 	if err := h.store.CreateTodo(r.Context(), todo); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to create todo")
+		writeServerError(w, err, "failed to create todo")
 		return
 	}
+	h.events.Dispatch(r.Context(), model.EventTodoCreated, todo)
 
-	writeJSON(w, http.StatusCreated, todo)
+	writeCreated(w, resourceURL("todos", todo.ID), todo)
 }
 
-// Get returns a single todo by ID.
+// checkTodoLimit reports whether the project has room for another todo,
+// writing a 409 if it is already at the configured cap.
+func (h *Todo) checkTodoLimit(w http.ResponseWriter, r *http.Request, projectID int64) bool {
+	if h.limits.MaxPerProject <= 0 {
+		return true
+	}
+	count, err := h.store.CountTodosByProject(r.Context(), projectID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return false
+	}
+	if count >= h.limits.MaxPerProject {
+		writeError(w, http.StatusConflict, fmt.Sprintf(
+			"todo limit reached: a project can have at most %s; delete some to make room", countNoun(h.limits.MaxPerProject, "todo")))
+		return false
+	}
+	return true
+}
+
+// ListCompleted returns the project's todos completed in the range given by
+// ?from= and ?to= (RFC3339; from inclusive, to exclusive), ordered by
+// completion time.
+func (h *Todo) ListCompleted(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+
+	v := validation{}
+	from, err := queryTime(r, "from")
+	if err != nil {
+		v.check(false, "from", err.Error())
+	}
+	to, err := queryTime(r, "to")
+	if err != nil {
+		v.check(false, "to", err.Error())
+	}
+	v.check(from != nil, "from", "from is required")
+	v.check(to != nil, "to", "to is required")
+	if from != nil && to != nil {
+		v.check(from.Before(*to), "to", "to must be after from")
+	}
+	if v.write(w) {
+		return
+	}
+
+	if !h.requireProject(w, r, projectID) {
+		return
+	}
+	userID := middleware.GetUserID(r.Context())
+	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, "you do not have access to this project")
+		return
+	}
+
+	todos, err := h.store.ListCompletedBetween(r.Context(), projectID, *from, *to)
+	if err != nil {
+		writeServerError(w, err, "failed to list completed todos")
+		return
+	}
+	writeJSON(w, http.StatusOK, todos)
+}
+
+// DeleteCompleted removes all completed todos in a project (owner or editor
+// only) and returns how many were deleted. A todo.deleted event is sent for
+// each of them.
+func (h *Todo) DeleteCompleted(w http.ResponseWriter, r *http.Request) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return
+	}
+	if !h.requireProject(w, r, projectID) {
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	role, err := h.store.GetMemberRole(r.Context(), projectID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if role == "" {
+		writeError(w, http.StatusForbidden, "you do not have access to this project")
+		return
+	}
+	if role == model.RoleViewer {
+		writeError(w, http.StatusForbidden, "viewers cannot delete todos")
+		return
+	}
+
+	// List the todos first so subscribers can be told which ones went.
+	completed, err := h.store.ListTodosByProject(r.Context(), projectID, store.TodoListParams{Status: model.StatusCompleted})
+	if err != nil {
+		writeServerError(w, err, "failed to list completed todos")
+		return
+	}
+	deleted, err := h.store.DeleteCompletedTodos(r.Context(), projectID)
+	if err != nil {
+		writeServerError(w, err, "failed to delete completed todos")
+		return
+	}
+	for i := range completed {
+		h.events.Dispatch(r.Context(), model.EventTodoDeleted, &completed[i])
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int64{"deleted": deleted})
+}
+
+// Get returns a single todo by ID. With ?expand=project the todo's project
+// is embedded under "project".
 func (h *Todo) Get(w http.ResponseWriter, r *http.Request) {
 	todoID, err := strconv.ParseInt(chi.URLParam(r, "todoID"), 10, 64)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "invalid todo id")
 		return
 	}
+	renderHTML, err := wantHTML(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	expand := r.URL.Query().Get("expand")
+	if expand != "" && expand != "project" {
+		writeError(w, http.StatusBadRequest, "expand must be 'project'")
+		return
+	}
 
 	todo, err := h.store.GetTodo(r.Context(), todoID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeError(w, http.StatusNotFound, "todo not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeTodoNotFound, "todo not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "failed to get todo")
+		writeServerError(w, err, "failed to get todo")
 		return
 	}
 
@@ -168,7 +494,7 @@ func (h *Todo) Get(w http.ResponseWriter, r *http.Request) {
 	userID := middleware.GetUserID(r.Context())
 	isMember, err := h.store.IsProjectMember(r.Context(), todo.ProjectID, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return
 	}
 	if !isMember {
@@ -176,7 +502,17 @@ func (h *Todo) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, todo)
+	resp := todoResponse{Todo: todo}
+	if renderHTML {
+		resp.DescriptionHTML = markdown.Render(todo.Description)
+	}
+	if expand == "project" {
+		if resp.Project, err = h.store.GetProject(r.Context(), todo.ProjectID); err != nil {
+			writeServerError(w, err, "failed to get project")
+			return
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // Update modifies an existing todo (owner or editor only).
@@ -189,18 +525,18 @@ func (h *Todo) Update(w http.ResponseWriter, r *http.Request) {
 
 	todo, err := h.store.GetTodo(r.Context(), todoID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeError(w, http.StatusNotFound, "todo not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeTodoNotFound, "todo not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "failed to get todo")
+		writeServerError(w, err, "failed to get todo")
 		return
 	}
 
 	userID := middleware.GetUserID(r.Context())
 	role, err := h.store.GetMemberRole(r.Context(), todo.ProjectID, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return
 	}
 	if role == "" {
@@ -217,48 +553,390 @@ func (h *Todo) Update(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	before := *todo
 
+	v := validation{}
 	if req.Title != nil {
 		todo.Title = *req.Title
 	}
 	if req.Description != nil {
 		todo.Description = *req.Description
 	}
-	if req.Status != nil {
-		if !model.ValidStatus(*req.Status) {
-			writeError(w, http.StatusBadRequest, "invalid status")
+	h.checkLengths(v, todo.Title, todo.Description)
+	// The project is only needed to check a new status or deadline.
+	var project *model.Project
+	if (req.Status != nil && *req.Status != todo.Status) || (req.Deadline != nil && *req.Deadline != "") {
+		if project, err = h.store.GetProject(r.Context(), todo.ProjectID); err != nil {
+			writeServerError(w, err, "failed to get project")
 			return
 		}
+	}
+	if req.Status != nil && *req.Status != todo.Status {
+		v.check(project.AllowsStatus(*req.Status), "status", statusError(project))
 		todo.Status = *req.Status
 	}
 	if req.Priority != nil {
-		if !model.ValidPriority(*req.Priority) {
-			writeError(w, http.StatusBadRequest, "invalid priority")
-			return
-		}
+		v.check(model.ValidPriority(*req.Priority), "priority", "invalid priority")
 		todo.Priority = *req.Priority
 	}
+	if req.PriorityRank.Set {
+		v.check(req.PriorityRank.Value == nil || model.ValidPriorityRank(*req.PriorityRank.Value), "priority_rank", priorityRankError)
+		todo.PriorityRank = req.PriorityRank.Value
+	}
+	if req.Estimate.Set {
+		v.check(req.Estimate.Value == nil || model.ValidEstimate(*req.Estimate.Value), "estimate", estimateError)
+		todo.Estimate = req.Estimate.Value
+	}
 	if req.Deadline != nil {
 		if *req.Deadline == "" {
 			todo.Deadline = nil
 		} else {
-			t, err := time.Parse(time.RFC3339, *req.Deadline)
-			if err != nil {
-				writeError(w, http.StatusBadRequest, "deadline must be in RFC3339 format")
-				return
-			}
-			todo.Deadline = &t
+			todo.Deadline = h.parseDeadline(v, *req.Deadline)
+			checkEndDate(v, project, todo.Deadline)
+		}
+	}
+	if req.ReminderOffset.Set {
+		todo.ReminderOffset = nil
+		if req.ReminderOffset.Value != nil {
+			todo.ReminderOffset = parseReminderOffset(v, *req.ReminderOffset.Value)
+		}
+	}
+
+	var assigneeIDs []int64
+	assigneesSet := req.AssigneeIDs.Set || req.AssigneeID.Set
+	v.check(!req.AssigneeIDs.Set || !req.AssigneeID.Set, "assignee_ids", assigneeConflictError)
+	switch {
+	case req.AssigneeIDs.Value != nil:
+		assigneeIDs = *req.AssigneeIDs.Value
+	case req.AssigneeID.Value != nil:
+		assigneeIDs = []int64{*req.AssigneeID.Value}
+	}
+	if assigneesSet {
+		if assigneeIDs, err = h.checkAssignees(r, v, todo.ProjectID, assigneeIDs); err != nil {
+			writeServerError(w, err, "internal server error")
+			return
+		}
+	}
+	if v.write(w) {
+		return
+	}
+	if h.blockOnDependencies && todo.Status == "completed" && before.Status != "completed" {
+		n, err := h.store.CountIncompleteDependencies(r.Context(), todo.ID)
+		if err != nil {
+			writeServerError(w, err, "internal server error")
+			return
+		}
+		if n > 0 {
+			writeError(w, http.StatusConflict, fmt.Sprintf("todo has %d incomplete dependencies", n))
+			return
 		}
 	}
 
-	if err := h.store.UpdateTodo(r.Context(), todo); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to update todo")
+	if assigneesSet && !slices.Equal(assigneeIDs, sortedAssigneeIDs(todo.Assignees)) {
+		err = h.store.UpdateTodoWithAssignees(r.Context(), todo, assigneeIDs)
+	} else {
+		err = h.store.UpdateTodo(r.Context(), todo)
+	}
+	if err != nil {
+		writeServerError(w, err, "failed to update todo")
 		return
 	}
+	if changes := diffTodo(&before, todo, userID); len(changes) > 0 {
+		if err := h.store.CreateTodoChanges(r.Context(), changes); err != nil {
+			log.Printf("record history for todo %d: %v", todo.ID, err)
+		}
+	}
+	h.events.Dispatch(r.Context(), model.EventTodoUpdated, todo)
+	if todo.Status == model.StatusCompleted && before.Status != model.StatusCompleted {
+		h.events.Dispatch(r.Context(), model.EventTodoCompleted, todo)
+	}
 
 	writeJSON(w, http.StatusOK, todo)
 }
 
+// Due returns the caller's incomplete todos, across all accessible projects,
+// whose deadline falls on a calendar day:
+//
+//	?date=YYYY-MM-DD (default: today in tz)
+//	?tz=<IANA zone, e.g. America/New_York> (default: the user's timezone, or UTC)
+//
+// The day runs from midnight to midnight in tz, so the same date can select
+// different todos for users in different zones.
+func (h *Todo) Due(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	var loc *time.Location
+	if tz := r.URL.Query().Get("tz"); tz != "" {
+		var err error
+		if loc, err = time.LoadLocation(tz); err != nil {
+			writeError(w, http.StatusBadRequest, "tz must be an IANA time zone like America/New_York")
+			return
+		}
+	} else {
+		user, err := h.store.GetUserByID(r.Context(), userID)
+		if err != nil {
+			writeServerError(w, err, "internal server error")
+			return
+		}
+		loc = user.Location()
+	}
+
+	now := time.Now().In(loc)
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	if date := r.URL.Query().Get("date"); date != "" {
+		var err error
+		if day, err = time.ParseInLocation(time.DateOnly, date, loc); err != nil {
+			writeError(w, http.StatusBadRequest, "date must be in YYYY-MM-DD format")
+			return
+		}
+	}
+
+	todos, err := h.store.ListTodosDueOn(r.Context(), userID, day)
+	if err != nil {
+		writeServerError(w, err, "failed to list todos")
+		return
+	}
+	writeJSON(w, http.StatusOK, todos)
+}
+
+// ListMine returns the todos in every project the user can access, grouped
+// by project in ascending project id order. It accepts the filters of
+// parseTodoListParams, with sort applying within each project, and pages
+// over todos with ?limit and ?offset, so a project's todos can continue on
+// the next page. X-Total-Count is the number of matching todos.
+func (h *Todo) ListMine(w http.ResponseWriter, r *http.Request) {
+	params, err := parseTodoListParams(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	pg, err := parsePagination(r, h.pagination)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	todos, total, err := h.store.ListTodosByUser(r.Context(), userID, params, pg.Limit, pg.Offset)
+	if err != nil {
+		writeServerError(w, err, "failed to list todos")
+		return
+	}
+	projects, err := h.store.ListProjectsByUser(r.Context(), userID)
+	if err != nil {
+		writeServerError(w, err, "failed to list projects")
+		return
+	}
+	byID := make(map[int64]*model.Project, len(projects))
+	for i := range projects {
+		byID[projects[i].ID] = &projects[i]
+	}
+
+	groups := []todoGroup{}
+	for _, t := range todos {
+		if n := len(groups); n > 0 && groups[n-1].Project.ID == t.ProjectID {
+			groups[n-1].Todos = append(groups[n-1].Todos, t)
+			continue
+		}
+		project, ok := byID[t.ProjectID]
+		if !ok {
+			continue // access was revoked between the two queries
+		}
+		groups = append(groups, todoGroup{Project: project, Todos: []model.Todo{t}})
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	writePageHeaders(w, pg)
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// History returns the change log of a todo, oldest first (members only).
+func (h *Todo) History(w http.ResponseWriter, r *http.Request) {
+	todoID, err := strconv.ParseInt(chi.URLParam(r, "todoID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid todo id")
+		return
+	}
+
+	todo, err := h.store.GetTodo(r.Context(), todoID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeTodoNotFound, "todo not found")
+			return
+		}
+		writeServerError(w, err, "failed to get todo")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	isMember, err := h.store.IsProjectMember(r.Context(), todo.ProjectID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	if !isMember {
+		writeError(w, http.StatusForbidden, "you do not have access to this todo")
+		return
+	}
+
+	changes, err := h.store.ListTodoHistory(r.Context(), todoID)
+	if err != nil {
+		writeServerError(w, err, "failed to list history")
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+// Activity returns the changes to todos in every project the user can
+// access, newest first, as the user's activity feed. It is paged with a
+// cursor rather than an offset so new activity does not shift the pages:
+// when a page is full, X-Next-Cursor holds the ?before value that fetches
+// the next one. ?limit works as on other lists.
+func (h *Todo) Activity(w http.ResponseWriter, r *http.Request) {
+	pg, err := parsePagination(r, h.pagination)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if pg.Offset != 0 {
+		writeError(w, http.StatusBadRequest, "the activity feed is paged with before, not offset")
+		return
+	}
+	before, err := queryInt64(r, "before")
+	if err != nil || before < 0 {
+		writeError(w, http.StatusBadRequest, "before must be a positive integer")
+		return
+	}
+
+	userID := middleware.GetUserID(r.Context())
+	activity, err := h.store.ListActivityForUser(r.Context(), userID, pg.Limit, before)
+	if err != nil {
+		writeServerError(w, err, "failed to list activity")
+		return
+	}
+	w.Header().Set("X-Page-Limit", strconv.Itoa(pg.Limit))
+	if len(activity) == pg.Limit {
+		w.Header().Set("X-Next-Cursor", strconv.FormatInt(activity[len(activity)-1].ID, 10))
+	}
+	writeJSON(w, http.StatusOK, activity)
+}
+
+// diffTodo returns one TodoChange per field that differs between before and
+// after, attributed to userID.
+func diffTodo(before, after *model.Todo, userID int64) []model.TodoChange {
+	var changes []model.TodoChange
+	add := func(field string, from, to *string) {
+		if (from == nil) == (to == nil) && (from == nil || *from == *to) {
+			return
+		}
+		changes = append(changes, model.TodoChange{
+			TodoID:   after.ID,
+			UserID:   &userID,
+			Field:    field,
+			OldValue: from,
+			NewValue: to,
+		})
+	}
+	add("title", &before.Title, &after.Title)
+	add("description", &before.Description, &after.Description)
+	add("status", &before.Status, &after.Status)
+	add("priority", &before.Priority, &after.Priority)
+	add("priority_rank", formatOptional(before.PriorityRank, strconv.Itoa), formatOptional(after.PriorityRank, strconv.Itoa))
+	add("deadline", formatOptional(before.Deadline, formatTime), formatOptional(after.Deadline, formatTime))
+	add("reminder_offset", formatOptional(before.ReminderOffset, model.Duration.String),
+		formatOptional(after.ReminderOffset, model.Duration.String))
+	add("estimate", formatOptional(before.Estimate, strconv.Itoa), formatOptional(after.Estimate, strconv.Itoa))
+	add("assignees", formatAssignees(before.Assignees), formatAssignees(after.Assignees))
+	return changes
+}
+
+// formatAssignees renders assignees as their comma-separated user ids in
+// ascending order, or nil when there are none.
+func formatAssignees(assignees []model.TodoAssignee) *string {
+	if len(assignees) == 0 {
+		return nil
+	}
+	ids := sortedAssigneeIDs(assignees)
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = formatID(id)
+	}
+	s := strings.Join(parts, ",")
+	return &s
+}
+
+func sortedAssigneeIDs(assignees []model.TodoAssignee) []int64 {
+	ids := make([]int64, len(assignees))
+	for i, a := range assignees {
+		ids[i] = a.UserID
+	}
+	slices.Sort(ids)
+	return ids
+}
+
+// parseDeadline parses an RFC3339 deadline and converts it to UTC, so every
+// driver stores and compares the same instant the same way. Deadlines
+// outside the configured bounds around now are rejected.
+func (h *Todo) parseDeadline(v validation, s string) *time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		v.check(false, "deadline", deadlineFormatError)
+		return nil
+	}
+	now := time.Now()
+	v.check(!t.Before(now.Add(-h.limits.DeadlineMaxPast)) && !t.After(now.Add(h.limits.DeadlineMaxFuture)), "deadline",
+		fmt.Sprintf("deadline must be within %s before and %s after now",
+			formatDays(h.limits.DeadlineMaxPast), formatDays(h.limits.DeadlineMaxFuture)))
+	t = t.UTC()
+	return &t
+}
+
+// checkEndDate records an error in v if deadline is after the project's end
+// date.
+func checkEndDate(v validation, project *model.Project, deadline *time.Time) {
+	if deadline != nil && !project.AllowsDeadline(*deadline) {
+		v.check(false, "deadline", "deadline must not be after the project's end date, "+project.EndDate.Format(time.RFC3339))
+	}
+}
+
+// formatDays formats d as a whole number of days, for messages about long
+// spans.
+func formatDays(d time.Duration) string {
+	days := int(d / (24 * time.Hour))
+	if days == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", days)
+}
+
+// parseReminderOffset parses s as a Go duration, truncated to whole seconds
+// since that is how it is stored, and records an error in v if it is invalid
+// or out of range.
+func parseReminderOffset(v validation, s string) *model.Duration {
+	d, err := time.ParseDuration(s)
+	v.check(err == nil && d >= 0 && d <= maxReminderOffset, "reminder_offset", reminderOffsetError)
+	offset := model.Duration(d.Truncate(time.Second))
+	return &offset
+}
+
+func formatOptional[T any](v *T, format func(T) string) *string {
+	if v == nil {
+		return nil
+	}
+	s := format(*v)
+	return &s
+}
+
+func formatTime(t time.Time) string { return t.UTC().Format(time.RFC3339) }
+
+func formatID(id int64) string { return strconv.FormatInt(id, 10) }
+
+// countNoun formats n followed by noun, adding an "s" unless n is 1.
+func countNoun(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
+
 // Delete removes a todo (owner or editor only).
 func (h *Todo) Delete(w http.ResponseWriter, r *http.Request) {
 	todoID, err := strconv.ParseInt(chi.URLParam(r, "todoID"), 10, 64)
@@ -269,18 +947,18 @@ func (h *Todo) Delete(w http.ResponseWriter, r *http.Request) {
 
 	todo, err := h.store.GetTodo(r.Context(), todoID)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			writeError(w, http.StatusNotFound, "todo not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeTodoNotFound, "todo not found")
 			return
 		}
-		writeError(w, http.StatusInternalServerError, "failed to get todo")
+		writeServerError(w, err, "failed to get todo")
 		return
 	}
 
 	userID := middleware.GetUserID(r.Context())
 	role, err := h.store.GetMemberRole(r.Context(), todo.ProjectID, userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return
 	}
 	if role == "" {
@@ -293,9 +971,129 @@ func (h *Todo) Delete(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := h.store.DeleteTodo(r.Context(), todoID); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to delete todo")
+		writeServerError(w, err, "failed to delete todo")
 		return
 	}
+	h.events.Dispatch(r.Context(), model.EventTodoDeleted, todo)
 
 	w.WriteHeader(http.StatusNoContent)
 }
+
+// parseTodoListParams reads the list filters shared by ListByProject and
+// Board:
+//
+//	?sort=created_at|priority|priority_rank
+//	?status=<status> (any well-formed name, since projects may define their own)
+//	?priority=low|medium|high
+//	?assignee_id=<user id>|unassigned
+//	?updated_since=<RFC3339 time> (only todos changed after it, for delta sync)
+//	?q=<text> (matched against title and description, ignoring case)
+func parseTodoListParams(r *http.Request) (store.TodoListParams, error) {
+	q := r.URL.Query()
+	params := store.TodoListParams{
+		Sort:     q.Get("sort"),
+		Status:   q.Get("status"),
+		Priority: q.Get("priority"),
+		Query:    q.Get("q"),
+	}
+	if !store.ValidTodoSort(params.Sort) {
+		return params, errors.New("sort must be 'created_at', 'priority', or 'priority_rank'")
+	}
+	if params.Status != "" && !model.ValidStatusName(params.Status) {
+		return params, errors.New("invalid status")
+	}
+	if params.Priority != "" && !model.ValidPriority(params.Priority) {
+		return params, errors.New("priority must be 'low', 'medium', or 'high'")
+	}
+	switch assignee := q.Get("assignee_id"); assignee {
+	case "":
+	case "unassigned":
+		params.Unassigned = true
+	default:
+		id, err := strconv.ParseInt(assignee, 10, 64)
+		if err != nil {
+			return params, errors.New("assignee_id must be a user id or 'unassigned'")
+		}
+		params.AssigneeID = id
+	}
+	var err error
+	if params.UpdatedSince, err = queryTime(r, "updated_since"); err != nil {
+		return params, err
+	}
+	return params, nil
+}
+
+// checkLengths enforces the configured title and description limits,
+// recording an error for each field that exceeds its limit. SQLite stores
+// TEXT without a limit, so this is the only check there.
+func (h *Todo) checkLengths(v validation, title, description string) {
+	v.check(utf8.RuneCountInString(title) <= h.limits.MaxTitle,
+		"title", fmt.Sprintf("title must be at most %d characters", h.limits.MaxTitle))
+	v.check(utf8.RuneCountInString(description) <= h.limits.MaxDescription,
+		"description", fmt.Sprintf("description must be at most %d characters", h.limits.MaxDescription))
+}
+
+// checkAssignees sorts and de-duplicates ids and records a validation error
+// if any of them is not a member of the project.
+func (h *Todo) checkAssignees(r *http.Request, v validation, projectID int64, ids []int64) ([]int64, error) {
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	for _, id := range ids {
+		isMember, err := h.store.IsProjectMember(r.Context(), projectID, id)
+		if err != nil {
+			return nil, err
+		}
+		v.check(isMember, "assignee_ids", "assignee is not a member of this project")
+	}
+	return ids, nil
+}
+
+// requireProject writes 404 if the project does not exist, so nested routes
+// report a stale project link as missing rather than forbidden.
+func (h *Todo) requireProject(w http.ResponseWriter, r *http.Request, projectID int64) bool {
+	exists, err := h.store.ProjectExists(r.Context(), projectID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return false
+	}
+	if !exists {
+		writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
+		return false
+	}
+	return true
+}
+
+// loadProject returns the project, writing 404 if it does not exist.
+func (h *Todo) loadProject(w http.ResponseWriter, r *http.Request, projectID int64) (*model.Project, bool) {
+	project, err := h.store.GetProject(r.Context(), projectID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
+			return nil, false
+		}
+		writeServerError(w, err, "internal server error")
+		return nil, false
+	}
+	return project, true
+}
+
+// statusError describes the statuses a project's todos may have.
+func statusError(project *model.Project) string {
+	return "status must be one of: " + strings.Join(project.AllowedStatuses(), ", ")
+}
+
+// requireMember checks that userID belongs to the project, writing 400 if
+// not. It is used to validate assignees.
+func (h *Todo) requireMember(w http.ResponseWriter, r *http.Request, projectID, userID int64) bool {
+	isMember, err := h.store.IsProjectMember(r.Context(), projectID, userID)
+	if err != nil {
+		writeServerError(w, err, "internal server error")
+		return false
+	}
+	if !isMember {
+		writeError(w, http.StatusBadRequest, "assignee is not a member of this project")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,1429 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/config"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+// createProject creates a project as the token's user and returns its ID.
+func createProject(t *testing.T, router http.Handler, token, name string) int64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", token, fmt.Sprintf(`{"name":%q}`, name)))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create project: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var project struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&project)
+	return project.ID
+}
+
+// createTodo creates a todo from a JSON body and returns its ID.
+func createTodo(t *testing.T, router http.Handler, token string, projectID int64, body string) int64 {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/todos", projectID), token, body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create todo: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var todo struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&todo)
+	return todo.ID
+}
+
+// addMember shares a project with username under the given role.
+func addMember(t *testing.T, router http.Handler, ownerToken string, projectID int64, username, role string) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	body := fmt.Sprintf(`{"username":%q,"role":%q}`, username, role)
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/members", projectID), ownerToken, body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("add member: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestDeleteCompletedTodos(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+
+	projectID := createProject(t, router, aliceToken, "Chores")
+	addMember(t, router, aliceToken, projectID, "bob", "viewer")
+
+	createTodo(t, router, aliceToken, projectID, `{"title":"Done 1","status":"completed"}`)
+	createTodo(t, router, aliceToken, projectID, `{"title":"Done 2","status":"completed"}`)
+	createTodo(t, router, aliceToken, projectID, `{"title":"Open"}`)
+
+	path := fmt.Sprintf("/api/projects/%d/todos/completed", projectID)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", path, bobToken, ""))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("viewer: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", path, aliceToken, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("owner: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var resp struct{ Deleted int64 }
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Deleted != 2 {
+		t.Errorf("deleted = %d, want 2", resp.Deleted)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos", projectID), aliceToken, ""))
+	var todos []struct{ Title string }
+	json.NewDecoder(rec.Body).Decode(&todos)
+	if len(todos) != 1 || todos[0].Title != "Open" {
+		t.Errorf("remaining todos = %+v, want [Open]", todos)
+	}
+}
+
+func TestHideCompletedTodos(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Chores")
+	createTodo(t, router, token, projectID, `{"title":"Done","status":"completed"}`)
+	createTodo(t, router, token, projectID, `{"title":"Open"}`)
+
+	list := func(query string) []string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos%s", projectID, query), token, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list%s: status = %d, body = %s", query, rec.Code, rec.Body.String())
+		}
+		var todos []struct{ Title string }
+		json.NewDecoder(rec.Body).Decode(&todos)
+		titles := make([]string, len(todos))
+		for i, todo := range todos {
+			titles[i] = todo.Title
+		}
+		slices.Sort(titles)
+		return titles
+	}
+
+	if got := list(""); !slices.Equal(got, []string{"Done", "Open"}) {
+		t.Errorf("default list = %v, want completed todos included", got)
+	}
+	if got := list("?include_completed=false"); !slices.Equal(got, []string{"Open"}) {
+		t.Errorf("include_completed=false = %v, want [Open]", got)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/projects/%d", projectID), token, `{"hide_completed":true}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(rec.Body.String(), `"hide_completed":true`) {
+		t.Errorf("update response = %s, want hide_completed set", rec.Body.String())
+	}
+
+	if got := list(""); !slices.Equal(got, []string{"Open"}) {
+		t.Errorf("hidden by default = %v, want [Open]", got)
+	}
+	if got := list("?include_completed=true"); !slices.Equal(got, []string{"Done", "Open"}) {
+		t.Errorf("include_completed=true = %v, want both", got)
+	}
+	if got := list("?status=completed"); !slices.Equal(got, []string{"Done"}) {
+		t.Errorf("status=completed = %v, want [Done]", got)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos?include_completed=maybe", projectID), token, ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad include_completed: status = %d, want 400", rec.Code)
+	}
+
+	// Stats still count the hidden todos.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/stats", projectID), token, ""))
+	var stats struct {
+		TotalTodos     int `json:"total_todos"`
+		CompletedTodos int `json:"completed_todos"`
+	}
+	json.NewDecoder(rec.Body).Decode(&stats)
+	if stats.TotalTodos != 2 || stats.CompletedTodos != 1 {
+		t.Errorf("stats = %+v, want 2 total and 1 completed", stats)
+	}
+}
+
+func TestSearchProjectTodos(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Home")
+	createTodo(t, router, token, projectID, `{"title":"Buy groceries","priority":"high"}`)
+	createTodo(t, router, token, projectID, `{"title":"Call plumber","description":"Kitchen sink leaks; get GROCERIES on the way"}`)
+	createTodo(t, router, token, projectID, `{"title":"Water plants"}`)
+	otherID := createProject(t, router, token, "Work")
+	createTodo(t, router, token, otherID, `{"title":"Groceries for the office party"}`)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"Buy groceries", "Call plumber", "Water plants"}},
+		{"?q=grocer", []string{"Buy groceries", "Call plumber"}},
+		{"?q=grocer&priority=high", []string{"Buy groceries"}},
+		{"?q=PLANT", []string{"Water plants"}},
+		{"?q=vacuum", []string{}},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos%s", projectID, tt.query), token, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, body = %s", tt.query, rec.Code, rec.Body.String())
+		}
+		var todos []struct{ Title string }
+		json.NewDecoder(rec.Body).Decode(&todos)
+		titles := []string{}
+		for _, todo := range todos {
+			titles = append(titles, todo.Title)
+		}
+		slices.Sort(titles)
+		if !slices.Equal(titles, tt.want) {
+			t.Errorf("%q: titles = %v, want %v", tt.query, titles, tt.want)
+		}
+	}
+}
+
+func TestTodoDeadlineNormalization(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{DeadlineMaxPast: 24 * time.Hour, DeadlineMaxFuture: 30 * 24 * time.Hour})
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Home")
+
+	local := time.Now().Add(48 * time.Hour).Truncate(time.Second).In(time.FixedZone("UTC+2", 2*60*60))
+	body := fmt.Sprintf(`{"title":"T","deadline":%q}`, local.Format(time.RFC3339))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/todos", projectID), token, body))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var todo struct {
+		ID       int64  `json:"id"`
+		Deadline string `json:"deadline"`
+	}
+	json.NewDecoder(rec.Body).Decode(&todo)
+	if want := local.UTC().Format(time.RFC3339); todo.Deadline != want {
+		t.Errorf("deadline = %q, want %q in UTC", todo.Deadline, want)
+	}
+
+	for _, deadline := range []time.Time{time.Now().Add(-48 * time.Hour), time.Now().Add(60 * 24 * time.Hour)} {
+		body := fmt.Sprintf(`{"deadline":%q}`, deadline.Format(time.RFC3339))
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", todo.ID), token, body))
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "deadline must be within 1 day before and 30 days after now") {
+			t.Errorf("deadline %v: status = %d, body = %s", deadline, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestTodoDeadlineWithinProjectEndDate(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	end := time.Now().Add(7 * 24 * time.Hour).Truncate(time.Second).UTC()
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", token, fmt.Sprintf(`{"name":"Sprint","end_date":%q}`, end.Format(time.RFC3339))))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create project: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var project struct {
+		ID      int64      `json:"id"`
+		EndDate *time.Time `json:"end_date"`
+	}
+	json.NewDecoder(rec.Body).Decode(&project)
+	if project.EndDate == nil || !project.EndDate.Equal(end) {
+		t.Fatalf("end_date = %v, want %v", project.EndDate, end)
+	}
+	todoID := createTodo(t, router, token, project.ID, `{"title":"Undated"}`)
+
+	tests := []struct {
+		name       string
+		deadline   time.Time
+		createCode int
+		updateCode int
+	}{
+		{"before", end.Add(-time.Hour), http.StatusCreated, http.StatusOK},
+		{"on", end, http.StatusCreated, http.StatusOK},
+		{"after", end.Add(time.Second), http.StatusBadRequest, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deadline := tt.deadline.Format(time.RFC3339)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/todos", project.ID), token,
+				fmt.Sprintf(`{"title":"T","deadline":%q}`, deadline)))
+			if rec.Code != tt.createCode {
+				t.Errorf("create: status = %d, want %d; body = %s", rec.Code, tt.createCode, rec.Body.String())
+			}
+
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", todoID), token, fmt.Sprintf(`{"deadline":%q}`, deadline)))
+			if rec.Code != tt.updateCode {
+				t.Errorf("update: status = %d, want %d; body = %s", rec.Code, tt.updateCode, rec.Body.String())
+			}
+			if tt.updateCode == http.StatusBadRequest && !strings.Contains(rec.Body.String(), "after the project's end date") {
+				t.Errorf("update: body = %s, want the end date error", rec.Body.String())
+			}
+		})
+	}
+
+	// Clearing the end date lifts the limit.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/projects/%d", project.ID), token, `{"end_date":null}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("clear end_date: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", todoID), token,
+		fmt.Sprintf(`{"deadline":%q}`, end.Add(24*time.Hour).Format(time.RFC3339))))
+	if rec.Code != http.StatusOK {
+		t.Errorf("deadline after cleared end date: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestTodoPriorityRankValidation(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Ranked")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/todos", projectID), token, `{"title":"Bad","priority_rank":-1}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("negative rank: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	todoID := createTodo(t, router, token, projectID, `{"title":"Good","priority_rank":5}`)
+
+	// Sending null clears the rank.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", todoID), token, `{"priority_rank":null}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var todo struct {
+		PriorityRank *int `json:"priority_rank"`
+	}
+	json.NewDecoder(rec.Body).Decode(&todo)
+	if todo.PriorityRank != nil {
+		t.Errorf("priority_rank = %d, want null", *todo.PriorityRank)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos?sort=bogus", projectID), token, ""))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("bad sort: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTodoBoard(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Kanban")
+
+	createTodo(t, router, token, projectID, `{"title":"A"}`)
+	createTodo(t, router, token, projectID, `{"title":"B","status":"in_progress"}`)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos/board", projectID), token, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("board: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	var board map[string][]struct{ Title string }
+	json.NewDecoder(rec.Body).Decode(&board)
+	if len(board["pending"]) != 1 || len(board["in_progress"]) != 1 {
+		t.Errorf("board = %+v, want one pending and one in_progress", board)
+	}
+	completed, ok := board["completed"]
+	if !ok || completed == nil || len(completed) != 0 {
+		t.Errorf("completed = %v, want present and empty", completed)
+	}
+}
+
+func TestTodoRecordsCreator(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Mine")
+	todoID := createTodo(t, router, token, projectID, `{"title":"Mine"}`)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/todos/%d", todoID), token, ""))
+	var todo struct {
+		CreatedBy     *int64 `json:"created_by"`
+		CreatedByName string `json:"created_by_name"`
+	}
+	json.NewDecoder(rec.Body).Decode(&todo)
+	if todo.CreatedBy == nil || todo.CreatedByName != "alice" {
+		t.Errorf("created_by = %v (%q), want alice", todo.CreatedBy, todo.CreatedByName)
+	}
+}
+
+func TestListTodosByAssignee(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	registerUser(t, router, "bob", "bob@example.com", "password123")
+	registerUser(t, router, "carol", "carol@example.com", "password123")
+
+	projectID := createProject(t, router, aliceToken, "Team")
+	addMember(t, router, aliceToken, projectID, "bob", "editor")
+
+	// bob is user 2, carol (not a member) is user 3.
+	createTodo(t, router, aliceToken, projectID, `{"title":"Bob's","assignee_id":2}`)
+	createTodo(t, router, aliceToken, projectID, `{"title":"Bob's urgent","assignee_id":2,"priority":"high"}`)
+	createTodo(t, router, aliceToken, projectID, `{"title":"Nobody's"}`)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/todos", projectID), aliceToken, `{"title":"Carol's","assignee_id":3}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("non-member assignee: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	tests := []struct {
+		query string
+		want  int
+	}{
+		{"assignee_id=2", 2},
+		{"assignee_id=2&priority=high", 1},
+		{"assignee_id=unassigned", 1},
+		{"assignee_id=2&status=completed", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos?%s", projectID, tt.query), aliceToken, ""))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			var todos []struct{ Title string }
+			json.NewDecoder(rec.Body).Decode(&todos)
+			if todos == nil || len(todos) != tt.want {
+				t.Errorf("got %d todos, want %d", len(todos), tt.want)
+			}
+		})
+	}
+}
+
+func TestNestedTodoRoutesMissingProject(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+	projectID := createProject(t, router, aliceToken, "Private")
+
+	routes := []struct{ method, path, body string }{
+		{"GET", "/api/projects/%d/todos", ""},
+		{"POST", "/api/projects/%d/todos", `{"title":"x"}`},
+		{"GET", "/api/projects/%d/todos/board", ""},
+		{"DELETE", "/api/projects/%d/todos/completed", ""},
+	}
+	for _, rt := range routes {
+		t.Run(rt.method+" "+rt.path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest(rt.method, fmt.Sprintf(rt.path, 9999), aliceToken, rt.body))
+			if rec.Code != http.StatusNotFound {
+				t.Errorf("missing project: status = %d, want %d", rec.Code, http.StatusNotFound)
+			}
+			rec = httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest(rt.method, fmt.Sprintf(rt.path, projectID), bobToken, rt.body))
+			if rec.Code != http.StatusForbidden {
+				t.Errorf("non-member: status = %d, want %d", rec.Code, http.StatusForbidden)
+			}
+		})
+	}
+}
+
+func TestTodoEstimates(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Sprint")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/todos", projectID), token, `{"title":"Bad","estimate":-1}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("negative estimate: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	done := createTodo(t, router, token, projectID, `{"title":"Done","estimate":3}`)
+	createTodo(t, router, token, projectID, `{"title":"Open","estimate":5}`)
+	unestimated := createTodo(t, router, token, projectID, `{"title":"Unknown"}`)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", done), token, `{"status":"completed"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("complete: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", unestimated), token, `{"status":"completed"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("complete: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/stats", projectID), token, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("stats: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var stats store.ProjectStats
+	json.NewDecoder(rec.Body).Decode(&stats)
+	want := store.ProjectStats{
+		TotalTodos:        3,
+		CompletedTodos:    2,
+		TodosByStatus:     map[string]int{"pending": 1, "completed": 2},
+		TodosByPriority:   map[string]int{"low": 0, "medium": 3, "high": 0},
+		EstimatedTodos:    2,
+		TotalEstimate:     8,
+		CompletedEstimate: 3,
+	}
+	if !reflect.DeepEqual(stats, want) {
+		t.Errorf("stats = %+v, want %+v", stats, want)
+	}
+
+	// Sending null clears the estimate.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", done), token, `{"estimate":null}`))
+	var todo model.Todo
+	json.NewDecoder(rec.Body).Decode(&todo)
+	if todo.Estimate != nil {
+		t.Errorf("estimate = %d, want null", *todo.Estimate)
+	}
+
+	other := registerUser(t, router, "bob", "bob@example.com", "password123")
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/stats", projectID), other, ""))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("non-member stats: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestTodoNullFieldsAreSerialized(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Mine")
+	todoID := createTodo(t, router, token, projectID, `{"title":"Someday"}`)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/todos/%d", todoID), token, ""))
+	var fields map[string]json.RawMessage
+	json.NewDecoder(rec.Body).Decode(&fields)
+	for _, name := range []string{"deadline", "priority_rank", "reminder_offset", "estimate"} {
+		if v, ok := fields[name]; !ok || string(v) != "null" {
+			t.Errorf("%s = %s (present %v), want null", name, v, ok)
+		}
+	}
+}
+
+func TestTodoReminderOffset(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Mine")
+	todoID := createTodo(t, router, token, projectID, `{"title":"Renew passport","reminder_offset":"24h"}`)
+	path := fmt.Sprintf("/api/todos/%d", todoID)
+
+	offset := func() string {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", path, token, ""))
+		var fields map[string]json.RawMessage
+		json.NewDecoder(rec.Body).Decode(&fields)
+		return string(fields["reminder_offset"])
+	}
+	if got := offset(); got != `"24h0m0s"` {
+		t.Errorf("reminder_offset = %s, want \"24h0m0s\"", got)
+	}
+
+	for _, bad := range []string{`"soon"`, `"-1h"`, `"2000h"`} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("PUT", path, token, `{"reminder_offset":`+bad+`}`))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("reminder_offset %s: status = %d, want %d", bad, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", path, token, `{"reminder_offset":null}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("clear: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got := offset(); got != "null" {
+		t.Errorf("reminder_offset after clear = %s, want null", got)
+	}
+}
+
+func TestProjectCustomStatuses(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Mine")
+	projectPath := fmt.Sprintf("/api/projects/%d", projectID)
+
+	// A workflow must keep pending and completed.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", projectPath, token, `{"name":"Mine","statuses":["pending","blocked"]}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("statuses without completed: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", projectPath, token,
+		`{"name":"Mine","statuses":["pending","blocked","review","completed"]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set statuses: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	todoID := createTodo(t, router, token, projectID, `{"title":"Waiting on legal","status":"blocked"}`)
+	todoPath := fmt.Sprintf("/api/todos/%d", todoID)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", todoPath, token, `{"status":"in_progress"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status outside the workflow: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", projectPath+"/todos/board", token, ""))
+	var board struct {
+		Other map[string][]struct{ ID int64 }
+	}
+	json.NewDecoder(rec.Body).Decode(&board)
+	if len(board.Other["blocked"]) != 1 || board.Other["blocked"][0].ID != todoID {
+		t.Errorf("board other = %+v, want the todo under blocked", board.Other)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", projectPath+"/todos?status=blocked", token, ""))
+	var todos []struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&todos)
+	if len(todos) != 1 {
+		t.Errorf("got %d blocked todos, want 1", len(todos))
+	}
+
+	// Resetting to the defaults leaves existing todos alone but rejects the
+	// custom status for new ones.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", projectPath, token, `{"name":"Mine","statuses":null}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reset statuses: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", todoPath, token, `{"title":"Still waiting","status":"blocked"}`))
+	if rec.Code != http.StatusOK {
+		t.Errorf("update keeping a dropped status: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", projectPath+"/todos", token, `{"title":"New","status":"blocked"}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("create with a dropped status: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestTodoValidationReportsAllFields(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Mine")
+
+	rec := httptest.NewRecorder()
+	body := `{"title":"","priority":"urgent","deadline":"tomorrow","assignee_ids":[42]}`
+	router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/todos", projectID), token, body))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	var resp struct {
+		Error  string
+		Errors map[string]string
+	}
+	json.NewDecoder(rec.Body).Decode(&resp)
+	for _, field := range []string{"title", "priority", "deadline", "assignee_ids"} {
+		if resp.Errors[field] == "" {
+			t.Errorf("missing error for %s: %v", field, resp.Errors)
+		}
+	}
+	if _, ok := resp.Errors["status"]; ok {
+		t.Errorf("unexpected status error: %v", resp.Errors)
+	}
+	if resp.Error != resp.Errors["assignee_ids"] {
+		t.Errorf("error = %q, want the first field's message", resp.Error)
+	}
+}
+
+func TestTodoMultipleAssignees(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	registerUser(t, router, "bob", "bob@example.com", "password123")
+
+	projectID := createProject(t, router, aliceToken, "Team")
+	addMember(t, router, aliceToken, projectID, "bob", "editor")
+	todoID := createTodo(t, router, aliceToken, projectID, `{"title":"Pair","assignee_ids":[2,1,2]}`)
+
+	assignees := func() []string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/todos/%d", todoID), aliceToken, ""))
+		var todo struct {
+			Assignees []struct{ Username string } `json:"assignees"`
+		}
+		json.NewDecoder(rec.Body).Decode(&todo)
+		var names []string
+		for _, a := range todo.Assignees {
+			names = append(names, a.Username)
+		}
+		return names
+	}
+	if got := assignees(); fmt.Sprint(got) != "[alice bob]" {
+		t.Errorf("assignees after create = %v, want [alice bob]", got)
+	}
+
+	for _, id := range []int{1, 2} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/projects/%d/todos?assignee_id=%d", projectID, id), aliceToken, ""))
+		var todos []struct{ ID int64 }
+		json.NewDecoder(rec.Body).Decode(&todos)
+		if len(todos) != 1 {
+			t.Errorf("assignee_id=%d: got %d todos, want 1", id, len(todos))
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", todoID), aliceToken, `{"assignee_ids":[2]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("update: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := assignees(); fmt.Sprint(got) != "[bob]" {
+		t.Errorf("assignees after update = %v, want [bob]", got)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", todoID), aliceToken, `{"assignee_ids":[1],"assignee_id":2}`))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("both fields: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", todoID), aliceToken, `{"assignee_ids":[]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("clear: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if got := assignees(); len(got) != 0 {
+		t.Errorf("assignees after clear = %v, want none", got)
+	}
+}
+
+func TestTodoLengthLimits(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{TodoMaxTitle: 10, TodoMaxDescription: 20})
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Limits")
+	path := fmt.Sprintf("/api/projects/%d/todos", projectID)
+
+	tests := []struct {
+		name        string
+		title, desc string
+		want        int
+	}{
+		{"title at limit", strings.Repeat("a", 10), "", http.StatusCreated},
+		{"title over limit", strings.Repeat("a", 11), "", http.StatusBadRequest},
+		{"multibyte title at limit", strings.Repeat("é", 10), "", http.StatusCreated},
+		{"description at limit", "ok", strings.Repeat("d", 20), http.StatusCreated},
+		{"description over limit", "ok", strings.Repeat("d", 21), http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := fmt.Sprintf(`{"title":%q,"description":%q}`, tt.title, tt.desc)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("POST", path, token, body))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d (body %s)", rec.Code, tt.want, rec.Body.String())
+			}
+		})
+	}
+
+	todoID := createTodo(t, router, token, projectID, `{"title":"short"}`)
+	todoPath := fmt.Sprintf("/api/todos/%d", todoID)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", todoPath, token, fmt.Sprintf(`{"title":%q}`, strings.Repeat("a", 11))))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("update over limit: status = %d, want 400", rec.Code)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", todoPath, token, fmt.Sprintf(`{"description":%q}`, strings.Repeat("d", 20))))
+	if rec.Code != http.StatusOK {
+		t.Errorf("update at limit: status = %d, want 200", rec.Code)
+	}
+}
+
+func TestTodoTitleDefaultLimit(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Defaults")
+	path := fmt.Sprintf("/api/projects/%d/todos", projectID)
+
+	for n, want := range map[int]int{255: http.StatusCreated, 256: http.StatusBadRequest} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("POST", path, token, fmt.Sprintf(`{"title":%q}`, strings.Repeat("a", n))))
+		if rec.Code != want {
+			t.Errorf("title of %d chars: status = %d, want %d", n, rec.Code, want)
+		}
+	}
+}
+
+func TestTodoHistory(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+	carolToken := registerUser(t, router, "carol", "carol@example.com", "password123")
+
+	projectID := createProject(t, router, aliceToken, "Tracked")
+	addMember(t, router, aliceToken, projectID, "bob", "editor")
+	todoID := createTodo(t, router, aliceToken, projectID, `{"title":"Ship it"}`)
+	todoPath := fmt.Sprintf("/api/todos/%d", todoID)
+
+	updates := []struct {
+		token, body string
+	}{
+		{aliceToken, `{"status":"in_progress","title":"Ship it"}`}, // unchanged title is not recorded
+		{bobToken, `{"status":"completed","assignee_id":2}`},
+		{bobToken, `{"assignee_id":null}`},
+	}
+	for _, u := range updates {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("PUT", todoPath, u.token, u.body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("update %s: status = %d, body = %s", u.body, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", todoPath+"/history", bobToken, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("history: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var changes []struct {
+		Username string
+		Field    string
+		OldValue *string `json:"old_value"`
+		NewValue *string `json:"new_value"`
+	}
+	json.NewDecoder(rec.Body).Decode(&changes)
+
+	str := func(s *string) string {
+		if s == nil {
+			return "<nil>"
+		}
+		return *s
+	}
+	var got []string
+	for _, c := range changes {
+		got = append(got, fmt.Sprintf("%s %s %s->%s", c.Username, c.Field, str(c.OldValue), str(c.NewValue)))
+	}
+	want := []string{
+		"alice status pending->in_progress",
+		"bob status in_progress->completed",
+		"bob assignees <nil>->2",
+		"bob assignees 2-><nil>",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("history =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", todoPath+"/history", carolToken, ""))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("non-member history: status = %d, want 403", rec.Code)
+	}
+}
+
+func TestListTodosUpdatedSince(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Sync")
+	createTodo(t, router, token, projectID, `{"title":"One"}`)
+	createTodo(t, router, token, projectID, `{"title":"Two"}`)
+
+	tests := []struct {
+		since     string
+		wantCode  int
+		wantCount int
+	}{
+		{time.Now().Add(-time.Hour).Format(time.RFC3339), http.StatusOK, 2},
+		{time.Now().Add(time.Hour).Format(time.RFC3339), http.StatusOK, 0},
+		{"yesterday", http.StatusBadRequest, 0},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		path := fmt.Sprintf("/api/projects/%d/todos?updated_since=%s", projectID, url.QueryEscape(tt.since))
+		router.ServeHTTP(rec, authedRequest("GET", path, token, ""))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d", tt.since, rec.Code, tt.wantCode)
+			continue
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		var todos []struct{ ID int64 }
+		json.NewDecoder(rec.Body).Decode(&todos)
+		if len(todos) != tt.wantCount {
+			t.Errorf("%s: got %d todos, want %d", tt.since, len(todos), tt.wantCount)
+		}
+	}
+}
+
+func TestListMyTodosGroupedByProject(t *testing.T) {
+	router := setupTestRouter(t)
+	alice := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bob := registerUser(t, router, "bob", "bob@example.com", "password123")
+
+	home := createProject(t, router, alice, "Home")
+	createTodo(t, router, alice, home, `{"title":"Dishes","priority":"high"}`)
+	createTodo(t, router, alice, home, `{"title":"Laundry","priority":"low"}`)
+	shared := createProject(t, router, bob, "Shared")
+	addMember(t, router, bob, shared, "alice", "viewer")
+	createTodo(t, router, bob, shared, `{"title":"Plan","priority":"high"}`)
+	private := createProject(t, router, bob, "Private")
+	createTodo(t, router, bob, private, `{"title":"Secret","priority":"high"}`)
+
+	type group struct {
+		Project struct{ ID int64 }
+		Todos   []struct{ Title string }
+	}
+	list := func(query string) ([]group, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", "/api/todos"+query, alice, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("list %q: status = %d, body = %s", query, rec.Code, rec.Body.String())
+		}
+		var groups []group
+		json.NewDecoder(rec.Body).Decode(&groups)
+		return groups, rec.Header().Get("X-Total-Count")
+	}
+
+	groups, total := list("")
+	if total != "3" || len(groups) != 2 || groups[0].Project.ID != home || groups[1].Project.ID != shared ||
+		len(groups[0].Todos) != 2 || len(groups[1].Todos) != 1 {
+		t.Errorf("all: total %s, groups %+v", total, groups)
+	}
+
+	groups, total = list("?priority=high")
+	if total != "2" || len(groups) != 2 || groups[0].Todos[0].Title != "Dishes" || groups[1].Todos[0].Title != "Plan" {
+		t.Errorf("high priority: total %s, groups %+v", total, groups)
+	}
+
+	groups, total = list("?limit=1&offset=2")
+	if total != "3" || len(groups) != 1 || groups[0].Project.ID != shared {
+		t.Errorf("last page: total %s, groups %+v", total, groups)
+	}
+}
+
+func TestTodosDueOnUsesTimeZone(t *testing.T) {
+	router := setupTestRouter(t)
+	aliceToken := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bobToken := registerUser(t, router, "bob", "bob@example.com", "password123")
+
+	projectID := createProject(t, router, aliceToken, "Deadlines")
+	// 03:00 UTC on March 11 is still the evening of March 10 in New York.
+	createTodo(t, router, aliceToken, projectID, `{"title":"Late evening NY","deadline":"2025-03-11T03:00:00Z"}`)
+	createTodo(t, router, aliceToken, projectID, `{"title":"Noon NY","deadline":"2025-03-10T16:00:00Z"}`)
+	createTodo(t, router, aliceToken, projectID, `{"title":"Done","deadline":"2025-03-10T16:00:00Z","status":"completed"}`)
+	createTodo(t, router, aliceToken, projectID, `{"title":"Next day","deadline":"2025-03-11T14:00:00Z"}`)
+	createTodo(t, router, bobToken, createProject(t, router, bobToken, "Bob's"), `{"title":"Not alice's","deadline":"2025-03-10T16:00:00Z"}`)
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"date=2025-03-10&tz=America/New_York", []string{"Noon NY", "Late evening NY"}},
+		{"date=2025-03-10", []string{"Noon NY"}},
+		{"date=2025-03-11", []string{"Late evening NY", "Next day"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest("GET", "/api/todos/due?"+tt.query, aliceToken, ""))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+			}
+			var todos []struct{ Title string }
+			json.NewDecoder(rec.Body).Decode(&todos)
+			var got []string
+			for _, todo := range todos {
+				got = append(got, todo.Title)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	for _, q := range []string{"date=03/10/2025", "tz=Mars/Olympus"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", "/api/todos/due?"+q, aliceToken, ""))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+func TestTodosDueDefaultsToUserTimeZone(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Deadlines")
+	createTodo(t, router, token, projectID, `{"title":"Late evening NY","deadline":"2025-03-11T03:00:00Z"}`)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("PUT", "/api/auth/me", token, `{"timezone":"America/New_York"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("set timezone: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	for _, tt := range []struct {
+		query string
+		want  int
+	}{
+		{"date=2025-03-10", 1},
+		{"date=2025-03-10&tz=UTC", 0},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", "/api/todos/due?"+tt.query, token, ""))
+		var todos []model.Todo
+		json.NewDecoder(rec.Body).Decode(&todos)
+		if len(todos) != tt.want {
+			t.Errorf("%s: got %d todos, want %d", tt.query, len(todos), tt.want)
+		}
+	}
+}
+
+func TestTodoDependencies(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{BlockIncompleteDependencies: true})
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Mine")
+	a := createTodo(t, router, token, projectID, `{"title":"A"}`)
+	b := createTodo(t, router, token, projectID, `{"title":"B"}`)
+	c := createTodo(t, router, token, projectID, `{"title":"C"}`)
+	other := createTodo(t, router, token, createProject(t, router, token, "Other"), `{"title":"X"}`)
+
+	add := func(todoID, dependsOnID int64) int {
+		rec := httptest.NewRecorder()
+		path := fmt.Sprintf("/api/todos/%d/dependencies", todoID)
+		router.ServeHTTP(rec, authedRequest("POST", path, token, fmt.Sprintf(`{"depends_on_id":%d}`, dependsOnID)))
+		return rec.Code
+	}
+	// A depends on B, B depends on C.
+	if code := add(a, b); code != http.StatusCreated {
+		t.Fatalf("add a->b: status = %d, want %d", code, http.StatusCreated)
+	}
+	if code := add(b, c); code != http.StatusCreated {
+		t.Fatalf("add b->c: status = %d, want %d", code, http.StatusCreated)
+	}
+	for _, tc := range []struct {
+		name      string
+		todo, dep int64
+		wantCode  int
+	}{
+		{"direct cycle", b, a, http.StatusConflict},
+		{"transitive cycle", c, a, http.StatusConflict},
+		{"self", a, a, http.StatusBadRequest},
+		{"cross project", a, other, http.StatusBadRequest},
+		{"missing", a, 9999, http.StatusNotFound},
+		{"duplicate", a, b, http.StatusCreated},
+	} {
+		if code := add(tc.todo, tc.dep); code != tc.wantCode {
+			t.Errorf("%s: status = %d, want %d", tc.name, code, tc.wantCode)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/todos/%d/dependencies", a), token, ""))
+	var deps []model.Todo
+	json.NewDecoder(rec.Body).Decode(&deps)
+	if len(deps) != 1 || deps[0].ID != b {
+		t.Fatalf("dependencies of A = %+v, want [B]", deps)
+	}
+
+	complete := func(todoID int64) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", todoID), token, `{"status":"completed"}`))
+		return rec.Code
+	}
+	if code := complete(a); code != http.StatusConflict {
+		t.Errorf("complete A with incomplete B: status = %d, want %d", code, http.StatusConflict)
+	}
+	if code := complete(c); code != http.StatusOK {
+		t.Errorf("complete C: status = %d, want %d", code, http.StatusOK)
+	}
+	if code := complete(b); code != http.StatusOK {
+		t.Errorf("complete B after C: status = %d, want %d", code, http.StatusOK)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", fmt.Sprintf("/api/todos/%d/dependencies/%d", b, c), token, ""))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("remove: status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	// With B->C gone, C may now depend on A.
+	if code := add(c, a); code != http.StatusCreated {
+		t.Errorf("add c->a after removal: status = %d, want %d", code, http.StatusCreated)
+	}
+}
+
+func TestTodoGraph(t *testing.T) {
+	router := setupTestRouter(t)
+	alice := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bob := registerUser(t, router, "bob", "bob@example.com", "password123")
+	projectID := createProject(t, router, alice, "Mine")
+	a := createTodo(t, router, alice, projectID, `{"title":"A"}`)
+	b := createTodo(t, router, alice, projectID, `{"title":"B"}`)
+	c := createTodo(t, router, alice, projectID, `{"title":"C"}`)
+	for _, dep := range [][2]int64{{a, b}, {a, c}, {b, c}} {
+		rec := httptest.NewRecorder()
+		path := fmt.Sprintf("/api/todos/%d/dependencies", dep[0])
+		router.ServeHTTP(rec, authedRequest("POST", path, alice, fmt.Sprintf(`{"depends_on_id":%d}`, dep[1])))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("add %d->%d: status = %d, want %d", dep[0], dep[1], rec.Code, http.StatusCreated)
+		}
+	}
+
+	path := fmt.Sprintf("/api/projects/%d/graph", projectID)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", path, alice, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var graph struct {
+		Nodes []struct {
+			ID      int64 `json:"id"`
+			Title   string
+			InCycle bool `json:"in_cycle"`
+		}
+		Edges    []model.TodoDependency
+		HasCycle bool `json:"has_cycle"`
+	}
+	json.NewDecoder(rec.Body).Decode(&graph)
+	if len(graph.Nodes) != 3 {
+		t.Errorf("nodes = %+v, want 3", graph.Nodes)
+	}
+	for _, n := range graph.Nodes {
+		if n.InCycle {
+			t.Errorf("node %s in_cycle = true, want false", n.Title)
+		}
+	}
+	wantEdges := []model.TodoDependency{{TodoID: a, DependsOnID: b}, {TodoID: a, DependsOnID: c}, {TodoID: b, DependsOnID: c}}
+	if !slices.Equal(graph.Edges, wantEdges) {
+		t.Errorf("edges = %+v, want %+v", graph.Edges, wantEdges)
+	}
+	if graph.HasCycle {
+		t.Error("has_cycle = true, want false")
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", path, bob, ""))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("non-member: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestGetTodoExpandProject(t *testing.T) {
+	router := setupTestRouter(t)
+	alice := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bob := registerUser(t, router, "bob", "bob@example.com", "password123")
+	projectID := createProject(t, router, alice, "Launch")
+	todoID := createTodo(t, router, alice, projectID, `{"title":"T"}`)
+	path := fmt.Sprintf("/api/todos/%d", todoID)
+
+	get := func(path, token string) (*httptest.ResponseRecorder, map[string]any) {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", path, token, ""))
+		var body map[string]any
+		json.NewDecoder(rec.Body).Decode(&body)
+		return rec, body
+	}
+
+	rec, body := get(path+"?expand=project", alice)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %v", rec.Code, body)
+	}
+	project, _ := body["project"].(map[string]any)
+	if project == nil || project["id"] != float64(projectID) || project["name"] != "Launch" || body["title"] != "T" {
+		t.Errorf("body = %v, want the todo with its project embedded", body)
+	}
+
+	if _, body := get(path, alice); body["project"] != nil {
+		t.Errorf("project embedded without expand: %v", body)
+	}
+	if rec, _ := get(path+"?expand=owner", alice); rec.Code != http.StatusBadRequest {
+		t.Errorf("expand=owner: status = %d, want 400", rec.Code)
+	}
+	if rec, body := get(path+"?expand=project", bob); rec.Code != http.StatusForbidden || body["project"] != nil {
+		t.Errorf("non-member: status = %d, body = %v; want 403 without the project", rec.Code, body)
+	}
+}
+
+func TestFieldSelection(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Work")
+	todoID := createTodo(t, router, token, projectID, `{"title":"One","description":"long text"}`)
+	createTodo(t, router, token, projectID, `{"title":"Two"}`)
+
+	keys := func(obj map[string]any) []string {
+		return slices.Sorted(maps.Keys(obj))
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/v1/projects/%d/todos?fields=id,title,status,bogus", projectID), token, ""))
+	var todos []map[string]any
+	if err := json.NewDecoder(rec.Body).Decode(&todos); err != nil || len(todos) != 2 {
+		t.Fatalf("list: %d todos, err %v", len(todos), err)
+	}
+	for _, todo := range todos {
+		if got := keys(todo); !slices.Equal(got, []string{"id", "status", "title"}) {
+			t.Errorf("list fields = %v, want [id status title]", got)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/v1/todos/%d?fields=title", todoID), token, ""))
+	var todo map[string]any
+	json.NewDecoder(rec.Body).Decode(&todo)
+	if got := keys(todo); !slices.Equal(got, []string{"title"}) || todo["title"] != "One" {
+		t.Errorf("get = %v, want only the title", todo)
+	}
+
+	// Without ?fields everything is returned, and errors are never trimmed.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/v1/todos/%d", todoID), token, ""))
+	todo = nil
+	json.NewDecoder(rec.Body).Decode(&todo)
+	if todo["description"] != "long text" {
+		t.Errorf("get without fields = %v, want the full todo", todo)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/v1/todos/99999?fields=id", token, ""))
+	var errResp map[string]any
+	json.NewDecoder(rec.Body).Decode(&errResp)
+	if rec.Code != http.StatusNotFound || errResp["error"] == nil || errResp["code"] == nil {
+		t.Errorf("missing todo: status %d, body %v, want an untrimmed 404", rec.Code, errResp)
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", fmt.Sprintf("/api/v1/projects/%d/todos/completed?fields=id", projectID), token, ""))
+	errResp = nil
+	json.NewDecoder(rec.Body).Decode(&errResp)
+	if rec.Code != http.StatusBadRequest || errResp["error"] == nil || errResp["errors"] == nil {
+		t.Errorf("invalid query: status %d, body %v, want an untrimmed 400", rec.Code, errResp)
+	}
+}
+
+func TestActivityFeed(t *testing.T) {
+	router := setupTestRouter(t)
+	alice := registerUser(t, router, "alice", "alice@example.com", "password123")
+	bob := registerUser(t, router, "bob", "bob@example.com", "password123")
+	carol := registerUser(t, router, "carol", "carol@example.com", "password123")
+
+	update := func(token string, todoID int64, body string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/v1/todos/%d", todoID), token, body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("update todo: status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+	}
+	own := createProject(t, router, alice, "Mine")
+	a := createTodo(t, router, alice, own, `{"title":"A"}`)
+	update(alice, a, `{"title":"A2"}`)
+	update(alice, a, `{"title":"A3"}`)
+	shared := createProject(t, router, bob, "Shared")
+	addMember(t, router, bob, shared, "alice", "editor")
+	b := createTodo(t, router, bob, shared, `{"title":"B"}`)
+	update(bob, b, `{"status":"completed"}`)
+	other := createProject(t, router, carol, "Other")
+	update(carol, createTodo(t, router, carol, other, `{"title":"C"}`), `{"title":"C2"}`)
+
+	type entry struct {
+		ID        int64  `json:"id"`
+		ProjectID int64  `json:"project_id"`
+		TodoTitle string `json:"todo_title"`
+		Field     string `json:"field"`
+		Username  string `json:"username"`
+	}
+	feed := func(query string) (*httptest.ResponseRecorder, []entry) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", "/api/v1/activity"+query, alice, ""))
+		var entries []entry
+		json.NewDecoder(rec.Body).Decode(&entries)
+		return rec, entries
+	}
+
+	rec, entries := feed("")
+	if rec.Code != http.StatusOK || len(entries) != 3 {
+		t.Fatalf("feed: status %d, entries %+v, want 3", rec.Code, entries)
+	}
+	want := []entry{
+		{ProjectID: shared, TodoTitle: "B", Field: "status", Username: "bob"},
+		{ProjectID: own, TodoTitle: "A3", Field: "title", Username: "alice"},
+		{ProjectID: own, TodoTitle: "A3", Field: "title", Username: "alice"},
+	}
+	for i, e := range entries {
+		if i > 0 && e.ID >= entries[i-1].ID {
+			t.Errorf("entries not newest first: %+v", entries)
+		}
+		e.ID = 0
+		if e != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, e, want[i])
+		}
+	}
+
+	// Page through with the cursor.
+	rec, page1 := feed("?limit=2")
+	cursor := rec.Header().Get("X-Next-Cursor")
+	if len(page1) != 2 || cursor != strconv.FormatInt(page1[1].ID, 10) {
+		t.Fatalf("page 1: %+v, cursor %q", page1, cursor)
+	}
+	rec, page2 := feed("?limit=2&before=" + cursor)
+	if len(page2) != 1 || page2[0].ID != entries[2].ID || rec.Header().Get("X-Next-Cursor") != "" {
+		t.Errorf("page 2: %+v, cursor %q, want the last entry and no cursor", page2, rec.Header().Get("X-Next-Cursor"))
+	}
+
+	for _, query := range []string{"?offset=1", "?before=-1", "?before=x"} {
+		if rec, _ := feed(query); rec.Code != http.StatusBadRequest {
+			t.Errorf("GET %s: status = %d, want %d", query, rec.Code, http.StatusBadRequest)
+		}
+	}
+
+	// Activity in a project alice was removed from disappears.
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/v1/auth/me", alice, ""))
+	var me struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&me)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", fmt.Sprintf("/api/v1/projects/%d/members/%d", shared, me.ID), bob, ""))
+	if rec.Code != http.StatusOK && rec.Code != http.StatusNoContent {
+		t.Fatalf("remove member: status = %d", rec.Code)
+	}
+	if _, entries := feed(""); len(entries) != 2 || entries[0].ProjectID != own {
+		t.Errorf("feed after removal = %+v, want only alice's project", entries)
+	}
+}
+
+func TestListCompletedTodos(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	other := registerUser(t, router, "bob", "bob@example.com", "password123")
+	projectID := createProject(t, router, token, "Done")
+	createTodo(t, router, token, projectID, `{"title":"Born done","status":"completed"}`)
+	laterID := createTodo(t, router, token, projectID, `{"title":"Done later"}`)
+	reopenedID := createTodo(t, router, token, projectID, `{"title":"Reopened","status":"completed"}`)
+	createTodo(t, router, token, projectID, `{"title":"Open"}`)
+
+	for id, status := range map[int64]string{laterID: "completed", reopenedID: "pending"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("PUT", fmt.Sprintf("/api/todos/%d", id), token, fmt.Sprintf(`{"status":%q}`, status)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("update todo %d: status = %d, body = %s", id, rec.Code, rec.Body.String())
+		}
+		var todo struct {
+			CompletedAt *time.Time `json:"completed_at"`
+		}
+		json.NewDecoder(rec.Body).Decode(&todo)
+		if (todo.CompletedAt != nil) != (status == "completed") {
+			t.Errorf("todo %d set to %s: completed_at = %v", id, status, todo.CompletedAt)
+		}
+	}
+
+	hour := func(n int) string {
+		return url.QueryEscape(time.Now().Add(time.Duration(n) * time.Hour).Format(time.RFC3339))
+	}
+	tests := []struct {
+		name      string
+		token     string
+		query     string
+		wantCode  int
+		wantTitle []string
+	}{
+		{"in range", token, "from=" + hour(-1) + "&to=" + hour(1), http.StatusOK, []string{"Born done", "Done later"}},
+		{"nothing completed", token, "from=" + hour(1) + "&to=" + hour(2), http.StatusOK, []string{}},
+		{"missing from", token, "to=" + hour(1), http.StatusBadRequest, nil},
+		{"empty range", token, "from=" + hour(1) + "&to=" + hour(1), http.StatusBadRequest, nil},
+		{"reversed range", token, "from=" + hour(1) + "&to=" + hour(-1), http.StatusBadRequest, nil},
+		{"bad format", token, "from=yesterday&to=" + hour(1), http.StatusBadRequest, nil},
+		{"not a member", other, "from=" + hour(-1) + "&to=" + hour(1), http.StatusForbidden, nil},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		path := fmt.Sprintf("/api/projects/%d/todos/completed?%s", projectID, tt.query)
+		router.ServeHTTP(rec, authedRequest("GET", path, tt.token, ""))
+		if rec.Code != tt.wantCode {
+			t.Errorf("%s: status = %d, want %d; body = %s", tt.name, rec.Code, tt.wantCode, rec.Body.String())
+			continue
+		}
+		if rec.Code != http.StatusOK {
+			continue
+		}
+		var todos []struct{ Title string }
+		json.NewDecoder(rec.Body).Decode(&todos)
+		titles := []string{}
+		for _, td := range todos {
+			titles = append(titles, td.Title)
+		}
+		if !slices.Equal(titles, tt.wantTitle) {
+			t.Errorf("%s: todos = %v, want %v", tt.name, titles, tt.wantTitle)
+		}
+	}
+}
+
+func TestTodoLimit(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{MaxTodosPerProject: 2})
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	projectID := createProject(t, router, token, "Small")
+	todosPath := fmt.Sprintf("/api/projects/%d/todos", projectID)
+
+	firstID := createTodo(t, router, token, projectID, `{"title":"One"}`)
+	createTodo(t, router, token, projectID, `{"title":"Two","status":"completed"}`)
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", todosPath, token, `{"title":"Three"}`))
+	if rec.Code != http.StatusConflict || !strings.Contains(rec.Body.String(), "at most 2 todos") {
+		t.Errorf("create past limit: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	// The cap is per project.
+	createTodo(t, router, token, createProject(t, router, token, "Other"), `{"title":"Elsewhere"}`)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", fmt.Sprintf("/api/todos/%d", firstID), token, ""))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	createTodo(t, router, token, projectID, `{"title":"Three"}`)
+
+	rec = httptest.NewRecorder()
+	body := `{"version":1,"name":"Big","todos":[{"title":"A"},{"title":"B"},{"title":"C"}]}`
+	router.ServeHTTP(rec, authedRequest("POST", "/api/templates/import", token, body))
+	if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "at most 2 todos") {
+		t.Errorf("import template past limit: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	// A project import keeps the todos that fit and skips the rest.
+	rec = httptest.NewRecorder()
+	body = `{"version":1,"projects":[{"id":1,"name":"Imported"}],
+		"todos":[{"id":1,"project_id":1,"title":"A"},{"id":2,"project_id":1,"title":"B"},{"id":3,"project_id":1,"title":"C"}]}`
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects/import", token, body))
+	var result struct {
+		Todos   int `json:"todos_created"`
+		Skipped []struct{ Kind, Ref string }
+	}
+	json.NewDecoder(rec.Body).Decode(&result)
+	if rec.Code != http.StatusCreated || result.Todos != 2 || len(result.Skipped) != 1 || result.Skipped[0].Ref != "3" {
+		t.Errorf("import past limit: status = %d, result = %+v", rec.Code, result)
+	}
+}
@@ -2,24 +2,49 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 
 	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/api/response"
+	"github.com/walidabualafia/bloom/internal/featureflag"
 	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/password"
 	"github.com/walidabualafia/bloom/internal/store"
 )
 
 // User handles admin user management endpoints.
 type User struct {
-	store store.Store
+	store       store.Store
+	maintenance *middleware.Maintenance
+	flags       *featureflag.Flags
+	pagination  Pagination
+	maxBulk     int  // projects per bulk delete
+	audit       bool // record user updates, deletes and project lookups in the admin audit log
+	passwords   password.Hasher
 }
 
-// NewUser creates a new User handler.
-func NewUser(s store.Store) *User {
-	return &User{store: s}
+// NewUser creates a new User handler. maxBulkDelete caps how many projects
+// DeleteProjects accepts in one call; values below 1 fall back to 100. If
+// audit is set, user updates, deletes and project lookups are recorded in
+// the admin audit log. passwords hashes the passwords of created users.
+func NewUser(s store.Store, maintenance *middleware.Maintenance, flags *featureflag.Flags, pagination Pagination,
+	maxBulkDelete int, audit bool, passwords password.Hasher) *User {
+	if maxBulkDelete < 1 {
+		maxBulkDelete = 100
+	}
+	return &User{store: s, maintenance: maintenance, flags: flags, pagination: pagination, maxBulk: maxBulkDelete,
+		audit: audit, passwords: passwords}
+}
+
+type maintenanceRequest struct {
+	Enabled *bool `json:"enabled"`
 }
 
 type updateUserRequest struct {
@@ -29,6 +54,8 @@ type updateUserRequest struct {
 }
 
 // Search returns users matching a query string (for sharing projects).
+// Supports ?limit and ?offset for paging (see parsePagination) and ?exclude_project_id to leave out
+// users who already belong to a project.
 func (h *User) Search(w http.ResponseWriter, r *http.Request) {
 	q := r.URL.Query().Get("q")
 	if q == "" {
@@ -36,35 +63,185 @@ func (h *User) Search(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	pg, err := parsePagination(r, h.pagination)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	excludeProjectID, err := queryInt64(r, "exclude_project_id")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	callerID := middleware.GetUserID(r.Context())
-	users, err := h.store.SearchUsers(r.Context(), q, callerID)
+
+	// Only members may use a project's membership as a filter; otherwise the
+	// results would reveal who belongs to it.
+	if excludeProjectID != 0 {
+		isMember, err := h.store.IsProjectMember(r.Context(), excludeProjectID, callerID)
+		if err != nil {
+			writeServerError(w, err, "internal server error")
+			return
+		}
+		if !isMember {
+			writeError(w, http.StatusForbidden, "you do not have access to this project")
+			return
+		}
+	}
+
+	users, err := h.store.SearchUsers(r.Context(), store.UserSearchParams{
+		Query:            q,
+		ExcludeID:        callerID,
+		ExcludeProjectID: excludeProjectID,
+		Limit:            pg.Limit,
+		Offset:           pg.Offset,
+	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to search users")
+		writeServerError(w, err, "failed to search users")
 		return
 	}
-	if users == nil {
-		users = []model.User{}
+	results := make([]model.PublicUser, len(users))
+	for i, u := range users {
+		results[i] = u.Public()
 	}
-	writeJSON(w, http.StatusOK, users)
+	writePageHeaders(w, pg)
+	writeJSON(w, http.StatusOK, results)
 }
 
-// List returns all users (admin only).
+// Memberships returns the caller's project memberships with their roles,
+// including projects they own.
+func (h *User) Memberships(w http.ResponseWriter, r *http.Request) {
+	userID := middleware.GetUserID(r.Context())
+	memberships, err := h.store.ListMembershipsByUser(r.Context(), userID)
+	if err != nil {
+		writeServerError(w, err, "failed to list memberships")
+		return
+	}
+	writeJSON(w, http.StatusOK, memberships)
+}
+
+// List returns one page of users, ordered by id (admin only):
+//
+//	?q=<text> (matched against username and email)
+//	?is_admin=true|false
+//	?limit=&offset=
+//
+// X-Total-Count is the number of users matching the filters.
 func (h *User) List(w http.ResponseWriter, r *http.Request) {
 	if !h.isAdmin(w, r) {
 		return
 	}
 
-	users, err := h.store.ListUsers(r.Context())
+	pg, err := parsePagination(r, h.pagination)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := store.UserFilter{Query: r.URL.Query().Get("q"), Limit: pg.Limit, Offset: pg.Offset}
+	if filter.IsAdmin, err = queryBool(r, "is_admin"); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	users, total, err := h.store.ListUsersFiltered(r.Context(), filter)
+	if err != nil {
+		writeServerError(w, err, "failed to list users")
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	writePageHeaders(w, pg)
+	writeJSON(w, http.StatusOK, users)
+}
+
+// defaultInactiveDays is the window Inactive uses when ?days is not given,
+// and maxInactiveDays the longest it accepts, which keeps the cutoff well
+// inside the years time.Time can represent.
+const (
+	defaultInactiveDays = 90
+	maxInactiveDays     = 36500
+)
+
+// Inactive lists users who have not logged in within the last ?days
+// (default 90), oldest activity first (admin only). Users who never logged
+// in count from when their account was created. Admins are left out unless
+// ?include_admins=true. Supports ?limit and ?offset like List. It only
+// reports; nothing is deleted.
+func (h *User) Inactive(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	pg, err := parsePagination(r, h.pagination)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to list users")
+		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if users == nil {
-		users = []model.User{}
+	days, err := queryInt(r, "days", defaultInactiveDays)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
 	}
+	if days < 1 || days > maxInactiveDays {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("days must be between 1 and %d", maxInactiveDays))
+		return
+	}
+	includeAdmins, err := queryBool(r, "include_admins")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	filter := store.UserFilter{Limit: pg.Limit, Offset: pg.Offset}
+	if includeAdmins == nil || !*includeAdmins {
+		nonAdmins := false
+		filter.IsAdmin = &nonAdmins
+	}
+
+	before := time.Now().AddDate(0, 0, -days)
+	users, total, err := h.store.ListInactiveUsers(r.Context(), before, filter)
+	if err != nil {
+		writeServerError(w, err, "failed to list users")
+		return
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	writePageHeaders(w, pg)
 	writeJSON(w, http.StatusOK, users)
 }
 
+type createUserRequest struct {
+	registerRequest
+	IsAdmin bool `json:"is_admin"`
+}
+
+// Create adds a user account (admin only). It works whether or not public
+// registration is enabled.
+func (h *User) Create(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	var req createUserRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	user, ok := newUser(w, req.registerRequest, h.passwords)
+	if !ok {
+		return
+	}
+	user.IsAdmin = req.IsAdmin
+
+	if err := h.store.CreateUser(r.Context(), user); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			writeError(w, http.StatusConflict, "username or email already exists")
+			return
+		}
+		writeServerError(w, err, "failed to create user")
+		return
+	}
+	writeJSON(w, http.StatusCreated, user)
+}
+
 // Update modifies a user (admin only).
 func (h *User) Update(w http.ResponseWriter, r *http.Request) {
 	if !h.isAdmin(w, r) {
@@ -79,7 +256,11 @@ func (h *User) Update(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.store.GetUserByID(r.Context(), userID)
 	if err != nil {
-		writeError(w, http.StatusNotFound, "user not found")
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeUserNotFound, "user not found")
+			return
+		}
+		writeServerError(w, err, "failed to get user")
 		return
 	}
 
@@ -89,18 +270,33 @@ func (h *User) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Username != nil {
+	changes := map[string]auditChange{}
+	if req.Username != nil && *req.Username != user.Username {
+		changes["username"] = auditChange{Old: user.Username, New: *req.Username}
 		user.Username = *req.Username
 	}
-	if req.Email != nil {
+	if req.Email != nil && *req.Email != user.Email {
+		changes["email"] = auditChange{Old: user.Email, New: *req.Email}
 		user.Email = *req.Email
 	}
-	if req.IsAdmin != nil {
+	if req.IsAdmin != nil && *req.IsAdmin != user.IsAdmin {
+		changes["is_admin"] = auditChange{Old: user.IsAdmin, New: *req.IsAdmin}
 		user.IsAdmin = *req.IsAdmin
 	}
 
-	if err := h.store.UpdateUser(r.Context(), user); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to update user")
+	var audit *model.AdminAuditEntry
+	if len(changes) > 0 {
+		if audit, err = h.auditEntry(r, model.AuditUserUpdate, user.ID, changes); err != nil {
+			writeServerError(w, err, "failed to update user")
+			return
+		}
+	}
+	if err := h.store.UpdateUser(r.Context(), user, audit); err != nil {
+		if errors.Is(err, store.ErrConflict) {
+			writeError(w, http.StatusConflict, "username or email already exists")
+			return
+		}
+		writeServerError(w, err, "failed to update user")
 		return
 	}
 
@@ -125,14 +321,196 @@ func (h *User) Delete(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.store.DeleteUser(r.Context(), userID); err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to delete user")
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeUserNotFound, "user not found")
+			return
+		}
+		writeServerError(w, err, "failed to get user")
+		return
+	}
+
+	audit, err := h.auditEntry(r, model.AuditUserDelete, userID, user)
+	if err != nil {
+		writeServerError(w, err, "failed to delete user")
+		return
+	}
+	if err := h.store.DeleteUser(r.Context(), userID, audit); err != nil {
+		writeServerError(w, err, "failed to delete user")
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// userProject is a project in an admin's listing of a user's projects, with
+// the user's role in it.
+type userProject struct {
+	model.Project
+	Role string `json:"role"`
+}
+
+// Projects returns one page of the projects a user owns or is a member of,
+// with their role in each, in the order the user sees them (admin only).
+// Supports ?limit and ?offset (see parsePagination); X-Total-Count is the
+// number of projects. Each lookup is recorded in the admin audit log.
+func (h *User) Projects(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	userID, err := strconv.ParseInt(chi.URLParam(r, "userID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+	pg, err := parsePagination(r, h.pagination)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	user, err := h.store.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeErrorCode(w, http.StatusNotFound, response.CodeUserNotFound, "user not found")
+			return
+		}
+		writeServerError(w, err, "internal server error")
+		return
+	}
+	projects, err := h.store.ListProjectsByUser(r.Context(), userID)
+	if err != nil {
+		writeServerError(w, err, "failed to list projects")
+		return
+	}
+	memberships, err := h.store.ListMembershipsByUser(r.Context(), userID)
+	if err != nil {
+		writeServerError(w, err, "failed to list projects")
+		return
+	}
+	roles := make(map[int64]string, len(memberships))
+	for _, m := range memberships {
+		roles[m.ProjectID] = m.Role
+	}
+
+	audit, err := h.auditEntry(r, model.AuditUserProjects, userID, map[string]string{"username": user.Username})
+	if err == nil {
+		err = h.store.CreateAdminAudit(r.Context(), audit)
+	}
+	if err != nil {
+		writeServerError(w, err, "failed to list projects")
+		return
+	}
+
+	paged := projects[min(pg.Offset, len(projects)):min(pg.Offset+pg.Limit, len(projects))]
+	result := make([]userProject, len(paged))
+	for i, p := range paged {
+		result[i] = userProject{Project: p, Role: roles[p.ID]}
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(len(projects)))
+	writePageHeaders(w, pg)
+	writeJSON(w, http.StatusOK, result)
+}
+
+// auditChange is one field's before and after values in an audit snapshot.
+type auditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+// auditEntry builds the admin audit record for an action by the caller, with
+// snapshot as its changes. It returns nil when auditing is off.
+func (h *User) auditEntry(r *http.Request, action string, targetID int64, snapshot any) (*model.AdminAuditEntry, error) {
+	if !h.audit {
+		return nil, nil
+	}
+	changes, err := json.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	actorID := middleware.GetUserID(r.Context())
+	return &model.AdminAuditEntry{ActorID: &actorID, Action: action, TargetID: targetID, Changes: changes}, nil
+}
+
+// recordAudit records an admin action that has already taken effect. A
+// failure is only logged, since the action cannot be undone.
+func (h *User) recordAudit(r *http.Request, action string, targetID int64, snapshot any) {
+	audit, err := h.auditEntry(r, action, targetID, snapshot)
+	if err == nil {
+		err = h.store.CreateAdminAudit(r.Context(), audit)
+	}
+	if err != nil {
+		log.Printf("admin audit %s: %v", action, err)
+	}
+}
+
+// Audit returns the admin audit log, newest first (admin only). Supports
+// ?limit and ?offset (see parsePagination).
+func (h *User) Audit(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	pg, err := parsePagination(r, h.pagination)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	entries, err := h.store.ListAdminAudit(r.Context(), pg.Limit, pg.Offset)
+	if err != nil {
+		writeServerError(w, err, "failed to list audit log")
+		return
+	}
+	writePageHeaders(w, pg)
+	writeJSON(w, http.StatusOK, entries)
+}
+
+type bulkDeleteRequest struct {
+	IDs []int64 `json:"ids"`
+}
+
+// DeleteProjects removes several projects at once (admin only). All deletes
+// happen in one transaction; ids that don't exist are ignored. Each removed
+// project is recorded in the admin audit log, in the same transaction.
+func (h *User) DeleteProjects(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	var req bulkDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if len(req.IDs) == 0 {
+		writeError(w, http.StatusBadRequest, "ids is required")
+		return
+	}
+	if len(req.IDs) > h.maxBulk {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("at most %d projects can be deleted at once", h.maxBulk))
+		return
+	}
+
+	audit, err := h.auditEntry(r, model.AuditProjectDelete, 0, map[string]any{"bulk": true, "ids": req.IDs})
+	if err != nil {
+		writeServerError(w, err, "failed to delete projects")
+		return
+	}
+	deleted, err := h.store.DeleteProjects(r.Context(), req.IDs, audit)
+	if err != nil {
+		writeServerError(w, err, "failed to delete projects")
+		return
+	}
+	adminID := middleware.GetUserID(r.Context())
+	for _, id := range deleted {
+		log.Printf("project %d deleted by admin %d (bulk)", id, adminID)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]int{"deleted": len(deleted)})
+}
+
 // Stats returns system-wide statistics (admin only).
 func (h *User) Stats(w http.ResponseWriter, r *http.Request) {
 	if !h.isAdmin(w, r) {
@@ -141,19 +519,153 @@ func (h *User) Stats(w http.ResponseWriter, r *http.Request) {
 
 	stats, err := h.store.GetStats(r.Context())
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "failed to get stats")
+		writeServerError(w, err, "failed to get stats")
 		return
 	}
 
 	writeJSON(w, http.StatusOK, stats)
 }
 
+// SlowQueries reports store calls that exceeded SLOW_QUERY_THRESHOLD
+// (admin only).
+func (h *User) SlowQueries(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	reporter, ok := h.store.(store.SlowQueryReporter)
+	if !ok {
+		writeJSON(w, http.StatusOK, store.SlowQueryStats{})
+		return
+	}
+	writeJSON(w, http.StatusOK, reporter.SlowQueryStats())
+}
+
+// RepairTodos resets todos whose status or priority is no longer valid to
+// the defaults and reports how many were fixed (admin only). Safe to run
+// repeatedly; see store.Store.RepairTodos.
+func (h *User) RepairTodos(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	repair, err := h.store.RepairTodos(r.Context())
+	if err != nil {
+		writeServerError(w, err, "failed to repair todos")
+		return
+	}
+	if repair.Todos > 0 {
+		log.Printf("repaired %d todos (%d statuses, %d priorities) for user %d",
+			repair.Todos, repair.Statuses, repair.Priorities, middleware.GetUserID(r.Context()))
+		h.recordAudit(r, model.AuditTodoRepair, 0, repair)
+	}
+	writeJSON(w, http.StatusOK, repair)
+}
+
+// GetMaintenance reports whether maintenance mode is on (admin only).
+func (h *User) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": h.maintenance.Enabled()})
+}
+
+// SetMaintenance turns maintenance mode on or off (admin only).
+func (h *User) SetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	var req maintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+
+	// Record the change first: toggling cannot fail, but the audit can.
+	audit, err := h.auditEntry(r, model.AuditMaintenance, 0,
+		map[string]auditChange{"enabled": {Old: h.maintenance.Enabled(), New: *req.Enabled}})
+	if err == nil {
+		err = h.store.CreateAdminAudit(r.Context(), audit)
+	}
+	if err != nil {
+		writeServerError(w, err, "failed to set maintenance mode")
+		return
+	}
+	h.maintenance.SetEnabled(*req.Enabled)
+	log.Printf("maintenance mode set to %t by user %d", *req.Enabled, middleware.GetUserID(r.Context()))
+	writeJSON(w, http.StatusOK, map[string]bool{"enabled": *req.Enabled})
+}
+
+type setFlagRequest struct {
+	Enabled *bool `json:"enabled"`
+}
+
+type featureFlagResponse struct {
+	model.FeatureFlag
+	Public bool `json:"public"` // reported by GET /meta
+}
+
+// ListFlags returns every feature flag that has been set, ordered by key
+// (admin only).
+func (h *User) ListFlags(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+	flags, err := h.flags.List(r.Context())
+	if err != nil {
+		writeServerError(w, err, "failed to list feature flags")
+		return
+	}
+	resp := make([]featureFlagResponse, len(flags))
+	for i, f := range flags {
+		resp[i] = featureFlagResponse{FeatureFlag: f, Public: h.flags.IsPublic(f.Key)}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// SetFlag turns a feature flag on or off, creating it if needed (admin
+// only). Other instances pick up the change when their cache expires.
+func (h *User) SetFlag(w http.ResponseWriter, r *http.Request) {
+	if !h.isAdmin(w, r) {
+		return
+	}
+
+	key := chi.URLParam(r, "key")
+	if !model.ValidFlagKey(key) {
+		writeError(w, http.StatusBadRequest, "key must be lowercase letters, digits, '.', '_' or '-', starting with a letter, at most 64 characters")
+		return
+	}
+	var req setFlagRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.Enabled == nil {
+		writeError(w, http.StatusBadRequest, "enabled is required")
+		return
+	}
+
+	flag, err := h.flags.Set(r.Context(), key, *req.Enabled)
+	if err != nil {
+		writeServerError(w, err, "failed to set feature flag")
+		return
+	}
+	log.Printf("feature flag %s set to %t by user %d", key, *req.Enabled, middleware.GetUserID(r.Context()))
+	h.recordAudit(r, model.AuditFlagSet, 0, map[string]any{"key": key, "enabled": *req.Enabled})
+	writeJSON(w, http.StatusOK, featureFlagResponse{FeatureFlag: *flag, Public: h.flags.IsPublic(key)})
+}
+
 // isAdmin checks if the current user is an admin. Writes 403 if not.
 func (h *User) isAdmin(w http.ResponseWriter, r *http.Request) bool {
 	userID := middleware.GetUserID(r.Context())
 	user, err := h.store.GetUserByID(r.Context(), userID)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "internal server error")
+		writeServerError(w, err, "internal server error")
 		return false
 	}
 	if !user.IsAdmin {
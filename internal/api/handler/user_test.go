@@ -0,0 +1,713 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/config"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+	"github.com/walidabualafia/bloom/internal/store/sqlite"
+)
+
+func TestExportUserData(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/projects", token, `{"name":"Mine"}`))
+	var project struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&project)
+
+	for _, title := range []string{"One", "Two"} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("POST", fmt.Sprintf("/api/projects/%d/todos", project.ID), token, fmt.Sprintf(`{"title":%q}`, title)))
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create todo: status = %d, body = %s", rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/users/me/export", token, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment") {
+		t.Errorf("Content-Disposition = %q, want attachment", cd)
+	}
+
+	var bundle struct {
+		User        map[string]any   `json:"user"`
+		Projects    []map[string]any `json:"projects"`
+		Memberships []map[string]any `json:"memberships"`
+		Todos       []map[string]any `json:"todos"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&bundle); err != nil {
+		t.Fatalf("decode export: %v", err)
+	}
+	if bundle.User["username"] != "alice" {
+		t.Errorf("user.username = %v, want alice", bundle.User["username"])
+	}
+	if _, ok := bundle.User["password"]; ok {
+		t.Error("export must not include the password hash")
+	}
+	if len(bundle.Projects) != 1 || len(bundle.Memberships) != 1 || len(bundle.Todos) != 2 {
+		t.Errorf("got %d projects, %d memberships, %d todos; want 1, 1, 2",
+			len(bundle.Projects), len(bundle.Memberships), len(bundle.Todos))
+	}
+}
+
+// disconnectingStore cancels the request, as a client hanging up would,
+// once the first project's todos have been read.
+type disconnectingStore struct {
+	store.Store
+	cancel context.CancelFunc
+	calls  int
+}
+
+func (s *disconnectingStore) ListTodosByProject(ctx context.Context, projectID int64, params store.TodoListParams) ([]model.Todo, error) {
+	s.calls++
+	todos, err := s.Store.ListTodosByProject(ctx, projectID, params)
+	s.cancel()
+	return todos, err
+}
+
+func TestExportStopsWhenClientDisconnects(t *testing.T) {
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if err := s.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ds := &disconnectingStore{Store: s, cancel: func() {}}
+	router := newTestRouter(t, ds, &config.Config{JWTSecret: testJWTSecret})
+
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+	for _, name := range []string{"A", "B", "C"} {
+		projectID := createProject(t, router, token, name)
+		createTodo(t, router, token, projectID, `{"title":"T"}`)
+	}
+
+	goroutines := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ds.cancel = cancel
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/users/me/export", token, "").WithContext(ctx))
+
+	// The query's rows are closed and nothing is left running. Todos are
+	// still read a project at a time, so a disconnect only takes effect
+	// between projects.
+	if inUse := s.Stats().InUse; inUse != 0 {
+		t.Errorf("%d connections still in use, want 0", inUse)
+	}
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > goroutines {
+		t.Errorf("%d goroutines after the export, want at most the %d before it", n, goroutines)
+	}
+
+	if ds.calls != 1 {
+		t.Errorf("listed todos of %d projects, want 1: the export should stop once the client is gone", ds.calls)
+	}
+	if body := rec.Body.String(); strings.HasSuffix(strings.TrimSpace(body), "]}") || strings.Contains(body, `"title"`) {
+		t.Errorf("body = %s, want it cut short before any todo", body)
+	}
+}
+
+// unavailableStore fails project listing, and lookups of the projects and
+// users marked down, as a store that cannot reach its database does.
+type unavailableStore struct {
+	store.Store
+	projects, users map[int64]bool
+}
+
+var errConnRefused = fmt.Errorf("%w: dial tcp: connection refused", store.ErrUnavailable)
+
+func (unavailableStore) ListProjectsByUser(context.Context, int64) ([]model.Project, error) {
+	return nil, errConnRefused
+}
+
+func (s unavailableStore) GetProject(ctx context.Context, id int64) (*model.Project, error) {
+	if s.projects[id] {
+		return nil, errConnRefused
+	}
+	return s.Store.GetProject(ctx, id)
+}
+
+func (s unavailableStore) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
+	if s.users[id] {
+		return nil, errConnRefused
+	}
+	return s.Store.GetUserByID(ctx, id)
+}
+
+func (s unavailableStore) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	user, err := s.Store.GetUserByUsername(ctx, username)
+	if err == nil && s.users[user.ID] {
+		return nil, errConnRefused
+	}
+	return user, err
+}
+
+func TestDatabaseUnavailable(t *testing.T) {
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if err := s.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	router := newTestRouter(t, unavailableStore{Store: s}, &config.Config{JWTSecret: testJWTSecret})
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/v1/projects", token, ""))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want 503: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Retry-After"); got == "" {
+		t.Error("no Retry-After header")
+	}
+	var resp struct{ Code string }
+	json.NewDecoder(rec.Body).Decode(&resp)
+	if resp.Code != "UNAVAILABLE" {
+		t.Errorf("code = %q, want UNAVAILABLE", resp.Code)
+	}
+}
+
+func TestLookupsDatabaseUnavailable(t *testing.T) {
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if err := s.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	us := unavailableStore{Store: s, projects: map[int64]bool{}, users: map[int64]bool{}}
+	router := newTestRouter(t, us, &config.Config{JWTSecret: testJWTSecret, RegistrationDisabled: true})
+
+	// The first account is an admin so it can create bob and use the admin
+	// routes.
+	admin := registerUser(t, router, "root", "root@example.com", "password123")
+	var me, bob struct{ ID int64 }
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/auth/me", admin, ""))
+	json.NewDecoder(rec.Body).Decode(&me)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/admin/users", admin,
+		`{"username":"bob","email":"bob@example.com","password":"password123"}`))
+	json.NewDecoder(rec.Body).Decode(&bob)
+	projectID := createProject(t, router, admin, "Work")
+
+	tests := []struct {
+		name, method, path, body string
+		downProject, downUser    int64
+	}{
+		{"add member, project", "POST", fmt.Sprintf("/api/projects/%d/members", projectID), `{"username":"bob"}`, projectID, 0},
+		{"add member, user", "POST", fmt.Sprintf("/api/projects/%d/members", projectID), `{"username":"bob"}`, 0, bob.ID},
+		{"remove member", "DELETE", fmt.Sprintf("/api/projects/%d/members/%d", projectID, bob.ID), "", projectID, 0},
+		{"me", "GET", "/api/auth/me", "", 0, me.ID},
+		{"update me", "PUT", "/api/auth/me", `{"locale":"en-US"}`, 0, me.ID},
+		{"admin update user", "PUT", fmt.Sprintf("/api/admin/users/%d", bob.ID), `{"username":"robert"}`, 0, bob.ID},
+		{"admin delete user", "DELETE", fmt.Sprintf("/api/admin/users/%d", bob.ID), "", 0, bob.ID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clear(us.projects)
+			clear(us.users)
+			us.projects[tt.downProject] = true
+			us.users[tt.downUser] = true
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, authedRequest(tt.method, tt.path, admin, tt.body))
+			if rec.Code != http.StatusServiceUnavailable {
+				t.Fatalf("status = %d, want 503: %s", rec.Code, rec.Body.String())
+			}
+			if got := rec.Header().Get("Retry-After"); got == "" {
+				t.Error("no Retry-After header")
+			}
+		})
+	}
+}
+
+func TestExportHead(t *testing.T) {
+	// The first account is an admin so it can use the admin export too.
+	router := setupTestRouterWithConfig(t, &config.Config{RegistrationDisabled: true})
+	admin := registerUser(t, router, "root", "root@example.com", "password123")
+	var me struct{ ID int64 }
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/auth/me", admin, ""))
+	json.NewDecoder(rec.Body).Decode(&me)
+
+	for _, path := range []string{"/api/users/me/export", fmt.Sprintf("/api/v1/admin/users/%d/export", me.ID)} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("HEAD", path, admin, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("HEAD %s: status = %d", path, rec.Code)
+		}
+		if cd := rec.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, "attachment") {
+			t.Errorf("HEAD %s: Content-Disposition = %q, want attachment", path, cd)
+		}
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("HEAD %s: Content-Type = %q", path, ct)
+		}
+		if rec.Body.Len() != 0 {
+			t.Errorf("HEAD %s: wrote %d body bytes", path, rec.Body.Len())
+		}
+	}
+
+	// Buffered JSON responses report their length to HEAD.
+	get := httptest.NewRecorder()
+	router.ServeHTTP(get, authedRequest("GET", "/api/auth/me", admin, ""))
+	head := httptest.NewRecorder()
+	router.ServeHTTP(head, authedRequest("HEAD", "/api/auth/me", admin, ""))
+	if head.Code != http.StatusOK {
+		t.Fatalf("HEAD /api/auth/me: status = %d", head.Code)
+	}
+	if want := strconv.Itoa(get.Body.Len()); head.Header().Get("Content-Length") != want {
+		t.Errorf("HEAD Content-Length = %q, want %s", head.Header().Get("Content-Length"), want)
+	}
+}
+
+func TestSearchUsersClampsLimit(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{DefaultPageSize: 2, MaxPageSize: 3})
+	token := registerUser(t, router, "alice", "alice@test.io", "password123")
+	for _, name := range []string{"anna", "andy", "amos", "abby"} {
+		registerUser(t, router, name, name+"@test.io", "password123")
+	}
+
+	tests := []struct {
+		query     string
+		wantLimit string
+		wantCount int
+	}{
+		{"", "2", 2},
+		{"&limit=3", "3", 3},
+		{"&limit=500", "3", 3},
+	}
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", "/api/users/search?q=a"+tt.query, token, ""))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%q: status = %d, body = %s", tt.query, rec.Code, rec.Body.String())
+		}
+		if got := rec.Header().Get("X-Page-Limit"); got != tt.wantLimit {
+			t.Errorf("%q: X-Page-Limit = %q, want %q", tt.query, got, tt.wantLimit)
+		}
+		var users []struct{ ID int64 }
+		json.NewDecoder(rec.Body).Decode(&users)
+		if len(users) != tt.wantCount {
+			t.Errorf("%q: got %d users, want %d", tt.query, len(users), tt.wantCount)
+		}
+	}
+
+	for _, q := range []string{"&limit=0", "&limit=abc", "&offset=-1"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", "/api/users/search?q=a"+q, token, ""))
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", q, rec.Code)
+		}
+	}
+}
+
+func TestBulkDeleteProjectsRequiresAdmin(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("DELETE", "/api/admin/projects", token, `{"ids":[1]}`))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestRepairTodosRequiresAdmin(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/admin/repair/todos", token, ""))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestAdminAuditRequiresAdmin(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/admin/audit", token, ""))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestAdminActionsAreAudited(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{RegistrationDisabled: true, AdminAudit: true})
+	admin := registerUser(t, router, "root", "root@example.com", "password123")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/admin/users", admin,
+		`{"username":"bob","email":"bob@example.com","password":"password123"}`))
+	var bob struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&bob)
+	projectID := createProject(t, router, admin, "Doomed")
+
+	for _, step := range []struct{ method, path, body string }{
+		{"PUT", fmt.Sprintf("/api/admin/users/%d", bob.ID), `{"username":"robert"}`},
+		{"DELETE", "/api/admin/projects", fmt.Sprintf(`{"ids":[%d]}`, projectID)},
+		{"POST", "/api/admin/maintenance", `{"enabled":true}`},
+	} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest(step.method, step.path, admin, step.body))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s %s: status = %d, body = %s", step.method, step.path, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/admin/audit", admin, ""))
+	var entries []struct {
+		ActorName string `json:"actor_name"`
+		Action    string
+		TargetID  int64 `json:"target_id"`
+		Changes   map[string]any
+	}
+	json.NewDecoder(rec.Body).Decode(&entries)
+	if len(entries) != 3 {
+		t.Fatalf("audit = %+v, want 3 entries", entries)
+	}
+	want := []struct {
+		action   string
+		targetID int64
+		field    string
+	}{
+		{model.AuditMaintenance, 0, "enabled"},
+		{model.AuditProjectDelete, projectID, "ids"},
+		{model.AuditUserUpdate, bob.ID, "username"},
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Action != w.action || e.TargetID != w.targetID || e.ActorName != "root" || e.Changes[w.field] == nil {
+			t.Errorf("entry %d = %+v, want %s on %d by root with %s", i, e, w.action, w.targetID, w.field)
+		}
+	}
+}
+
+func TestSearchUsersOmitsEmail(t *testing.T) {
+	router := setupTestRouter(t)
+	token := registerUser(t, router, "alice", "alice@test.io", "password123")
+	registerUser(t, router, "bob", "bob@test.io", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/users/search?q=bob", token, ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var users []map[string]any
+	json.NewDecoder(rec.Body).Decode(&users)
+	if len(users) != 1 || users[0]["username"] != "bob" {
+		t.Fatalf("users = %v, want only bob", users)
+	}
+	for _, field := range []string{"email", "is_admin", "created_at"} {
+		if _, ok := users[0][field]; ok {
+			t.Errorf("search result includes %q", field)
+		}
+	}
+}
+
+func TestRegistrationDisabled(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{RegistrationDisabled: true})
+
+	// The first account on an empty instance bootstraps an admin.
+	admin := registerUser(t, router, "root", "root@example.com", "password123")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/auth/me", admin, ""))
+	var me struct {
+		IsAdmin bool `json:"is_admin"`
+	}
+	json.NewDecoder(rec.Body).Decode(&me)
+	if !me.IsAdmin {
+		t.Fatal("bootstrap user is not an admin")
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/auth/register", "",
+		`{"username":"eve","email":"eve@example.com","password":"password123"}`))
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "registration is disabled") {
+		t.Errorf("register: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/admin/users", admin,
+		`{"username":"bob","email":"bob@example.com","password":"password123"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("admin create: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/auth/login", "", `{"username":"bob","password":"password123"}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("login as created user: status = %d", rec.Code)
+	}
+	var login struct{ Token string }
+	json.NewDecoder(rec.Body).Decode(&login)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/admin/users", login.Token,
+		`{"username":"carol","email":"carol@example.com","password":"password123"}`))
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("non-admin create: status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/meta", nil))
+	if !strings.Contains(rec.Body.String(), `"registration_enabled":false`) {
+		t.Errorf("meta = %s, want registration_enabled false", rec.Body.String())
+	}
+}
+
+func TestDemoModeLocksAccounts(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{RegistrationDisabled: true, DemoMode: true})
+	admin := registerUser(t, router, "root", "root@example.com", "password123")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/admin/users", admin,
+		`{"username":"bob","email":"bob@example.com","password":"password123"}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("admin create: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var bob struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&bob)
+
+	for _, method := range []string{"PUT", "DELETE"} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest(method, fmt.Sprintf("/api/admin/users/%d", bob.ID), admin, `{"is_admin":true}`))
+		if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "demo") {
+			t.Errorf("%s user: status = %d, body = %s", method, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/auth/login", "", `{"username":"bob","password":"password123"}`))
+	if rec.Code != http.StatusOK {
+		t.Errorf("login after blocked delete: status = %d", rec.Code)
+	}
+}
+
+func TestAdminListUserProjects(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{RegistrationDisabled: true, AdminAudit: true})
+	admin := registerUser(t, router, "root", "root@example.com", "password123")
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/admin/users", admin,
+		`{"username":"bob","email":"bob@example.com","password":"password123"}`))
+	var bob struct{ ID int64 }
+	json.NewDecoder(rec.Body).Decode(&bob)
+
+	createProject(t, router, admin, "Own")
+	shared := createProject(t, router, admin, "Shared")
+	addMember(t, router, admin, shared, "bob", "editor")
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", path, admin, ""))
+		return rec
+	}
+	rec = get(fmt.Sprintf("/api/admin/users/%d/projects", bob.ID))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	var projects []struct {
+		ID   int64
+		Role string
+	}
+	json.NewDecoder(rec.Body).Decode(&projects)
+	if len(projects) != 1 || projects[0].ID != shared || projects[0].Role != "editor" {
+		t.Errorf("projects = %+v, want Shared as editor", projects)
+	}
+
+	rec = get("/api/admin/users/1/projects?limit=1")
+	json.NewDecoder(rec.Body).Decode(&projects)
+	if len(projects) != 1 || projects[0].Role != "owner" || rec.Header().Get("X-Total-Count") != "2" {
+		t.Errorf("page = %+v, X-Total-Count = %q; want one owned project of 2", projects, rec.Header().Get("X-Total-Count"))
+	}
+
+	if rec := get("/api/admin/users/9999/projects"); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown user: status = %d, want 404", rec.Code)
+	}
+
+	rec = get("/api/admin/audit")
+	var audit []struct {
+		Action   string
+		TargetID int64 `json:"target_id"`
+	}
+	json.NewDecoder(rec.Body).Decode(&audit)
+	if len(audit) != 2 || audit[1].Action != model.AuditUserProjects || audit[1].TargetID != bob.ID {
+		t.Errorf("audit = %+v, want a user.projects entry for each lookup", audit)
+	}
+}
+
+func TestInactiveUsers(t *testing.T) {
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	if err := s.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	router := newTestRouter(t, s, &config.Config{JWTSecret: testJWTSecret, RegistrationDisabled: true})
+	admin := registerUser(t, router, "root", "root@example.com", "password123")
+
+	// Each user last logged in this long ago.
+	day := 24 * time.Hour
+	lastLogin := map[string]time.Duration{"old": 100 * day, "recent": 10 * day, "oldadmin": 200 * day, "edge": 30*day + time.Hour}
+	for name, ago := range lastLogin {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("POST", "/api/admin/users", admin, fmt.Sprintf(
+			`{"username":%q,"email":"%s@example.com","password":"password123","is_admin":%t}`, name, name, name == "oldadmin")))
+		var user struct{ ID int64 }
+		json.NewDecoder(rec.Body).Decode(&user)
+		if err := s.RecordLogin(context.Background(), user.ID, time.Now().Add(-ago)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	inactive := func(token, query string) (int, []string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("GET", "/api/v1/admin/users/inactive"+query, token, ""))
+		var users []struct{ Username string }
+		json.NewDecoder(rec.Body).Decode(&users)
+		var names []string
+		for _, u := range users {
+			names = append(names, u.Username)
+		}
+		return rec.Code, names
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"old"}},
+		{"?include_admins=true", []string{"oldadmin", "old"}},
+		{"?days=30", []string{"old", "edge"}},
+		{"?days=31", []string{"old"}},
+		{"?days=5", []string{"old", "edge", "recent"}},
+		{"?days=5&limit=1&offset=1", []string{"edge"}},
+	}
+	for _, tt := range tests {
+		code, got := inactive(admin, tt.query)
+		if code != http.StatusOK || !slices.Equal(got, tt.want) {
+			t.Errorf("GET %s: status %d, users %v, want %v", tt.query, code, got, tt.want)
+		}
+	}
+	for _, query := range []string{"?days=0", "?days=-1", "?days=36501", "?days=99999999999", "?days=soon", "?include_admins=maybe"} {
+		if code, _ := inactive(admin, query); code != http.StatusBadRequest {
+			t.Errorf("GET %s: status = %d, want %d", query, code, http.StatusBadRequest)
+		}
+	}
+
+	// Logging in takes a user off the report.
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/auth/login", "", `{"username":"old","password":"password123"}`))
+	var login struct {
+		Token string
+		User  struct {
+			LastLoginAt *time.Time `json:"last_login_at"`
+		}
+	}
+	json.NewDecoder(rec.Body).Decode(&login)
+	if login.User.LastLoginAt == nil || time.Since(*login.User.LastLoginAt) > time.Minute {
+		t.Errorf("last_login_at after login = %v, want now", login.User.LastLoginAt)
+	}
+	if _, got := inactive(admin, ""); len(got) != 0 {
+		t.Errorf("inactive after login = %v, want none", got)
+	}
+
+	if code, _ := inactive(login.Token, ""); code != http.StatusForbidden {
+		t.Errorf("non-admin: status = %d, want %d", code, http.StatusForbidden)
+	}
+}
+
+func TestFeatureFlags(t *testing.T) {
+	router := setupTestRouterWithConfig(t, &config.Config{
+		RegistrationDisabled: true,
+		PublicFeatureFlags:   []string{"board.swimlanes"},
+	})
+	admin := registerUser(t, router, "root", "root@example.com", "password123")
+
+	set := func(key, body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, authedRequest("PUT", "/api/admin/flags/"+key, admin, body))
+		return rec
+	}
+	if rec := set("board.swimlanes", `{"enabled":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("set public flag: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := set("search.fuzzy", `{"enabled":true}`); rec.Code != http.StatusOK {
+		t.Fatalf("set private flag: status = %d, body = %s", rec.Code, rec.Body.String())
+	}
+	if rec := set("Bad.Key", `{"enabled":true}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("invalid key: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := set("search.fuzzy", `{}`); rec.Code != http.StatusBadRequest {
+		t.Errorf("missing enabled: status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("GET", "/api/admin/flags", admin, ""))
+	var flags []struct {
+		Key     string
+		Enabled bool
+		Public  bool
+	}
+	json.NewDecoder(rec.Body).Decode(&flags)
+	if len(flags) != 2 || flags[0].Key != "board.swimlanes" || !flags[0].Public || flags[1].Key != "search.fuzzy" || flags[1].Public {
+		t.Errorf("flags = %+v, want board.swimlanes (public) and search.fuzzy", flags)
+	}
+
+	// Only public flags are shown in /meta, and a change shows at once.
+	set("board.swimlanes", `{"enabled":false}`)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/meta", nil))
+	var meta struct{ Flags map[string]bool }
+	json.NewDecoder(rec.Body).Decode(&meta)
+	if want := map[string]bool{"board.swimlanes": false}; !maps.Equal(meta.Flags, want) {
+		t.Errorf("meta flags = %v, want %v", meta.Flags, want)
+	}
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/admin/users", admin,
+		`{"username":"bob","email":"bob@example.com","password":"password123"}`))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, authedRequest("POST", "/api/auth/login", "", `{"username":"bob","password":"password123"}`))
+	var login struct{ Token string }
+	json.NewDecoder(rec.Body).Decode(&login)
+	for _, req := range []*http.Request{
+		authedRequest("GET", "/api/admin/flags", login.Token, ""),
+		authedRequest("PUT", "/api/admin/flags/search.fuzzy", login.Token, `{"enabled":false}`),
+	} {
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("non-admin %s %s: status = %d, want %d", req.Method, req.URL.Path, rec.Code, http.StatusForbidden)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/walidabualafia/bloom/internal/api/response"
+)
+
+// validation collects field errors so a handler can report every problem
+// with a request at once instead of stopping at the first. Keys are the JSON
+// field names.
+type validation map[string]string
+
+// validationResponse is the payload for a failed validation. Error repeats
+// one of the field messages for clients that only read the standard field.
+type validationResponse struct {
+	Error  string            `json:"error"`
+	Code   string            `json:"code"`
+	Errors map[string]string `json:"errors"`
+}
+
+// check records message against field unless ok. Only the first failure for
+// each field is kept.
+func (v validation) check(ok bool, field, message string) {
+	if ok {
+		return
+	}
+	if _, exists := v[field]; !exists {
+		v[field] = message
+	}
+}
+
+// write sends the collected errors as a 400 and reports whether there were
+// any, so callers can `if v.write(w) { return }`.
+func (v validation) write(w http.ResponseWriter) bool {
+	if len(v) == 0 {
+		return false
+	}
+	fields := make([]string, 0, len(v))
+	for field := range v {
+		fields = append(fields, field)
+	}
+	slices.Sort(fields)
+	response.WriteJSON(w, http.StatusBadRequest, validationResponse{Error: v[fields[0]], Code: response.CodeValidationFailed, Errors: v})
+	return true
+}
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/api/response"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+type webhookRequest struct {
+	URL    *string   `json:"url"`
+	Secret *string   `json:"secret"`
+	Events *[]string `json:"events"`
+}
+
+var webhookEventsError = "events must be a non-empty list of: " + strings.Join(model.WebhookEvents, ", ")
+
+// ListWebhooks returns a project's webhooks without their secrets (owner
+// only).
+func (h *Project) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.webhookProjectID(w, r)
+	if !ok {
+		return
+	}
+
+	hooks, err := h.store.ListWebhooks(r.Context(), projectID)
+	if err != nil {
+		writeServerError(w, err, "failed to list webhooks")
+		return
+	}
+	for i := range hooks {
+		hooks[i].Secret = ""
+	}
+	writeJSON(w, http.StatusOK, hooks)
+}
+
+// CreateWebhook registers a URL to be notified of todo events (owner only).
+// If no secret is given one is generated; either way it is only returned
+// here.
+//
+// For each subscribed event the URL receives a POST with the headers
+// X-Bloom-Event (the event name) and X-Bloom-Signature ("sha256=" and the hex
+// HMAC-SHA256 of the raw body, keyed with the secret), and a body like:
+//
+//	{
+//	  "event": "todo.updated",
+//	  "project_id": 1,
+//	  "todo": {"id": 7, "title": "...", "status": "in_progress", ...},
+//	  "occurred_at": "2026-01-02T15:04:05Z"
+//	}
+//
+// todo is the todo as returned by GET /todos/{id}; for todo.deleted it is
+// the todo just before deletion. Completing a todo sends todo.updated and
+// then todo.completed. A delivery that fails or does not return 2xx is
+// retried with backoff a few times, then logged and dropped.
+func (h *Project) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.webhookProjectID(w, r)
+	if !ok {
+		return
+	}
+
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	hook := &model.Webhook{ProjectID: projectID}
+	if !applyWebhookRequest(w, hook, &req, true, h.allowPrivateWebhooks) {
+		return
+	}
+	if hook.Secret == "" {
+		secret, err := newWebhookSecret()
+		if err != nil {
+			writeServerError(w, err, "failed to generate secret")
+			return
+		}
+		hook.Secret = secret
+	}
+
+	if err := h.store.CreateWebhook(r.Context(), hook); err != nil {
+		writeServerError(w, err, "failed to create webhook")
+		return
+	}
+	writeJSON(w, http.StatusCreated, hook)
+}
+
+// UpdateWebhook changes a webhook's url, events or secret (owner only).
+// Omitted fields are left as they are.
+func (h *Project) UpdateWebhook(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.webhookProjectID(w, r)
+	if !ok {
+		return
+	}
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "webhookID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook id")
+		return
+	}
+
+	hook, err := h.store.GetWebhook(r.Context(), projectID, webhookID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		writeServerError(w, err, "failed to get webhook")
+		return
+	}
+	var req webhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !applyWebhookRequest(w, hook, &req, false, h.allowPrivateWebhooks) {
+		return
+	}
+
+	if err := h.store.UpdateWebhook(r.Context(), hook); err != nil {
+		writeServerError(w, err, "failed to update webhook")
+		return
+	}
+	hook.Secret = ""
+	writeJSON(w, http.StatusOK, hook)
+}
+
+// DeleteWebhook removes a webhook (owner only).
+func (h *Project) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	projectID, ok := h.webhookProjectID(w, r)
+	if !ok {
+		return
+	}
+	webhookID, err := strconv.ParseInt(chi.URLParam(r, "webhookID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid webhook id")
+		return
+	}
+
+	if err := h.store.DeleteWebhook(r.Context(), projectID, webhookID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			writeError(w, http.StatusNotFound, "webhook not found")
+			return
+		}
+		writeServerError(w, err, "failed to delete webhook")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// applyWebhookRequest validates req and copies its fields onto hook. On
+// create, url and events are required. allowPrivate permits URLs on
+// loopback and private hosts.
+func applyWebhookRequest(w http.ResponseWriter, hook *model.Webhook, req *webhookRequest, create, allowPrivate bool) bool {
+	v := validation{}
+	if req.URL != nil || create {
+		v.check(req.URL != nil && model.ValidWebhookURL(*req.URL, allowPrivate), "url", "url must be an http or https URL on a public host")
+		if req.URL != nil {
+			hook.URL = *req.URL
+		}
+	}
+	if req.Events != nil || create {
+		v.check(req.Events != nil && model.ValidWebhookEvents(*req.Events), "events", webhookEventsError)
+		if req.Events != nil {
+			hook.Events = *req.Events
+		}
+	}
+	if req.Secret != nil {
+		v.check(len(*req.Secret) >= 16 && len(*req.Secret) <= 128, "secret", "secret must be 16 to 128 characters")
+		hook.Secret = *req.Secret
+	}
+	return !v.write(w)
+}
+
+// webhookProjectID parses the project in the URL and checks that the caller
+// owns it.
+func (h *Project) webhookProjectID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	projectID, err := strconv.ParseInt(chi.URLParam(r, "projectID"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid project id")
+		return 0, false
+	}
+
+	project, err := h.store.GetProject(r.Context(), projectID)
+	if err != nil {
+		writeErrorCode(w, http.StatusNotFound, response.CodeProjectNotFound, "project not found")
+		return 0, false
+	}
+	if project.OwnerID != middleware.GetUserID(r.Context()) {
+		writeError(w, http.StatusForbidden, "only the owner can manage webhooks")
+		return 0, false
+	}
+	return projectID, true
+}
+
+// newWebhookSecret returns a random hex secret for signing deliveries.
+func newWebhookSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
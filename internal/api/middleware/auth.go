@@ -2,12 +2,15 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/walidabualafia/bloom/internal/api/response"
 )
 
 type contextKey string
@@ -15,71 +18,137 @@ type contextKey string
 const (
 	// UserIDKey is the context key for the authenticated user's ID.
 	UserIDKey contextKey = "userID"
+	// TokenExpiryKey is the context key for the expiry time of the token
+	// the request was authenticated with.
+	TokenExpiryKey contextKey = "tokenExpiry"
 )
 
+// TokenCookie is the name of the cookie holding the JWT when cookie auth is
+// enabled.
+const TokenCookie = "bloom_token"
+
+// TokenLifetime is how long a token from GenerateToken is valid.
+const TokenLifetime = 72 * time.Hour
+
+// CSRFHeader must be present on state-changing requests authenticated by
+// TokenCookie. Browsers only let a cross-site page set it after a CORS
+// preflight, which the CORS allow-list rejects, so a forged form post or
+// cross-site fetch cannot ride on the cookie.
+const CSRFHeader = "X-Requested-With"
+
 // Auth returns middleware that validates JWT tokens from the Authorization header.
-func Auth(jwtSecret string) func(http.Handler) http.Handler {
+// Tokens signed with jwtSecret or any of previousSecrets are accepted, so the
+// secret can be rotated without signing everyone out; new tokens are only
+// ever signed with jwtSecret. leeway allows for clock skew between servers
+// when checking exp, nbf, and iat; tokens issued further in the future than
+// that are rejected.
+//
+// If cookieAuth is set, requests without an Authorization header may
+// instead carry the token in the TokenCookie cookie. Such requests must send
+// CSRFHeader unless their method is safe (GET, HEAD or OPTIONS).
+func Auth(jwtSecret string, previousSecrets []string, leeway time.Duration, cookieAuth bool) func(http.Handler) http.Handler {
+	secrets := append([]string{jwtSecret}, previousSecrets...)
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var tokenString string
 			header := r.Header.Get("Authorization")
-			if header == "" {
-				http.Error(w, `{"error":"missing authorization header"}`, http.StatusUnauthorized)
-				return
-			}
-
-			parts := strings.SplitN(header, " ", 2)
-			if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
-				http.Error(w, `{"error":"invalid authorization format"}`, http.StatusUnauthorized)
+			cookie, cookieErr := r.Cookie(TokenCookie)
+			switch {
+			case header != "":
+				parts := strings.SplitN(header, " ", 2)
+				if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+					response.WriteJSONError(w, http.StatusUnauthorized, "invalid authorization format")
+					return
+				}
+				tokenString = parts[1]
+			case cookieAuth && cookieErr == nil:
+				if !safeMethod(r.Method) && r.Header.Get(CSRFHeader) == "" {
+					response.WriteJSONError(w, http.StatusForbidden, "missing "+CSRFHeader+" header")
+					return
+				}
+				tokenString = cookie.Value
+			default:
+				response.WriteJSONError(w, http.StatusUnauthorized, "missing authorization header")
 				return
 			}
 
-			token, err := jwt.Parse(parts[1], func(token *jwt.Token) (any, error) {
-				if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-					return nil, jwt.ErrSignatureInvalid
-				}
-				return []byte(jwtSecret), nil
-			})
+			token, err := parseToken(tokenString, secrets, leeway)
 			if err != nil || !token.Valid {
-				http.Error(w, `{"error":"invalid or expired token"}`, http.StatusUnauthorized)
+				response.WriteJSONError(w, http.StatusUnauthorized, "invalid or expired token")
 				return
 			}
 
 			claims, ok := token.Claims.(jwt.MapClaims)
 			if !ok {
-				http.Error(w, `{"error":"invalid token claims"}`, http.StatusUnauthorized)
+				response.WriteJSONError(w, http.StatusUnauthorized, "invalid token claims")
 				return
 			}
 
 			sub, err := claims.GetSubject()
 			if err != nil {
-				http.Error(w, `{"error":"invalid token subject"}`, http.StatusUnauthorized)
+				response.WriteJSONError(w, http.StatusUnauthorized, "invalid token subject")
 				return
 			}
 
 			userID, err := strconv.ParseInt(sub, 10, 64)
 			if err != nil {
-				http.Error(w, `{"error":"invalid user id in token"}`, http.StatusUnauthorized)
+				response.WriteJSONError(w, http.StatusUnauthorized, "invalid user id in token")
 				return
 			}
 
 			ctx := context.WithValue(r.Context(), UserIDKey, userID)
+			if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+				ctx = context.WithValue(ctx, TokenExpiryKey, exp.Time)
+			}
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// parseToken verifies tokenString against each secret in turn, returning the
+// first successful parse. Only a bad signature moves on to the next secret.
+func parseToken(tokenString string, secrets []string, leeway time.Duration) (*jwt.Token, error) {
+	var token *jwt.Token
+	var err error
+	for _, secret := range secrets {
+		token, err = jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
+			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, jwt.ErrSignatureInvalid
+			}
+			return []byte(secret), nil
+		}, jwt.WithLeeway(leeway), jwt.WithIssuedAt())
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) {
+			break
+		}
+	}
+	return token, err
+}
+
 // GetUserID extracts the authenticated user ID from the request context.
 func GetUserID(ctx context.Context) int64 {
 	id, _ := ctx.Value(UserIDKey).(int64)
 	return id
 }
 
+// GetTokenExpiry returns when the request's token expires, or the zero time
+// if it has no expiry.
+func GetTokenExpiry(ctx context.Context) time.Time {
+	exp, _ := ctx.Value(TokenExpiryKey).(time.Time)
+	return exp
+}
+
 // GenerateToken creates a signed JWT for the given user ID.
 func GenerateToken(userID int64, secret string) (string, error) {
+	now := time.Now()
 	claims := jwt.MapClaims{
 		"sub": strconv.FormatInt(userID, 10),
-		"iat": time.Now().Unix(),
-		"exp": time.Now().Add(72 * time.Hour).Unix(),
+		"iat": now.Unix(),
+		"nbf": now.Unix(),
+		"exp": now.Add(TokenLifetime).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secret))
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/walidabualafia/bloom/internal/api/response"
+)
+
+// RequireJSON rejects POST and PUT requests whose body is not declared as
+// application/json with 415, so clients get a clear error instead of a
+// vague decode failure. Parameters such as charset are allowed. Requests
+// without a body are let through, as are the routes in jsonExempt.
+func RequireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method == http.MethodPost || r.Method == http.MethodPut) && r.ContentLength != 0 && !jsonExempt(r) {
+			mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if err != nil || mediaType != "application/json" {
+				response.WriteJSONError(w, http.StatusUnsupportedMediaType, "Content-Type must be application/json")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// jsonExemptPaths lists the routes, relative to the API prefix, that
+// accept bodies other than JSON, such as file uploads. There are none yet.
+var jsonExemptPaths = map[string]bool{}
+
+func jsonExempt(r *http.Request) bool {
+	// Match both /api/v1/... and the unversioned /api/... alias.
+	path := strings.TrimPrefix(r.URL.Path, "/api")
+	path = strings.TrimPrefix(path, "/v1")
+	return jsonExemptPaths[path]
+}
@@ -0,0 +1,37 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+)
+
+func TestRequireJSON(t *testing.T) {
+	h := middleware.RequireJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	tests := []struct {
+		method, contentType, body string
+		want                      int
+	}{
+		{http.MethodPost, "application/json", `{}`, http.StatusOK},
+		{http.MethodPut, "application/json; charset=utf-8", `{}`, http.StatusOK},
+		{http.MethodPost, "", `{}`, http.StatusUnsupportedMediaType},
+		{http.MethodPut, "text/plain", `{}`, http.StatusUnsupportedMediaType},
+		{http.MethodPost, "application/x-www-form-urlencoded", "name=P", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "", "", http.StatusOK},
+		{http.MethodDelete, "text/plain", `{}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, "/api/v1/projects", strings.NewReader(tt.body))
+		if tt.contentType != "" {
+			req.Header.Set("Content-Type", tt.contentType)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("%s %q with %q: status = %d, want %d", tt.method, tt.body, tt.contentType, rec.Code, tt.want)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/walidabualafia/bloom/internal/api/response"
+)
+
+// DemoLocked, when enabled, rejects every request to the wrapped routes
+// with 403. Demo mode puts it in front of destructive account actions so
+// visitors sharing the demo accounts cannot rename, demote or delete them
+// for everyone else until the next reset.
+func DemoLocked(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			response.WriteJSONError(w, http.StatusForbidden, "this action is disabled on the demo instance")
+		})
+	}
+}
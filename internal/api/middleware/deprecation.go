@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+)
+
+// Deprecated marks every response from the wrapped routes as deprecated with
+// a "Deprecation: true" header. If sunset is non-zero, a Sunset header
+// announces when the routes will stop working.
+func Deprecated(sunset time.Time) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Deprecation", "true")
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
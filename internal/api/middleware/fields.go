@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// fieldsWriter carries the fields a client selected to the handler writing
+// the response.
+type fieldsWriter struct {
+	http.ResponseWriter
+	fields map[string]bool
+}
+
+func (w *fieldsWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Fields lets clients trim GET responses to the JSON fields listed in
+// ?fields=, such as fields=id,title,status, to save bandwidth. It only
+// records the selection; handlers apply it when writing, see
+// SelectedFields. Unknown field names are ignored.
+func Fields(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		v := r.URL.Query().Get("fields")
+		if v == "" || (r.Method != http.MethodGet && r.Method != http.MethodHead) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		fields := map[string]bool{}
+		for _, f := range strings.Split(v, ",") {
+			if f = strings.TrimSpace(f); f != "" {
+				fields[f] = true
+			}
+		}
+		next.ServeHTTP(&fieldsWriter{ResponseWriter: w, fields: fields}, r)
+	})
+}
+
+// SelectedFields returns the fields the client selected for the response
+// written to w, or nil if it did not select any.
+func SelectedFields(w http.ResponseWriter) map[string]bool {
+	for {
+		switch rw := w.(type) {
+		case *fieldsWriter:
+			return rw.fields
+		case interface{ Unwrap() http.ResponseWriter }:
+			w = rw.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
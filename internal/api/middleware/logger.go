@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 	"time"
+
+	"github.com/walidabualafia/bloom/internal/store"
 )
 
 // responseWriter wraps http.ResponseWriter to capture the status code.
@@ -18,11 +20,21 @@ func (rw *responseWriter) WriteHeader(code int) {
 }
 
 // Logger logs each HTTP request with method, path, status, and duration.
+// When the store is wrapped with store.NewTimed, the time spent in the
+// database is appended as db_ms.
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+		ctx, timer := store.WithQueryTimer(r.Context())
 		wrapped := &responseWriter{ResponseWriter: w, status: http.StatusOK}
-		next.ServeHTTP(wrapped, r)
-		log.Printf("%s %s %d %s", r.Method, r.URL.Path, wrapped.status, time.Since(start).Round(time.Millisecond))
+		next.ServeHTTP(wrapped, r.WithContext(ctx))
+
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if timer.Count() > 0 {
+			dbMS := float64(timer.Total()) / float64(time.Millisecond)
+			log.Printf("%s %s %d %s db_ms=%.2f", r.Method, r.URL.Path, wrapped.status, elapsed, dbMS)
+			return
+		}
+		log.Printf("%s %s %d %s", r.Method, r.URL.Path, wrapped.status, elapsed)
 	})
 }
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/walidabualafia/bloom/internal/api/response"
+)
+
+// maintenanceRetryAfter is the Retry-After value (in seconds) sent with 503
+// responses while maintenance mode is on.
+const maintenanceRetryAfter = "120"
+
+// Maintenance holds the read-only switch. While enabled, mutating API
+// requests are rejected with 503 and reads continue to work. It can be
+// toggled at runtime.
+type Maintenance struct {
+	enabled atomic.Bool
+}
+
+// NewMaintenance creates a Maintenance switch with the given initial state.
+func NewMaintenance(enabled bool) *Maintenance {
+	m := &Maintenance{}
+	m.enabled.Store(enabled)
+	return m
+}
+
+// Enabled reports whether maintenance mode is on.
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// SetEnabled turns maintenance mode on or off.
+func (m *Maintenance) SetEnabled(enabled bool) {
+	m.enabled.Store(enabled)
+}
+
+// Middleware rejects non-GET requests with 503 while maintenance mode is on.
+// Registering, signing in and out and the maintenance toggle itself are
+// always allowed so users can still sign in and admins can switch the mode
+// back off, as are POSTs that only read, like the batch role lookup and
+// template export. Profile updates on /auth/me are blocked like other writes.
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if m.Enabled() && !maintenanceExempt(r) {
+			w.Header().Set("Retry-After", maintenanceRetryAfter)
+			response.WriteJSONError(w, http.StatusServiceUnavailable,
+				"the server is in maintenance mode; changes are temporarily disabled")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func maintenanceExempt(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	// Match both /api/v1/... and the unversioned /api/... alias.
+	path := strings.TrimPrefix(r.URL.Path, "/api")
+	path = strings.TrimPrefix(path, "/v1")
+	switch path {
+	case "/auth/register", "/auth/login", "/auth/logout", "/admin/maintenance", "/projects/roles":
+		return true
+	}
+	return strings.HasPrefix(path, "/projects/") && strings.HasSuffix(path, "/export-template")
+}
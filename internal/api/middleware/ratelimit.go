@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/api/response"
+)
+
+// UserRateLimit limits how many requests each signed-in user may make per
+// minute, keyed by the user id Auth puts in the context, so it must run
+// after Auth. Writes and reads (GET, HEAD, OPTIONS) are counted separately
+// against their own limit; zero means unlimited. Each user may burst up to
+// a full minute's allowance, which then refills steadily. Requests over the
+// limit get 429 with a Retry-After header.
+//
+// Create one UserRateLimit and share its Middleware between route groups so
+// a user has the same allowance on every API prefix.
+type UserRateLimit struct {
+	writes, reads int
+
+	mu        sync.Mutex
+	buckets   map[rateKey]*rateBucket
+	lastSweep time.Time
+}
+
+type rateKey struct {
+	userID int64
+	write  bool
+}
+
+// rateBucket is a token bucket: tokens refill at the limit per minute up
+// to the limit, and each request takes one.
+type rateBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// NewUserRateLimit creates a UserRateLimit allowing each user writes
+// mutating and reads read-only requests per minute.
+func NewUserRateLimit(writes, reads int) *UserRateLimit {
+	return &UserRateLimit{writes: writes, reads: reads, buckets: map[rateKey]*rateBucket{}}
+}
+
+// Middleware enforces the limits.
+func (l *UserRateLimit) Middleware(next http.Handler) http.Handler {
+	if l.writes <= 0 && l.reads <= 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID := GetUserID(r.Context())
+		if userID != 0 {
+			if wait := l.take(userID, !safeMethod(r.Method), time.Now()); wait > 0 {
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				response.WriteJSONError(w, http.StatusTooManyRequests, "too many requests; slow down")
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take spends one of the user's tokens, returning zero if there was one and
+// otherwise how long until there will be.
+func (l *UserRateLimit) take(userID int64, write bool, now time.Time) time.Duration {
+	limit := l.reads
+	if write {
+		limit = l.writes
+	}
+	if limit <= 0 {
+		return 0
+	}
+	perSecond := float64(limit) / 60
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.sweep(now)
+
+	key := rateKey{userID, write}
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &rateBucket{tokens: float64(limit), last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = min(float64(limit), b.tokens+now.Sub(b.last).Seconds()*perSecond)
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / perSecond * float64(time.Second))
+}
+
+// sweep drops, at most once a minute, the buckets of users idle for a
+// minute or more. Their buckets have refilled completely, so forgetting
+// them changes nothing.
+func (l *UserRateLimit) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+)
+
+func TestUserRateLimit(t *testing.T) {
+	h := middleware.NewUserRateLimit(2, 0).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func(method string, userID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/", nil)
+		if userID != 0 {
+			req = req.WithContext(context.WithValue(req.Context(), middleware.UserIDKey, userID))
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := range 2 {
+		if rec := do(http.MethodPost, 1); rec.Code != http.StatusOK {
+			t.Fatalf("write %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+	rec := do(http.MethodDelete, 1)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 over the limit, got %d", rec.Code)
+	}
+	// Two per minute refill one every 30s.
+	if got := rec.Header().Get("Retry-After"); got != "30" {
+		t.Errorf("Retry-After = %q, want 30", got)
+	}
+
+	for i := range 5 {
+		if rec := do(http.MethodGet, 1); rec.Code != http.StatusOK {
+			t.Fatalf("read %d: expected reads to be unlimited, got %d", i+1, rec.Code)
+		}
+	}
+	if rec := do(http.MethodPost, 2); rec.Code != http.StatusOK {
+		t.Errorf("expected another user to have their own allowance, got %d", rec.Code)
+	}
+	for i := range 3 {
+		if rec := do(http.MethodPost, 0); rec.Code != http.StatusOK {
+			t.Fatalf("anonymous write %d: expected 200, got %d", i+1, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// RealIP sets r.RemoteAddr to the client's IP address. X-Forwarded-For and
+// X-Real-IP are only honored when the request comes directly from one of the
+// trusted proxies; otherwise anyone could claim any address by sending them.
+// X-Forwarded-For is read from the right, skipping trusted proxies, so the
+// result is the last hop no trusted proxy vouches for rather than whatever
+// the client put at the front of the list.
+//
+// With no trusted proxies the forwarded headers are ignored entirely.
+func RealIP(trusted []netip.Prefix) func(http.Handler) http.Handler {
+	isTrusted := func(ip netip.Addr) bool {
+		for _, p := range trusted {
+			if p.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, ok := remoteIP(r.RemoteAddr)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+			client := peer
+			if isTrusted(peer) {
+				client = forwardedFor(r, peer, isTrusted)
+			}
+			r.RemoteAddr = client.String()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// forwardedFor returns the client address reported by the trusted proxy
+// peer, or peer itself if the headers name none.
+func forwardedFor(r *http.Request, peer netip.Addr, isTrusted func(netip.Addr) bool) netip.Addr {
+	if xff := r.Header.Values("X-Forwarded-For"); len(xff) > 0 {
+		hops := strings.Split(strings.Join(xff, ","), ",")
+		client := peer
+		for i := len(hops) - 1; i >= 0; i-- {
+			ip, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break // garbage; don't trust anything further left
+			}
+			client = ip.Unmap()
+			if !isTrusted(client) {
+				break
+			}
+		}
+		return client
+	}
+	if ip, err := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); err == nil {
+		return ip.Unmap()
+	}
+	return peer
+}
+
+// remoteIP parses the IP out of an http.Request RemoteAddr, which is
+// normally host:port.
+func remoteIP(addr string) (netip.Addr, bool) {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		host = addr
+	}
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
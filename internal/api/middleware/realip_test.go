@@ -0,0 +1,51 @@
+package middleware_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/walidabualafia/bloom/internal/api/middleware"
+)
+
+func TestRealIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	tests := []struct {
+		name       string
+		trusted    []netip.Prefix
+		remoteAddr string
+		xff        string
+		xRealIP    string
+		want       string
+	}{
+		{"no proxies trusted ignores headers", nil, "203.0.113.9:1234", "198.51.100.1", "198.51.100.2", "203.0.113.9"},
+		{"untrusted peer ignores headers", trusted, "203.0.113.9:1234", "198.51.100.1", "", "203.0.113.9"},
+		{"trusted peer forwards", trusted, "10.0.0.1:1234", "198.51.100.1", "", "198.51.100.1"},
+		{"spoofed entries left of the client are skipped", trusted, "10.0.0.1:1234", "1.2.3.4, 198.51.100.1, 10.0.0.2", "", "198.51.100.1"},
+		{"X-Real-IP from trusted peer", trusted, "10.0.0.1:1234", "", "198.51.100.7", "198.51.100.7"},
+		{"trusted peer without headers", trusted, "10.0.0.1:1234", "", "", "10.0.0.1"},
+		{"garbage stops the walk", trusted, "10.0.0.1:1234", "198.51.100.1, nonsense, 10.0.0.2", "", "10.0.0.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := middleware.RealIP(tt.trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r.RemoteAddr
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.xff != "" {
+				req.Header.Set("X-Forwarded-For", tt.xff)
+			}
+			if tt.xRealIP != "" {
+				req.Header.Set("X-Real-IP", tt.xRealIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Errorf("RemoteAddr = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"runtime/debug"
+
+	chimw "github.com/go-chi/chi/v5/middleware"
+
+	"github.com/walidabualafia/bloom/internal/api/response"
+)
+
+// panicResponse is the body sent when a handler panics. RequestID matches
+// the id in the server log, so a report can be traced to its stack.
+type panicResponse struct {
+	Error     string `json:"error"`
+	Code      string `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+}
+
+// Recoverer turns a panic in a later handler into a JSON 500 with the code
+// PANIC, so clients always get the usual error shape. The panic and its
+// stack are logged with the request id; neither is sent to the client.
+// Like chi's Recoverer, http.ErrAbortHandler is passed on so the server can
+// abort the response.
+func Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			if rvr == http.ErrAbortHandler {
+				panic(rvr)
+			}
+			reqID := chimw.GetReqID(r.Context())
+			log.Printf("panic: %s %s request_id=%s: %v\n%s", r.Method, r.URL.Path, reqID, rvr, debug.Stack())
+			if r.Header.Get("Connection") == "Upgrade" {
+				return
+			}
+			response.WriteJSON(w, http.StatusInternalServerError, panicResponse{
+				Error:     "internal server error",
+				Code:      response.CodePanic,
+				RequestID: reqID,
+			})
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
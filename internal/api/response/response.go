@@ -0,0 +1,86 @@
+// Package response writes the JSON responses shared by the API handlers and
+// middleware.
+package response
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+)
+
+// Error codes identify the kind of failure in the code field of an error
+// response, so clients can act on it without parsing the message. They are
+// part of the API: never change or reuse one.
+const (
+	CodeBadRequest           = "BAD_REQUEST"
+	CodeValidationFailed     = "VALIDATION_FAILED"
+	CodeUnauthorized         = "UNAUTHORIZED"
+	CodeForbidden            = "FORBIDDEN"
+	CodeNotFound             = "NOT_FOUND"
+	CodeProjectNotFound      = "PROJECT_NOT_FOUND"
+	CodeTodoNotFound         = "TODO_NOT_FOUND"
+	CodeUserNotFound         = "USER_NOT_FOUND"
+	CodeConflict             = "CONFLICT"
+	CodeUnsupportedMediaType = "UNSUPPORTED_MEDIA_TYPE"
+	CodeRateLimited          = "RATE_LIMITED"
+	CodeMaintenance          = "MAINTENANCE"
+	CodeUnavailable          = "UNAVAILABLE"
+	CodeInternal             = "INTERNAL_ERROR"
+	CodePanic                = "PANIC"
+)
+
+// statusCodes maps statuses to the code used when no more specific one is
+// given.
+var statusCodes = map[int]string{
+	http.StatusBadRequest:           CodeBadRequest,
+	http.StatusUnauthorized:         CodeUnauthorized,
+	http.StatusForbidden:            CodeForbidden,
+	http.StatusNotFound:             CodeNotFound,
+	http.StatusConflict:             CodeConflict,
+	http.StatusUnsupportedMediaType: CodeUnsupportedMediaType,
+	http.StatusTooManyRequests:      CodeRateLimited,
+	http.StatusServiceUnavailable:   CodeMaintenance,
+	http.StatusInternalServerError:  CodeInternal,
+}
+
+// StatusCode returns the generic error code for status.
+func StatusCode(status int) string {
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	return CodeInternal
+}
+
+// errorResponse is a standard error payload.
+type errorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// WriteJSON serializes data as JSON and writes it to the response. The
+// body is encoded up front so Content-Length is always set, which also lets
+// HEAD requests report it; the server drops the body itself for HEAD.
+func WriteJSON(w http.ResponseWriter, status int, data any) {
+	body, err := json.Marshal(data)
+	if err != nil {
+		status = http.StatusInternalServerError
+		body, _ = json.Marshal(errorResponse{Error: "internal server error", Code: CodeInternal})
+	}
+	body = append(body, '\n')
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	w.Write(body) //nolint:errcheck
+}
+
+// WriteJSONError writes a JSON error response of the form
+// {"error": message, "code": code}, with the generic code for status.
+func WriteJSONError(w http.ResponseWriter, status int, message string) {
+	WriteJSONErrorCode(w, status, StatusCode(status), message)
+}
+
+// WriteJSONErrorCode is like WriteJSONError with a specific code, such as
+// CodeProjectNotFound.
+func WriteJSONErrorCode(w http.ResponseWriter, status int, code, message string) {
+	WriteJSON(w, status, errorResponse{Error: message, Code: code})
+}
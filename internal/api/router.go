@@ -1,81 +1,194 @@
 package api
 
 import (
+	"time"
+
 	"github.com/go-chi/chi/v5"
 	chimw "github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
 
 	"github.com/walidabualafia/bloom/internal/api/handler"
 	"github.com/walidabualafia/bloom/internal/api/middleware"
+	"github.com/walidabualafia/bloom/internal/config"
+	"github.com/walidabualafia/bloom/internal/featureflag"
+	"github.com/walidabualafia/bloom/internal/password"
 	"github.com/walidabualafia/bloom/internal/store"
+	"github.com/walidabualafia/bloom/internal/webhook"
 )
 
+// NewEvents creates the webhook dispatcher configured by cfg, for
+// NewRouter.
+func NewEvents(s store.Store, cfg *config.Config) *webhook.Dispatcher {
+	return webhook.New(s, webhook.Options{
+		Timeout:              cfg.WebhookTimeout,
+		MaxAttempts:          cfg.WebhookMaxAttempts,
+		AllowPrivateNetworks: cfg.WebhookAllowPrivateNetworks,
+	})
+}
+
 // NewRouter creates and configures the Chi router with all API routes.
-func NewRouter(s store.Store, jwtSecret string) *chi.Mux {
+// Todo events are sent through events, which the caller closes at shutdown.
+func NewRouter(s store.Store, cfg *config.Config, events *webhook.Dispatcher) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Global middleware
 	r.Use(chimw.RequestID)
-	r.Use(chimw.RealIP)
+	r.Use(middleware.RealIP(cfg.TrustedProxies))
 	r.Use(middleware.Logger)
-	r.Use(chimw.Recoverer)
+	r.Use(middleware.Recoverer)
+	r.Use(chimw.GetHead) // HEAD is served by the GET handler unless a route defines its own
 	r.Use(cors.Handler(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:*", "https://*"},
+		AllowedOrigins:   cfg.CORSAllowedOrigins,
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type"},
-		AllowCredentials: true,
-		MaxAge:           300,
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", middleware.CSRFHeader},
+		ExposedHeaders:   cfg.CORSExposedHeaders,
+		AllowCredentials: cfg.CORSAllowCredentials,
+		MaxAge:           cfg.CORSMaxAge,
 	}))
 
+	maintenance := middleware.NewMaintenance(cfg.MaintenanceMode)
+	rateLimit := middleware.NewUserRateLimit(cfg.RateLimitWrites, cfg.RateLimitReads)
+
 	// Handlers
-	auth := handler.NewAuth(s, jwtSecret)
-	project := handler.NewProject(s)
-	todo := handler.NewTodo(s)
-	user := handler.NewUser(s)
+	passwords := password.NewHasher(cfg.PasswordPepper)
+	auth := handler.NewAuth(s, cfg.JWTSecret, !cfg.RegistrationDisabled, handler.AuthCookie{Enabled: cfg.AuthCookie, Secure: !cfg.IsDevelopment()},
+		passwords)
+	project := handler.NewProject(s, cfg.MaxProjectsPerUser, cfg.MaxProjectMembers, cfg.MaxTodosPerProject,
+		cfg.WebhookAllowPrivateNetworks)
+	pagination := handler.Pagination{DefaultLimit: cfg.DefaultPageSize, MaxLimit: cfg.MaxPageSize}
+	todo := handler.NewTodo(s, handler.TodoLimits{
+		MaxTitle:          cfg.TodoMaxTitle,
+		MaxDescription:    cfg.TodoMaxDescription,
+		DeadlineMaxPast:   cfg.DeadlineMaxPast,
+		DeadlineMaxFuture: cfg.DeadlineMaxFuture,
+		MaxPerProject:     cfg.MaxTodosPerProject,
+	}, pagination, cfg.BlockIncompleteDependencies, events)
+	flags := featureflag.New(s, cfg.FeatureFlagCacheTTL, cfg.PublicFeatureFlags)
+	user := handler.NewUser(s, maintenance, flags, pagination, cfg.MaxBulkDelete, cfg.AdminAudit, passwords)
+	meta := handler.NewMeta(handler.MetaFeatures{
+		RegistrationEnabled:         !cfg.RegistrationDisabled,
+		CookieAuth:                  cfg.AuthCookie,
+		BlockIncompleteDependencies: cfg.BlockIncompleteDependencies,
+		Reminders:                   cfg.ReminderInterval > 0,
+		MaxProjectsPerUser:          cfg.MaxProjectsPerUser,
+		MaxProjectMembers:           cfg.MaxProjectMembers,
+		MaxTodosPerProject:          cfg.MaxTodosPerProject,
+		TodoMaxTitle:                cfg.TodoMaxTitle,
+		TodoMaxDescription:          cfg.TodoMaxDescription,
+		MaxPageSize:                 cfg.MaxPageSize,
+		DemoMode:                    cfg.DemoMode,
+	}, maintenance, flags)
 
-	// Public routes
-	r.Route("/api", func(r chi.Router) {
+	// The API is served under /api/v1. The unversioned /api prefix is an
+	// alias kept for existing clients and marked deprecated.
+	routes := func(r chi.Router) {
+		if cfg.SecurityHeaders && cfg.SecurityHeadersAPI {
+			r.Use(middleware.SecurityHeaders(cfg.ContentSecurityPolicy, cfg.ReferrerPolicy))
+		}
+		r.Use(maintenance.Middleware)
+		r.Use(middleware.RequireJSON)
+		r.Use(middleware.Fields)
+
+		// Public routes
+		r.Get("/meta", meta.Get)
+		r.Get("/openapi.json", handler.OpenAPI)
 		r.Post("/auth/register", auth.Register)
 		r.Post("/auth/login", auth.Login)
+		r.Post("/auth/logout", auth.Logout)
 
 		// Protected routes
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.Auth(jwtSecret))
+			r.Use(middleware.Auth(cfg.JWTSecret, cfg.JWTSecretPrevious, cfg.JWTLeeway, cfg.AuthCookie))
+			r.Use(rateLimit.Middleware)
 
 			// Current user
 			r.Get("/auth/me", auth.Me)
+			r.Put("/auth/me", auth.UpdateMe)
+			r.Get("/auth/validate", auth.Validate)
 
 			// Projects
 			r.Get("/projects", project.List)
 			r.Post("/projects", project.Create)
+			r.Get("/projects/templates", project.ListTemplates)
+			r.Post("/projects/roles", project.Roles)
+			r.Post("/projects/reorder", project.Reorder)
+			r.Post("/projects/from-template/{templateID}", project.CreateFromTemplate)
+			r.Post("/templates/import", project.ImportTemplate)
+			r.Post("/projects/import", project.Import)
 			r.Get("/projects/{projectID}", project.Get)
 			r.Get("/projects/{projectID}/role", project.GetRole)
+			r.Get("/projects/{projectID}/stats", project.Stats)
 			r.Put("/projects/{projectID}", project.Update)
 			r.Delete("/projects/{projectID}", project.Delete)
+			r.Post("/projects/{projectID}/favorite", project.Favorite)
+			r.Delete("/projects/{projectID}/favorite", project.Unfavorite)
+			r.Post("/projects/{projectID}/export-template", project.ExportTemplate)
 
 			// Project members
 			r.Get("/projects/{projectID}/members", project.ListMembers)
 			r.Post("/projects/{projectID}/members", project.AddMember)
+			r.Get("/projects/{projectID}/members/{userID}", project.GetMember)
 			r.Delete("/projects/{projectID}/members/{userID}", project.RemoveMember)
 
+			// Project invites (for people without an account)
+			r.Get("/projects/{projectID}/invites", project.ListInvites)
+			r.Post("/projects/{projectID}/invites", project.CreateInvite)
+			r.Delete("/projects/{projectID}/invites/{inviteID}", project.RevokeInvite)
+
+			// Project webhooks
+			r.Get("/projects/{projectID}/webhooks", project.ListWebhooks)
+			r.Post("/projects/{projectID}/webhooks", project.CreateWebhook)
+			r.Put("/projects/{projectID}/webhooks/{webhookID}", project.UpdateWebhook)
+			r.Delete("/projects/{projectID}/webhooks/{webhookID}", project.DeleteWebhook)
+
 			// Todos (scoped to project)
 			r.Get("/projects/{projectID}/todos", todo.ListByProject)
 			r.Post("/projects/{projectID}/todos", todo.Create)
+			r.Get("/projects/{projectID}/todos/board", todo.Board)
+			r.Get("/projects/{projectID}/graph", todo.Graph)
+			r.Get("/projects/{projectID}/todos/completed", todo.ListCompleted)
+			r.Delete("/projects/{projectID}/todos/completed", todo.DeleteCompleted)
 
 			// Todos (direct access)
+			r.Get("/todos", todo.ListMine)
+			r.Get("/todos/due", todo.Due)
+			r.Get("/activity", todo.Activity)
 			r.Get("/todos/{todoID}", todo.Get)
 			r.Put("/todos/{todoID}", todo.Update)
 			r.Delete("/todos/{todoID}", todo.Delete)
+			r.Get("/todos/{todoID}/history", todo.History)
+			r.Get("/todos/{todoID}/dependencies", todo.ListDependencies)
+			r.Post("/todos/{todoID}/dependencies", todo.AddDependency)
+			r.Delete("/todos/{todoID}/dependencies/{dependsOnID}", todo.RemoveDependency)
 
 			// User search (for sharing)
 			r.Get("/users/search", user.Search)
+			r.Get("/users/me/memberships", user.Memberships)
+			r.Get("/users/me/export", user.Export)
 
 			// Admin
 			r.Get("/admin/stats", user.Stats)
 			r.Get("/admin/users", user.List)
-			r.Put("/admin/users/{userID}", user.Update)
-			r.Delete("/admin/users/{userID}", user.Delete)
+			r.Get("/admin/users/inactive", user.Inactive)
+			r.Post("/admin/users", user.Create)
+			r.With(middleware.DemoLocked(cfg.DemoMode)).Put("/admin/users/{userID}", user.Update)
+			r.With(middleware.DemoLocked(cfg.DemoMode)).Delete("/admin/users/{userID}", user.Delete)
+			r.Get("/admin/audit", user.Audit)
+			r.Get("/admin/users/{userID}/export", user.AdminExport)
+			r.Get("/admin/users/{userID}/projects", user.Projects)
+			r.Delete("/admin/projects", user.DeleteProjects)
+			r.Get("/admin/db/slow-queries", user.SlowQueries)
+			r.Post("/admin/repair/todos", user.RepairTodos)
+			r.Get("/admin/maintenance", user.GetMaintenance)
+			r.Post("/admin/maintenance", user.SetMaintenance)
+			r.Get("/admin/flags", user.ListFlags)
+			r.Put("/admin/flags/{key}", user.SetFlag)
 		})
+	}
+	r.Route("/api/v1", routes)
+	r.Route("/api", func(r chi.Router) {
+		r.Use(middleware.Deprecated(time.Time{}))
+		routes(r)
 	})
 
 	return r
@@ -2,7 +2,11 @@ package config
 
 import (
 	"fmt"
+	"net/netip"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config holds all application configuration, loaded from environment variables.
@@ -12,8 +16,145 @@ type Config struct {
 	DatabaseURL string
 	JWTSecret   string
 	Environment string
+
+	// JWTSecretPrevious lists retired signing secrets whose tokens are still
+	// accepted while sessions move over to JWTSecret.
+	JWTSecretPrevious []string
+
+	// PasswordPepper is an optional secret mixed into every password hash;
+	// see password.Hasher. Changing it invalidates all passwords.
+	PasswordPepper string
+
+	// DatabaseReadURL optionally points PostgreSQL reads at a replica.
+	DatabaseReadURL string
+
+	// ShutdownTimeout is how long in-flight requests get to finish after
+	// SIGINT/SIGTERM before their connections are closed. Queued webhook
+	// deliveries get whatever is left of it.
+	ShutdownTimeout time.Duration
+
+	// JWTLeeway is the clock skew tolerated when validating token times.
+	JWTLeeway time.Duration
+
+	// RegistrationDisabled (REGISTRATION_ENABLED=false) stops public
+	// sign-ups. Only admins can then create accounts, except that the first
+	// account on an empty instance may still register and becomes an admin.
+	RegistrationDisabled bool
+
+	// AuthCookie also issues the JWT as an HttpOnly cookie on login and
+	// accepts it in place of the Authorization header.
+	AuthCookie bool
+
+	// MaxProjectsPerUser caps how many projects a non-admin user may own.
+	// Zero means unlimited.
+	MaxProjectsPerUser int
+
+	// MaxProjectMembers caps how many members, besides its owner, a project
+	// may have. Zero means unlimited.
+	MaxProjectMembers int
+
+	// MaxTodosPerProject caps how many todos a project may have. Zero
+	// means unlimited.
+	MaxTodosPerProject int
+
+	// RateLimitWrites and RateLimitReads cap how many mutating and
+	// read-only requests each signed-in user may make per minute. Zero
+	// means unlimited.
+	RateLimitWrites int
+	RateLimitReads  int
+
+	// TodoMaxTitle and TodoMaxDescription cap todo text lengths in
+	// characters.
+	TodoMaxTitle       int
+	TodoMaxDescription int
+
+	// DeadlineMaxPast and DeadlineMaxFuture bound how far from now a todo
+	// deadline may be set.
+	DeadlineMaxPast   time.Duration
+	DeadlineMaxFuture time.Duration
+
+	// WebhookTimeout bounds each webhook delivery attempt, and
+	// WebhookMaxAttempts caps how often a failed delivery is tried.
+	WebhookTimeout     time.Duration
+	WebhookMaxAttempts int
+	// WebhookAllowPrivateNetworks lets webhooks target loopback, link-local
+	// and private addresses, which are refused by default.
+	WebhookAllowPrivateNetworks bool
+
+	// BlockIncompleteDependencies rejects completing a todo while any todo
+	// it depends on is still incomplete.
+	BlockIncompleteDependencies bool
+
+	// MaxBulkDelete caps how many projects one admin bulk delete may remove.
+	MaxBulkDelete int
+
+	// AdminAudit records every admin change (user updates and deletes, bulk
+	// project deletes, maintenance mode, feature flags and todo repairs) and
+	// lookups of a user's projects, viewable at GET /api/admin/audit.
+	AdminAudit bool
+
+	// DefaultPageSize and MaxPageSize bound ?limit on paginated endpoints.
+	DefaultPageSize int
+	MaxPageSize     int
+
+	// DBTiming wraps the store to record per-request database time, logged
+	// as db_ms.
+	DBTiming bool
+
+	// SlowQueryThreshold counts store calls slower than this, reported at
+	// GET /api/admin/db/slow-queries. Zero disables tracking.
+	SlowQueryThreshold time.Duration
+
+	// ReminderInterval is how often the deadline reminder scan runs; zero,
+	// the default, disables reminders. Reminders only go to the server log
+	// for now. ReminderLeadTime is how far ahead of a deadline a
+	// todo becomes due for its reminder.
+	ReminderInterval time.Duration
+	ReminderLeadTime time.Duration
+
+	// DemoMode wipes the database and reseeds the demo data every
+	// DemoResetInterval, and blocks admin changes to accounts. It is
+	// refused in production.
+	DemoMode          bool
+	DemoResetInterval time.Duration
+
+	// MaintenanceMode starts the server read-only. Admins can toggle it at
+	// runtime via POST /api/admin/maintenance.
+	MaintenanceMode bool
+
+	// FeatureFlagCacheTTL is how long each instance caches the feature
+	// flags admins set via PUT /api/admin/flags/{key}. PublicFeatureFlags
+	// are the flags GET /api/meta reports to everyone.
+	FeatureFlagCacheTTL time.Duration
+	PublicFeatureFlags  []string
+
+	// SecurityHeaders adds CSP, X-Content-Type-Options, X-Frame-Options and
+	// Referrer-Policy to the embedded frontend's responses, and to API
+	// responses too if SecurityHeadersAPI is set. On by default in
+	// production.
+	SecurityHeaders       bool
+	SecurityHeadersAPI    bool
+	ContentSecurityPolicy string
+	ReferrerPolicy        string
+
+	// TrustedProxies are the reverse proxies whose X-Forwarded-For and
+	// X-Real-IP headers are believed. Empty means the headers are ignored
+	// and the connection's address is the client's.
+	TrustedProxies []netip.Prefix
+
+	// CORS settings for browser clients served from another origin.
+	CORSAllowedOrigins   []string
+	CORSAllowCredentials bool
+	CORSExposedHeaders   []string
+	CORSMaxAge           int // seconds
 }
 
+// defaultContentSecurityPolicy allows the built frontend's own scripts,
+// styles and API calls and nothing inline except style attributes, which
+// React sets.
+const defaultContentSecurityPolicy = "default-src 'self'; script-src 'self'; style-src 'self' 'unsafe-inline'; " +
+	"img-src 'self' data:; connect-src 'self'; object-src 'none'; base-uri 'self'; frame-ancestors 'none'"
+
 // Load reads configuration from environment variables with sensible defaults.
 func Load() (*Config, error) {
 	cfg := &Config{
@@ -22,6 +163,187 @@ func Load() (*Config, error) {
 		DatabaseURL: getEnv("DATABASE_URL", "bloom.db"),
 		JWTSecret:   os.Getenv("JWT_SECRET"),
 		Environment: getEnv("ENVIRONMENT", "development"),
+
+		PasswordPepper: os.Getenv("PASSWORD_PEPPER"),
+
+		DatabaseReadURL: os.Getenv("DATABASE_READ_URL"),
+	}
+
+	var err error
+	if cfg.ShutdownTimeout, err = getEnvDuration("SHUTDOWN_TIMEOUT", 10*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.ShutdownTimeout <= 0 {
+		return nil, fmt.Errorf("SHUTDOWN_TIMEOUT must be positive")
+	}
+	if cfg.JWTLeeway, err = getEnvDuration("JWT_LEEWAY", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.JWTLeeway < 0 {
+		return nil, fmt.Errorf("JWT_LEEWAY must not be negative")
+	}
+	registration, err := getEnvBool("REGISTRATION_ENABLED", true)
+	if err != nil {
+		return nil, err
+	}
+	cfg.RegistrationDisabled = !registration
+	if cfg.AuthCookie, err = getEnvBool("AUTH_COOKIE", false); err != nil {
+		return nil, err
+	}
+	if cfg.MaxProjectsPerUser, err = getEnvInt("MAX_PROJECTS_PER_USER", 0); err != nil {
+		return nil, err
+	}
+	if cfg.MaxProjectsPerUser < 0 {
+		return nil, fmt.Errorf("MAX_PROJECTS_PER_USER must not be negative")
+	}
+	if cfg.MaxProjectMembers, err = getEnvInt("MAX_PROJECT_MEMBERS", 0); err != nil {
+		return nil, err
+	}
+	if cfg.MaxProjectMembers < 0 {
+		return nil, fmt.Errorf("MAX_PROJECT_MEMBERS must not be negative")
+	}
+	if cfg.MaxTodosPerProject, err = getEnvInt("MAX_TODOS_PER_PROJECT", 0); err != nil {
+		return nil, err
+	}
+	if cfg.MaxTodosPerProject < 0 {
+		return nil, fmt.Errorf("MAX_TODOS_PER_PROJECT must not be negative")
+	}
+	if cfg.RateLimitWrites, err = getEnvInt("RATE_LIMIT_WRITES", 300); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimitReads, err = getEnvInt("RATE_LIMIT_READS", 0); err != nil {
+		return nil, err
+	}
+	if cfg.RateLimitWrites < 0 || cfg.RateLimitReads < 0 {
+		return nil, fmt.Errorf("RATE_LIMIT_WRITES and RATE_LIMIT_READS must not be negative")
+	}
+	if cfg.TodoMaxTitle, err = getEnvInt("TODO_MAX_TITLE_LENGTH", 255); err != nil {
+		return nil, err
+	}
+	if cfg.TodoMaxTitle < 1 || cfg.TodoMaxTitle > 255 {
+		return nil, fmt.Errorf("TODO_MAX_TITLE_LENGTH must be between 1 and 255")
+	}
+	if cfg.TodoMaxDescription, err = getEnvInt("TODO_MAX_DESCRIPTION_LENGTH", 10000); err != nil {
+		return nil, err
+	}
+	if cfg.TodoMaxDescription < 1 {
+		return nil, fmt.Errorf("TODO_MAX_DESCRIPTION_LENGTH must be positive")
+	}
+	if cfg.DeadlineMaxPast, err = getEnvDuration("DEADLINE_MAX_PAST", 10*365*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.DeadlineMaxFuture, err = getEnvDuration("DEADLINE_MAX_FUTURE", 100*365*24*time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.DeadlineMaxPast <= 0 || cfg.DeadlineMaxFuture <= 0 {
+		return nil, fmt.Errorf("DEADLINE_MAX_PAST and DEADLINE_MAX_FUTURE must be positive")
+	}
+	if cfg.MaxBulkDelete, err = getEnvInt("MAX_BULK_DELETE", 100); err != nil {
+		return nil, err
+	}
+	if cfg.MaxBulkDelete < 1 {
+		return nil, fmt.Errorf("MAX_BULK_DELETE must be positive")
+	}
+	if cfg.DefaultPageSize, err = getEnvInt("DEFAULT_PAGE_SIZE", 10); err != nil {
+		return nil, err
+	}
+	if cfg.MaxPageSize, err = getEnvInt("MAX_PAGE_SIZE", 50); err != nil {
+		return nil, err
+	}
+	if cfg.DefaultPageSize < 1 || cfg.MaxPageSize < cfg.DefaultPageSize {
+		return nil, fmt.Errorf("DEFAULT_PAGE_SIZE must be positive and no larger than MAX_PAGE_SIZE")
+	}
+	if cfg.DBTiming, err = getEnvBool("DB_TIMING", false); err != nil {
+		return nil, err
+	}
+	if cfg.SlowQueryThreshold, err = getEnvDuration("SLOW_QUERY_THRESHOLD", 0); err != nil {
+		return nil, err
+	}
+	if cfg.ReminderInterval, err = getEnvDuration("REMINDER_INTERVAL", 0); err != nil {
+		return nil, err
+	}
+	if cfg.ReminderInterval < 0 {
+		return nil, fmt.Errorf("REMINDER_INTERVAL must not be negative")
+	}
+	if cfg.ReminderLeadTime, err = getEnvDuration("REMINDER_LEAD_TIME", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.ReminderLeadTime <= 0 {
+		return nil, fmt.Errorf("REMINDER_LEAD_TIME must be positive")
+	}
+	if cfg.DemoMode, err = getEnvBool("DEMO_MODE", false); err != nil {
+		return nil, err
+	}
+	if cfg.DemoResetInterval, err = getEnvDuration("DEMO_RESET_INTERVAL", time.Hour); err != nil {
+		return nil, err
+	}
+	if cfg.DemoResetInterval <= 0 {
+		return nil, fmt.Errorf("DEMO_RESET_INTERVAL must be positive")
+	}
+	if cfg.DemoMode && cfg.Environment == "production" {
+		return nil, fmt.Errorf("DEMO_MODE deletes all data and cannot be enabled in production")
+	}
+	if cfg.MaintenanceMode, err = getEnvBool("MAINTENANCE_MODE", false); err != nil {
+		return nil, err
+	}
+	if cfg.FeatureFlagCacheTTL, err = getEnvDuration("FEATURE_FLAG_CACHE_TTL", 30*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.FeatureFlagCacheTTL <= 0 {
+		return nil, fmt.Errorf("FEATURE_FLAG_CACHE_TTL must be positive")
+	}
+	cfg.PublicFeatureFlags = getEnvList("PUBLIC_FEATURE_FLAGS", nil)
+	if cfg.AdminAudit, err = getEnvBool("ADMIN_AUDIT", true); err != nil {
+		return nil, err
+	}
+	if cfg.BlockIncompleteDependencies, err = getEnvBool("BLOCK_INCOMPLETE_DEPENDENCIES", false); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookTimeout, err = getEnvDuration("WEBHOOK_TIMEOUT", 5*time.Second); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookMaxAttempts, err = getEnvInt("WEBHOOK_MAX_ATTEMPTS", 3); err != nil {
+		return nil, err
+	}
+	if cfg.WebhookTimeout <= 0 || cfg.WebhookMaxAttempts < 1 {
+		return nil, fmt.Errorf("WEBHOOK_TIMEOUT and WEBHOOK_MAX_ATTEMPTS must be positive")
+	}
+	if cfg.WebhookAllowPrivateNetworks, err = getEnvBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false); err != nil {
+		return nil, err
+	}
+
+	if cfg.SecurityHeaders, err = getEnvBool("SECURITY_HEADERS", cfg.Environment == "production"); err != nil {
+		return nil, err
+	}
+	if cfg.SecurityHeadersAPI, err = getEnvBool("SECURITY_HEADERS_API", false); err != nil {
+		return nil, err
+	}
+	cfg.ContentSecurityPolicy = getEnv("CONTENT_SECURITY_POLICY", defaultContentSecurityPolicy)
+	cfg.ReferrerPolicy = getEnv("REFERRER_POLICY", "strict-origin-when-cross-origin")
+
+	cfg.JWTSecretPrevious = getEnvList("JWT_SECRET_PREVIOUS", nil)
+
+	for _, entry := range getEnvList("TRUSTED_PROXIES", nil) {
+		prefix, err := parsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("TRUSTED_PROXIES: %w", err)
+		}
+		cfg.TrustedProxies = append(cfg.TrustedProxies, prefix)
+	}
+	cfg.CORSAllowedOrigins = getEnvList("CORS_ALLOWED_ORIGINS", []string{"http://localhost:*", "https://*"})
+	cfg.CORSExposedHeaders = getEnvList("CORS_EXPOSED_HEADERS", []string{"X-Total-Count", "X-Request-ID", "ETag", "X-Page-Limit", "X-Page-Offset", "X-Next-Cursor"})
+	if cfg.CORSAllowCredentials, err = getEnvBool("CORS_ALLOW_CREDENTIALS", true); err != nil {
+		return nil, err
+	}
+	if cfg.CORSMaxAge, err = getEnvInt("CORS_MAX_AGE", 300); err != nil {
+		return nil, err
+	}
+	if cfg.CORSAllowCredentials {
+		for _, origin := range cfg.CORSAllowedOrigins {
+			if origin == "*" {
+				return nil, fmt.Errorf("CORS_ALLOWED_ORIGINS cannot contain '*' when CORS_ALLOW_CREDENTIALS is true; browsers reject that combination")
+			}
+		}
 	}
 
 	if cfg.JWTSecret == "" {
@@ -35,6 +357,9 @@ func Load() (*Config, error) {
 	if cfg.DBDriver != "sqlite" && cfg.DBDriver != "postgres" {
 		return nil, fmt.Errorf("DB_DRIVER must be 'sqlite' or 'postgres', got '%s'", cfg.DBDriver)
 	}
+	if cfg.DatabaseReadURL != "" && cfg.DBDriver != "postgres" {
+		return nil, fmt.Errorf("DATABASE_READ_URL is only supported with DB_DRIVER=postgres")
+	}
 
 	return cfg, nil
 }
@@ -50,3 +375,72 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) (int, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer, got '%s'", key, v)
+	}
+	return n, nil
+}
+
+func getEnvDuration(key string, fallback time.Duration) (time.Duration, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration like '30s', got '%s'", key, v)
+	}
+	return d, nil
+}
+
+func getEnvBool(key string, fallback bool) (bool, error) {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback, nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return false, fmt.Errorf("%s must be a boolean, got '%s'", key, v)
+	}
+	return b, nil
+}
+
+// parsePrefix parses a CIDR range, or a single IP address as a range
+// containing only that address.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if !strings.Contains(s, "/") {
+		ip, err := netip.ParseAddr(s)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return netip.PrefixFrom(ip.Unmap(), ip.Unmap().BitLen()), nil
+	}
+	prefix, err := netip.ParsePrefix(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return prefix.Masked(), nil
+}
+
+// getEnvList reads a comma-separated list, trimming spaces and dropping
+// empty entries.
+func getEnvList(key string, fallback []string) []string {
+	v := os.Getenv(key)
+	if v == "" {
+		return fallback
+	}
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,143 @@
+// Package demo fills a database with sample accounts, projects and todos,
+// and in demo mode periodically wipes it and fills it again so a public
+// instance stays clean.
+package demo
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/password"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+// Password is the password of every seeded account. It is public: demo
+// visitors sign in with it.
+const Password = "bloom-demo"
+
+// Usernames of the seeded accounts. Admin is an administrator.
+const (
+	User     = "demo"
+	Teammate = "alex"
+	Admin    = "admin"
+)
+
+// Seed creates the demo accounts and their projects, hashing Password with
+// passwords. It expects an empty database; on one with data it fails if the
+// demo accounts already exist.
+func Seed(ctx context.Context, s store.Store, passwords password.Hasher) error {
+	hash, err := passwords.Hash(Password)
+	if err != nil {
+		return fmt.Errorf("hash password: %w", err)
+	}
+	users := map[string]*model.User{}
+	for _, name := range []string{User, Teammate, Admin} {
+		u := &model.User{Username: name, Email: name + "@example.com", Password: hash, IsAdmin: name == Admin}
+		if err := s.CreateUser(ctx, u); err != nil {
+			return fmt.Errorf("create user %s: %w", name, err)
+		}
+		users[name] = u
+	}
+
+	now := time.Now().UTC().Truncate(time.Hour)
+	in := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	points := func(n int) *int { return &n }
+	const day = 24 * time.Hour
+
+	imports := []store.ProjectImport{
+		{
+			Project: &model.Project{
+				Name:        "Website relaunch",
+				Description: "Everything for the new marketing site.",
+				Color:       "#3b82f6",
+				OwnerID:     users[User].ID,
+			},
+			Todos: []model.Todo{
+				{Title: "Collect feedback on the old site", Status: model.StatusCompleted, Priority: model.PriorityMedium, Estimate: points(2)},
+				{Title: "Draft the new sitemap", Status: model.StatusCompleted, Priority: model.PriorityHigh, Estimate: points(3)},
+				{Title: "Design the landing page", Status: model.StatusInProgress, Priority: model.PriorityHigh, Deadline: in(2 * day), Estimate: points(5)},
+				{Title: "Write copy for the pricing page", Status: model.StatusPending, Priority: model.PriorityMedium, Deadline: in(5 * day), Estimate: points(3)},
+				{Title: "Set up redirects from old URLs", Status: model.StatusPending, Priority: model.PriorityLow, Estimate: points(1)},
+				{Title: "Launch announcement", Description: "Blog post and newsletter.", Status: model.StatusPending, Priority: model.PriorityMedium, Deadline: in(14 * day)},
+			},
+			Members: []model.ProjectMember{{UserID: users[Teammate].ID, Role: model.RoleEditor}},
+		},
+		{
+			Project: &model.Project{Name: "Personal", Color: "#22c55e", OwnerID: users[User].ID},
+			Todos: []model.Todo{
+				{Title: "Renew passport", Status: model.StatusPending, Priority: model.PriorityHigh, Deadline: in(-day)},
+				{Title: "Book dentist appointment", Status: model.StatusPending, Priority: model.PriorityMedium, Deadline: in(3 * day)},
+				{Title: "Read \"The Pragmatic Programmer\"", Status: model.StatusInProgress, Priority: model.PriorityLow},
+			},
+		},
+		{
+			Project: &model.Project{
+				Name:        "Conference talk",
+				Description: "Slides and rehearsal for the spring meetup.",
+				Color:       "#f97316",
+				OwnerID:     users[Teammate].ID,
+			},
+			Todos: []model.Todo{
+				{Title: "Submit abstract", Status: model.StatusCompleted, Priority: model.PriorityHigh},
+				{Title: "Outline slides", Status: model.StatusInProgress, Priority: model.PriorityMedium, Deadline: in(7 * day)},
+				{Title: "Dry run with the team", Status: model.StatusPending, Priority: model.PriorityMedium, Deadline: in(10 * day)},
+			},
+			Members: []model.ProjectMember{{UserID: users[User].ID, Role: model.RoleViewer}},
+		},
+	}
+	if err := s.ImportProjects(ctx, imports); err != nil {
+		return fmt.Errorf("create projects: %w", err)
+	}
+	return nil
+}
+
+// Resetter wipes the database and seeds it again every interval.
+type Resetter struct {
+	store     store.Store
+	interval  time.Duration
+	passwords password.Hasher
+}
+
+// NewResetter creates a Resetter. interval must be positive. passwords is
+// passed to Seed.
+func NewResetter(s store.Store, interval time.Duration, passwords password.Hasher) *Resetter {
+	return &Resetter{store: s, interval: interval, passwords: passwords}
+}
+
+// Run resets every interval until ctx is cancelled. Callers normally Reset
+// once themselves before starting it, so the instance never serves stale
+// data.
+func (r *Resetter) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if err := r.Reset(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("demo: reset failed: %v", err)
+		}
+	}
+}
+
+// Reset deletes all data and seeds the demo data, logging when it is done.
+func (r *Resetter) Reset(ctx context.Context) error {
+	start := time.Now()
+	if err := r.store.ResetData(ctx); err != nil {
+		return err
+	}
+	if err := Seed(ctx, r.store, r.passwords); err != nil {
+		return err
+	}
+	log.Printf("demo: data reset in %s; sign in as %q with password %q; next reset in %s",
+		time.Since(start).Round(time.Millisecond), User, Password, r.interval)
+	return nil
+}
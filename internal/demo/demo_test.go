@@ -0,0 +1,61 @@
+package demo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/demo"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/password"
+	"github.com/walidabualafia/bloom/internal/store/sqlite"
+)
+
+func TestResetReplacesDataWithSeed(t *testing.T) {
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	resetter := demo.NewResetter(s, time.Hour, password.Hasher{})
+	if err := resetter.Reset(ctx); err != nil {
+		t.Fatalf("first reset: %v", err)
+	}
+	user, err := s.GetUserByUsername(ctx, demo.User)
+	if err != nil {
+		t.Fatalf("get demo user: %v", err)
+	}
+	seeded, _ := s.ListProjectsByUser(ctx, user.ID)
+
+	// Visitor changes are thrown away by the next reset.
+	visitor := &model.User{Username: "visitor", Email: "visitor@example.com", Password: "pw"}
+	s.CreateUser(ctx, visitor)
+	s.CreateProject(ctx, &model.Project{Name: "Scratch", OwnerID: user.ID})
+
+	if err := resetter.Reset(ctx); err != nil {
+		t.Fatalf("second reset: %v", err)
+	}
+	if _, err := s.GetUserByUsername(ctx, "visitor"); err == nil {
+		t.Error("visitor account survived the reset")
+	}
+	users, _ := s.ListUsers(ctx)
+	if len(users) != 3 {
+		t.Errorf("got %d users after reset, want 3", len(users))
+	}
+	again, err := s.GetUserByUsername(ctx, demo.User)
+	if err != nil {
+		t.Fatalf("get demo user after reset: %v", err)
+	}
+	if again.ID != user.ID {
+		t.Errorf("demo user id = %d after reset, want %d", again.ID, user.ID)
+	}
+	projects, _ := s.ListProjectsByUser(ctx, again.ID)
+	if len(projects) != len(seeded) || len(projects) == 0 {
+		t.Errorf("got %d projects after reset, want %d", len(projects), len(seeded))
+	}
+}
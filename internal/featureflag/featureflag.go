@@ -0,0 +1,110 @@
+// Package featureflag switches experimental behavior on and off at runtime
+// using flags stored in the database, so it can be tried out without a
+// redeploy.
+package featureflag
+
+import (
+	"context"
+	"log"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+// defaultTTL is how long flags are cached when New is given no TTL.
+const defaultTTL = 30 * time.Second
+
+// Flags caches the stored flags so checking one is a map lookup. The cache
+// is reloaded once it is older than the TTL, and updated immediately by
+// Set, so changes made through this instance apply at once and changes made
+// through another instance within the TTL.
+type Flags struct {
+	store  store.Store
+	ttl    time.Duration
+	public []string
+
+	mu      sync.Mutex
+	enabled map[string]bool
+	loaded  time.Time
+}
+
+// New creates Flags that cache for ttl. public lists the keys that Public
+// reports; every other flag is visible to admins only.
+func New(s store.Store, ttl time.Duration, public []string) *Flags {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+	return &Flags{store: s, ttl: ttl, public: public}
+}
+
+// Enabled reports whether the flag is on. Flags that were never set are
+// off. If the flags cannot be loaded, the last known values are used.
+func (f *Flags) Enabled(ctx context.Context, key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refresh(ctx)
+	return f.enabled[key]
+}
+
+// Public returns the state of each public flag.
+func (f *Flags) Public(ctx context.Context) map[string]bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.refresh(ctx)
+	flags := make(map[string]bool, len(f.public))
+	for _, key := range f.public {
+		flags[key] = f.enabled[key]
+	}
+	return flags
+}
+
+// IsPublic reports whether the flag is listed in GET /meta.
+func (f *Flags) IsPublic(key string) bool {
+	return slices.Contains(f.public, key)
+}
+
+// List returns every stored flag, bypassing the cache.
+func (f *Flags) List(ctx context.Context) ([]model.FeatureFlag, error) {
+	return f.store.ListFeatureFlags(ctx)
+}
+
+// Set stores the flag and updates the cache.
+func (f *Flags) Set(ctx context.Context, key string, enabled bool) (*model.FeatureFlag, error) {
+	flag := &model.FeatureFlag{Key: key, Enabled: enabled}
+	if err := f.store.SetFeatureFlag(ctx, flag); err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.enabled != nil {
+		f.enabled[key] = enabled
+	}
+	return flag, nil
+}
+
+// refresh reloads the cache if it has expired. The caller holds f.mu.
+func (f *Flags) refresh(ctx context.Context) {
+	if f.enabled != nil && time.Since(f.loaded) < f.ttl {
+		return
+	}
+	flags, err := f.store.ListFeatureFlags(ctx)
+	if err != nil {
+		log.Printf("featureflag: load flags: %v", err)
+		if f.enabled == nil {
+			f.enabled = map[string]bool{}
+		}
+		// Keep the stale values, but try again only after another TTL so a
+		// database outage does not turn every check into a query.
+		f.loaded = time.Now()
+		return
+	}
+	enabled := make(map[string]bool, len(flags))
+	for _, flag := range flags {
+		enabled[flag.Key] = flag.Enabled
+	}
+	f.enabled = enabled
+	f.loaded = time.Now()
+}
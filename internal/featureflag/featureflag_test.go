@@ -0,0 +1,49 @@
+package featureflag_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/featureflag"
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store/sqlite"
+)
+
+func TestFlagsCache(t *testing.T) {
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	const ttl = 50 * time.Millisecond
+	flags := featureflag.New(s, ttl, []string{"public"})
+	if flags.Enabled(ctx, "beta") {
+		t.Error("unset flag is enabled")
+	}
+
+	// Set through these Flags applies at once.
+	if _, err := flags.Set(ctx, "beta", true); err != nil {
+		t.Fatalf("set: %v", err)
+	}
+	if !flags.Enabled(ctx, "beta") {
+		t.Error("flag not enabled right after Set")
+	}
+
+	// A change made elsewhere, e.g. by another instance, waits for the TTL.
+	if err := s.SetFeatureFlag(ctx, &model.FeatureFlag{Key: "public", Enabled: true}); err != nil {
+		t.Fatalf("set in store: %v", err)
+	}
+	if flags.Public(ctx)["public"] {
+		t.Error("change from another instance visible before the cache expired")
+	}
+	time.Sleep(ttl)
+	if got := flags.Public(ctx); len(got) != 1 || !got["public"] {
+		t.Errorf("public = %v, want only public enabled", got)
+	}
+}
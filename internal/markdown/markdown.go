@@ -0,0 +1,163 @@
+// Package markdown renders a small subset of Markdown to HTML that is safe to
+// insert into a page.
+//
+// Safety comes from construction rather than filtering: the source is
+// HTML-escaped before any formatting is applied, so raw HTML is always shown
+// as text, and the renderer itself only emits the tags p, br, h1–h6, ul, ol,
+// li, pre, code, strong, em and a. Links are kept only for http, https and
+// mailto URLs.
+//
+// Supported syntax: ATX headings (# to ######), paragraphs, "-", "*" or "+"
+// bullet lists, "1." numbered lists, fenced code blocks (```), `code`,
+// **strong**, *em* and [text](url) links.
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	orderedItem = regexp.MustCompile(`^\d+[.)]\s+`)
+	link        = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	strong      = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	em          = regexp.MustCompile(`\*([^*]+)\*`)
+)
+
+// Render converts Markdown source to sanitized HTML.
+func Render(src string) string {
+	lines := strings.Split(strings.ReplaceAll(src, "\r\n", "\n"), "\n")
+
+	var b strings.Builder
+	var para []string
+	list := "" // "ul" or "ol" while a list is open
+	flushPara := func() {
+		if len(para) == 0 {
+			return
+		}
+		for i, line := range para {
+			para[i] = inline(line)
+		}
+		b.WriteString("<p>" + strings.Join(para, "<br>\n") + "</p>\n")
+		para = nil
+	}
+	closeList := func() {
+		if list != "" {
+			b.WriteString("</" + list + ">\n")
+			list = ""
+		}
+	}
+	openList := func(kind string) {
+		if list != kind {
+			closeList()
+			b.WriteString("<" + kind + ">\n")
+			list = kind
+		}
+	}
+
+	for i := 0; i < len(lines); i++ {
+		line := strings.TrimSpace(lines[i])
+		switch {
+		case strings.HasPrefix(line, "```"):
+			flushPara()
+			closeList()
+			b.WriteString("<pre><code>")
+			for i++; i < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[i]), "```"); i++ {
+				b.WriteString(html.EscapeString(lines[i]) + "\n")
+			}
+			b.WriteString("</code></pre>\n")
+		case line == "":
+			flushPara()
+			closeList()
+		case headingLevel(line) > 0:
+			flushPara()
+			closeList()
+			level := headingLevel(line)
+			fmt.Fprintf(&b, "<h%d>%s</h%d>\n", level, inline(strings.TrimSpace(line[level:])), level)
+		case len(line) > 1 && strings.ContainsRune("-*+", rune(line[0])) && line[1] == ' ':
+			flushPara()
+			openList("ul")
+			b.WriteString("<li>" + inline(strings.TrimSpace(line[2:])) + "</li>\n")
+		case orderedItem.MatchString(line):
+			flushPara()
+			openList("ol")
+			b.WriteString("<li>" + inline(orderedItem.ReplaceAllString(line, "")) + "</li>\n")
+		default:
+			closeList()
+			para = append(para, line)
+		}
+	}
+	flushPara()
+	closeList()
+	return b.String()
+}
+
+// headingLevel returns n for a line starting with n (1–6) '#' characters and
+// a space, and 0 otherwise.
+func headingLevel(line string) int {
+	n := 0
+	for n < len(line) && line[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || n >= len(line) || line[n] != ' ' {
+		return 0
+	}
+	return n
+}
+
+// inline escapes one line of text and applies code spans, links and
+// emphasis. Nothing inside a code span is formatted.
+func inline(s string) string {
+	parts := strings.Split(s, "`")
+	var b strings.Builder
+	for i, part := range parts {
+		switch {
+		case i%2 == 0:
+			b.WriteString(links(html.EscapeString(part)))
+		case i == len(parts)-1: // unmatched backtick
+			b.WriteString("`" + links(html.EscapeString(part)))
+		default:
+			b.WriteString("<code>" + html.EscapeString(part) + "</code>")
+		}
+	}
+	return b.String()
+}
+
+// links turns [text](url) into anchors and applies emphasis to everything
+// except the URLs. s must already be escaped.
+func links(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range link.FindAllStringSubmatchIndex(s, -1) {
+		b.WriteString(emphasis(s[last:m[0]]))
+		text, href := emphasis(s[m[2]:m[3]]), html.UnescapeString(s[m[4]:m[5]])
+		if safeURL(href) {
+			fmt.Fprintf(&b, `<a href="%s" rel="nofollow noopener">%s</a>`, html.EscapeString(href), text)
+		} else {
+			b.WriteString(text)
+		}
+		last = m[1]
+	}
+	b.WriteString(emphasis(s[last:]))
+	return b.String()
+}
+
+func emphasis(s string) string {
+	s = strong.ReplaceAllString(s, "<strong>$1</strong>")
+	return em.ReplaceAllString(s, "<em>$1</em>")
+}
+
+func safeURL(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "http", "https", "mailto":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package markdown_test
+
+import (
+	"testing"
+
+	"github.com/walidabualafia/bloom/internal/markdown"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"paragraph", "hello\nworld", "<p>hello<br>\nworld</p>\n"},
+		{"heading", "## Plan", "<h2>Plan</h2>\n"},
+		{"not a heading", "#hashtag", "<p>#hashtag</p>\n"},
+		{"emphasis", "**bold** and *soft*", "<p><strong>bold</strong> and <em>soft</em></p>\n"},
+		{"code span", "run `a <b> *c*`", "<p>run <code>a &lt;b&gt; *c*</code></p>\n"},
+		{"bullets", "- one\n- two", "<ul>\n<li>one</li>\n<li>two</li>\n</ul>\n"},
+		{"numbered", "1. one\n2. two", "<ol>\n<li>one</li>\n<li>two</li>\n</ol>\n"},
+		{"fenced code", "```\n<b>x</b>\n```", "<pre><code>&lt;b&gt;x&lt;/b&gt;\n</code></pre>\n"},
+		{"link", "[docs](https://example.com/a?b=1&c=2)",
+			`<p><a href="https://example.com/a?b=1&amp;c=2" rel="nofollow noopener">docs</a></p>` + "\n"},
+
+		// Anything that could execute must come out inert.
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"event handler", `<img src=x onerror="alert(1)">`, "<p>&lt;img src=x onerror=&#34;alert(1)&#34;&gt;</p>\n"},
+		{"javascript link", "[click](javascript:void(0))", "<p>click)</p>\n"},
+		{"quote in url", `[x](https://a.com/"onmouseover="alert(1))`,
+			`<p><a href="https://a.com/&#34;onmouseover=&#34;alert(1" rel="nofollow noopener">x</a>)</p>` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := markdown.Render(tt.src); got != tt.want {
+				t.Errorf("Render(%q) =\n%q\nwant\n%q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
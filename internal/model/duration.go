@@ -0,0 +1,34 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Duration is a time.Duration that is written to JSON as a string like
+// "24h0m0s" and stored in the database as whole seconds.
+type Duration time.Duration
+
+func (d Duration) String() string { return time.Duration(d).String() }
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// Value implements driver.Valuer.
+func (d Duration) Value() (driver.Value, error) {
+	return int64(time.Duration(d) / time.Second), nil
+}
+
+// Scan implements sql.Scanner.
+func (d *Duration) Scan(src any) error {
+	seconds, ok := src.(int64)
+	if !ok {
+		return fmt.Errorf("cannot scan %T into Duration", src)
+	}
+	*d = Duration(time.Duration(seconds) * time.Second)
+	return nil
+}
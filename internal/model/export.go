@@ -0,0 +1,47 @@
+package model
+
+// ExportVersion is the current version of the data export bundle served by
+// the export endpoints and accepted by the project import. Bump it whenever
+// a change would stop older servers from importing a bundle correctly.
+const ExportVersion = 1
+
+// ProjectImport is the part of an export bundle read when importing
+// projects:
+//
+//	{"version": 1, "projects": [...], "todos": [...], "members": [...]}
+//
+// IDs in the bundle only link todos and members to their projects; the
+// imported rows get new IDs. Members are not part of the export, which holds
+// only the exporting user's data, and may be added by hand to re-invite
+// collaborators who already have an account on this instance.
+type ProjectImport struct {
+	Version  int            `json:"version"`
+	Projects []Project      `json:"projects"`
+	Todos    []Todo         `json:"todos"`
+	Members  []ImportMember `json:"members"`
+}
+
+// ImportMember is a collaborator to add to an imported project, matched to
+// a local user by email.
+type ImportMember struct {
+	ProjectID int64  `json:"project_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+}
+
+// ImportResult reports what a project import created and what it skipped.
+type ImportResult struct {
+	Projects []Project    `json:"projects"`
+	Todos    int          `json:"todos_created"`
+	Members  int          `json:"members_added"`
+	Skipped  []ImportSkip `json:"skipped"`
+}
+
+// ImportSkip is one entry of an import bundle that was left out. Kind is
+// "todo" or "member" and Ref identifies it within the bundle: the todo's
+// original ID or the member's email.
+type ImportSkip struct {
+	Kind   string `json:"kind"`
+	Ref    string `json:"ref"`
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import (
+	"regexp"
+	"time"
+)
+
+// FeatureFlag switches an experimental behavior on or off at runtime. Flags
+// that have never been set do not exist and count as off.
+type FeatureFlag struct {
+	Key       string    `json:"key"`
+	Enabled   bool      `json:"enabled"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+var flagKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_.-]{0,63}$`)
+
+// ValidFlagKey checks whether key is a lowercase flag name such as
+// "board.swimlanes" of at most 64 characters.
+func ValidFlagKey(key string) bool {
+	return flagKeyPattern.MatchString(key)
+}
@@ -1,16 +1,116 @@
 package model
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+)
 
-// Project represents a collection of todos owned by a user.
+// Project represents a collection of todos owned by a user. A project with
+// IsTemplate set is a blueprint whose todos are copied into new projects.
+// Statuses is the project's custom workflow, or nil to use DefaultStatuses.
+// HideCompleted leaves completed todos out of the project's todo list unless
+// a request asks for them. DefaultMemberRole is the role given to members
+// added without one; the store sets it to RoleViewer if it is empty. EndDate
+// is the planned completion, after which no todo may be due; nil means none.
 type Project struct {
-	ID          int64     `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	OwnerID     int64     `json:"owner_id"`
-	OwnerName   string    `json:"owner_name,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                int64      `json:"id"`
+	Name              string     `json:"name"`
+	Description       string     `json:"description"`
+	Color             string     `json:"color"`
+	IsTemplate        bool       `json:"is_template"`
+	Statuses          StatusList `json:"statuses"`
+	HideCompleted     bool       `json:"hide_completed"`
+	DefaultMemberRole string     `json:"default_member_role"`
+	EndDate           *time.Time `json:"end_date"`
+	Favorited         bool       `json:"favorited"` // by the requesting user; set only in lists
+	OwnerID           int64      `json:"owner_id"`
+	OwnerName         string     `json:"owner_name,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// AllowsDeadline reports whether a todo in the project may be due at
+// deadline, which must not be after the project's end date.
+func (p *Project) AllowsDeadline(deadline time.Time) bool {
+	return p.EndDate == nil || !deadline.After(*p.EndDate)
+}
+
+// AllowedStatuses returns the statuses the project's todos may have.
+func (p *Project) AllowedStatuses() []string {
+	if len(p.Statuses) == 0 {
+		return DefaultStatuses
+	}
+	return p.Statuses
+}
+
+// AllowsStatus checks whether status is one of the project's statuses.
+func (p *Project) AllowsStatus(status string) bool {
+	return slices.Contains(p.AllowedStatuses(), status)
+}
+
+// MaxProjectStatuses caps the length of a project's custom workflow.
+const MaxProjectStatuses = 20
+
+var statusPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,29}$`)
+
+// ValidStatusName checks whether s can be used as a custom status: lowercase
+// letters, digits and underscores, starting with a letter, up to 30
+// characters.
+func ValidStatusName(s string) bool {
+	return statusPattern.MatchString(s)
+}
+
+// ValidStatusList checks whether statuses can be a project's workflow: at
+// most MaxProjectStatuses distinct, well-formed names including
+// StatusPending, which new todos start in, and StatusCompleted, which marks a
+// todo as done. An empty list is valid and means DefaultStatuses.
+func ValidStatusList(statuses []string) bool {
+	if len(statuses) == 0 {
+		return true
+	}
+	if len(statuses) > MaxProjectStatuses ||
+		!slices.Contains(statuses, StatusPending) || !slices.Contains(statuses, StatusCompleted) {
+		return false
+	}
+	seen := make(map[string]bool, len(statuses))
+	for _, s := range statuses {
+		if !ValidStatusName(s) || seen[s] {
+			return false
+		}
+		seen[s] = true
+	}
+	return true
+}
+
+// StatusList is a list of statuses stored in the database as a JSON array.
+// A nil list is stored as NULL.
+type StatusList []string
+
+// Value implements driver.Valuer.
+func (l StatusList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal([]string(l))
+	return string(b), err
+}
+
+// Scan implements sql.Scanner.
+func (l *StatusList) Scan(src any) error {
+	switch v := src.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	}
+	return fmt.Errorf("cannot scan %T into StatusList", src)
 }
 
 // ProjectMember represents a user's membership in a project.
@@ -20,3 +120,45 @@ type ProjectMember struct {
 	Username  string `json:"username,omitempty"`
 	Role      string `json:"role"` // "viewer" or "editor"
 }
+
+// Project roles. The owner role is implied by Project.OwnerID and is never
+// stored in project_members.
+const (
+	RoleOwner  = "owner"
+	RoleEditor = "editor"
+	RoleViewer = "viewer"
+)
+
+// AssignableRoles lists the roles that may be granted through the members
+// endpoints. Ownership changes only happen through a project transfer.
+var AssignableRoles = []string{RoleViewer, RoleEditor}
+
+// ValidAssignableRole checks whether a role may be granted to a member.
+func ValidAssignableRole(role string) bool {
+	for _, r := range AssignableRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+var colorPattern = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+
+// ValidColor checks whether a color is a hex code of the form #RRGGBB.
+// The empty string is valid and means "no color".
+func ValidColor(c string) bool {
+	return c == "" || colorPattern.MatchString(c)
+}
+
+// ProjectInvite is a pending invitation for someone without an account to
+// join a project. It becomes a membership when a user registers with the
+// invited email address.
+type ProjectInvite struct {
+	ID        int64     `json:"id"`
+	ProjectID int64     `json:"project_id"`
+	Email     string    `json:"email"`
+	Role      string    `json:"role"`
+	Token     string    `json:"token"`
+	CreatedAt time.Time `json:"created_at"`
+}
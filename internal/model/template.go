@@ -0,0 +1,33 @@
+package model
+
+// TemplateVersion is the current version of the ProjectTemplate format.
+// Bump it whenever a change would stop older servers from importing a
+// template correctly.
+const TemplateVersion = 1
+
+// ProjectTemplate is a portable copy of a project's structure that is safe
+// to share outside the instance. It carries only what is needed to recreate
+// the project's outline: no descriptions, deadlines, assignees, history or
+// user ids. Todos are listed in the order they were created.
+//
+// Version 1:
+//
+//	{
+//	  "version": 1,
+//	  "name": "Launch checklist",
+//	  "color": "#6366f1",
+//	  "todos": [{"title": "Write docs", "priority": "high", "priority_rank": 1}]
+//	}
+type ProjectTemplate struct {
+	Version int            `json:"version"`
+	Name    string         `json:"name"`
+	Color   string         `json:"color"`
+	Todos   []TemplateTodo `json:"todos"`
+}
+
+// TemplateTodo is one todo in a ProjectTemplate.
+type TemplateTodo struct {
+	Title        string `json:"title"`
+	Priority     string `json:"priority"`
+	PriorityRank *int   `json:"priority_rank"`
+}
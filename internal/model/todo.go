@@ -3,16 +3,70 @@ package model
 import "time"
 
 // Todo represents a single task within a project.
+//
+// PriorityRank is an optional finer-grained ordering within a project; lower
+// ranks sort first. ReminderOffset is how long before Deadline the reminder
+// is sent; nil uses the server's REMINDER_LEAD_TIME. Estimate is the effort
+// in points, nil when nobody has estimated it. CompletedAt is when the todo
+// last became completed, nil unless its status is completed. CreatedBy is
+// nil for todos that predate creator tracking or whose creator has been
+// deleted. Assignees is always non-nil and sorted by username.
+//
+// Optional pointer fields are always present in JSON, as null when unset,
+// so clients can tell "no deadline" apart from a field they didn't receive.
+// New nullable fields should follow suit and not use omitempty.
 type Todo struct {
-	ID          int64      `json:"id"`
-	ProjectID   int64      `json:"project_id"`
-	Title       string     `json:"title"`
-	Description string     `json:"description"`
-	Status      string     `json:"status"`
-	Priority    string     `json:"priority"`
-	Deadline    *time.Time `json:"deadline,omitempty"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
+	ID             int64          `json:"id"`
+	ProjectID      int64          `json:"project_id"`
+	Title          string         `json:"title"`
+	Description    string         `json:"description"`
+	Status         string         `json:"status"`
+	Priority       string         `json:"priority"`
+	PriorityRank   *int           `json:"priority_rank"`
+	Deadline       *time.Time     `json:"deadline"`
+	ReminderOffset *Duration      `json:"reminder_offset"`
+	Estimate       *int           `json:"estimate"`
+	CompletedAt    *time.Time     `json:"completed_at"`
+	CreatedBy      *int64         `json:"created_by"`
+	CreatedByName  string         `json:"created_by_name,omitempty"`
+	Assignees      []TodoAssignee `json:"assignees"`
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+}
+
+// TodoAssignee is a user a todo is assigned to.
+type TodoAssignee struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
+
+// TodoDependency records that TodoID cannot be finished before DependsOnID.
+// Both todos belong to the same project.
+type TodoDependency struct {
+	TodoID      int64 `json:"todo_id"`
+	DependsOnID int64 `json:"depends_on_id"`
+}
+
+// TodoChange records one field of a todo changing in an update. OldValue and
+// NewValue are nil when the field was unset (e.g. no deadline). UserID is nil
+// if the user who made the change has since been deleted.
+type TodoChange struct {
+	ID        int64     `json:"id"`
+	TodoID    int64     `json:"todo_id"`
+	UserID    *int64    `json:"user_id"`
+	Username  string    `json:"username,omitempty"`
+	Field     string    `json:"field"`
+	OldValue  *string   `json:"old_value"`
+	NewValue  *string   `json:"new_value"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Activity is a TodoChange in a user's activity feed, with the todo's
+// project and title so the feed can be shown without looking them up.
+type Activity struct {
+	TodoChange
+	ProjectID int64  `json:"project_id"`
+	TodoTitle string `json:"todo_title"`
 }
 
 // Valid status values for a Todo.
@@ -29,7 +83,20 @@ const (
 	PriorityHigh   = "high"
 )
 
-// ValidStatus checks whether a status string is valid.
+// Bounds for Todo.PriorityRank.
+const (
+	MinPriorityRank = 0
+	MaxPriorityRank = 10000
+)
+
+// MaxEstimate bounds Todo.Estimate, which must not be negative.
+const MaxEstimate = 1000000
+
+// DefaultStatuses are the statuses available in projects that don't define
+// their own workflow.
+var DefaultStatuses = []string{StatusPending, StatusInProgress, StatusCompleted}
+
+// ValidStatus checks whether a status is one of DefaultStatuses.
 func ValidStatus(s string) bool {
 	switch s {
 	case StatusPending, StatusInProgress, StatusCompleted:
@@ -46,3 +113,13 @@ func ValidPriority(p string) bool {
 	}
 	return false
 }
+
+// ValidPriorityRank checks whether a priority rank is within bounds.
+func ValidPriorityRank(rank int) bool {
+	return rank >= MinPriorityRank && rank <= MaxPriorityRank
+}
+
+// ValidEstimate checks whether an effort estimate is within bounds.
+func ValidEstimate(estimate int) bool {
+	return estimate >= 0 && estimate <= MaxEstimate
+}
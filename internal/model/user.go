@@ -1,14 +1,94 @@
 package model
 
-import "time"
+import (
+	"encoding/json"
+	"regexp"
+	"time"
+)
 
-// User represents an application user.
+// User represents an application user. Timezone is an IANA zone name and
+// Locale a language tag such as en-US; both are empty until the user sets
+// them. LastLoginAt is when the user last signed in with their password, or
+// nil if they never have.
 type User struct {
-	ID        int64     `json:"id"`
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
-	IsAdmin   bool      `json:"is_admin"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          int64      `json:"id"`
+	Username    string     `json:"username"`
+	Email       string     `json:"email"`
+	Password    string     `json:"-"`
+	IsAdmin     bool       `json:"is_admin"`
+	Timezone    string     `json:"timezone"`
+	Locale      string     `json:"locale"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// Location returns the user's time zone, or UTC if none is set.
+func (u User) Location() *time.Location {
+	if u.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(u.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// ValidTimezone checks whether tz is a zone in the IANA time zone database.
+// The empty string is valid and means UTC.
+func ValidTimezone(tz string) bool {
+	if tz == "" {
+		return true
+	}
+	// LoadLocation also accepts "Local", which depends on the server.
+	_, err := time.LoadLocation(tz)
+	return err == nil && tz != "Local"
+}
+
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// ValidLocale checks whether a locale looks like a BCP 47 language tag such
+// as en or pt-BR. The empty string is valid and means "unset".
+func ValidLocale(l string) bool {
+	return l == "" || (len(l) <= 35 && localePattern.MatchString(l))
+}
+
+// PublicUser is the subset of a user that may be shown to any authenticated
+// user, e.g. in search results when sharing a project. It deliberately omits
+// the email address and account metadata.
+type PublicUser struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+}
+
+// Public returns the user's public profile.
+func (u User) Public() PublicUser {
+	return PublicUser{ID: u.ID, Username: u.Username}
+}
+
+// Admin audit actions. The comment on each says what its TargetID refers
+// to; actions without one have a zero TargetID.
+const (
+	AuditUserUpdate    = "user.update"    // user
+	AuditUserDelete    = "user.delete"    // user
+	AuditUserProjects  = "user.projects"  // user; an admin listed their projects
+	AuditProjectDelete = "project.delete" // project; bulk deletes record one per project
+	AuditMaintenance   = "maintenance.set"
+	AuditTodoRepair    = "todos.repair"
+	AuditFlagSet       = "flag.set"
+)
+
+// AdminAuditEntry records one change an admin made, or one lookup of another
+// user's data. TargetID is the affected user or project, depending on
+// Action, and Changes a JSON snapshot of what changed or was looked up.
+// ActorID is nil if the admin has since been deleted.
+type AdminAuditEntry struct {
+	ID        int64           `json:"id"`
+	ActorID   *int64          `json:"actor_id"`
+	ActorName string          `json:"actor_name,omitempty"`
+	Action    string          `json:"action"`
+	TargetID  int64           `json:"target_id"`
+	Changes   json.RawMessage `json:"changes"`
+	CreatedAt time.Time       `json:"created_at"`
 }
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Webhook events fired for changes to a project's todos.
+const (
+	EventTodoCreated   = "todo.created"
+	EventTodoUpdated   = "todo.updated"
+	EventTodoCompleted = "todo.completed"
+	EventTodoDeleted   = "todo.deleted"
+)
+
+// WebhookEvents lists the events a webhook can subscribe to.
+var WebhookEvents = []string{EventTodoCreated, EventTodoUpdated, EventTodoCompleted, EventTodoDeleted}
+
+// Webhook is a URL that receives a signed POST whenever one of Events
+// happens in its project. Secret is the HMAC-SHA256 key for the
+// X-Bloom-Signature header; it is only shown when the webhook is created.
+type Webhook struct {
+	ID        int64     `json:"id"`
+	ProjectID int64     `json:"project_id"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"`
+	Events    []string  `json:"events"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Subscribes reports whether the webhook wants event.
+func (w *Webhook) Subscribes(event string) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// ValidWebhookURL checks whether s is an absolute http or https URL. Unless
+// allowPrivate is set, its host must not be localhost or an address
+// WebhookAddressAllowed rejects. Names that resolve to such addresses pass
+// here; deliveries check the address actually dialed.
+func ValidWebhookURL(s string, allowPrivate bool) bool {
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return false
+	}
+	if allowPrivate {
+		return true
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		return WebhookAddressAllowed(ip)
+	}
+	return true
+}
+
+// WebhookAddressAllowed reports whether webhooks may be delivered to ip.
+// Loopback, link-local (including cloud metadata endpoints), private,
+// unspecified and multicast addresses are refused so a webhook cannot be
+// used to reach the server's own network.
+func WebhookAddressAllowed(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() &&
+		!ip.IsPrivate() && !ip.IsUnspecified() && !ip.IsMulticast()
+}
+
+// ValidWebhookEvents checks whether events is a non-empty list of distinct
+// WebhookEvents.
+func ValidWebhookEvents(events []string) bool {
+	if len(events) == 0 {
+		return false
+	}
+	for i, e := range events {
+		if !slices.Contains(WebhookEvents, e) || slices.Contains(events[:i], e) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestHasherRoundTrip(t *testing.T) {
+	for _, pepper := range []string{"", "server-secret"} {
+		h := NewHasher(pepper)
+		hash, err := h.Hash("correct horse")
+		if err != nil {
+			t.Fatalf("pepper %q: hash: %v", pepper, err)
+		}
+		if !h.Verify(hash, "correct horse") {
+			t.Errorf("pepper %q: the right password does not verify", pepper)
+		}
+		if h.Verify(hash, "wrong horse") {
+			t.Errorf("pepper %q: a wrong password verifies", pepper)
+		}
+	}
+}
+
+func TestHasherPepper(t *testing.T) {
+	peppered := NewHasher("server-secret")
+	hash, err := peppered.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if NewHasher("").Verify(hash, "correct horse") || NewHasher("other-secret").Verify(hash, "correct horse") {
+		t.Error("a peppered hash verifies with another pepper")
+	}
+
+	// Without a pepper, hashes are plain bcrypt, so those stored before
+	// peppers existed keep working.
+	plain, _ := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if !(Hasher{}).Verify(string(plain), "correct horse") {
+		t.Error("a plain bcrypt hash does not verify without a pepper")
+	}
+
+	// The pepper is never cut off, however long the password.
+	long := strings.Repeat("x", 100)
+	hash, err = peppered.Hash(long)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if NewHasher("").Verify(hash, long) || !peppered.Verify(hash, long) {
+		t.Error("a long password's hash does not depend on the pepper")
+	}
+}
@@ -0,0 +1,90 @@
+// Package reminder periodically dispatches reminders for todos whose
+// deadlines are approaching.
+package reminder
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+// Notifier delivers a reminder for one todo. It is called once per todo and
+// deadline, unless it returns an error.
+type Notifier func(ctx context.Context, todo model.Todo) error
+
+// Scheduler scans every interval for todos that have reached their reminder
+// time and passes each one to the notifier. Todos without their own
+// reminder offset are reminded lead before their deadline.
+type Scheduler struct {
+	store    store.Store
+	notify   Notifier
+	interval time.Duration
+	lead     time.Duration
+}
+
+// New creates a Scheduler. interval must be positive.
+func New(s store.Store, notify Notifier, interval, lead time.Duration) *Scheduler {
+	return &Scheduler{store: s, notify: notify, interval: interval, lead: lead}
+}
+
+// Run scans immediately and then every interval until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		s.Scan(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Scan claims the todos currently due for a reminder and notifies for each.
+// A todo whose notification fails is released again, so the next scan
+// retries it.
+func (s *Scheduler) Scan(ctx context.Context) {
+	todos, err := s.store.ClaimTodoReminders(ctx, time.Now(), s.lead)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("reminders: %v", err)
+		}
+		return
+	}
+	for _, todo := range todos {
+		if err := s.notify(ctx, todo); err != nil {
+			log.Printf("reminders: notify for todo %d: %v", todo.ID, err)
+			// The scan may have been stopped mid-way; release the claim anyway.
+			if err := s.store.ReleaseTodoReminder(context.WithoutCancel(ctx), todo.ID); err != nil {
+				log.Printf("reminders: release todo %d: %v", todo.ID, err)
+			}
+		}
+	}
+}
+
+// LogNotifier writes reminders to the server log, addressed to the todo's
+// assignees or, if it has none, its creator.
+func LogNotifier(_ context.Context, todo model.Todo) error {
+	log.Printf("reminder: todo %d %q is due %s (for %s)",
+		todo.ID, todo.Title, todo.Deadline.UTC().Format(time.RFC3339), recipients(todo))
+	return nil
+}
+
+func recipients(todo model.Todo) string {
+	if len(todo.Assignees) == 0 {
+		if todo.CreatedByName == "" {
+			return "nobody"
+		}
+		return todo.CreatedByName
+	}
+	names := make([]string, len(todo.Assignees))
+	for i, a := range todo.Assignees {
+		names[i] = a.Username
+	}
+	return strings.Join(names, ", ")
+}
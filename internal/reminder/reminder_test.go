@@ -0,0 +1,98 @@
+package reminder_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/reminder"
+	"github.com/walidabualafia/bloom/internal/store/sqlite"
+)
+
+// setupDueTodo returns a store holding one todo due in a minute.
+func setupDueTodo(t *testing.T) (*sqlite.Store, *model.Todo) {
+	t.Helper()
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	deadline := time.Now().Add(time.Minute)
+	todo := &model.Todo{ProjectID: project.ID, Title: "T", Status: model.StatusPending, Priority: model.PriorityMedium, Deadline: &deadline}
+	s.CreateTodo(ctx, todo)
+	return s, todo
+}
+
+func TestRunNotifiesOnceAndStops(t *testing.T) {
+	s, todo := setupDueTodo(t)
+	ctx := context.Background()
+
+	notified := make(chan int64, 10)
+	notify := func(_ context.Context, todo model.Todo) error {
+		notified <- todo.ID
+		return nil
+	}
+	scheduler := reminder.New(s, notify, 10*time.Millisecond, time.Hour)
+
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		scheduler.Run(runCtx)
+		close(done)
+	}()
+
+	select {
+	case id := <-notified:
+		if id != todo.ID {
+			t.Errorf("notified todo %d, want %d", id, todo.ID)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("no reminder sent")
+	}
+	time.Sleep(50 * time.Millisecond) // several more scans
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after cancel")
+	}
+	if len(notified) != 0 {
+		t.Errorf("got %d duplicate reminders", len(notified))
+	}
+}
+
+func TestScanRetriesFailedNotify(t *testing.T) {
+	s, todo := setupDueTodo(t)
+	ctx := context.Background()
+
+	var calls int
+	notify := func(context.Context, model.Todo) error {
+		calls++
+		if calls == 1 {
+			return errors.New("mail server down")
+		}
+		return nil
+	}
+	scheduler := reminder.New(s, notify, time.Minute, time.Hour)
+
+	for range 3 {
+		scheduler.Scan(ctx)
+	}
+	if calls != 2 {
+		t.Errorf("notified %d times, want 2: one failure, one retry and then no more", calls)
+	}
+	if claimed, err := s.ClaimTodoReminders(ctx, time.Now(), time.Hour); err != nil || len(claimed) != 0 {
+		t.Errorf("todo %d claimable again after a successful reminder: %v, %v", todo.ID, claimed, err)
+	}
+}
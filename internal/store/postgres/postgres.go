@@ -3,12 +3,18 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"time"
 
 	"github.com/walidabualafia/bloom/internal/model"
 	"github.com/walidabualafia/bloom/internal/store"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 const migrationSQL = `
@@ -18,6 +24,9 @@ CREATE TABLE IF NOT EXISTS users (
 	email VARCHAR(255) UNIQUE NOT NULL,
 	password VARCHAR(255) NOT NULL,
 	is_admin BOOLEAN DEFAULT FALSE,
+	timezone VARCHAR(64) DEFAULT '',
+	locale VARCHAR(35) DEFAULT '',
+	last_login_at TIMESTAMP WITH TIME ZONE,
 	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
 	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
 );
@@ -26,6 +35,12 @@ CREATE TABLE IF NOT EXISTS projects (
 	id BIGSERIAL PRIMARY KEY,
 	name VARCHAR(255) NOT NULL,
 	description TEXT DEFAULT '',
+	color VARCHAR(7) DEFAULT '',
+	is_template BOOLEAN DEFAULT FALSE,
+	statuses JSONB,
+	hide_completed BOOLEAN DEFAULT FALSE,
+	default_member_role VARCHAR(20) DEFAULT 'viewer',
+	end_date TIMESTAMP WITH TIME ZONE,
 	owner_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
 	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
@@ -38,27 +53,167 @@ CREATE TABLE IF NOT EXISTS todos (
 	description TEXT DEFAULT '',
 	status VARCHAR(50) DEFAULT 'pending',
 	priority VARCHAR(50) DEFAULT 'medium',
+	priority_rank INTEGER,
 	deadline TIMESTAMP WITH TIME ZONE,
+	created_by BIGINT REFERENCES users(id) ON DELETE SET NULL,
+	reminded_at TIMESTAMP WITH TIME ZONE,
+	reminder_offset BIGINT,
+	estimate INTEGER,
+	completed_at TIMESTAMP WITH TIME ZONE,
 	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
 	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
 );
 
+CREATE INDEX IF NOT EXISTS idx_todos_project ON todos(project_id);
+
+CREATE TABLE IF NOT EXISTS todo_assignees (
+	todo_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	PRIMARY KEY (todo_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS todo_dependencies (
+	todo_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	depends_on_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	PRIMARY KEY (todo_id, depends_on_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_dependencies_depends_on ON todo_dependencies(depends_on_id);
+
+CREATE TABLE IF NOT EXISTS todo_history (
+	id BIGSERIAL PRIMARY KEY,
+	todo_id BIGINT NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	user_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+	field VARCHAR(50) NOT NULL,
+	old_value TEXT,
+	new_value TEXT,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_history_todo ON todo_history(todo_id);
+
+CREATE TABLE IF NOT EXISTS admin_audit (
+	id BIGSERIAL PRIMARY KEY,
+	actor_id BIGINT REFERENCES users(id) ON DELETE SET NULL,
+	action VARCHAR(50) NOT NULL,
+	target_id BIGINT NOT NULL,
+	changes JSONB NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
 CREATE TABLE IF NOT EXISTS project_members (
 	project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
 	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 	role VARCHAR(50) DEFAULT 'viewer',
 	PRIMARY KEY (project_id, user_id)
 );
+
+CREATE TABLE IF NOT EXISTS project_favorites (
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	PRIMARY KEY (user_id, project_id)
+);
+
+CREATE TABLE IF NOT EXISTS project_order (
+	user_id BIGINT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (user_id, project_id)
+);
+
+CREATE TABLE IF NOT EXISTS project_invites (
+	id BIGSERIAL PRIMARY KEY,
+	project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	email VARCHAR(255) NOT NULL,
+	role VARCHAR(50) DEFAULT 'viewer',
+	token VARCHAR(64) UNIQUE NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+	UNIQUE (project_id, email)
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	id BIGSERIAL PRIMARY KEY,
+	project_id BIGINT NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	secret VARCHAR(128) NOT NULL,
+	events TEXT[] NOT NULL,
+	created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_project ON webhooks(project_id);
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+	key VARCHAR(64) PRIMARY KEY,
+	enabled BOOLEAN NOT NULL DEFAULT FALSE,
+	updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS locale VARCHAR(35) DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_login_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS color VARCHAR(7) DEFAULT '';
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS is_template BOOLEAN DEFAULT FALSE;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS statuses JSONB;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS hide_completed BOOLEAN DEFAULT FALSE;
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS default_member_role VARCHAR(20) DEFAULT 'viewer';
+ALTER TABLE projects ADD COLUMN IF NOT EXISTS end_date TIMESTAMP WITH TIME ZONE;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS priority_rank INTEGER;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS created_by BIGINT REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS assignee_id BIGINT REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS reminded_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS reminder_offset BIGINT;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS estimate INTEGER;
+ALTER TABLE todos ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP WITH TIME ZONE;
+
+-- Move assignments from the legacy single-assignee column into todo_assignees.
+INSERT INTO todo_assignees (todo_id, user_id)
+	SELECT id, assignee_id FROM todos WHERE assignee_id IS NOT NULL
+	ON CONFLICT DO NOTHING;
+UPDATE todos SET assignee_id = NULL WHERE assignee_id IS NOT NULL;
+
+-- Date todos completed before completed_at was recorded by their last
+-- update, the best estimate there is.
+UPDATE todos SET completed_at = updated_at WHERE status = 'completed' AND completed_at IS NULL;
 `
 
+// projectColumns lists the project columns in the order scanProject expects.
+// Queries alias projects as p and join the owner as u.
+const projectColumns = `p.id, p.name, p.description, p.color, p.is_template, p.statuses, p.hide_completed,
+	p.default_member_role, p.end_date, p.owner_id,
+	u.username,
+	p.created_at, p.updated_at`
+
+// todoColumns lists the todo columns in the order scanTodo expects. Queries
+// select them FROM todoFrom, which resolves the creator's username.
+// Assignees live in todo_assignees and are loaded separately.
+const (
+	todoColumns = `t.id, t.project_id, t.title, t.description, t.status, t.priority, t.priority_rank, t.deadline,
+		t.reminder_offset, t.estimate, t.completed_at, t.created_by, cu.username, t.created_at, t.updated_at`
+	todoFrom = `todos t
+		LEFT JOIN users cu ON t.created_by = cu.id`
+)
+
 // scannable abstracts *sql.Row and *sql.Rows for reuse in scan helpers.
 type scannable interface {
 	Scan(dest ...any) error
 }
 
+// queryRower abstracts *sql.DB and *sql.Tx for helpers used in and out of
+// transactions.
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
 // Store implements store.Store backed by PostgreSQL.
+//
+// Writes always go to db. The List*, Search*, Get* and GetStats methods read
+// from read, which is a replica connection when one is configured and db
+// otherwise. Reads a write path depends on (e.g. usernameOf after an insert)
+// stay on the primary so they see their own writes.
 type Store struct {
-	db *sql.DB
+	db   *sql.DB
+	read *sql.DB
 }
 
 // Compile-time check that Store implements store.Store.
@@ -66,14 +221,105 @@ var _ store.Store = (*Store)(nil)
 
 // New opens a PostgreSQL connection with the given DSN and returns a Store.
 func New(dsn string) (*Store, error) {
-	db, err := sql.Open("postgres", dsn)
+	return NewWithReplica(dsn, "")
+}
+
+// NewWithReplica opens a primary connection and, if readDSN is non-empty, a
+// second connection to a read replica. See Store for how methods are routed.
+func NewWithReplica(dsn, readDSN string) (*Store, error) {
+	db, err := open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if readDSN == "" {
+		return newStore(db, nil), nil
+	}
+	read, err := open(readDSN)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("read replica: %w", err)
+	}
+	return newStore(db, read), nil
+}
+
+func open(dsn string) (*sql.DB, error) {
+	connector, err := pq.NewConnector(dsn)
 	if err != nil {
 		return nil, fmt.Errorf("open postgres: %w", err)
 	}
+	db := sql.OpenDB(retryConnector{Connector: connector, backoff: connectBackoff})
 	if err := db.Ping(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("ping postgres: %w", err)
 	}
-	return &Store{db: db}, nil
+	return db, nil
+}
+
+// Opening a connection is tried connectAttempts times, waiting
+// connectBackoff before the second attempt and doubling the wait after, so
+// a database that is briefly unreachable costs a request some latency
+// rather than an error.
+const (
+	connectAttempts = 3
+	connectBackoff  = 100 * time.Millisecond
+)
+
+// retryConnector retries opening a connection after a transient error and
+// reports a database it still cannot reach as store.ErrUnavailable. Only
+// connecting is retried: nothing has been sent yet, so it is safe for
+// writes. A connection lost mid-query fails that query; database/sql then
+// discards the connection and the next query connects afresh.
+type retryConnector struct {
+	driver.Connector
+	backoff time.Duration
+}
+
+func (c retryConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	backoff := c.backoff
+	for attempt := 1; ; attempt++ {
+		conn, err := c.Connector.Connect(ctx)
+		if err == nil || !transient(err) {
+			return conn, err
+		}
+		if attempt == connectAttempts {
+			return nil, fmt.Errorf("%w: %w", store.ErrUnavailable, err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w: %w", store.ErrUnavailable, err)
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// transient reports whether err means the server could not be reached or
+// is not accepting connections, rather than that it rejected the request.
+func transient(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		// Class 08 is connection exceptions; 57P01-57P03 are the server
+		// shutting down, crashing or still starting up.
+		switch pqErr.Code {
+		case "57P01", "57P02", "57P03":
+			return true
+		}
+		return pqErr.Code.Class() == "08"
+	}
+	return false
+}
+
+// newStore wraps already-open connections. A nil read uses the primary.
+func newStore(db, read *sql.DB) *Store {
+	if read == nil {
+		read = db
+	}
+	return &Store{db: db, read: read}
 }
 
 func (s *Store) Migrate(_ context.Context) error {
@@ -81,26 +327,71 @@ func (s *Store) Migrate(_ context.Context) error {
 	return err
 }
 
+func (s *Store) ResetData(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx,
+		`TRUNCATE users, projects, todos, todo_assignees, todo_dependencies, todo_history, admin_audit,
+		 project_members, project_favorites, project_order, project_invites, webhooks, feature_flags RESTART IDENTITY`)
+	if err != nil {
+		return fmt.Errorf("reset data: %w", err)
+	}
+	return nil
+}
+
 func (s *Store) Close() error {
+	if s.read != s.db {
+		if err := s.read.Close(); err != nil {
+			s.db.Close()
+			return err
+		}
+	}
 	return s.db.Close()
 }
 
+// uniqueViolation is the SQLSTATE of a unique constraint failure.
+const uniqueViolation = "23505"
+
+// notFound translates sql.ErrNoRows into store.ErrNotFound so callers
+// never depend on database/sql.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+// wrapConflict marks unique constraint failures as store.ErrConflict and
+// returns other errors unchanged.
+func wrapConflict(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %w", store.ErrConflict, err)
+	}
+	return err
+}
+
 // ── Scan helpers ─────────────────────────────────────────────────────────────
 
 func scanUser(row scannable) (*model.User, error) {
 	var u model.User
-	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Password, &u.IsAdmin, &u.CreatedAt, &u.UpdatedAt)
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Password, &u.IsAdmin, &u.Timezone, &u.Locale, &u.LastLoginAt,
+		&u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
 	return &u, nil
 }
 
-func scanProject(row scannable) (*model.Project, error) {
+// scanProject scans projectColumns. When withFavorite is set, a trailing
+// favorited flag is scanned too.
+func scanProject(row scannable, withFavorite bool) (*model.Project, error) {
 	var p model.Project
 	var ownerName sql.NullString
-	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.OwnerID, &ownerName, &p.CreatedAt, &p.UpdatedAt)
-	if err != nil {
+	dest := []any{&p.ID, &p.Name, &p.Description, &p.Color, &p.IsTemplate, &p.Statuses, &p.HideCompleted,
+		&p.DefaultMemberRole, &p.EndDate, &p.OwnerID, &ownerName, &p.CreatedAt, &p.UpdatedAt}
+	if withFavorite {
+		dest = append(dest, &p.Favorited)
+	}
+	if err := row.Scan(dest...); err != nil {
 		return nil, err
 	}
 	p.OwnerName = ownerName.String
@@ -109,55 +400,102 @@ func scanProject(row scannable) (*model.Project, error) {
 
 func scanTodo(row scannable) (*model.Todo, error) {
 	var t model.Todo
-	err := row.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &t.Deadline, &t.CreatedAt, &t.UpdatedAt)
+	var createdByName sql.NullString
+	err := row.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &t.PriorityRank, &t.Deadline,
+		&t.ReminderOffset, &t.Estimate, &t.CompletedAt, &t.CreatedBy, &createdByName, &t.CreatedAt, &t.UpdatedAt)
 	if err != nil {
 		return nil, err
 	}
+	if t.Deadline != nil {
+		// lib/pq returns the session's zone; report UTC like SQLite does.
+		deadline := t.Deadline.UTC()
+		t.Deadline = &deadline
+	}
+	t.CreatedByName = createdByName.String
+	t.Assignees = []model.TodoAssignee{}
 	return &t, nil
 }
 
 // ── Users ────────────────────────────────────────────────────────────────────
 
 func (s *Store) CreateUser(ctx context.Context, user *model.User) error {
-	err := s.db.QueryRowContext(ctx,
-		`INSERT INTO users (username, email, password, is_admin)
-		 VALUES ($1, $2, $3, $4)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO users (username, email, password, is_admin, timezone, locale)
+		 VALUES ($1, $2, $3, $4, $5, $6)
 		 RETURNING id, created_at, updated_at`,
-		user.Username, user.Email, user.Password, user.IsAdmin,
+		user.Username, user.Email, user.Password, user.IsAdmin, user.Timezone, user.Locale,
 	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
 	if err != nil {
-		return fmt.Errorf("create user: %w", err)
+		return fmt.Errorf("create user: %w", wrapConflict(err))
+	}
+
+	// Turn pending invites for this email into memberships.
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO project_members (project_id, user_id, role)
+		 SELECT project_id, $1, role FROM project_invites WHERE lower(email) = lower($2)
+		 ON CONFLICT (project_id, user_id) DO UPDATE SET role = EXCLUDED.role`,
+		user.ID, user.Email,
+	)
+	if err != nil {
+		return fmt.Errorf("apply invites: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM project_invites WHERE lower(email) = lower($1)`, user.Email)
+	if err != nil {
+		return fmt.Errorf("clear invites: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
 	}
 	return nil
 }
 
 func (s *Store) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, username, email, password, is_admin, created_at, updated_at
+	row := s.read.QueryRowContext(ctx,
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
 		 FROM users WHERE id = $1`, id)
-	return scanUser(row)
+	v, err := scanUser(row)
+	return v, notFound(err)
 }
 
 func (s *Store) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, username, email, password, is_admin, created_at, updated_at
+	row := s.read.QueryRowContext(ctx,
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
 		 FROM users WHERE username = $1`, username)
-	return scanUser(row)
+	v, err := scanUser(row)
+	return v, notFound(err)
 }
 
-func (s *Store) SearchUsers(ctx context.Context, query string, excludeID int64) ([]model.User, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, username, email, password, is_admin, created_at, updated_at
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	row := s.read.QueryRowContext(ctx,
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
+		 FROM users WHERE lower(email) = lower($1)`, email)
+	v, err := scanUser(row)
+	return v, notFound(err)
+}
+
+func (s *Store) SearchUsers(ctx context.Context, params store.UserSearchParams) ([]model.User, error) {
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
 		 FROM users WHERE id != $1 AND (username ILIKE '%' || $2 || '%' OR email ILIKE '%' || $2 || '%')
-		 ORDER BY username LIMIT 10`,
-		excludeID, query,
+		 AND id NOT IN (SELECT owner_id FROM projects WHERE id = $3)
+		 AND id NOT IN (SELECT user_id FROM project_members WHERE project_id = $3)
+		 ORDER BY CASE WHEN username ILIKE $2 THEN 0 WHEN username ILIKE $2 || '%' THEN 1 ELSE 2 END, username
+		 LIMIT $4 OFFSET $5`,
+		params.ExcludeID, params.Query, params.ExcludeProjectID, params.Limit, params.Offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("search users: %w", err)
 	}
 	defer rows.Close()
 
-	var users []model.User
+	users := []model.User{}
 	for rows.Next() {
 		u, err := scanUser(rows)
 		if err != nil {
@@ -169,15 +507,15 @@ func (s *Store) SearchUsers(ctx context.Context, query string, excludeID int64)
 }
 
 func (s *Store) ListUsers(ctx context.Context) ([]model.User, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, username, email, password, is_admin, created_at, updated_at
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
 		 FROM users ORDER BY id`)
 	if err != nil {
 		return nil, fmt.Errorf("list users: %w", err)
 	}
 	defer rows.Close()
 
-	var users []model.User
+	users := []model.User{}
 	for rows.Next() {
 		u, err := scanUser(rows)
 		if err != nil {
@@ -188,31 +526,159 @@ func (s *Store) ListUsers(ctx context.Context) ([]model.User, error) {
 	return users, rows.Err()
 }
 
-func (s *Store) UpdateUser(ctx context.Context, user *model.User) error {
-	err := s.db.QueryRowContext(ctx,
-		`UPDATE users SET username = $1, email = $2, password = $3, is_admin = $4, updated_at = NOW()
-		 WHERE id = $5 RETURNING updated_at`,
-		user.Username, user.Email, user.Password, user.IsAdmin, user.ID,
-	).Scan(&user.UpdatedAt)
+func (s *Store) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count users: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Store) ListUsersFiltered(ctx context.Context, filter store.UserFilter) ([]model.User, int, error) {
+	where, args := userFilterWhere(filter)
+	return s.listUserPage(ctx, where, args, `id`, filter)
+}
+
+func (s *Store) ListInactiveUsers(ctx context.Context, before time.Time, filter store.UserFilter) ([]model.User, int, error) {
+	where, args := userFilterWhere(filter)
+	args = append(args, before)
+	where += fmt.Sprintf(` AND COALESCE(last_login_at, created_at) < $%d`, len(args))
+	return s.listUserPage(ctx, where, args, `COALESCE(last_login_at, created_at), id`, filter)
+}
+
+// userFilterWhere returns the WHERE clause, and its arguments, matching
+// filter's Query and IsAdmin. Further conditions number their placeholders
+// from len(args)+1.
+func userFilterWhere(filter store.UserFilter) (string, []any) {
+	where := `TRUE`
+	var args []any
+	if filter.Query != "" {
+		args = append(args, filter.Query)
+		where += fmt.Sprintf(` AND (username ILIKE '%%' || $%[1]d || '%%' OR email ILIKE '%%' || $%[1]d || '%%')`, len(args))
+	}
+	if filter.IsAdmin != nil {
+		args = append(args, *filter.IsAdmin)
+		where += fmt.Sprintf(` AND is_admin = $%d`, len(args))
+	}
+	return where, args
+}
+
+// listUserPage returns filter's page of the users matching where, sorted by
+// orderBy, and how many match in total.
+func (s *Store) listUserPage(ctx context.Context, where string, args []any, orderBy string, filter store.UserFilter) ([]model.User, int, error) {
+	var total int
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count users: %w", err)
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
+		 FROM users WHERE `+where+` ORDER BY `+orderBy+fmt.Sprintf(` LIMIT $%d OFFSET $%d`, len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *u)
+	}
+	return users, total, rows.Err()
+}
+
+func (s *Store) UpdateUser(ctx context.Context, user *model.User, audit *model.AdminAuditEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("update user: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
 	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var updatedAt time.Time
+	err = tx.QueryRowContext(ctx,
+		`UPDATE users SET username = $1, email = $2, password = $3, is_admin = $4, timezone = $5, locale = $6,
+		 updated_at = NOW() WHERE id = $7 RETURNING updated_at`,
+		user.Username, user.Email, user.Password, user.IsAdmin, user.Timezone, user.Locale, user.ID,
+	).Scan(&updatedAt)
+	if err != nil {
+		return fmt.Errorf("update user: %w", wrapConflict(err))
+	}
+	if err := insertAdminAudit(ctx, tx, audit); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	user.UpdatedAt = updatedAt
 	return nil
 }
 
-func (s *Store) DeleteUser(ctx context.Context, id int64) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id)
-	return err
+func (s *Store) RecordLogin(ctx context.Context, userID int64, at time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE users SET last_login_at = $1 WHERE id = $2`, at, userID); err != nil {
+		return fmt.Errorf("record login: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id int64, audit *model.AdminAuditEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, id); err != nil {
+		return err
+	}
+	if err := insertAdminAudit(ctx, tx, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) CreateAdminAudit(ctx context.Context, audit *model.AdminAuditEntry) error {
+	return insertAdminAudit(ctx, s.db, audit)
+}
+
+// insertAdminAudit records audit using either the store's db or a
+// transaction. A nil audit is skipped.
+func insertAdminAudit(ctx context.Context, db queryRower, audit *model.AdminAuditEntry) error {
+	if audit == nil {
+		return nil
+	}
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO admin_audit (actor_id, action, target_id, changes) VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		audit.ActorID, audit.Action, audit.TargetID, string(audit.Changes),
+	).Scan(&audit.ID, &audit.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create admin audit: %w", err)
+	}
+	return nil
 }
 
 // ── Projects ─────────────────────────────────────────────────────────────────
 
 func (s *Store) CreateProject(ctx context.Context, project *model.Project) error {
-	err := s.db.QueryRowContext(ctx,
-		`INSERT INTO projects (name, description, owner_id)
-		 VALUES ($1, $2, $3)
+	return insertProject(ctx, s.db, project)
+}
+
+// insertProject inserts project using either the store's db or a transaction.
+func insertProject(ctx context.Context, db queryRower, project *model.Project) error {
+	if project.DefaultMemberRole == "" {
+		project.DefaultMemberRole = model.RoleViewer
+	}
+	err := db.QueryRowContext(ctx,
+		`INSERT INTO projects (name, description, color, is_template, statuses, hide_completed, default_member_role,
+		 end_date, owner_id)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 		 RETURNING id, created_at, updated_at`,
-		project.Name, project.Description, project.OwnerID,
+		project.Name, project.Description, project.Color, project.IsTemplate, project.Statuses, project.HideCompleted,
+		project.DefaultMemberRole, project.EndDate, project.OwnerID,
 	).Scan(&project.ID, &project.CreatedAt, &project.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("create project: %w", err)
@@ -221,21 +687,46 @@ func (s *Store) CreateProject(ctx context.Context, project *model.Project) error
 }
 
 func (s *Store) GetProject(ctx context.Context, id int64) (*model.Project, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT p.id, p.name, p.description, p.owner_id, u.username, p.created_at, p.updated_at
+	row := s.read.QueryRowContext(ctx,
+		`SELECT `+projectColumns+`
 		 FROM projects p JOIN users u ON p.owner_id = u.id
 		 WHERE p.id = $1`, id)
-	return scanProject(row)
+	v, err := scanProject(row, false)
+	return v, notFound(err)
+}
+
+func (s *Store) GetProjectOwner(ctx context.Context, projectID int64) (*model.User, error) {
+	row := s.read.QueryRowContext(ctx,
+		`SELECT u.id, u.username, u.email, u.password, u.is_admin, u.timezone, u.locale, u.last_login_at, u.created_at, u.updated_at
+		 FROM projects p JOIN users u ON p.owner_id = u.id
+		 WHERE p.id = $1`, projectID)
+	v, err := scanUser(row)
+	return v, notFound(err)
 }
 
 func (s *Store) ListProjectsByUser(ctx context.Context, userID int64) ([]model.Project, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT DISTINCT p.id, p.name, p.description, p.owner_id, u.username, p.created_at, p.updated_at
+	return s.listProjectsForUser(ctx, userID, false)
+}
+
+func (s *Store) ListTemplatesByUser(ctx context.Context, userID int64) ([]model.Project, error) {
+	return s.listProjectsForUser(ctx, userID, true)
+}
+
+// listProjectsForUser returns projects the user owns or is a member of,
+// optionally restricted to templates.
+func (s *Store) listProjectsForUser(ctx context.Context, userID int64, templatesOnly bool) ([]model.Project, error) {
+	filter := ""
+	if templatesOnly {
+		filter = ` AND p.is_template`
+	}
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT `+projectColumns+`,
+		 EXISTS(SELECT 1 FROM project_favorites f WHERE f.project_id = p.id AND f.user_id = $1) AS favorited
 		 FROM projects p
 		 JOIN users u ON p.owner_id = u.id
-		 LEFT JOIN project_members pm ON p.id = pm.project_id
-		 WHERE p.owner_id = $1 OR pm.user_id = $1
-		 ORDER BY p.updated_at DESC`,
+		 LEFT JOIN project_order po ON po.project_id = p.id AND po.user_id = $1
+		 WHERE (p.owner_id = $1 OR EXISTS(SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = $1))`+filter+`
+		 ORDER BY favorited DESC, po.position IS NULL, po.position, p.updated_at DESC`,
 		userID,
 	)
 	if err != nil {
@@ -243,9 +734,9 @@ func (s *Store) ListProjectsByUser(ctx context.Context, userID int64) ([]model.P
 	}
 	defer rows.Close()
 
-	var projects []model.Project
+	projects := []model.Project{}
 	for rows.Next() {
-		p, err := scanProject(rows)
+		p, err := scanProject(rows, true)
 		if err != nil {
 			return nil, err
 		}
@@ -254,11 +745,22 @@ func (s *Store) ListProjectsByUser(ctx context.Context, userID int64) ([]model.P
 	return projects, rows.Err()
 }
 
+func (s *Store) CountProjectsByOwner(ctx context.Context, ownerID int64) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM projects WHERE owner_id = $1`, ownerID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count projects: %w", err)
+	}
+	return count, nil
+}
+
 func (s *Store) UpdateProject(ctx context.Context, project *model.Project) error {
 	err := s.db.QueryRowContext(ctx,
-		`UPDATE projects SET name = $1, description = $2, updated_at = NOW()
-		 WHERE id = $3 RETURNING updated_at`,
-		project.Name, project.Description, project.ID,
+		`UPDATE projects SET name = $1, description = $2, color = $3, is_template = $4, statuses = $5, hide_completed = $6,
+		 default_member_role = $7, end_date = $8, updated_at = NOW()
+		 WHERE id = $9 RETURNING updated_at`,
+		project.Name, project.Description, project.Color, project.IsTemplate, project.Statuses, project.HideCompleted,
+		project.DefaultMemberRole, project.EndDate, project.ID,
 	).Scan(&project.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("update project: %w", err)
@@ -266,103 +768,901 @@ func (s *Store) UpdateProject(ctx context.Context, project *model.Project) error
 	return nil
 }
 
-func (s *Store) DeleteProject(ctx context.Context, id int64) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM projects WHERE id = $1`, id)
-	return err
-}
-
-// ── Todos ────────────────────────────────────────────────────────────────────
+func (s *Store) CreateProjectFromTemplate(ctx context.Context, templateID int64, project *model.Project) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
 
-func (s *Store) CreateTodo(ctx context.Context, todo *model.Todo) error {
-	err := s.db.QueryRowContext(ctx,
-		`INSERT INTO todos (project_id, title, description, status, priority, deadline)
-		 VALUES ($1, $2, $3, $4, $5, $6)
-		 RETURNING id, created_at, updated_at`,
-		todo.ProjectID, todo.Title, todo.Description, todo.Status, todo.Priority, todo.Deadline,
-	).Scan(&todo.ID, &todo.CreatedAt, &todo.UpdatedAt)
+	if err := insertProject(ctx, tx, project); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO todos (project_id, title, description, status, priority, priority_rank, created_by)
+		 SELECT $1, title, description, 'pending', priority, priority_rank, $2
+		 FROM todos WHERE project_id = $3 ORDER BY id`,
+		project.ID, project.OwnerID, templateID,
+	)
 	if err != nil {
-		return fmt.Errorf("create todo: %w", err)
+		return fmt.Errorf("copy template todos: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
 	}
 	return nil
 }
 
-func (s *Store) GetTodo(ctx context.Context, id int64) (*model.Todo, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, project_id, title, description, status, priority, deadline, created_at, updated_at
-		 FROM todos WHERE id = $1`, id)
-	return scanTodo(row)
-}
-
-func (s *Store) ListTodosByProject(ctx context.Context, projectID int64) ([]model.Todo, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, project_id, title, description, status, priority, deadline, created_at, updated_at
-		 FROM todos WHERE project_id = $1 ORDER BY created_at DESC`, projectID)
+func (s *Store) ImportProjectTemplate(ctx context.Context, tmpl *model.ProjectTemplate, project *model.Project) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("list todos: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback() //nolint:errcheck
 
-	var todos []model.Todo
-	for rows.Next() {
-		t, err := scanTodo(rows)
+	if err := insertProject(ctx, tx, project); err != nil {
+		return err
+	}
+	for _, todo := range tmpl.Todos {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO todos (project_id, title, description, status, priority, priority_rank, created_by)
+			 VALUES ($1, $2, '', 'pending', $3, $4, $5)`,
+			project.ID, todo.Title, todo.Priority, todo.PriorityRank, project.OwnerID,
+		)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("import template todo: %w", err)
 		}
-		todos = append(todos, *t)
 	}
-	return todos, rows.Err()
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
 }
 
-func (s *Store) UpdateTodo(ctx context.Context, todo *model.Todo) error {
-	err := s.db.QueryRowContext(ctx,
-		`UPDATE todos SET title = $1, description = $2, status = $3, priority = $4, deadline = $5, updated_at = NOW()
-		 WHERE id = $6 RETURNING updated_at`,
-		todo.Title, todo.Description, todo.Status, todo.Priority, todo.Deadline, todo.ID,
-	).Scan(&todo.UpdatedAt)
+func (s *Store) ImportProjects(ctx context.Context, imports []store.ProjectImport) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("update todo: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, imp := range imports {
+		project := imp.Project
+		if err := insertProject(ctx, tx, project); err != nil {
+			return err
+		}
+		for _, todo := range imp.Todos {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO todos (project_id, title, description, status, priority, priority_rank, deadline, reminder_offset,
+				 estimate, created_by, completed_at)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CASE WHEN $11::boolean THEN NOW() END)`,
+				project.ID, todo.Title, todo.Description, todo.Status, todo.Priority, todo.PriorityRank,
+				todo.Deadline, todo.ReminderOffset, todo.Estimate, project.OwnerID, todo.Status == model.StatusCompleted,
+			)
+			if err != nil {
+				return fmt.Errorf("import todo: %w", err)
+			}
+		}
+		for _, m := range imp.Members {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3)
+				 ON CONFLICT (project_id, user_id) DO UPDATE SET role = $3`,
+				project.ID, m.UserID, m.Role,
+			)
+			if err != nil {
+				return fmt.Errorf("import member: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
 	}
 	return nil
 }
 
-func (s *Store) DeleteTodo(ctx context.Context, id int64) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM todos WHERE id = $1`, id)
-	return err
+func (s *Store) SetProjectFavorite(ctx context.Context, userID, projectID int64, favorite bool) error {
+	var err error
+	if favorite {
+		_, err = s.db.ExecContext(ctx,
+			`INSERT INTO project_favorites (user_id, project_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+			userID, projectID)
+	} else {
+		_, err = s.db.ExecContext(ctx,
+			`DELETE FROM project_favorites WHERE user_id = $1 AND project_id = $2`, userID, projectID)
+	}
+	if err != nil {
+		return fmt.Errorf("set favorite: %w", err)
+	}
+	return nil
 }
 
-// ── Project Members ──────────────────────────────────────────────────────────
+func (s *Store) SetProjectOrder(ctx context.Context, userID int64, projectIDs []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
 
-func (s *Store) AddProjectMember(ctx context.Context, projectID, userID int64, role string) error {
-	_, err := s.db.ExecContext(ctx,
-		`INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3)
-		 ON CONFLICT (project_id, user_id) DO UPDATE SET role = $3`,
-		projectID, userID, role,
-	)
-	return err
+	if _, err := tx.ExecContext(ctx, `DELETE FROM project_order WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("clear order: %w", err)
+	}
+	for i, id := range projectIDs {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO project_order (user_id, project_id, position)
+			 SELECT $1::BIGINT, p.id, $2::INTEGER FROM projects p
+			 WHERE p.id = $3 AND (p.owner_id = $1 OR EXISTS(SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = $1))`,
+			userID, i, id)
+		if err != nil {
+			return fmt.Errorf("set order of project %d: %w", id, err)
+		}
+	}
+	return tx.Commit()
 }
 
-func (s *Store) RemoveProjectMember(ctx context.Context, projectID, userID int64) error {
-	_, err := s.db.ExecContext(ctx,
-		`DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`,
-		projectID, userID,
-	)
-	return err
+func (s *Store) GetProjectStats(ctx context.Context, projectID int64) (*store.ProjectStats, error) {
+	stats := &store.ProjectStats{}
+	err := s.read.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END), 0),
+			COUNT(estimate),
+			COALESCE(SUM(estimate), 0),
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN estimate ELSE 0 END), 0)
+		 FROM todos WHERE project_id = $1`, projectID,
+	).Scan(&stats.TotalTodos, &stats.CompletedTodos, &stats.EstimatedTodos, &stats.TotalEstimate, &stats.CompletedEstimate)
+	if err != nil {
+		return nil, err
+	}
+	stats.TodosByStatus, err = countTodosByStatus(ctx, s.read,
+		`SELECT status, COUNT(*) FROM todos WHERE project_id = $1 GROUP BY status`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
 }
 
-func (s *Store) ListProjectMembers(ctx context.Context, projectID int64) ([]model.ProjectMember, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT pm.project_id, pm.user_id, u.username, pm.role
-		 FROM project_members pm
-		 JOIN users u ON pm.user_id = u.id
-		 WHERE pm.project_id = $1`, projectID)
+func (s *Store) CountTodosByPriority(ctx context.Context, projectID int64) (map[string]int, error) {
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT priority, COUNT(*) FROM todos WHERE project_id = $1 GROUP BY priority`, projectID)
 	if err != nil {
-		return nil, fmt.Errorf("list members: %w", err)
+		return nil, err
 	}
 	defer rows.Close()
 
-	var members []model.ProjectMember
+	counts := map[string]int{model.PriorityLow: 0, model.PriorityMedium: 0, model.PriorityHigh: 0}
 	for rows.Next() {
-		var m model.ProjectMember
+		var priority string
+		var n int
+		if err := rows.Scan(&priority, &n); err != nil {
+			return nil, err
+		}
+		counts[priority] = n
+	}
+	return counts, rows.Err()
+}
+
+func (s *Store) DeleteProject(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM projects WHERE id = $1`, id)
+	return err
+}
+
+func (s *Store) DeleteProjects(ctx context.Context, ids []int64, audit *model.AdminAuditEntry) ([]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var deleted []int64
+	for _, id := range ids {
+		result, err := tx.ExecContext(ctx, `DELETE FROM projects WHERE id = $1`, id)
+		if err != nil {
+			return nil, fmt.Errorf("delete project %d: %w", id, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue
+		}
+		deleted = append(deleted, id)
+		if audit != nil {
+			entry := *audit
+			entry.TargetID = id
+			if err := insertAdminAudit(ctx, tx, &entry); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return deleted, nil
+}
+
+// ── Todos ────────────────────────────────────────────────────────────────────
+
+func (s *Store) CreateTodo(ctx context.Context, todo *model.Todo) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO todos (project_id, title, description, status, priority, priority_rank, deadline, reminder_offset,
+		 estimate, created_by, completed_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, CASE WHEN $11::boolean THEN NOW() END)
+		 RETURNING id, completed_at, created_at, updated_at`,
+		todo.ProjectID, todo.Title, todo.Description, todo.Status, todo.Priority, todo.PriorityRank, todo.Deadline,
+		todo.ReminderOffset, todo.Estimate, todo.CreatedBy, todo.Status == model.StatusCompleted,
+	).Scan(&todo.ID, &todo.CompletedAt, &todo.CreatedAt, &todo.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("create todo: %w", err)
+	}
+	ids := make([]int64, len(todo.Assignees))
+	for i, a := range todo.Assignees {
+		ids[i] = a.UserID
+	}
+	if err := insertAssignees(ctx, tx, todo.ID, ids); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	if todo.CreatedByName, err = s.usernameOf(ctx, todo.CreatedBy); err != nil {
+		return fmt.Errorf("resolve creator: %w", err)
+	}
+	if todo.Assignees, err = listAssignees(ctx, s.db, todo.ID); err != nil {
+		return fmt.Errorf("resolve assignees: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetTodo(ctx context.Context, id int64) (*model.Todo, error) {
+	row := s.read.QueryRowContext(ctx,
+		`SELECT `+todoColumns+` FROM `+todoFrom+` WHERE t.id = $1`, id)
+	todo, err := scanTodo(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if todo.Assignees, err = listAssignees(ctx, s.read, id); err != nil {
+		return nil, fmt.Errorf("list assignees: %w", err)
+	}
+	return todo, nil
+}
+
+// queryTodos runs a SELECT of todoColumns on db and loads each todo's
+// assignees from the same connection.
+func queryTodos(ctx context.Context, db *sql.DB, query string, args ...any) ([]model.Todo, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := []model.Todo{}
+	for rows.Next() {
+		t, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := attachAssignees(ctx, db, todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// attachAssignees fills in Assignees for a batch of todos with one query.
+func attachAssignees(ctx context.Context, db *sql.DB, todos []model.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*model.Todo, len(todos))
+	ids := make([]int64, len(todos))
+	for i := range todos {
+		byID[todos[i].ID] = &todos[i]
+		ids[i] = todos[i].ID
+	}
+	rows, err := db.QueryContext(ctx,
+		`SELECT ta.todo_id, ta.user_id, u.username
+		 FROM todo_assignees ta JOIN users u ON ta.user_id = u.id
+		 WHERE ta.todo_id = ANY($1)
+		 ORDER BY u.username`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("list assignees: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var todoID int64
+		var a model.TodoAssignee
+		if err := rows.Scan(&todoID, &a.UserID, &a.Username); err != nil {
+			return err
+		}
+		t := byID[todoID]
+		t.Assignees = append(t.Assignees, a)
+	}
+	return rows.Err()
+}
+
+func (s *Store) ListTodoAssignees(ctx context.Context, todoID int64) ([]model.TodoAssignee, error) {
+	return listAssignees(ctx, s.read, todoID)
+}
+
+// listAssignees takes the connection explicitly so write paths can read their
+// own writes from the primary.
+func listAssignees(ctx context.Context, db *sql.DB, todoID int64) ([]model.TodoAssignee, error) {
+	rows, err := db.QueryContext(ctx,
+		`SELECT ta.user_id, u.username
+		 FROM todo_assignees ta JOIN users u ON ta.user_id = u.id
+		 WHERE ta.todo_id = $1
+		 ORDER BY u.username`, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("list assignees: %w", err)
+	}
+	defer rows.Close()
+
+	assignees := []model.TodoAssignee{}
+	for rows.Next() {
+		var a model.TodoAssignee
+		if err := rows.Scan(&a.UserID, &a.Username); err != nil {
+			return nil, err
+		}
+		assignees = append(assignees, a)
+	}
+	return assignees, rows.Err()
+}
+
+func (s *Store) SetTodoAssignees(ctx context.Context, todoID int64, userIDs []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := replaceAssignees(ctx, tx, todoID, userIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceAssignees(ctx context.Context, tx *sql.Tx, todoID int64, userIDs []int64) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM todo_assignees WHERE todo_id = $1`, todoID); err != nil {
+		return fmt.Errorf("clear assignees: %w", err)
+	}
+	return insertAssignees(ctx, tx, todoID, userIDs)
+}
+
+func insertAssignees(ctx context.Context, tx *sql.Tx, todoID int64, userIDs []int64) error {
+	for _, userID := range userIDs {
+		if _, err := tx.ExecContext(ctx,
+			`INSERT INTO todo_assignees (todo_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`, todoID, userID,
+		); err != nil {
+			return fmt.Errorf("add assignee %d: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ListTodosByProject(ctx context.Context, projectID int64, params store.TodoListParams) ([]model.Todo, error) {
+	where, args := todoFilters(`t.project_id = $1`, []any{projectID}, params)
+	todos, err := queryTodos(ctx, s.read,
+		`SELECT `+todoColumns+` FROM `+todoFrom+` WHERE `+where+` ORDER BY `+todoOrderBy(params.Sort), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list todos: %w", err)
+	}
+	return todos, nil
+}
+
+func (s *Store) ListTodosByUser(ctx context.Context, userID int64, params store.TodoListParams, limit, offset int) ([]model.Todo, int, error) {
+	where, args := todoFilters(`t.project_id IN (
+			SELECT id FROM projects WHERE owner_id = $1
+			UNION
+			SELECT project_id FROM project_members WHERE user_id = $1
+		 )`, []any{userID}, params)
+
+	var total int
+	if err := s.read.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos t WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count todos: %w", err)
+	}
+	args = append(args, limit, offset)
+	todos, err := queryTodos(ctx, s.read,
+		`SELECT `+todoColumns+` FROM `+todoFrom+` WHERE `+where+`
+		 ORDER BY t.project_id, `+todoOrderBy(params.Sort)+fmt.Sprintf(`, t.id LIMIT $%d OFFSET $%d`, len(args)-1, len(args)),
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list todos: %w", err)
+	}
+	return todos, total, nil
+}
+
+// todoFilters appends the conditions for params to where and args,
+// numbering placeholders after those already in args.
+func todoFilters(where string, args []any, params store.TodoListParams) (string, []any) {
+	if params.Status != "" {
+		args = append(args, params.Status)
+		where += fmt.Sprintf(` AND t.status = $%d`, len(args))
+	}
+	if params.HideCompleted && params.Status == "" {
+		args = append(args, model.StatusCompleted)
+		where += fmt.Sprintf(` AND t.status <> $%d`, len(args))
+	}
+	if params.Priority != "" {
+		args = append(args, params.Priority)
+		where += fmt.Sprintf(` AND t.priority = $%d`, len(args))
+	}
+	if params.Query != "" {
+		args = append(args, params.Query)
+		where += fmt.Sprintf(` AND (t.title ILIKE '%%' || $%[1]d || '%%' OR t.description ILIKE '%%' || $%[1]d || '%%')`, len(args))
+	}
+	if params.Unassigned {
+		where += ` AND NOT EXISTS (SELECT 1 FROM todo_assignees ta WHERE ta.todo_id = t.id)`
+	} else if params.AssigneeID != 0 {
+		args = append(args, params.AssigneeID)
+		where += fmt.Sprintf(` AND EXISTS (SELECT 1 FROM todo_assignees ta WHERE ta.todo_id = t.id AND ta.user_id = $%d)`, len(args))
+	}
+	if params.UpdatedSince != nil {
+		args = append(args, *params.UpdatedSince)
+		where += fmt.Sprintf(` AND t.updated_at > $%d`, len(args))
+	}
+	return where, args
+}
+
+func (s *Store) GetTodosByIDs(ctx context.Context, ids []int64, userID int64) ([]model.Todo, error) {
+	if len(ids) == 0 {
+		return []model.Todo{}, nil
+	}
+	todos, err := queryTodos(ctx, s.read,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 WHERE t.id = ANY($1)
+		 AND t.project_id IN (
+			SELECT id FROM projects WHERE owner_id = $2
+			UNION
+			SELECT project_id FROM project_members WHERE user_id = $2
+		 )
+		 ORDER BY t.id`, pq.Array(ids), userID)
+	if err != nil {
+		return nil, fmt.Errorf("get todos: %w", err)
+	}
+	return todos, nil
+}
+
+func (s *Store) ListTodosDueOn(ctx context.Context, userID int64, day time.Time) ([]model.Todo, error) {
+	todos, err := queryTodos(ctx, s.read,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 WHERE t.deadline >= $1 AND t.deadline < $2 AND t.status != 'completed'
+		 AND t.project_id IN (
+			SELECT id FROM projects WHERE owner_id = $3
+			UNION
+			SELECT project_id FROM project_members WHERE user_id = $3
+		 )
+		 ORDER BY t.deadline, t.id`,
+		day, day.AddDate(0, 0, 1), userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list todos due: %w", err)
+	}
+	return todos, nil
+}
+
+// CountTodosByProject reads from the primary since it guards a write.
+func (s *Store) CountTodosByProject(ctx context.Context, projectID int64) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos WHERE project_id = $1`, projectID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count todos: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Store) ListCompletedBetween(ctx context.Context, projectID int64, from, to time.Time) ([]model.Todo, error) {
+	todos, err := queryTodos(ctx, s.read,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 WHERE t.project_id = $1 AND t.status = 'completed' AND t.completed_at >= $2 AND t.completed_at < $3
+		 ORDER BY t.completed_at, t.id`,
+		projectID, from, to,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list completed todos: %w", err)
+	}
+	return todos, nil
+}
+
+func (s *Store) UpdateTodo(ctx context.Context, todo *model.Todo) error {
+	return s.updateTodo(ctx, todo, false, nil)
+}
+
+func (s *Store) UpdateTodoWithAssignees(ctx context.Context, todo *model.Todo, assigneeIDs []int64) error {
+	return s.updateTodo(ctx, todo, true, assigneeIDs)
+}
+
+// updateTodo saves todo's fields and, if setAssignees, replaces its
+// assignees with assigneeIDs in the same transaction.
+func (s *Store) updateTodo(ctx context.Context, todo *model.Todo, setAssignees bool, assigneeIDs []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// A new deadline or reminder offset gets a fresh reminder. A todo that
+	// stays completed keeps its completion time.
+	err = tx.QueryRowContext(ctx,
+		`UPDATE todos SET title = $1, description = $2, status = $3, priority = $4, priority_rank = $5, deadline = $6,
+		 reminder_offset = $7, estimate = $8,
+		 reminded_at = CASE WHEN deadline IS NOT DISTINCT FROM $6 AND reminder_offset IS NOT DISTINCT FROM $7
+			THEN reminded_at END,
+		 completed_at = CASE WHEN $10::boolean THEN COALESCE(completed_at, NOW()) END,
+		 updated_at = NOW()
+		 WHERE id = $9 RETURNING completed_at, updated_at`,
+		todo.Title, todo.Description, todo.Status, todo.Priority, todo.PriorityRank, todo.Deadline, todo.ReminderOffset,
+		todo.Estimate, todo.ID, todo.Status == model.StatusCompleted,
+	).Scan(&todo.CompletedAt, &todo.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("update todo: %w", err)
+	}
+	if setAssignees {
+		if err := replaceAssignees(ctx, tx, todo.ID, assigneeIDs); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	if setAssignees {
+		if todo.Assignees, err = s.ListTodoAssignees(ctx, todo.ID); err != nil {
+			return fmt.Errorf("resolve assignees: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ClaimTodoReminders(ctx context.Context, at time.Time, defaultOffset time.Duration) ([]model.Todo, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE todos SET reminded_at = NOW()
+		 WHERE reminded_at IS NULL AND status != 'completed' AND deadline > $1
+		 AND deadline - COALESCE(reminder_offset, $2) * INTERVAL '1 second' <= $1
+		 RETURNING id`,
+		at, int64(defaultOffset/time.Second),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	// Read back from the primary, which has just been written.
+	return queryTodos(ctx, s.db,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 WHERE t.id = ANY($1)
+		 ORDER BY t.deadline, t.id`, pq.Array(ids))
+}
+
+func (s *Store) ReleaseTodoReminder(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE todos SET reminded_at = NULL WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("release reminder: %w", err)
+	}
+	return nil
+}
+
+// dependencyReachesSQL reports whether $2 can be reached from $1 by
+// following depends_on edges, counting $1 itself.
+const dependencyReachesSQL = `WITH RECURSIVE reach(id) AS (
+	SELECT $1::BIGINT
+	UNION
+	SELECT d.depends_on_id FROM todo_dependencies d JOIN reach r ON d.todo_id = r.id
+)
+SELECT EXISTS(SELECT 1 FROM reach WHERE id = $2)`
+
+func (s *Store) AddTodoDependency(ctx context.Context, todoID, dependsOnID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// Serialize dependency changes so two concurrent inserts can't each pass
+	// the cycle check and together close a cycle. Reads are not blocked.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE todo_dependencies IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return fmt.Errorf("lock dependencies: %w", err)
+	}
+	// The new edge closes a cycle if todoID is already reachable from
+	// dependsOnID.
+	var cycle bool
+	if err := tx.QueryRowContext(ctx, dependencyReachesSQL, dependsOnID, todoID).Scan(&cycle); err != nil {
+		return fmt.Errorf("check dependency cycle: %w", err)
+	}
+	if cycle {
+		return store.ErrDependencyCycle
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO todo_dependencies (todo_id, depends_on_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`,
+		todoID, dependsOnID)
+	if err != nil {
+		return fmt.Errorf("add dependency: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *Store) RemoveTodoDependency(ctx context.Context, todoID, dependsOnID int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM todo_dependencies WHERE todo_id = $1 AND depends_on_id = $2`, todoID, dependsOnID)
+	return err
+}
+
+func (s *Store) ListTodoDependencies(ctx context.Context, todoID int64) ([]model.Todo, error) {
+	todos, err := queryTodos(ctx, s.read,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 JOIN todo_dependencies d ON d.depends_on_id = t.id
+		 WHERE d.todo_id = $1
+		 ORDER BY t.created_at, t.id`, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("list dependencies: %w", err)
+	}
+	return todos, nil
+}
+
+func (s *Store) ListProjectDependencies(ctx context.Context, projectID int64) ([]model.TodoDependency, error) {
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT d.todo_id, d.depends_on_id
+		 FROM todo_dependencies d JOIN todos t ON d.todo_id = t.id
+		 WHERE t.project_id = $1
+		 ORDER BY d.todo_id, d.depends_on_id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	deps := []model.TodoDependency{}
+	for rows.Next() {
+		var d model.TodoDependency
+		if err := rows.Scan(&d.TodoID, &d.DependsOnID); err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	return deps, rows.Err()
+}
+
+// CountIncompleteDependencies reads from the primary since it guards a write.
+func (s *Store) CountIncompleteDependencies(ctx context.Context, todoID int64) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM todo_dependencies d JOIN todos t ON d.depends_on_id = t.id
+		 WHERE d.todo_id = $1 AND t.status != 'completed'`, todoID).Scan(&n)
+	return n, err
+}
+
+func (s *Store) DeleteTodo(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM todos WHERE id = $1`, id)
+	return err
+}
+
+func (s *Store) DeleteCompletedTodos(ctx context.Context, projectID int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM todos WHERE project_id = $1 AND status = 'completed'`, projectID)
+	if err != nil {
+		return 0, fmt.Errorf("delete completed todos: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (s *Store) CreateTodoChanges(ctx context.Context, changes []model.TodoChange) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for i := range changes {
+		c := &changes[i]
+		err := tx.QueryRowContext(ctx,
+			`INSERT INTO todo_history (todo_id, user_id, field, old_value, new_value)
+			 VALUES ($1, $2, $3, $4, $5)
+			 RETURNING id, created_at`,
+			c.TodoID, c.UserID, c.Field, c.OldValue, c.NewValue,
+		).Scan(&c.ID, &c.CreatedAt)
+		if err != nil {
+			return fmt.Errorf("create todo change: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (s *Store) ListTodoHistory(ctx context.Context, todoID int64) ([]model.TodoChange, error) {
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT h.id, h.todo_id, h.user_id, u.username, h.field, h.old_value, h.new_value, h.created_at
+		 FROM todo_history h
+		 LEFT JOIN users u ON h.user_id = u.id
+		 WHERE h.todo_id = $1
+		 ORDER BY h.created_at, h.id`, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("list todo history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []model.TodoChange{}
+	for rows.Next() {
+		var c model.TodoChange
+		var username sql.NullString
+		if err := rows.Scan(&c.ID, &c.TodoID, &c.UserID, &username, &c.Field, &c.OldValue, &c.NewValue, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.Username = username.String
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+func (s *Store) ListActivityForUser(ctx context.Context, userID int64, limit int, before int64) ([]model.Activity, error) {
+	// IDs grow with time, so ordering by them is newest first and makes
+	// them a stable cursor.
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT h.id, h.todo_id, h.user_id, u.username, h.field, h.old_value, h.new_value, h.created_at,
+		 t.project_id, t.title
+		 FROM todo_history h
+		 JOIN todos t ON h.todo_id = t.id
+		 JOIN projects p ON t.project_id = p.id
+		 LEFT JOIN users u ON h.user_id = u.id
+		 WHERE (p.owner_id = $1 OR EXISTS(SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = $1))
+		 AND ($2 <= 0 OR h.id < $2)
+		 ORDER BY h.id DESC LIMIT $3`,
+		userID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list activity: %w", err)
+	}
+	defer rows.Close()
+
+	activity := []model.Activity{}
+	for rows.Next() {
+		var a model.Activity
+		var username sql.NullString
+		if err := rows.Scan(&a.ID, &a.TodoID, &a.UserID, &username, &a.Field, &a.OldValue, &a.NewValue, &a.CreatedAt,
+			&a.ProjectID, &a.TodoTitle); err != nil {
+			return nil, err
+		}
+		a.Username = username.String
+		activity = append(activity, a)
+	}
+	return activity, rows.Err()
+}
+
+// ── Project Members ──────────────────────────────────────────────────────────
+
+func (s *Store) AddProjectMember(ctx context.Context, projectID, userID int64, role string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO project_members (project_id, user_id, role) VALUES ($1, $2, $3)
+		 ON CONFLICT (project_id, user_id) DO UPDATE SET role = $3`,
+		projectID, userID, role,
+	)
+	return err
+}
+
+func (s *Store) RemoveProjectMember(ctx context.Context, projectID, userID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM project_members WHERE project_id = $1 AND user_id = $2`,
+		projectID, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM project_favorites WHERE project_id = $1 AND user_id = $2`,
+		projectID, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM project_order WHERE project_id = $1 AND user_id = $2`,
+		projectID, userID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) CountProjectMembers(ctx context.Context, projectID int64) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM project_members WHERE project_id = $1)
+		      + (SELECT COUNT(*) FROM project_invites WHERE project_id = $1)`,
+		projectID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count members: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Store) ListProjectMembers(ctx context.Context, projectID int64) ([]model.ProjectMember, error) {
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT pm.project_id, pm.user_id, u.username, pm.role
+		 FROM project_members pm
+		 JOIN users u ON pm.user_id = u.id
+		 WHERE pm.project_id = $1
+		 ORDER BY `+memberRoleOrder+`, u.username`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list members: %w", err)
+	}
+	defer rows.Close()
+
+	members := []model.ProjectMember{}
+	for rows.Next() {
+		var m model.ProjectMember
+		if err := rows.Scan(&m.ProjectID, &m.UserID, &m.Username, &m.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (s *Store) GetProjectMember(ctx context.Context, projectID, userID int64) (*model.ProjectMember, error) {
+	var m model.ProjectMember
+	err := s.read.QueryRowContext(ctx,
+		`SELECT p.id, p.owner_id, u.username, 'owner'
+		 FROM projects p
+		 JOIN users u ON p.owner_id = u.id
+		 WHERE p.id = $1 AND p.owner_id = $2
+		 UNION ALL
+		 SELECT pm.project_id, pm.user_id, u.username, pm.role
+		 FROM project_members pm
+		 JOIN users u ON pm.user_id = u.id
+		 WHERE pm.project_id = $1 AND pm.user_id = $2`,
+		projectID, userID,
+	).Scan(&m.ProjectID, &m.UserID, &m.Username, &m.Role)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &m, nil
+}
+
+func (s *Store) ListMembershipsByUser(ctx context.Context, userID int64) ([]model.ProjectMember, error) {
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT p.id, p.owner_id, u.username, 'owner'
+		 FROM projects p
+		 JOIN users u ON p.owner_id = u.id
+		 WHERE p.owner_id = $1
+		 UNION ALL
+		 SELECT pm.project_id, pm.user_id, u.username, pm.role
+		 FROM project_members pm
+		 JOIN users u ON pm.user_id = u.id
+		 WHERE pm.user_id = $1
+		 ORDER BY 1`,
+		userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list memberships: %w", err)
+	}
+	defer rows.Close()
+
+	members := []model.ProjectMember{}
+	for rows.Next() {
+		var m model.ProjectMember
 		if err := rows.Scan(&m.ProjectID, &m.UserID, &m.Username, &m.Role); err != nil {
 			return nil, err
 		}
@@ -371,6 +1671,43 @@ func (s *Store) ListProjectMembers(ctx context.Context, projectID int64) ([]mode
 	return members, rows.Err()
 }
 
+func (s *Store) ProjectExists(ctx context.Context, id int64) (bool, error) {
+	var exists bool
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM projects WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists, nil
+}
+
+func (s *Store) GetMemberRoles(ctx context.Context, userID int64, projectIDs []int64) (map[int64]string, error) {
+	roles := make(map[int64]string)
+	if len(projectIDs) == 0 {
+		return roles, nil
+	}
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT p.id, CASE WHEN p.owner_id = $1 THEN 'owner' ELSE pm.role END
+		 FROM projects p
+		 LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = $1
+		 WHERE p.id = ANY($2)`, userID, pq.Array(projectIDs))
+	if err != nil {
+		return nil, fmt.Errorf("get member roles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var role sql.NullString
+		if err := rows.Scan(&id, &role); err != nil {
+			return nil, err
+		}
+		if role.Valid {
+			roles[id] = role.String
+		}
+	}
+	return roles, rows.Err()
+}
+
 func (s *Store) IsProjectMember(ctx context.Context, projectID, userID int64) (bool, error) {
 	var exists bool
 	err := s.db.QueryRowContext(ctx,
@@ -389,9 +1726,9 @@ func (s *Store) IsProjectMember(ctx context.Context, projectID, userID int64) (b
 func (s *Store) GetMemberRole(ctx context.Context, projectID, userID int64) (string, error) {
 	// Check if user is the owner first.
 	var ownerID int64
-	err := s.db.QueryRowContext(ctx, `SELECT owner_id FROM projects WHERE id = $1`, projectID).Scan(&ownerID)
+	err := s.read.QueryRowContext(ctx, `SELECT owner_id FROM projects WHERE id = $1`, projectID).Scan(&ownerID)
 	if err != nil {
-		return "", err
+		return "", notFound(err)
 	}
 	if ownerID == userID {
 		return "owner", nil
@@ -399,7 +1736,7 @@ func (s *Store) GetMemberRole(ctx context.Context, projectID, userID int64) (str
 
 	// Check project_members table.
 	var role string
-	err = s.db.QueryRowContext(ctx,
+	err = s.read.QueryRowContext(ctx,
 		`SELECT role FROM project_members WHERE project_id = $1 AND user_id = $2`,
 		projectID, userID,
 	).Scan(&role)
@@ -409,11 +1746,149 @@ func (s *Store) GetMemberRole(ctx context.Context, projectID, userID int64) (str
 	return role, nil
 }
 
+// ── Project Invites ──────────────────────────────────────────────────────────
+
+func (s *Store) CreateProjectInvite(ctx context.Context, invite *model.ProjectInvite) error {
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO project_invites (project_id, email, role, token)
+		 VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		invite.ProjectID, invite.Email, invite.Role, invite.Token,
+	).Scan(&invite.ID, &invite.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create invite: %w", wrapConflict(err))
+	}
+	return nil
+}
+
+func (s *Store) ListProjectInvites(ctx context.Context, projectID int64) ([]model.ProjectInvite, error) {
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT id, project_id, email, role, token, created_at
+		 FROM project_invites WHERE project_id = $1 ORDER BY created_at`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []model.ProjectInvite{}
+	for rows.Next() {
+		var inv model.ProjectInvite
+		if err := rows.Scan(&inv.ID, &inv.ProjectID, &inv.Email, &inv.Role, &inv.Token, &inv.CreatedAt); err != nil {
+			return nil, err
+		}
+		invites = append(invites, inv)
+	}
+	return invites, rows.Err()
+}
+
+// DeleteProjectInvite returns store.ErrNotFound if the invite does not exist in
+// the given project.
+func (s *Store) DeleteProjectInvite(ctx context.Context, projectID, inviteID int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM project_invites WHERE id = $1 AND project_id = $2`, inviteID, projectID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// ── Webhooks ─────────────────────────────────────────────────────────────────
+
+func (s *Store) CreateWebhook(ctx context.Context, hook *model.Webhook) error {
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO webhooks (project_id, url, secret, events) VALUES ($1, $2, $3, $4)
+		 RETURNING id, created_at`,
+		hook.ProjectID, hook.URL, hook.Secret, pq.Array(hook.Events),
+	).Scan(&hook.ID, &hook.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("create webhook: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetWebhook(ctx context.Context, projectID, webhookID int64) (*model.Webhook, error) {
+	row := s.read.QueryRowContext(ctx,
+		`SELECT id, project_id, url, secret, events, created_at
+		 FROM webhooks WHERE id = $1 AND project_id = $2`, webhookID, projectID)
+	v, err := scanWebhook(row)
+	return v, notFound(err)
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, projectID int64) ([]model.Webhook, error) {
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT id, project_id, url, secret, events, created_at
+		 FROM webhooks WHERE project_id = $1 ORDER BY id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []model.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, hook *model.Webhook) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE webhooks SET url = $1, secret = $2, events = $3 WHERE id = $4 AND project_id = $5`,
+		hook.URL, hook.Secret, pq.Array(hook.Events), hook.ID, hook.ProjectID,
+	)
+	if err != nil {
+		return fmt.Errorf("update webhook: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, projectID, webhookID int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM webhooks WHERE id = $1 AND project_id = $2`, webhookID, projectID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func scanWebhook(row scannable) (*model.Webhook, error) {
+	var hook model.Webhook
+	err := row.Scan(&hook.ID, &hook.ProjectID, &hook.URL, &hook.Secret, pq.Array(&hook.Events), &hook.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &hook, nil
+}
+
 // ── Admin ────────────────────────────────────────────────────────────────────
 
 func (s *Store) GetStats(ctx context.Context) (*store.Stats, error) {
 	stats := &store.Stats{}
-	err := s.db.QueryRowContext(ctx,
+	err := s.read.QueryRowContext(ctx,
 		`SELECT
 			(SELECT COUNT(*) FROM users),
 			(SELECT COUNT(*) FROM projects),
@@ -423,5 +1898,181 @@ func (s *Store) GetStats(ctx context.Context) (*store.Stats, error) {
 	if err != nil {
 		return nil, err
 	}
+	if stats.TodosByStatus, err = countTodosByStatus(ctx, s.read, `SELECT status, COUNT(*) FROM todos GROUP BY status`); err != nil {
+		return nil, err
+	}
 	return stats, nil
 }
+
+func (s *Store) RepairTodos(ctx context.Context) (*store.TodoRepair, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT t.id, t.status, t.priority, p.statuses FROM todos t JOIN projects p ON t.project_id = p.id FOR UPDATE OF t`)
+	if err != nil {
+		return nil, fmt.Errorf("scan todos: %w", err)
+	}
+	defer rows.Close()
+
+	type fix struct {
+		id               int64
+		status, priority string
+	}
+	var fixes []fix
+	repair := &store.TodoRepair{}
+	for rows.Next() {
+		var f fix
+		var project model.Project
+		if err := rows.Scan(&f.id, &f.status, &f.priority, &project.Statuses); err != nil {
+			return nil, err
+		}
+		broken := false
+		if !project.AllowsStatus(f.status) {
+			f.status = model.StatusPending
+			repair.Statuses++
+			broken = true
+		}
+		if !model.ValidPriority(f.priority) {
+			f.priority = model.PriorityMedium
+			repair.Priorities++
+			broken = true
+		}
+		if broken {
+			fixes = append(fixes, f)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	for _, f := range fixes {
+		_, err := tx.ExecContext(ctx, `UPDATE todos SET status = $1, priority = $2,
+			 completed_at = CASE WHEN status = $1 THEN completed_at END, updated_at = NOW() WHERE id = $3`,
+			f.status, f.priority, f.id)
+		if err != nil {
+			return nil, fmt.Errorf("repair todo %d: %w", f.id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	repair.Todos = len(fixes)
+	return repair, nil
+}
+
+// countTodosByStatus runs query, which must select status and count pairs.
+func countTodosByStatus(ctx context.Context, db *sql.DB, query string, args ...any) (map[string]int, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+func (s *Store) ListAdminAudit(ctx context.Context, limit, offset int) ([]model.AdminAuditEntry, error) {
+	rows, err := s.read.QueryContext(ctx,
+		`SELECT a.id, a.actor_id, u.username, a.action, a.target_id, a.changes, a.created_at
+		 FROM admin_audit a
+		 LEFT JOIN users u ON a.actor_id = u.id
+		 ORDER BY a.created_at DESC, a.id DESC
+		 LIMIT $1 OFFSET $2`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list admin audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.AdminAuditEntry{}
+	for rows.Next() {
+		var e model.AdminAuditEntry
+		var actorName sql.NullString
+		var changes []byte
+		if err := rows.Scan(&e.ID, &e.ActorID, &actorName, &e.Action, &e.TargetID, &changes, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.ActorName = actorName.String
+		e.Changes = json.RawMessage(changes)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ── Feature flags ────────────────────────────────────────────────────────────
+
+// ListFeatureFlags reads from the primary so a flag change takes effect on
+// every instance as soon as its cache expires, however far the replica lags.
+func (s *Store) ListFeatureFlags(ctx context.Context) ([]model.FeatureFlag, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT key, enabled, updated_at FROM feature_flags ORDER BY key`)
+	if err != nil {
+		return nil, fmt.Errorf("list feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []model.FeatureFlag{}
+	for rows.Next() {
+		var f model.FeatureFlag
+		if err := rows.Scan(&f.Key, &f.Enabled, &f.UpdatedAt); err != nil {
+			return nil, err
+		}
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+func (s *Store) SetFeatureFlag(ctx context.Context, flag *model.FeatureFlag) error {
+	err := s.db.QueryRowContext(ctx,
+		`INSERT INTO feature_flags (key, enabled, updated_at) VALUES ($1, $2, NOW())
+		 ON CONFLICT (key) DO UPDATE SET enabled = EXCLUDED.enabled, updated_at = EXCLUDED.updated_at
+		 RETURNING updated_at`,
+		flag.Key, flag.Enabled,
+	).Scan(&flag.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("set feature flag: %w", err)
+	}
+	return nil
+}
+
+// ── Utilities ────────────────────────────────────────────────────────────────
+
+// memberRoleOrder sorts project_members rows by role, most privileged first.
+// Owners are not stored in project_members but are ranked for completeness.
+const memberRoleOrder = `CASE pm.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END`
+
+// todoOrderBy maps a store.TodoListParams sort to an ORDER BY clause.
+func todoOrderBy(sort string) string {
+	switch sort {
+	case store.TodoSortPriority:
+		return `CASE t.priority WHEN 'high' THEN 0 WHEN 'medium' THEN 1 ELSE 2 END,
+			t.priority_rank NULLS LAST, t.created_at DESC`
+	case store.TodoSortPriorityRank:
+		return `t.priority_rank NULLS LAST, t.created_at DESC`
+	default:
+		return `t.created_at DESC`
+	}
+}
+
+// usernameOf returns the username for an optional user id, or "" when id is
+// nil.
+func (s *Store) usernameOf(ctx context.Context, id *int64) (string, error) {
+	if id == nil {
+		return "", nil
+	}
+	var username string
+	err := s.db.QueryRowContext(ctx, `SELECT username FROM users WHERE id = $1`, *id).Scan(&username)
+	return username, err
+}
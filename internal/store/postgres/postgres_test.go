@@ -0,0 +1,290 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+
+	"github.com/lib/pq"
+)
+
+// recordingDriver counts every statement sent to a connection and fails it,
+// which is enough to see where the Store routes each call.
+type recordingDriver struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+var errRecorded = errors.New("recorded")
+
+func (d *recordingDriver) Open(string) (driver.Conn, error) { return &recordingConn{d: d}, nil }
+
+// Connect and Driver make recordingDriver a driver.Connector, so it can be
+// used with sql.OpenDB without registering a global driver name.
+func (d *recordingDriver) Connect(context.Context) (driver.Conn, error) { return d.Open("") }
+func (d *recordingDriver) Driver() driver.Driver                        { return d }
+
+func (d *recordingDriver) record(query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.queries = append(d.queries, query)
+}
+
+func (d *recordingDriver) reset() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	q := d.queries
+	d.queries = nil
+	return q
+}
+
+type recordingConn struct{ d *recordingDriver }
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return &recordingStmt{d: c.d, query: query}, nil
+}
+func (c *recordingConn) Close() error              { return nil }
+func (c *recordingConn) Begin() (driver.Tx, error) { return recordingTx{}, nil }
+
+type recordingTx struct{}
+
+func (recordingTx) Commit() error   { return nil }
+func (recordingTx) Rollback() error { return nil }
+
+type recordingStmt struct {
+	d     *recordingDriver
+	query string
+}
+
+func (s *recordingStmt) Close() error  { return nil }
+func (s *recordingStmt) NumInput() int { return -1 }
+func (s *recordingStmt) Exec([]driver.Value) (driver.Result, error) {
+	s.d.record(s.query)
+	return nil, errRecorded
+}
+func (s *recordingStmt) Query([]driver.Value) (driver.Rows, error) {
+	s.d.record(s.query)
+	return nil, errRecorded
+}
+
+func setupReplicaStore(t *testing.T) (s *Store, primary, replica *recordingDriver) {
+	t.Helper()
+	primary, replica = &recordingDriver{}, &recordingDriver{}
+	s = newStore(sql.OpenDB(primary), sql.OpenDB(replica))
+	t.Cleanup(func() { s.Close() })
+	return s, primary, replica
+}
+
+func TestWritesNeverUseReplica(t *testing.T) {
+	s, primary, replica := setupReplicaStore(t)
+	ctx := context.Background()
+	userID := int64(1)
+
+	writes := map[string]func() error{
+		"Migrate":    func() error { return s.Migrate(ctx) },
+		"CreateUser": func() error { return s.CreateUser(ctx, &model.User{Email: "a@test.io"}) },
+		"UpdateUser": func() error {
+			return s.UpdateUser(ctx, &model.User{ID: 1}, &model.AdminAuditEntry{Action: model.AuditUserUpdate})
+		},
+		"RecordLogin": func() error { return s.RecordLogin(ctx, 1, time.Now()) },
+		"CreateAdminAudit": func() error {
+			return s.CreateAdminAudit(ctx, &model.AdminAuditEntry{Action: model.AuditUserProjects})
+		},
+		"DeleteUser": func() error {
+			return s.DeleteUser(ctx, 1, &model.AdminAuditEntry{Action: model.AuditUserDelete})
+		},
+		"CreateProject": func() error { return s.CreateProject(ctx, &model.Project{OwnerID: 1}) },
+		"UpdateProject": func() error { return s.UpdateProject(ctx, &model.Project{ID: 1}) },
+		"DeleteProject": func() error { return s.DeleteProject(ctx, 1) },
+		"CreateProjectFromTemplate": func() error {
+			return s.CreateProjectFromTemplate(ctx, 1, &model.Project{OwnerID: 1})
+		},
+		"ImportProjectTemplate": func() error {
+			return s.ImportProjectTemplate(ctx, &model.ProjectTemplate{Todos: []model.TemplateTodo{{Title: "a"}}},
+				&model.Project{OwnerID: 1})
+		},
+		"ImportProjects": func() error {
+			return s.ImportProjects(ctx, []store.ProjectImport{{
+				Project: &model.Project{OwnerID: 1},
+				Todos:   []model.Todo{{Title: "a"}},
+				Members: []model.ProjectMember{{UserID: 2, Role: model.RoleEditor}},
+			}})
+		},
+		"SetProjectFavorite": func() error { return s.SetProjectFavorite(ctx, 1, 1, true) },
+		"DeleteProjects":     func() error { _, err := s.DeleteProjects(ctx, []int64{1, 2}, nil); return err },
+		"CreateTodo":         func() error { return s.CreateTodo(ctx, &model.Todo{ProjectID: 1, CreatedBy: &userID}) },
+		"UpdateTodo":         func() error { return s.UpdateTodo(ctx, &model.Todo{ID: 1}) },
+		"DeleteTodo":         func() error { return s.DeleteTodo(ctx, 1) },
+		"ClaimTodoReminders": func() error {
+			_, err := s.ClaimTodoReminders(ctx, time.Now(), time.Hour)
+			return err
+		},
+		"UpdateTodoWithAssignees": func() error {
+			return s.UpdateTodoWithAssignees(ctx, &model.Todo{ID: 1}, []int64{2})
+		},
+		"ReleaseTodoReminder":  func() error { return s.ReleaseTodoReminder(ctx, 1) },
+		"DeleteCompletedTodos": func() error { _, err := s.DeleteCompletedTodos(ctx, 1); return err },
+		"CreateTodoChanges": func() error {
+			return s.CreateTodoChanges(ctx, []model.TodoChange{{TodoID: 1, Field: "title"}})
+		},
+		"SetTodoAssignees":     func() error { return s.SetTodoAssignees(ctx, 1, []int64{2}) },
+		"AddTodoDependency":    func() error { return s.AddTodoDependency(ctx, 1, 2) },
+		"RemoveTodoDependency": func() error { return s.RemoveTodoDependency(ctx, 1, 2) },
+		"CountTodosByProject":  func() error { _, err := s.CountTodosByProject(ctx, 1); return err },
+		"CountIncompleteDependencies": func() error {
+			_, err := s.CountIncompleteDependencies(ctx, 1)
+			return err
+		},
+		"AddProjectMember":    func() error { return s.AddProjectMember(ctx, 1, 2, model.RoleViewer) },
+		"RemoveProjectMember": func() error { return s.RemoveProjectMember(ctx, 1, 2) },
+		"CreateProjectInvite": func() error { return s.CreateProjectInvite(ctx, &model.ProjectInvite{ProjectID: 1}) },
+		"DeleteProjectInvite": func() error { return s.DeleteProjectInvite(ctx, 1, 1) },
+		"CreateWebhook":       func() error { return s.CreateWebhook(ctx, &model.Webhook{ProjectID: 1}) },
+		"UpdateWebhook":       func() error { return s.UpdateWebhook(ctx, &model.Webhook{ID: 1, ProjectID: 1}) },
+		"DeleteWebhook":       func() error { return s.DeleteWebhook(ctx, 1, 1) },
+		"RepairTodos":         func() error { _, err := s.RepairTodos(ctx); return err },
+		"ResetData":           func() error { return s.ResetData(ctx) },
+		"SetProjectOrder":     func() error { return s.SetProjectOrder(ctx, 1, []int64{1}) },
+		"ListFeatureFlags":    func() error { _, err := s.ListFeatureFlags(ctx); return err },
+		"SetFeatureFlag":      func() error { return s.SetFeatureFlag(ctx, &model.FeatureFlag{Key: "a"}) },
+	}
+	for name, write := range writes {
+		primary.reset()
+		write() // every statement fails; only the routing matters
+		if q := replica.reset(); len(q) != 0 {
+			t.Errorf("%s sent %d statement(s) to the replica: %q", name, len(q), q)
+		}
+		if len(primary.reset()) == 0 {
+			t.Errorf("%s sent nothing to the primary", name)
+		}
+	}
+}
+
+func TestReadsUseReplica(t *testing.T) {
+	s, primary, replica := setupReplicaStore(t)
+	ctx := context.Background()
+
+	reads := map[string]func() error{
+		"GetUserByID":         func() error { _, err := s.GetUserByID(ctx, 1); return err },
+		"SearchUsers":         func() error { _, err := s.SearchUsers(ctx, store.UserSearchParams{Query: "a"}); return err },
+		"ListUsers":           func() error { _, err := s.ListUsers(ctx); return err },
+		"ListActivityForUser": func() error { _, err := s.ListActivityForUser(ctx, 1, 10, 0); return err },
+		"ListUsersFiltered": func() error {
+			_, _, err := s.ListUsersFiltered(ctx, store.UserFilter{Query: "a", Limit: 10})
+			return err
+		},
+		"ListInactiveUsers": func() error {
+			_, _, err := s.ListInactiveUsers(ctx, time.Now(), store.UserFilter{Limit: 10})
+			return err
+		},
+		"GetProjectOwner":         func() error { _, err := s.GetProjectOwner(ctx, 1); return err },
+		"ListProjectsByUser":      func() error { _, err := s.ListProjectsByUser(ctx, 1); return err },
+		"ListTemplatesByUser":     func() error { _, err := s.ListTemplatesByUser(ctx, 1); return err },
+		"ListTodosByProject":      func() error { _, err := s.ListTodosByProject(ctx, 1, store.TodoListParams{}); return err },
+		"GetTodosByIDs":           func() error { _, err := s.GetTodosByIDs(ctx, []int64{1, 2}, 1); return err },
+		"ListTodosDueOn":          func() error { _, err := s.ListTodosDueOn(ctx, 1, time.Now()); return err },
+		"ListCompletedBetween":    func() error { _, err := s.ListCompletedBetween(ctx, 1, time.Now(), time.Now()); return err },
+		"ListTodosByUser":         func() error { _, _, err := s.ListTodosByUser(ctx, 1, store.TodoListParams{}, 10, 0); return err },
+		"ListTodoHistory":         func() error { _, err := s.ListTodoHistory(ctx, 1); return err },
+		"ListTodoAssignees":       func() error { _, err := s.ListTodoAssignees(ctx, 1); return err },
+		"ListTodoDependencies":    func() error { _, err := s.ListTodoDependencies(ctx, 1); return err },
+		"ListProjectDependencies": func() error { _, err := s.ListProjectDependencies(ctx, 1); return err },
+		"ListProjectMembers":      func() error { _, err := s.ListProjectMembers(ctx, 1); return err },
+		"GetProjectMember":        func() error { _, err := s.GetProjectMember(ctx, 1, 2); return err },
+		"GetMemberRoles":          func() error { _, err := s.GetMemberRoles(ctx, 1, []int64{1, 2}); return err },
+		"GetStats":                func() error { _, err := s.GetStats(ctx); return err },
+		"GetProjectStats":         func() error { _, err := s.GetProjectStats(ctx, 1); return err },
+		"CountTodosByPriority":    func() error { _, err := s.CountTodosByPriority(ctx, 1); return err },
+		"ListAdminAudit":          func() error { _, err := s.ListAdminAudit(ctx, 10, 0); return err },
+		"GetWebhook":              func() error { _, err := s.GetWebhook(ctx, 1, 1); return err },
+		"ListWebhooks":            func() error { _, err := s.ListWebhooks(ctx, 1); return err },
+	}
+	for name, read := range reads {
+		read()
+		if len(replica.reset()) == 0 {
+			t.Errorf("%s did not query the replica", name)
+		}
+		if q := primary.reset(); len(q) != 0 {
+			t.Errorf("%s sent %d statement(s) to the primary: %q", name, len(q), q)
+		}
+	}
+}
+
+func TestWrapConflict(t *testing.T) {
+	dup := &pq.Error{Code: uniqueViolation}
+	if err := wrapConflict(fmt.Errorf("insert: %w", dup)); !errors.Is(err, store.ErrConflict) || !errors.Is(err, dup) {
+		t.Errorf("unique violation: got %v, want ErrConflict wrapping the driver error", err)
+	}
+	if err := wrapConflict(&pq.Error{Code: "23503"}); errors.Is(err, store.ErrConflict) {
+		t.Errorf("foreign key violation reported as a conflict: %v", err)
+	}
+
+	// Errors that never reach a constraint, like a failed connection, are
+	// not conflicts either.
+	s, _, _ := setupReplicaStore(t)
+	if err := s.CreateUser(context.Background(), &model.User{Email: "a@test.io"}); err == nil || errors.Is(err, store.ErrConflict) {
+		t.Errorf("CreateUser on a failing connection = %v, want a non-conflict error", err)
+	}
+}
+
+// flakyConnector fails the first failures connection attempts with err, as
+// a database that is restarting would, then connects to a recordingDriver.
+type flakyConnector struct {
+	*recordingDriver
+	failures, attempts int
+	err                error
+}
+
+func (c *flakyConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	c.attempts++
+	if c.attempts <= c.failures {
+		return nil, c.err
+	}
+	return c.recordingDriver.Connect(ctx)
+}
+
+func TestRetryConnector(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: syscall.ECONNREFUSED}
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		wantAttempts int
+		unavailable  bool
+	}{
+		{"connects", 0, nil, 1, false},
+		{"recovers", connectAttempts - 1, refused, connectAttempts, false},
+		{"connection refused", 100, refused, connectAttempts, true},
+		{"server starting", 100, &pq.Error{Code: "57P03"}, connectAttempts, true},
+		{"bad password", 100, &pq.Error{Code: "28P01"}, 1, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := &flakyConnector{recordingDriver: &recordingDriver{}, failures: tt.failures, err: tt.err}
+			db := sql.OpenDB(retryConnector{Connector: c, backoff: time.Millisecond})
+			s := newStore(db, db)
+			defer s.Close()
+
+			_, err := s.GetUserByID(context.Background(), 1)
+			if c.attempts != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", c.attempts, tt.wantAttempts)
+			}
+			if errors.Is(err, store.ErrUnavailable) != tt.unavailable {
+				t.Errorf("err = %v, want ErrUnavailable: %t", err, tt.unavailable)
+			}
+			if tt.unavailable && !errors.Is(err, tt.err) {
+				t.Errorf("err = %v, want it to wrap %v", err, tt.err)
+			}
+		})
+	}
+}
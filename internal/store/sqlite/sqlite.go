@@ -3,13 +3,17 @@ package sqlite
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/walidabualafia/bloom/internal/model"
 	"github.com/walidabualafia/bloom/internal/store"
 
-	_ "modernc.org/sqlite"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
 )
 
 const migrationSQL = `
@@ -19,6 +23,9 @@ CREATE TABLE IF NOT EXISTS users (
 	email TEXT UNIQUE NOT NULL,
 	password TEXT NOT NULL,
 	is_admin INTEGER DEFAULT 0,
+	timezone TEXT DEFAULT '',
+	locale TEXT DEFAULT '',
+	last_login_at TEXT,
 	created_at TEXT NOT NULL,
 	updated_at TEXT NOT NULL
 );
@@ -27,6 +34,12 @@ CREATE TABLE IF NOT EXISTS projects (
 	id INTEGER PRIMARY KEY AUTOINCREMENT,
 	name TEXT NOT NULL,
 	description TEXT DEFAULT '',
+	color TEXT DEFAULT '',
+	is_template INTEGER DEFAULT 0,
+	statuses TEXT,
+	hide_completed INTEGER DEFAULT 0,
+	default_member_role TEXT DEFAULT 'viewer',
+	end_date TEXT,
 	owner_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 	created_at TEXT NOT NULL,
 	updated_at TEXT NOT NULL
@@ -39,24 +52,171 @@ CREATE TABLE IF NOT EXISTS todos (
 	description TEXT DEFAULT '',
 	status TEXT DEFAULT 'pending',
 	priority TEXT DEFAULT 'medium',
+	priority_rank INTEGER,
 	deadline TEXT,
+	created_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	reminded_at TEXT,
+	reminder_offset INTEGER,
+	estimate INTEGER,
+	completed_at TEXT,
 	created_at TEXT NOT NULL,
 	updated_at TEXT NOT NULL
 );
 
+CREATE INDEX IF NOT EXISTS idx_todos_project ON todos(project_id);
+
+CREATE TABLE IF NOT EXISTS todo_assignees (
+	todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	PRIMARY KEY (todo_id, user_id)
+);
+
+CREATE TABLE IF NOT EXISTS todo_dependencies (
+	todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	depends_on_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	PRIMARY KEY (todo_id, depends_on_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_dependencies_depends_on ON todo_dependencies(depends_on_id);
+
+CREATE TABLE IF NOT EXISTS todo_history (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	todo_id INTEGER NOT NULL REFERENCES todos(id) ON DELETE CASCADE,
+	user_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	field TEXT NOT NULL,
+	old_value TEXT,
+	new_value TEXT,
+	created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_todo_history_todo ON todo_history(todo_id);
+
+CREATE TABLE IF NOT EXISTS admin_audit (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+	action TEXT NOT NULL,
+	target_id INTEGER NOT NULL,
+	changes TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+
 CREATE TABLE IF NOT EXISTS project_members (
 	project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
 	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
 	role TEXT DEFAULT 'viewer',
 	PRIMARY KEY (project_id, user_id)
 );
+
+CREATE TABLE IF NOT EXISTS project_favorites (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	created_at TEXT NOT NULL,
+	PRIMARY KEY (user_id, project_id)
+);
+
+CREATE TABLE IF NOT EXISTS project_order (
+	user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (user_id, project_id)
+);
+
+CREATE TABLE IF NOT EXISTS project_invites (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	email TEXT NOT NULL,
+	role TEXT DEFAULT 'viewer',
+	token TEXT UNIQUE NOT NULL,
+	created_at TEXT NOT NULL,
+	UNIQUE (project_id, email)
+);
+
+CREATE TABLE IF NOT EXISTS webhooks (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	project_id INTEGER NOT NULL REFERENCES projects(id) ON DELETE CASCADE,
+	url TEXT NOT NULL,
+	secret TEXT NOT NULL,
+	events TEXT NOT NULL,
+	created_at TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_project ON webhooks(project_id);
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+	key TEXT PRIMARY KEY,
+	enabled INTEGER NOT NULL DEFAULT 0,
+	updated_at TEXT NOT NULL
+);
+`
+
+// columnMigrations adds columns introduced after the initial schema to
+// databases created by older versions. Each entry is applied only if the
+// column does not already exist.
+var columnMigrations = []struct {
+	table, column, definition string
+}{
+	{"users", "timezone", "TEXT DEFAULT ''"},
+	{"users", "locale", "TEXT DEFAULT ''"},
+	{"users", "last_login_at", "TEXT"},
+	{"projects", "color", "TEXT DEFAULT ''"},
+	{"projects", "is_template", "INTEGER DEFAULT 0"},
+	{"projects", "statuses", "TEXT"},
+	{"projects", "hide_completed", "INTEGER DEFAULT 0"},
+	{"projects", "default_member_role", "TEXT DEFAULT 'viewer'"},
+	{"projects", "end_date", "TEXT"},
+	{"todos", "priority_rank", "INTEGER"},
+	{"todos", "created_by", "INTEGER REFERENCES users(id) ON DELETE SET NULL"},
+	{"todos", "assignee_id", "INTEGER REFERENCES users(id) ON DELETE SET NULL"},
+	{"todos", "reminded_at", "TEXT"},
+	{"todos", "reminder_offset", "INTEGER"},
+	{"todos", "estimate", "INTEGER"},
+	{"todos", "completed_at", "TEXT"},
+}
+
+// assigneeMigrationSQL moves assignments from the legacy single-assignee
+// todos.assignee_id column into todo_assignees. The column is cleared as it
+// is copied, so running this again is a no-op.
+const assigneeMigrationSQL = `
+INSERT OR IGNORE INTO todo_assignees (todo_id, user_id)
+	SELECT id, assignee_id FROM todos WHERE assignee_id IS NOT NULL;
+UPDATE todos SET assignee_id = NULL WHERE assignee_id IS NOT NULL;
+`
+
+// completedAtMigrationSQL dates todos completed before completed_at was
+// recorded by their last update, the best estimate there is. Completed
+// todos always have completed_at set afterwards, so running this again is a
+// no-op.
+const completedAtMigrationSQL = `
+UPDATE todos SET completed_at = updated_at WHERE status = 'completed' AND completed_at IS NULL;
 `
 
+// projectColumns lists the project columns in the order scanProject expects.
+// Queries alias projects as p and join the owner as u.
+const projectColumns = `p.id, p.name, p.description, p.color, p.is_template, p.statuses, p.hide_completed,
+	p.default_member_role, p.end_date, p.owner_id, u.username,
+	p.created_at, p.updated_at`
+
+// todoColumns lists the todo columns in the order scanTodo expects. Queries
+// select them FROM todoFrom, which resolves the creator's username.
+// Assignees live in todo_assignees and are loaded separately.
+const (
+	todoColumns = `t.id, t.project_id, t.title, t.description, t.status, t.priority, t.priority_rank, t.deadline,
+		t.reminder_offset, t.estimate, t.completed_at, t.created_by, cu.username, t.created_at, t.updated_at`
+	todoFrom = `todos t
+		LEFT JOIN users cu ON t.created_by = cu.id`
+)
+
 // scannable abstracts *sql.Row and *sql.Rows for reuse in scan helpers.
 type scannable interface {
 	Scan(dest ...any) error
 }
 
+// execer abstracts *sql.DB and *sql.Tx for helpers used in and out of
+// transactions.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
 // Store implements store.Store backed by SQLite.
 type Store struct {
 	db *sql.DB
@@ -84,17 +244,100 @@ func New(dsn string) (*Store, error) {
 	return &Store{db: db}, nil
 }
 
-func (s *Store) Migrate(_ context.Context) error {
-	_, err := s.db.Exec(migrationSQL)
+func (s *Store) Migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, migrationSQL); err != nil {
+		return err
+	}
+	for _, m := range columnMigrations {
+		if err := s.addColumnIfMissing(ctx, m.table, m.column, m.definition); err != nil {
+			return fmt.Errorf("migrate %s.%s: %w", m.table, m.column, err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, assigneeMigrationSQL); err != nil {
+		return fmt.Errorf("migrate assignees: %w", err)
+	}
+	if _, err := s.db.ExecContext(ctx, completedAtMigrationSQL); err != nil {
+		return fmt.Errorf("migrate completed_at: %w", err)
+	}
+	return nil
+}
+
+// addColumnIfMissing adds a column to a table unless it is already present.
+// SQLite has no ADD COLUMN IF NOT EXISTS, so we consult table_info first.
+func (s *Store) addColumnIfMissing(ctx context.Context, table, column, definition string) error {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, table, column,
+	).Scan(&count)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+	_, err = s.db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
 	return err
 }
 
+// resetTables lists every table, children before the tables they reference.
+var resetTables = []string{
+	"webhooks", "project_invites", "project_order", "project_favorites", "project_members", "admin_audit",
+	"todo_history", "todo_dependencies", "todo_assignees", "todos", "projects", "users", "feature_flags",
+}
+
+func (s *Store) ResetData(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	for _, table := range resetTables {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM "+table); err != nil {
+			return fmt.Errorf("reset %s: %w", table, err)
+		}
+	}
+	// Restart AUTOINCREMENT ids so reseeded rows get the same ids each time.
+	if _, err := tx.ExecContext(ctx, "DELETE FROM sqlite_sequence"); err != nil {
+		return fmt.Errorf("reset ids: %w", err)
+	}
+	return tx.Commit()
+}
+
 func (s *Store) Close() error {
 	return s.db.Close()
 }
 
+// Stats returns the connection pool statistics, such as how many
+// connections are in use.
+func (s *Store) Stats() sql.DBStats {
+	return s.db.Stats()
+}
+
 // ── Helpers ──────────────────────────────────────────────────────────────────
 
+// notFound translates sql.ErrNoRows into store.ErrNotFound so callers
+// never depend on database/sql.
+func notFound(err error) error {
+	if errors.Is(err, sql.ErrNoRows) {
+		return store.ErrNotFound
+	}
+	return err
+}
+
+// wrapConflict marks UNIQUE and PRIMARY KEY constraint failures as
+// store.ErrConflict and returns other errors unchanged.
+func wrapConflict(err error) error {
+	var serr *sqlite.Error
+	if errors.As(err, &serr) {
+		switch serr.Code() {
+		case sqlite3.SQLITE_CONSTRAINT_UNIQUE, sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY:
+			return fmt.Errorf("%w: %w", store.ErrConflict, err)
+		}
+	}
+	return err
+}
+
 func now() string {
 	return time.Now().UTC().Format(time.RFC3339)
 }
@@ -125,26 +368,40 @@ func timeToNullString(t *time.Time) sql.NullString {
 func scanUser(row scannable) (*model.User, error) {
 	var u model.User
 	var isAdmin int
+	var lastLoginAt sql.NullString
 	var createdAt, updatedAt string
-	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Password, &isAdmin, &createdAt, &updatedAt)
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Password, &isAdmin, &u.Timezone, &u.Locale, &lastLoginAt,
+		&createdAt, &updatedAt)
 	if err != nil {
 		return nil, err
 	}
 	u.IsAdmin = isAdmin != 0
+	u.LastLoginAt = parseNullableTime(lastLoginAt)
 	u.CreatedAt = parseTime(createdAt)
 	u.UpdatedAt = parseTime(updatedAt)
 	return &u, nil
 }
 
-func scanProject(row scannable) (*model.Project, error) {
+// scanProject scans projectColumns. When withFavorite is set, a trailing
+// favorited flag is scanned too.
+func scanProject(row scannable, withFavorite bool) (*model.Project, error) {
 	var p model.Project
-	var ownerName sql.NullString
+	var ownerName, endDate sql.NullString
+	var isTemplate, hideCompleted, favorited int
 	var createdAt, updatedAt string
-	err := row.Scan(&p.ID, &p.Name, &p.Description, &p.OwnerID, &ownerName, &createdAt, &updatedAt)
-	if err != nil {
+	dest := []any{&p.ID, &p.Name, &p.Description, &p.Color, &isTemplate, &p.Statuses, &hideCompleted,
+		&p.DefaultMemberRole, &endDate, &p.OwnerID, &ownerName, &createdAt, &updatedAt}
+	if withFavorite {
+		dest = append(dest, &favorited)
+	}
+	if err := row.Scan(dest...); err != nil {
 		return nil, err
 	}
+	p.IsTemplate = isTemplate != 0
+	p.HideCompleted = hideCompleted != 0
+	p.Favorited = favorited != 0
 	p.OwnerName = ownerName.String
+	p.EndDate = parseNullableTime(endDate)
 	p.CreatedAt = parseTime(createdAt)
 	p.UpdatedAt = parseTime(updatedAt)
 	return &p, nil
@@ -152,13 +409,17 @@ func scanProject(row scannable) (*model.Project, error) {
 
 func scanTodo(row scannable) (*model.Todo, error) {
 	var t model.Todo
-	var deadline sql.NullString
+	var deadline, completedAt, createdByName sql.NullString
 	var createdAt, updatedAt string
-	err := row.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &deadline, &createdAt, &updatedAt)
+	err := row.Scan(&t.ID, &t.ProjectID, &t.Title, &t.Description, &t.Status, &t.Priority, &t.PriorityRank, &deadline,
+		&t.ReminderOffset, &t.Estimate, &completedAt, &t.CreatedBy, &createdByName, &createdAt, &updatedAt)
 	if err != nil {
 		return nil, err
 	}
+	t.CreatedByName = createdByName.String
+	t.Assignees = []model.TodoAssignee{}
 	t.Deadline = parseNullableTime(deadline)
+	t.CompletedAt = parseNullableTime(completedAt)
 	t.CreatedAt = parseTime(createdAt)
 	t.UpdatedAt = parseTime(updatedAt)
 	return &t, nil
@@ -167,19 +428,43 @@ func scanTodo(row scannable) (*model.Todo, error) {
 // ── Users ────────────────────────────────────────────────────────────────────
 
 func (s *Store) CreateUser(ctx context.Context, user *model.User) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
 	ts := now()
-	result, err := s.db.ExecContext(ctx,
-		`INSERT INTO users (username, email, password, is_admin, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?)`,
-		user.Username, user.Email, user.Password, boolToInt(user.IsAdmin), ts, ts,
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO users (username, email, password, is_admin, timezone, locale, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		user.Username, user.Email, user.Password, boolToInt(user.IsAdmin), user.Timezone, user.Locale, ts, ts,
 	)
 	if err != nil {
-		return fmt.Errorf("create user: %w", err)
+		return fmt.Errorf("create user: %w", wrapConflict(err))
 	}
 	id, err := result.LastInsertId()
 	if err != nil {
 		return fmt.Errorf("last insert id: %w", err)
 	}
+
+	// Turn pending invites for this email into memberships.
+	_, err = tx.ExecContext(ctx,
+		`INSERT OR REPLACE INTO project_members (project_id, user_id, role)
+		 SELECT project_id, ?, role FROM project_invites WHERE lower(email) = lower(?)`,
+		id, user.Email,
+	)
+	if err != nil {
+		return fmt.Errorf("apply invites: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, `DELETE FROM project_invites WHERE lower(email) = lower(?)`, user.Email)
+	if err != nil {
+		return fmt.Errorf("clear invites: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
 	user.ID = id
 	user.CreatedAt = parseTime(ts)
 	user.UpdatedAt = parseTime(ts)
@@ -188,31 +473,47 @@ func (s *Store) CreateUser(ctx context.Context, user *model.User) error {
 
 func (s *Store) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, username, email, password, is_admin, created_at, updated_at
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
 		 FROM users WHERE id = ?`, id)
-	return scanUser(row)
+	v, err := scanUser(row)
+	return v, notFound(err)
 }
 
 func (s *Store) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT id, username, email, password, is_admin, created_at, updated_at
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
 		 FROM users WHERE username = ?`, username)
-	return scanUser(row)
+	v, err := scanUser(row)
+	return v, notFound(err)
+}
+
+func (s *Store) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
+		 FROM users WHERE lower(email) = lower(?)`, email)
+	v, err := scanUser(row)
+	return v, notFound(err)
 }
 
-func (s *Store) SearchUsers(ctx context.Context, query string, excludeID int64) ([]model.User, error) {
+func (s *Store) SearchUsers(ctx context.Context, params store.UserSearchParams) ([]model.User, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, username, email, password, is_admin, created_at, updated_at
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
 		 FROM users WHERE id != ? AND (username LIKE '%' || ? || '%' OR email LIKE '%' || ? || '%')
-		 ORDER BY username LIMIT 10`,
-		excludeID, query, query,
+		 AND id NOT IN (SELECT owner_id FROM projects WHERE id = ?)
+		 AND id NOT IN (SELECT user_id FROM project_members WHERE project_id = ?)
+		 ORDER BY CASE WHEN username LIKE ? THEN 0 WHEN username LIKE ? || '%' THEN 1 ELSE 2 END, username
+		 LIMIT ? OFFSET ?`,
+		params.ExcludeID, params.Query, params.Query,
+		params.ExcludeProjectID, params.ExcludeProjectID,
+		params.Query, params.Query,
+		params.Limit, params.Offset,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("search users: %w", err)
 	}
 	defer rows.Close()
 
-	var users []model.User
+	users := []model.User{}
 	for rows.Next() {
 		u, err := scanUser(rows)
 		if err != nil {
@@ -225,14 +526,14 @@ func (s *Store) SearchUsers(ctx context.Context, query string, excludeID int64)
 
 func (s *Store) ListUsers(ctx context.Context) ([]model.User, error) {
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, username, email, password, is_admin, created_at, updated_at
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
 		 FROM users ORDER BY id`)
 	if err != nil {
 		return nil, fmt.Errorf("list users: %w", err)
 	}
 	defer rows.Close()
 
-	var users []model.User
+	users := []model.User{}
 	for rows.Next() {
 		u, err := scanUser(rows)
 		if err != nil {
@@ -243,33 +544,163 @@ func (s *Store) ListUsers(ctx context.Context) ([]model.User, error) {
 	return users, rows.Err()
 }
 
-func (s *Store) UpdateUser(ctx context.Context, user *model.User) error {
+func (s *Store) CountUsers(ctx context.Context) (int, error) {
+	var count int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users`).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count users: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Store) ListUsersFiltered(ctx context.Context, filter store.UserFilter) ([]model.User, int, error) {
+	where, args := userFilterWhere(filter)
+	return s.listUserPage(ctx, where, args, `id`, filter)
+}
+
+func (s *Store) ListInactiveUsers(ctx context.Context, before time.Time, filter store.UserFilter) ([]model.User, int, error) {
+	where, args := userFilterWhere(filter)
+	where += ` AND COALESCE(last_login_at, created_at) < ?`
+	args = append(args, before.UTC().Format(time.RFC3339))
+	return s.listUserPage(ctx, where, args, `COALESCE(last_login_at, created_at), id`, filter)
+}
+
+// userFilterWhere returns the WHERE clause, and its arguments, matching
+// filter's Query and IsAdmin.
+func userFilterWhere(filter store.UserFilter) (string, []any) {
+	where := `1 = 1`
+	var args []any
+	if filter.Query != "" {
+		where += ` AND (username LIKE '%' || ? || '%' OR email LIKE '%' || ? || '%')`
+		args = append(args, filter.Query, filter.Query)
+	}
+	if filter.IsAdmin != nil {
+		where += ` AND is_admin = ?`
+		args = append(args, boolToInt(*filter.IsAdmin))
+	}
+	return where, args
+}
+
+// listUserPage returns filter's page of the users matching where, sorted by
+// orderBy, and how many match in total.
+func (s *Store) listUserPage(ctx context.Context, where string, args []any, orderBy string, filter store.UserFilter) ([]model.User, int, error) {
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM users WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count users: %w", err)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, username, email, password, is_admin, timezone, locale, last_login_at, created_at, updated_at
+		 FROM users WHERE `+where+` ORDER BY `+orderBy+` LIMIT ? OFFSET ?`,
+		append(args, filter.Limit, filter.Offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []model.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		users = append(users, *u)
+	}
+	return users, total, rows.Err()
+}
+
+func (s *Store) UpdateUser(ctx context.Context, user *model.User, audit *model.AdminAuditEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
 	ts := now()
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE users SET username = ?, email = ?, password = ?, is_admin = ?, updated_at = ?
-		 WHERE id = ?`,
-		user.Username, user.Email, user.Password, boolToInt(user.IsAdmin), ts, user.ID,
+	_, err = tx.ExecContext(ctx,
+		`UPDATE users SET username = ?, email = ?, password = ?, is_admin = ?, timezone = ?, locale = ?,
+		 updated_at = ? WHERE id = ?`,
+		user.Username, user.Email, user.Password, boolToInt(user.IsAdmin), user.Timezone, user.Locale, ts, user.ID,
 	)
 	if err != nil {
-		return fmt.Errorf("update user: %w", err)
+		return fmt.Errorf("update user: %w", wrapConflict(err))
+	}
+	if err := insertAdminAudit(ctx, tx, audit); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return err
 	}
 	user.UpdatedAt = parseTime(ts)
 	return nil
 }
 
-func (s *Store) DeleteUser(ctx context.Context, id int64) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id)
-	return err
+func (s *Store) RecordLogin(ctx context.Context, userID int64, at time.Time) error {
+	_, err := s.db.ExecContext(ctx, `UPDATE users SET last_login_at = ? WHERE id = ?`,
+		at.UTC().Format(time.RFC3339), userID)
+	if err != nil {
+		return fmt.Errorf("record login: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) DeleteUser(ctx context.Context, id int64, audit *model.AdminAuditEntry) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM users WHERE id = ?`, id); err != nil {
+		return err
+	}
+	if err := insertAdminAudit(ctx, tx, audit); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) CreateAdminAudit(ctx context.Context, audit *model.AdminAuditEntry) error {
+	return insertAdminAudit(ctx, s.db, audit)
+}
+
+// insertAdminAudit records audit using either the store's db or a
+// transaction. A nil audit is skipped.
+func insertAdminAudit(ctx context.Context, db execer, audit *model.AdminAuditEntry) error {
+	if audit == nil {
+		return nil
+	}
+	ts := now()
+	result, err := db.ExecContext(ctx,
+		`INSERT INTO admin_audit (actor_id, action, target_id, changes, created_at) VALUES (?, ?, ?, ?, ?)`,
+		audit.ActorID, audit.Action, audit.TargetID, string(audit.Changes), ts,
+	)
+	if err != nil {
+		return fmt.Errorf("create admin audit: %w", err)
+	}
+	if audit.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("last insert id: %w", err)
+	}
+	audit.CreatedAt = parseTime(ts)
+	return nil
 }
 
 // ── Projects ─────────────────────────────────────────────────────────────────
 
 func (s *Store) CreateProject(ctx context.Context, project *model.Project) error {
+	return insertProject(ctx, s.db, project)
+}
+
+// insertProject inserts project using either the store's db or a transaction.
+func insertProject(ctx context.Context, db execer, project *model.Project) error {
+	if project.DefaultMemberRole == "" {
+		project.DefaultMemberRole = model.RoleViewer
+	}
 	ts := now()
-	result, err := s.db.ExecContext(ctx,
-		`INSERT INTO projects (name, description, owner_id, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?)`,
-		project.Name, project.Description, project.OwnerID, ts, ts,
+	result, err := db.ExecContext(ctx,
+		`INSERT INTO projects (name, description, color, is_template, statuses, hide_completed, default_member_role,
+		 end_date, owner_id, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		project.Name, project.Description, project.Color, boolToInt(project.IsTemplate), project.Statuses,
+		boolToInt(project.HideCompleted), project.DefaultMemberRole, timeToNullString(project.EndDate), project.OwnerID, ts, ts,
 	)
 	if err != nil {
 		return fmt.Errorf("create project: %w", err)
@@ -286,30 +717,55 @@ func (s *Store) CreateProject(ctx context.Context, project *model.Project) error
 
 func (s *Store) GetProject(ctx context.Context, id int64) (*model.Project, error) {
 	row := s.db.QueryRowContext(ctx,
-		`SELECT p.id, p.name, p.description, p.owner_id, u.username, p.created_at, p.updated_at
+		`SELECT `+projectColumns+`
 		 FROM projects p JOIN users u ON p.owner_id = u.id
 		 WHERE p.id = ?`, id)
-	return scanProject(row)
+	v, err := scanProject(row, false)
+	return v, notFound(err)
+}
+
+func (s *Store) GetProjectOwner(ctx context.Context, projectID int64) (*model.User, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT u.id, u.username, u.email, u.password, u.is_admin, u.timezone, u.locale, u.last_login_at, u.created_at, u.updated_at
+		 FROM projects p JOIN users u ON p.owner_id = u.id
+		 WHERE p.id = ?`, projectID)
+	v, err := scanUser(row)
+	return v, notFound(err)
 }
 
 func (s *Store) ListProjectsByUser(ctx context.Context, userID int64) ([]model.Project, error) {
+	return s.listProjectsForUser(ctx, userID, false)
+}
+
+func (s *Store) ListTemplatesByUser(ctx context.Context, userID int64) ([]model.Project, error) {
+	return s.listProjectsForUser(ctx, userID, true)
+}
+
+// listProjectsForUser returns projects the user owns or is a member of,
+// optionally restricted to templates.
+func (s *Store) listProjectsForUser(ctx context.Context, userID int64, templatesOnly bool) ([]model.Project, error) {
+	filter := ""
+	if templatesOnly {
+		filter = ` AND p.is_template = 1`
+	}
 	rows, err := s.db.QueryContext(ctx,
-		`SELECT DISTINCT p.id, p.name, p.description, p.owner_id, u.username, p.created_at, p.updated_at
+		`SELECT `+projectColumns+`,
+		 EXISTS(SELECT 1 FROM project_favorites f WHERE f.project_id = p.id AND f.user_id = ?) AS favorited
 		 FROM projects p
 		 JOIN users u ON p.owner_id = u.id
-		 LEFT JOIN project_members pm ON p.id = pm.project_id
-		 WHERE p.owner_id = ? OR pm.user_id = ?
-		 ORDER BY p.updated_at DESC`,
-		userID, userID,
+		 LEFT JOIN project_order po ON po.project_id = p.id AND po.user_id = ?
+		 WHERE (p.owner_id = ? OR EXISTS(SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = ?))`+filter+`
+		 ORDER BY favorited DESC, po.position IS NULL, po.position, p.updated_at DESC`,
+		userID, userID, userID, userID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("list projects: %w", err)
 	}
 	defer rows.Close()
 
-	var projects []model.Project
+	projects := []model.Project{}
 	for rows.Next() {
-		p, err := scanProject(rows)
+		p, err := scanProject(rows, true)
 		if err != nil {
 			return nil, err
 		}
@@ -318,11 +774,23 @@ func (s *Store) ListProjectsByUser(ctx context.Context, userID int64) ([]model.P
 	return projects, rows.Err()
 }
 
+func (s *Store) CountProjectsByOwner(ctx context.Context, ownerID int64) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM projects WHERE owner_id = ?`, ownerID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count projects: %w", err)
+	}
+	return count, nil
+}
+
 func (s *Store) UpdateProject(ctx context.Context, project *model.Project) error {
 	ts := now()
 	_, err := s.db.ExecContext(ctx,
-		`UPDATE projects SET name = ?, description = ?, updated_at = ? WHERE id = ?`,
-		project.Name, project.Description, ts, project.ID,
+		`UPDATE projects SET name = ?, description = ?, color = ?, is_template = ?, statuses = ?, hide_completed = ?,
+		 default_member_role = ?, end_date = ?, updated_at = ?
+		 WHERE id = ?`,
+		project.Name, project.Description, project.Color, boolToInt(project.IsTemplate), project.Statuses,
+		boolToInt(project.HideCompleted), project.DefaultMemberRole, timeToNullString(project.EndDate), ts, project.ID,
 	)
 	if err != nil {
 		return fmt.Errorf("update project: %w", err)
@@ -331,111 +799,927 @@ func (s *Store) UpdateProject(ctx context.Context, project *model.Project) error
 	return nil
 }
 
-func (s *Store) DeleteProject(ctx context.Context, id int64) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM projects WHERE id = ?`, id)
-	return err
-}
-
-// ── Todos ────────────────────────────────────────────────────────────────────
+func (s *Store) CreateProjectFromTemplate(ctx context.Context, templateID int64, project *model.Project) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
 
-func (s *Store) CreateTodo(ctx context.Context, todo *model.Todo) error {
+	if err := insertProject(ctx, tx, project); err != nil {
+		return err
+	}
 	ts := now()
-	dl := timeToNullString(todo.Deadline)
-	result, err := s.db.ExecContext(ctx,
-		`INSERT INTO todos (project_id, title, description, status, priority, deadline, created_at, updated_at)
-		 VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
-		todo.ProjectID, todo.Title, todo.Description, todo.Status, todo.Priority, dl, ts, ts,
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO todos (project_id, title, description, status, priority, priority_rank, created_by, created_at, updated_at)
+		 SELECT ?, title, description, 'pending', priority, priority_rank, ?, ?, ?
+		 FROM todos WHERE project_id = ? ORDER BY id`,
+		project.ID, project.OwnerID, ts, ts, templateID,
 	)
 	if err != nil {
-		return fmt.Errorf("create todo: %w", err)
+		return fmt.Errorf("copy template todos: %w", err)
 	}
-	id, err := result.LastInsertId()
-	if err != nil {
-		return fmt.Errorf("last insert id: %w", err)
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
 	}
-	todo.ID = id
-	todo.CreatedAt = parseTime(ts)
-	todo.UpdatedAt = parseTime(ts)
 	return nil
 }
 
-func (s *Store) GetTodo(ctx context.Context, id int64) (*model.Todo, error) {
-	row := s.db.QueryRowContext(ctx,
-		`SELECT id, project_id, title, description, status, priority, deadline, created_at, updated_at
-		 FROM todos WHERE id = ?`, id)
-	return scanTodo(row)
-}
-
-func (s *Store) ListTodosByProject(ctx context.Context, projectID int64) ([]model.Todo, error) {
-	rows, err := s.db.QueryContext(ctx,
-		`SELECT id, project_id, title, description, status, priority, deadline, created_at, updated_at
-		 FROM todos WHERE project_id = ? ORDER BY created_at DESC`, projectID)
+func (s *Store) ImportProjectTemplate(ctx context.Context, tmpl *model.ProjectTemplate, project *model.Project) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return nil, fmt.Errorf("list todos: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
 	}
-	defer rows.Close()
+	defer tx.Rollback() //nolint:errcheck
 
-	var todos []model.Todo
-	for rows.Next() {
-		t, err := scanTodo(rows)
+	if err := insertProject(ctx, tx, project); err != nil {
+		return err
+	}
+	ts := now()
+	for _, todo := range tmpl.Todos {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO todos (project_id, title, description, status, priority, priority_rank, created_by, created_at, updated_at)
+			 VALUES (?, ?, '', 'pending', ?, ?, ?, ?, ?)`,
+			project.ID, todo.Title, todo.Priority, todo.PriorityRank, project.OwnerID, ts, ts,
+		)
 		if err != nil {
-			return nil, err
+			return fmt.Errorf("import template todo: %w", err)
 		}
-		todos = append(todos, *t)
 	}
-	return todos, rows.Err()
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	return nil
 }
 
-func (s *Store) UpdateTodo(ctx context.Context, todo *model.Todo) error {
-	ts := now()
-	dl := timeToNullString(todo.Deadline)
-	_, err := s.db.ExecContext(ctx,
-		`UPDATE todos SET title = ?, description = ?, status = ?, priority = ?, deadline = ?, updated_at = ?
-		 WHERE id = ?`,
-		todo.Title, todo.Description, todo.Status, todo.Priority, dl, ts, todo.ID,
-	)
+func (s *Store) ImportProjects(ctx context.Context, imports []store.ProjectImport) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("update todo: %w", err)
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	ts := now()
+	for _, imp := range imports {
+		project := imp.Project
+		if err := insertProject(ctx, tx, project); err != nil {
+			return err
+		}
+		for _, todo := range imp.Todos {
+			_, err := tx.ExecContext(ctx,
+				`INSERT INTO todos (project_id, title, description, status, priority, priority_rank, deadline, reminder_offset,
+				 estimate, completed_at, created_by, created_at, updated_at)
+				 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CASE WHEN ? THEN ? END, ?, ?, ?)`,
+				project.ID, todo.Title, todo.Description, todo.Status, todo.Priority, todo.PriorityRank,
+				timeToNullString(todo.Deadline), todo.ReminderOffset, todo.Estimate,
+				boolToInt(todo.Status == model.StatusCompleted), ts, project.OwnerID, ts, ts,
+			)
+			if err != nil {
+				return fmt.Errorf("import todo: %w", err)
+			}
+		}
+		for _, m := range imp.Members {
+			_, err := tx.ExecContext(ctx,
+				`INSERT OR REPLACE INTO project_members (project_id, user_id, role) VALUES (?, ?, ?)`,
+				project.ID, m.UserID, m.Role,
+			)
+			if err != nil {
+				return fmt.Errorf("import member: %w", err)
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
 	}
-	todo.UpdatedAt = parseTime(ts)
 	return nil
 }
 
-func (s *Store) DeleteTodo(ctx context.Context, id int64) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM todos WHERE id = ?`, id)
-	return err
+func (s *Store) SetProjectFavorite(ctx context.Context, userID, projectID int64, favorite bool) error {
+	var err error
+	if favorite {
+		_, err = s.db.ExecContext(ctx,
+			`INSERT OR IGNORE INTO project_favorites (user_id, project_id, created_at) VALUES (?, ?, ?)`,
+			userID, projectID, now())
+	} else {
+		_, err = s.db.ExecContext(ctx,
+			`DELETE FROM project_favorites WHERE user_id = ? AND project_id = ?`, userID, projectID)
+	}
+	if err != nil {
+		return fmt.Errorf("set favorite: %w", err)
+	}
+	return nil
 }
 
-// ── Project Members ──────────────────────────────────────────────────────────
-
-func (s *Store) AddProjectMember(ctx context.Context, projectID, userID int64, role string) error {
-	_, err := s.db.ExecContext(ctx,
-		`INSERT OR REPLACE INTO project_members (project_id, user_id, role) VALUES (?, ?, ?)`,
-		projectID, userID, role,
-	)
-	return err
-}
+func (s *Store) SetProjectOrder(ctx context.Context, userID int64, projectIDs []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
 
-func (s *Store) RemoveProjectMember(ctx context.Context, projectID, userID int64) error {
-	_, err := s.db.ExecContext(ctx,
-		`DELETE FROM project_members WHERE project_id = ? AND user_id = ?`,
-		projectID, userID,
-	)
-	return err
+	if _, err := tx.ExecContext(ctx, `DELETE FROM project_order WHERE user_id = ?`, userID); err != nil {
+		return fmt.Errorf("clear order: %w", err)
+	}
+	for i, id := range projectIDs {
+		_, err := tx.ExecContext(ctx,
+			`INSERT INTO project_order (user_id, project_id, position)
+			 SELECT ?, p.id, ? FROM projects p
+			 WHERE p.id = ? AND (p.owner_id = ? OR EXISTS(SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = ?))`,
+			userID, i, id, userID, userID)
+		if err != nil {
+			return fmt.Errorf("set order of project %d: %w", id, err)
+		}
+	}
+	return tx.Commit()
 }
 
-func (s *Store) ListProjectMembers(ctx context.Context, projectID int64) ([]model.ProjectMember, error) {
-	rows, err := s.db.QueryContext(ctx,
+func (s *Store) GetProjectStats(ctx context.Context, projectID int64) (*store.ProjectStats, error) {
+	stats := &store.ProjectStats{}
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*),
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN 1 ELSE 0 END), 0),
+			COUNT(estimate),
+			COALESCE(SUM(estimate), 0),
+			COALESCE(SUM(CASE WHEN status = 'completed' THEN estimate ELSE 0 END), 0)
+		 FROM todos WHERE project_id = ?`, projectID,
+	).Scan(&stats.TotalTodos, &stats.CompletedTodos, &stats.EstimatedTodos, &stats.TotalEstimate, &stats.CompletedEstimate)
+	if err != nil {
+		return nil, err
+	}
+	stats.TodosByStatus, err = countTodosByStatus(ctx, s.db,
+		`SELECT status, COUNT(*) FROM todos WHERE project_id = ? GROUP BY status`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (s *Store) CountTodosByPriority(ctx context.Context, projectID int64) (map[string]int, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT priority, COUNT(*) FROM todos WHERE project_id = ? GROUP BY priority`, projectID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{model.PriorityLow: 0, model.PriorityMedium: 0, model.PriorityHigh: 0}
+	for rows.Next() {
+		var priority string
+		var n int
+		if err := rows.Scan(&priority, &n); err != nil {
+			return nil, err
+		}
+		counts[priority] = n
+	}
+	return counts, rows.Err()
+}
+
+func (s *Store) DeleteProject(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM projects WHERE id = ?`, id)
+	return err
+}
+
+func (s *Store) DeleteProjects(ctx context.Context, ids []int64, audit *model.AdminAuditEntry) ([]int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	var deleted []int64
+	for _, id := range ids {
+		result, err := tx.ExecContext(ctx, `DELETE FROM projects WHERE id = ?`, id)
+		if err != nil {
+			return nil, fmt.Errorf("delete project %d: %w", id, err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			continue
+		}
+		deleted = append(deleted, id)
+		if audit != nil {
+			entry := *audit
+			entry.TargetID = id
+			if err := insertAdminAudit(ctx, tx, &entry); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	return deleted, nil
+}
+
+// ── Todos ────────────────────────────────────────────────────────────────────
+
+func (s *Store) CreateTodo(ctx context.Context, todo *model.Todo) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	ts := now()
+	dl := timeToNullString(todo.Deadline)
+	result, err := tx.ExecContext(ctx,
+		`INSERT INTO todos (project_id, title, description, status, priority, priority_rank, deadline, reminder_offset,
+		 estimate, completed_at, created_by, created_at, updated_at)
+		 VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, CASE WHEN ? THEN ? END, ?, ?, ?)`,
+		todo.ProjectID, todo.Title, todo.Description, todo.Status, todo.Priority, todo.PriorityRank, dl, todo.ReminderOffset,
+		todo.Estimate, boolToInt(todo.Status == model.StatusCompleted), ts, todo.CreatedBy, ts, ts,
+	)
+	if err != nil {
+		return fmt.Errorf("create todo: %w", err)
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("last insert id: %w", err)
+	}
+	if err := insertAssignees(ctx, tx, id, assigneeIDs(todo.Assignees)); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	todo.ID = id
+	todo.CreatedAt = parseTime(ts)
+	todo.UpdatedAt = parseTime(ts)
+	todo.CompletedAt = nil
+	if todo.Status == model.StatusCompleted {
+		todo.CompletedAt = &todo.CreatedAt
+	}
+	if todo.CreatedByName, err = s.usernameOf(ctx, todo.CreatedBy); err != nil {
+		return fmt.Errorf("resolve creator: %w", err)
+	}
+	if todo.Assignees, err = s.ListTodoAssignees(ctx, id); err != nil {
+		return fmt.Errorf("resolve assignees: %w", err)
+	}
+	return nil
+}
+
+func (s *Store) GetTodo(ctx context.Context, id int64) (*model.Todo, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT `+todoColumns+` FROM `+todoFrom+` WHERE t.id = ?`, id)
+	todo, err := scanTodo(row)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	if todo.Assignees, err = s.ListTodoAssignees(ctx, id); err != nil {
+		return nil, fmt.Errorf("list assignees: %w", err)
+	}
+	return todo, nil
+}
+
+func (s *Store) GetTodosByIDs(ctx context.Context, ids []int64, userID int64) ([]model.Todo, error) {
+	if len(ids) == 0 {
+		return []model.Todo{}, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	args := make([]any, 0, len(ids)+2)
+	for _, id := range ids {
+		args = append(args, id)
+	}
+	args = append(args, userID, userID)
+	todos, err := s.queryTodos(ctx,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 WHERE t.id IN (`+placeholders+`)
+		 AND t.project_id IN (
+			SELECT id FROM projects WHERE owner_id = ?
+			UNION
+			SELECT project_id FROM project_members WHERE user_id = ?
+		 )
+		 ORDER BY t.id`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get todos: %w", err)
+	}
+	return todos, nil
+}
+
+// queryTodos runs a SELECT of todoColumns and loads each todo's assignees.
+func (s *Store) queryTodos(ctx context.Context, query string, args ...any) ([]model.Todo, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	todos := []model.Todo{}
+	for rows.Next() {
+		t, err := scanTodo(rows)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, *t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if err := s.attachAssignees(ctx, todos); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+// attachAssignees fills in Assignees for a batch of todos with one query.
+func (s *Store) attachAssignees(ctx context.Context, todos []model.Todo) error {
+	if len(todos) == 0 {
+		return nil
+	}
+	byID := make(map[int64]*model.Todo, len(todos))
+	placeholders := make([]string, len(todos))
+	args := make([]any, len(todos))
+	for i := range todos {
+		byID[todos[i].ID] = &todos[i]
+		placeholders[i] = "?"
+		args[i] = todos[i].ID
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT ta.todo_id, ta.user_id, u.username
+		 FROM todo_assignees ta JOIN users u ON ta.user_id = u.id
+		 WHERE ta.todo_id IN (`+strings.Join(placeholders, ", ")+`)
+		 ORDER BY u.username`, args...)
+	if err != nil {
+		return fmt.Errorf("list assignees: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var todoID int64
+		var a model.TodoAssignee
+		if err := rows.Scan(&todoID, &a.UserID, &a.Username); err != nil {
+			return err
+		}
+		t := byID[todoID]
+		t.Assignees = append(t.Assignees, a)
+	}
+	return rows.Err()
+}
+
+func (s *Store) ListTodoAssignees(ctx context.Context, todoID int64) ([]model.TodoAssignee, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT ta.user_id, u.username
+		 FROM todo_assignees ta JOIN users u ON ta.user_id = u.id
+		 WHERE ta.todo_id = ?
+		 ORDER BY u.username`, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("list assignees: %w", err)
+	}
+	defer rows.Close()
+
+	assignees := []model.TodoAssignee{}
+	for rows.Next() {
+		var a model.TodoAssignee
+		if err := rows.Scan(&a.UserID, &a.Username); err != nil {
+			return nil, err
+		}
+		assignees = append(assignees, a)
+	}
+	return assignees, rows.Err()
+}
+
+func (s *Store) SetTodoAssignees(ctx context.Context, todoID int64, userIDs []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if err := replaceAssignees(ctx, tx, todoID, userIDs); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func replaceAssignees(ctx context.Context, db execer, todoID int64, userIDs []int64) error {
+	if _, err := db.ExecContext(ctx, `DELETE FROM todo_assignees WHERE todo_id = ?`, todoID); err != nil {
+		return fmt.Errorf("clear assignees: %w", err)
+	}
+	return insertAssignees(ctx, db, todoID, userIDs)
+}
+
+func insertAssignees(ctx context.Context, db execer, todoID int64, userIDs []int64) error {
+	for _, userID := range userIDs {
+		if _, err := db.ExecContext(ctx,
+			`INSERT OR IGNORE INTO todo_assignees (todo_id, user_id) VALUES (?, ?)`, todoID, userID,
+		); err != nil {
+			return fmt.Errorf("add assignee %d: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+func assigneeIDs(assignees []model.TodoAssignee) []int64 {
+	ids := make([]int64, len(assignees))
+	for i, a := range assignees {
+		ids[i] = a.UserID
+	}
+	return ids
+}
+
+func (s *Store) ListTodosByProject(ctx context.Context, projectID int64, params store.TodoListParams) ([]model.Todo, error) {
+	where, args := todoFilters(`t.project_id = ?`, []any{projectID}, params)
+	todos, err := s.queryTodos(ctx,
+		`SELECT `+todoColumns+` FROM `+todoFrom+` WHERE `+where+` ORDER BY `+todoOrderBy(params.Sort), args...)
+	if err != nil {
+		return nil, fmt.Errorf("list todos: %w", err)
+	}
+	return todos, nil
+}
+
+func (s *Store) ListTodosByUser(ctx context.Context, userID int64, params store.TodoListParams, limit, offset int) ([]model.Todo, int, error) {
+	where, args := todoFilters(`t.project_id IN (
+			SELECT id FROM projects WHERE owner_id = ?
+			UNION
+			SELECT project_id FROM project_members WHERE user_id = ?
+		 )`, []any{userID, userID}, params)
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos t WHERE `+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count todos: %w", err)
+	}
+	todos, err := s.queryTodos(ctx,
+		`SELECT `+todoColumns+` FROM `+todoFrom+` WHERE `+where+`
+		 ORDER BY t.project_id, `+todoOrderBy(params.Sort)+`, t.id LIMIT ? OFFSET ?`,
+		append(args, limit, offset)...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("list todos: %w", err)
+	}
+	return todos, total, nil
+}
+
+// todoFilters appends the conditions for params to where and args.
+func todoFilters(where string, args []any, params store.TodoListParams) (string, []any) {
+	if params.Status != "" {
+		where += ` AND t.status = ?`
+		args = append(args, params.Status)
+	}
+	if params.HideCompleted && params.Status == "" {
+		where += ` AND t.status <> ?`
+		args = append(args, model.StatusCompleted)
+	}
+	if params.Priority != "" {
+		where += ` AND t.priority = ?`
+		args = append(args, params.Priority)
+	}
+	if params.Query != "" {
+		where += ` AND (t.title LIKE '%' || ? || '%' OR t.description LIKE '%' || ? || '%')`
+		args = append(args, params.Query, params.Query)
+	}
+	if params.Unassigned {
+		where += ` AND NOT EXISTS (SELECT 1 FROM todo_assignees ta WHERE ta.todo_id = t.id)`
+	} else if params.AssigneeID != 0 {
+		where += ` AND EXISTS (SELECT 1 FROM todo_assignees ta WHERE ta.todo_id = t.id AND ta.user_id = ?)`
+		args = append(args, params.AssigneeID)
+	}
+	if params.UpdatedSince != nil {
+		where += ` AND t.updated_at > ?`
+		args = append(args, params.UpdatedSince.UTC().Format(time.RFC3339))
+	}
+	return where, args
+}
+
+func (s *Store) ListTodosDueOn(ctx context.Context, userID int64, day time.Time) ([]model.Todo, error) {
+	// Deadlines are stored as UTC RFC3339 strings, which sort chronologically.
+	start := day.UTC().Format(time.RFC3339)
+	end := day.AddDate(0, 0, 1).UTC().Format(time.RFC3339)
+	todos, err := s.queryTodos(ctx,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 WHERE t.deadline >= ? AND t.deadline < ? AND t.status != 'completed'
+		 AND t.project_id IN (
+			SELECT id FROM projects WHERE owner_id = ?
+			UNION
+			SELECT project_id FROM project_members WHERE user_id = ?
+		 )
+		 ORDER BY t.deadline, t.id`,
+		start, end, userID, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list todos due: %w", err)
+	}
+	return todos, nil
+}
+
+func (s *Store) CountTodosByProject(ctx context.Context, projectID int64) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM todos WHERE project_id = ?`, projectID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count todos: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Store) ListCompletedBetween(ctx context.Context, projectID int64, from, to time.Time) ([]model.Todo, error) {
+	todos, err := s.queryTodos(ctx,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 WHERE t.project_id = ? AND t.status = 'completed' AND t.completed_at >= ? AND t.completed_at < ?
+		 ORDER BY t.completed_at, t.id`,
+		projectID, from.UTC().Format(time.RFC3339), to.UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list completed todos: %w", err)
+	}
+	return todos, nil
+}
+
+func (s *Store) UpdateTodo(ctx context.Context, todo *model.Todo) error {
+	return s.updateTodo(ctx, todo, false, nil)
+}
+
+func (s *Store) UpdateTodoWithAssignees(ctx context.Context, todo *model.Todo, assigneeIDs []int64) error {
+	return s.updateTodo(ctx, todo, true, assigneeIDs)
+}
+
+// updateTodo saves todo's fields and, if setAssignees, replaces its
+// assignees with assigneeIDs in the same transaction.
+func (s *Store) updateTodo(ctx context.Context, todo *model.Todo, setAssignees bool, assigneeIDs []int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	ts := now()
+	dl := timeToNullString(todo.Deadline)
+	// A new deadline or reminder offset gets a fresh reminder. A todo that
+	// stays completed keeps its completion time.
+	var completedAt sql.NullString
+	err = tx.QueryRowContext(ctx,
+		`UPDATE todos SET title = ?, description = ?, status = ?, priority = ?, priority_rank = ?, deadline = ?,
+		 reminder_offset = ?, estimate = ?,
+		 reminded_at = CASE WHEN deadline IS ? AND reminder_offset IS ? THEN reminded_at END,
+		 completed_at = CASE WHEN ? THEN COALESCE(completed_at, ?) END, updated_at = ?
+		 WHERE id = ? RETURNING completed_at`,
+		todo.Title, todo.Description, todo.Status, todo.Priority, todo.PriorityRank, dl, todo.ReminderOffset, todo.Estimate,
+		dl, todo.ReminderOffset, boolToInt(todo.Status == model.StatusCompleted), ts, ts, todo.ID,
+	).Scan(&completedAt)
+	found := err == nil
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("update todo: %w", err)
+	}
+	if setAssignees && found {
+		if err := replaceAssignees(ctx, tx, todo.ID, assigneeIDs); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+	todo.CompletedAt = parseNullableTime(completedAt)
+	todo.UpdatedAt = parseTime(ts)
+	if setAssignees && found {
+		if todo.Assignees, err = s.ListTodoAssignees(ctx, todo.ID); err != nil {
+			return fmt.Errorf("resolve assignees: %w", err)
+		}
+	}
+	return nil
+}
+
+func (s *Store) ClaimTodoReminders(ctx context.Context, at time.Time, defaultOffset time.Duration) ([]model.Todo, error) {
+	ts := at.UTC().Format(time.RFC3339)
+	rows, err := s.db.QueryContext(ctx,
+		`UPDATE todos SET reminded_at = ?
+		 WHERE reminded_at IS NULL AND status != 'completed' AND deadline > ?
+		 AND datetime(deadline, printf('-%d seconds', COALESCE(reminder_offset, ?))) <= datetime(?)
+		 RETURNING id`,
+		now(), ts, int64(defaultOffset/time.Second), ts,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("claim reminders: %w", err)
+	}
+	defer rows.Close()
+
+	var placeholders []string
+	var ids []any
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		placeholders = append(placeholders, "?")
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	return s.queryTodos(ctx,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 WHERE t.id IN (`+strings.Join(placeholders, ", ")+`)
+		 ORDER BY t.deadline, t.id`, ids...)
+}
+
+func (s *Store) ReleaseTodoReminder(ctx context.Context, id int64) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE todos SET reminded_at = NULL WHERE id = ?`, id); err != nil {
+		return fmt.Errorf("release reminder: %w", err)
+	}
+	return nil
+}
+
+// dependencyReachesSQL reports whether the second argument can be reached
+// from the first by following depends_on edges, counting the start itself.
+const dependencyReachesSQL = `WITH RECURSIVE reach(id) AS (
+	SELECT ?
+	UNION
+	SELECT d.depends_on_id FROM todo_dependencies d JOIN reach r ON d.todo_id = r.id
+)
+SELECT EXISTS(SELECT 1 FROM reach WHERE id = ?)`
+
+func (s *Store) AddTodoDependency(ctx context.Context, todoID, dependsOnID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	// The new edge closes a cycle if todoID is already reachable from
+	// dependsOnID.
+	var cycle bool
+	if err := tx.QueryRowContext(ctx, dependencyReachesSQL, dependsOnID, todoID).Scan(&cycle); err != nil {
+		return fmt.Errorf("check dependency cycle: %w", err)
+	}
+	if cycle {
+		return store.ErrDependencyCycle
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT OR IGNORE INTO todo_dependencies (todo_id, depends_on_id) VALUES (?, ?)`, todoID, dependsOnID)
+	if err != nil {
+		return fmt.Errorf("add dependency: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *Store) RemoveTodoDependency(ctx context.Context, todoID, dependsOnID int64) error {
+	_, err := s.db.ExecContext(ctx,
+		`DELETE FROM todo_dependencies WHERE todo_id = ? AND depends_on_id = ?`, todoID, dependsOnID)
+	return err
+}
+
+func (s *Store) ListTodoDependencies(ctx context.Context, todoID int64) ([]model.Todo, error) {
+	todos, err := s.queryTodos(ctx,
+		`SELECT `+todoColumns+` FROM `+todoFrom+`
+		 JOIN todo_dependencies d ON d.depends_on_id = t.id
+		 WHERE d.todo_id = ?
+		 ORDER BY t.created_at, t.id`, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("list dependencies: %w", err)
+	}
+	return todos, nil
+}
+
+func (s *Store) ListProjectDependencies(ctx context.Context, projectID int64) ([]model.TodoDependency, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT d.todo_id, d.depends_on_id
+		 FROM todo_dependencies d JOIN todos t ON d.todo_id = t.id
+		 WHERE t.project_id = ?
+		 ORDER BY d.todo_id, d.depends_on_id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list dependencies: %w", err)
+	}
+	defer rows.Close()
+
+	deps := []model.TodoDependency{}
+	for rows.Next() {
+		var d model.TodoDependency
+		if err := rows.Scan(&d.TodoID, &d.DependsOnID); err != nil {
+			return nil, err
+		}
+		deps = append(deps, d)
+	}
+	return deps, rows.Err()
+}
+
+func (s *Store) CountIncompleteDependencies(ctx context.Context, todoID int64) (int, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM todo_dependencies d JOIN todos t ON d.depends_on_id = t.id
+		 WHERE d.todo_id = ? AND t.status != 'completed'`, todoID).Scan(&n)
+	return n, err
+}
+
+func (s *Store) DeleteTodo(ctx context.Context, id int64) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM todos WHERE id = ?`, id)
+	return err
+}
+
+func (s *Store) DeleteCompletedTodos(ctx context.Context, projectID int64) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM todos WHERE project_id = ? AND status = 'completed'`, projectID)
+	if err != nil {
+		return 0, fmt.Errorf("delete completed todos: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func (s *Store) CreateTodoChanges(ctx context.Context, changes []model.TodoChange) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	ts := now()
+	for i := range changes {
+		c := &changes[i]
+		result, err := tx.ExecContext(ctx,
+			`INSERT INTO todo_history (todo_id, user_id, field, old_value, new_value, created_at)
+			 VALUES (?, ?, ?, ?, ?, ?)`,
+			c.TodoID, c.UserID, c.Field, c.OldValue, c.NewValue, ts,
+		)
+		if err != nil {
+			return fmt.Errorf("create todo change: %w", err)
+		}
+		if c.ID, err = result.LastInsertId(); err != nil {
+			return fmt.Errorf("last insert id: %w", err)
+		}
+		c.CreatedAt = parseTime(ts)
+	}
+	return tx.Commit()
+}
+
+func (s *Store) ListTodoHistory(ctx context.Context, todoID int64) ([]model.TodoChange, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT h.id, h.todo_id, h.user_id, u.username, h.field, h.old_value, h.new_value, h.created_at
+		 FROM todo_history h
+		 LEFT JOIN users u ON h.user_id = u.id
+		 WHERE h.todo_id = ?
+		 ORDER BY h.created_at, h.id`, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("list todo history: %w", err)
+	}
+	defer rows.Close()
+
+	changes := []model.TodoChange{}
+	for rows.Next() {
+		var c model.TodoChange
+		var username sql.NullString
+		var createdAt string
+		if err := rows.Scan(&c.ID, &c.TodoID, &c.UserID, &username, &c.Field, &c.OldValue, &c.NewValue, &createdAt); err != nil {
+			return nil, err
+		}
+		c.Username = username.String
+		c.CreatedAt = parseTime(createdAt)
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+func (s *Store) ListActivityForUser(ctx context.Context, userID int64, limit int, before int64) ([]model.Activity, error) {
+	// IDs grow with time, so ordering by them is newest first and makes
+	// them a stable cursor.
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT h.id, h.todo_id, h.user_id, u.username, h.field, h.old_value, h.new_value, h.created_at,
+		 t.project_id, t.title
+		 FROM todo_history h
+		 JOIN todos t ON h.todo_id = t.id
+		 JOIN projects p ON t.project_id = p.id
+		 LEFT JOIN users u ON h.user_id = u.id
+		 WHERE (p.owner_id = ? OR EXISTS(SELECT 1 FROM project_members pm WHERE pm.project_id = p.id AND pm.user_id = ?))
+		 AND (? <= 0 OR h.id < ?)
+		 ORDER BY h.id DESC LIMIT ?`,
+		userID, userID, before, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list activity: %w", err)
+	}
+	defer rows.Close()
+
+	activity := []model.Activity{}
+	for rows.Next() {
+		var a model.Activity
+		var username sql.NullString
+		var createdAt string
+		if err := rows.Scan(&a.ID, &a.TodoID, &a.UserID, &username, &a.Field, &a.OldValue, &a.NewValue, &createdAt,
+			&a.ProjectID, &a.TodoTitle); err != nil {
+			return nil, err
+		}
+		a.Username = username.String
+		a.CreatedAt = parseTime(createdAt)
+		activity = append(activity, a)
+	}
+	return activity, rows.Err()
+}
+
+// ── Project Members ──────────────────────────────────────────────────────────
+
+func (s *Store) AddProjectMember(ctx context.Context, projectID, userID int64, role string) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT OR REPLACE INTO project_members (project_id, user_id, role) VALUES (?, ?, ?)`,
+		projectID, userID, role,
+	)
+	return err
+}
+
+func (s *Store) RemoveProjectMember(ctx context.Context, projectID, userID int64) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM project_members WHERE project_id = ? AND user_id = ?`,
+		projectID, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM project_favorites WHERE project_id = ? AND user_id = ?`,
+		projectID, userID,
+	); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM project_order WHERE project_id = ? AND user_id = ?`,
+		projectID, userID,
+	); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *Store) CountProjectMembers(ctx context.Context, projectID int64) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx,
+		`SELECT (SELECT COUNT(*) FROM project_members WHERE project_id = ?)
+		      + (SELECT COUNT(*) FROM project_invites WHERE project_id = ?)`,
+		projectID, projectID,
+	).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count members: %w", err)
+	}
+	return count, nil
+}
+
+func (s *Store) ListProjectMembers(ctx context.Context, projectID int64) ([]model.ProjectMember, error) {
+	rows, err := s.db.QueryContext(ctx,
 		`SELECT pm.project_id, pm.user_id, u.username, pm.role
 		 FROM project_members pm
 		 JOIN users u ON pm.user_id = u.id
-		 WHERE pm.project_id = ?`, projectID)
+		 WHERE pm.project_id = ?
+		 ORDER BY `+memberRoleOrder+`, u.username`, projectID)
 	if err != nil {
 		return nil, fmt.Errorf("list members: %w", err)
 	}
 	defer rows.Close()
 
-	var members []model.ProjectMember
+	members := []model.ProjectMember{}
+	for rows.Next() {
+		var m model.ProjectMember
+		if err := rows.Scan(&m.ProjectID, &m.UserID, &m.Username, &m.Role); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+func (s *Store) GetProjectMember(ctx context.Context, projectID, userID int64) (*model.ProjectMember, error) {
+	var m model.ProjectMember
+	err := s.db.QueryRowContext(ctx,
+		`SELECT p.id, p.owner_id, u.username, 'owner'
+		 FROM projects p
+		 JOIN users u ON p.owner_id = u.id
+		 WHERE p.id = ? AND p.owner_id = ?
+		 UNION ALL
+		 SELECT pm.project_id, pm.user_id, u.username, pm.role
+		 FROM project_members pm
+		 JOIN users u ON pm.user_id = u.id
+		 WHERE pm.project_id = ? AND pm.user_id = ?`,
+		projectID, userID, projectID, userID,
+	).Scan(&m.ProjectID, &m.UserID, &m.Username, &m.Role)
+	if err != nil {
+		return nil, notFound(err)
+	}
+	return &m, nil
+}
+
+func (s *Store) ListMembershipsByUser(ctx context.Context, userID int64) ([]model.ProjectMember, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT p.id, p.owner_id, u.username, 'owner'
+		 FROM projects p
+		 JOIN users u ON p.owner_id = u.id
+		 WHERE p.owner_id = ?
+		 UNION ALL
+		 SELECT pm.project_id, pm.user_id, u.username, pm.role
+		 FROM project_members pm
+		 JOIN users u ON pm.user_id = u.id
+		 WHERE pm.user_id = ?
+		 ORDER BY 1`,
+		userID, userID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("list memberships: %w", err)
+	}
+	defer rows.Close()
+
+	members := []model.ProjectMember{}
 	for rows.Next() {
 		var m model.ProjectMember
 		if err := rows.Scan(&m.ProjectID, &m.UserID, &m.Username, &m.Role); err != nil {
@@ -446,6 +1730,48 @@ func (s *Store) ListProjectMembers(ctx context.Context, projectID int64) ([]mode
 	return members, rows.Err()
 }
 
+func (s *Store) ProjectExists(ctx context.Context, id int64) (bool, error) {
+	var exists int
+	err := s.db.QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM projects WHERE id = ?)`, id).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	return exists == 1, nil
+}
+
+func (s *Store) GetMemberRoles(ctx context.Context, userID int64, projectIDs []int64) (map[int64]string, error) {
+	roles := make(map[int64]string)
+	if len(projectIDs) == 0 {
+		return roles, nil
+	}
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(projectIDs)), ", ")
+	args := []any{userID, userID}
+	for _, id := range projectIDs {
+		args = append(args, id)
+	}
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT p.id, CASE WHEN p.owner_id = ? THEN 'owner' ELSE pm.role END
+		 FROM projects p
+		 LEFT JOIN project_members pm ON pm.project_id = p.id AND pm.user_id = ?
+		 WHERE p.id IN (`+placeholders+`)`, args...)
+	if err != nil {
+		return nil, fmt.Errorf("get member roles: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id int64
+		var role sql.NullString
+		if err := rows.Scan(&id, &role); err != nil {
+			return nil, err
+		}
+		if role.Valid {
+			roles[id] = role.String
+		}
+	}
+	return roles, rows.Err()
+}
+
 func (s *Store) IsProjectMember(ctx context.Context, projectID, userID int64) (bool, error) {
 	var exists int
 	err := s.db.QueryRowContext(ctx,
@@ -466,7 +1792,7 @@ func (s *Store) GetMemberRole(ctx context.Context, projectID, userID int64) (str
 	var ownerID int64
 	err := s.db.QueryRowContext(ctx, `SELECT owner_id FROM projects WHERE id = ?`, projectID).Scan(&ownerID)
 	if err != nil {
-		return "", err
+		return "", notFound(err)
 	}
 	if ownerID == userID {
 		return "owner", nil
@@ -484,6 +1810,160 @@ func (s *Store) GetMemberRole(ctx context.Context, projectID, userID int64) (str
 	return role, nil
 }
 
+// ── Project Invites ──────────────────────────────────────────────────────────
+
+func (s *Store) CreateProjectInvite(ctx context.Context, invite *model.ProjectInvite) error {
+	ts := now()
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO project_invites (project_id, email, role, token, created_at)
+		 VALUES (?, ?, ?, ?, ?)`,
+		invite.ProjectID, invite.Email, invite.Role, invite.Token, ts,
+	)
+	if err != nil {
+		return fmt.Errorf("create invite: %w", wrapConflict(err))
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("last insert id: %w", err)
+	}
+	invite.ID = id
+	invite.CreatedAt = parseTime(ts)
+	return nil
+}
+
+func (s *Store) ListProjectInvites(ctx context.Context, projectID int64) ([]model.ProjectInvite, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, project_id, email, role, token, created_at
+		 FROM project_invites WHERE project_id = ? ORDER BY created_at`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list invites: %w", err)
+	}
+	defer rows.Close()
+
+	invites := []model.ProjectInvite{}
+	for rows.Next() {
+		var inv model.ProjectInvite
+		var createdAt string
+		if err := rows.Scan(&inv.ID, &inv.ProjectID, &inv.Email, &inv.Role, &inv.Token, &createdAt); err != nil {
+			return nil, err
+		}
+		inv.CreatedAt = parseTime(createdAt)
+		invites = append(invites, inv)
+	}
+	return invites, rows.Err()
+}
+
+// DeleteProjectInvite returns store.ErrNotFound if the invite does not exist in
+// the given project.
+func (s *Store) DeleteProjectInvite(ctx context.Context, projectID, inviteID int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM project_invites WHERE id = ? AND project_id = ?`, inviteID, projectID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// ── Webhooks ─────────────────────────────────────────────────────────────────
+
+func (s *Store) CreateWebhook(ctx context.Context, hook *model.Webhook) error {
+	ts := now()
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO webhooks (project_id, url, secret, events, created_at) VALUES (?, ?, ?, ?, ?)`,
+		hook.ProjectID, hook.URL, hook.Secret, strings.Join(hook.Events, ","), ts,
+	)
+	if err != nil {
+		return fmt.Errorf("create webhook: %w", err)
+	}
+	if hook.ID, err = result.LastInsertId(); err != nil {
+		return fmt.Errorf("last insert id: %w", err)
+	}
+	hook.CreatedAt = parseTime(ts)
+	return nil
+}
+
+func (s *Store) GetWebhook(ctx context.Context, projectID, webhookID int64) (*model.Webhook, error) {
+	row := s.db.QueryRowContext(ctx,
+		`SELECT id, project_id, url, secret, events, created_at
+		 FROM webhooks WHERE id = ? AND project_id = ?`, webhookID, projectID)
+	v, err := scanWebhook(row)
+	return v, notFound(err)
+}
+
+func (s *Store) ListWebhooks(ctx context.Context, projectID int64) ([]model.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT id, project_id, url, secret, events, created_at
+		 FROM webhooks WHERE project_id = ? ORDER BY id`, projectID)
+	if err != nil {
+		return nil, fmt.Errorf("list webhooks: %w", err)
+	}
+	defer rows.Close()
+
+	hooks := []model.Webhook{}
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return nil, err
+		}
+		hooks = append(hooks, *hook)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *Store) UpdateWebhook(ctx context.Context, hook *model.Webhook) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE webhooks SET url = ?, secret = ?, events = ? WHERE id = ? AND project_id = ?`,
+		hook.URL, hook.Secret, strings.Join(hook.Events, ","), hook.ID, hook.ProjectID,
+	)
+	if err != nil {
+		return fmt.Errorf("update webhook: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) DeleteWebhook(ctx context.Context, projectID, webhookID int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM webhooks WHERE id = ? AND project_id = ?`, webhookID, projectID)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrNotFound
+	}
+	return nil
+}
+
+// scanWebhook reads a webhook row. Events are stored comma-separated, which
+// is safe since event names never contain commas.
+func scanWebhook(row scannable) (*model.Webhook, error) {
+	var hook model.Webhook
+	var events, createdAt string
+	if err := row.Scan(&hook.ID, &hook.ProjectID, &hook.URL, &hook.Secret, &events, &createdAt); err != nil {
+		return nil, err
+	}
+	hook.Events = strings.Split(events, ",")
+	hook.CreatedAt = parseTime(createdAt)
+	return &hook, nil
+}
+
 // ── Admin ────────────────────────────────────────────────────────────────────
 
 func (s *Store) GetStats(ctx context.Context) (*store.Stats, error) {
@@ -504,9 +1984,159 @@ func (s *Store) GetStats(ctx context.Context) (*store.Stats, error) {
 	if err != nil {
 		return nil, err
 	}
+	if stats.TodosByStatus, err = countTodosByStatus(ctx, s.db, `SELECT status, COUNT(*) FROM todos GROUP BY status`); err != nil {
+		return nil, err
+	}
 	return stats, nil
 }
 
+func (s *Store) RepairTodos(ctx context.Context) (*store.TodoRepair, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("begin tx: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	rows, err := tx.QueryContext(ctx,
+		`SELECT t.id, t.status, t.priority, p.statuses FROM todos t JOIN projects p ON t.project_id = p.id`)
+	if err != nil {
+		return nil, fmt.Errorf("scan todos: %w", err)
+	}
+	defer rows.Close()
+
+	type fix struct {
+		id               int64
+		status, priority string
+	}
+	var fixes []fix
+	repair := &store.TodoRepair{}
+	for rows.Next() {
+		var f fix
+		var project model.Project
+		if err := rows.Scan(&f.id, &f.status, &f.priority, &project.Statuses); err != nil {
+			return nil, err
+		}
+		broken := false
+		if !project.AllowsStatus(f.status) {
+			f.status = model.StatusPending
+			repair.Statuses++
+			broken = true
+		}
+		if !model.ValidPriority(f.priority) {
+			f.priority = model.PriorityMedium
+			repair.Priorities++
+			broken = true
+		}
+		if broken {
+			fixes = append(fixes, f)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	ts := now()
+	for _, f := range fixes {
+		_, err := tx.ExecContext(ctx, `UPDATE todos SET status = ?, priority = ?,
+			 completed_at = CASE WHEN status = ? THEN completed_at END, updated_at = ? WHERE id = ?`,
+			f.status, f.priority, f.status, ts, f.id)
+		if err != nil {
+			return nil, fmt.Errorf("repair todo %d: %w", f.id, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("commit: %w", err)
+	}
+	repair.Todos = len(fixes)
+	return repair, nil
+}
+
+// countTodosByStatus runs query, which must select status and count pairs.
+func countTodosByStatus(ctx context.Context, db *sql.DB, query string, args ...any) (map[string]int, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := map[string]int{}
+	for rows.Next() {
+		var status string
+		var n int
+		if err := rows.Scan(&status, &n); err != nil {
+			return nil, err
+		}
+		counts[status] = n
+	}
+	return counts, rows.Err()
+}
+
+func (s *Store) ListAdminAudit(ctx context.Context, limit, offset int) ([]model.AdminAuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT a.id, a.actor_id, u.username, a.action, a.target_id, a.changes, a.created_at
+		 FROM admin_audit a
+		 LEFT JOIN users u ON a.actor_id = u.id
+		 ORDER BY a.created_at DESC, a.id DESC
+		 LIMIT ? OFFSET ?`, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("list admin audit: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []model.AdminAuditEntry{}
+	for rows.Next() {
+		var e model.AdminAuditEntry
+		var actorName sql.NullString
+		var changes, createdAt string
+		if err := rows.Scan(&e.ID, &e.ActorID, &actorName, &e.Action, &e.TargetID, &changes, &createdAt); err != nil {
+			return nil, err
+		}
+		e.ActorName = actorName.String
+		e.Changes = json.RawMessage(changes)
+		e.CreatedAt = parseTime(createdAt)
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// ── Feature flags ────────────────────────────────────────────────────────────
+
+func (s *Store) ListFeatureFlags(ctx context.Context) ([]model.FeatureFlag, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT key, enabled, updated_at FROM feature_flags ORDER BY key`)
+	if err != nil {
+		return nil, fmt.Errorf("list feature flags: %w", err)
+	}
+	defer rows.Close()
+
+	flags := []model.FeatureFlag{}
+	for rows.Next() {
+		var f model.FeatureFlag
+		var enabled int
+		var updatedAt string
+		if err := rows.Scan(&f.Key, &enabled, &updatedAt); err != nil {
+			return nil, err
+		}
+		f.Enabled = enabled != 0
+		f.UpdatedAt = parseTime(updatedAt)
+		flags = append(flags, f)
+	}
+	return flags, rows.Err()
+}
+
+func (s *Store) SetFeatureFlag(ctx context.Context, flag *model.FeatureFlag) error {
+	ts := now()
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO feature_flags (key, enabled, updated_at) VALUES (?, ?, ?)
+		 ON CONFLICT (key) DO UPDATE SET enabled = excluded.enabled, updated_at = excluded.updated_at`,
+		flag.Key, boolToInt(flag.Enabled), ts)
+	if err != nil {
+		return fmt.Errorf("set feature flag: %w", err)
+	}
+	flag.UpdatedAt = parseTime(ts)
+	return nil
+}
+
 // ── Utilities ────────────────────────────────────────────────────────────────
 
 func boolToInt(b bool) int {
@@ -515,3 +2145,31 @@ func boolToInt(b bool) int {
 	}
 	return 0
 }
+
+// memberRoleOrder sorts project_members rows by role, most privileged first.
+// Owners are not stored in project_members but are ranked for completeness.
+const memberRoleOrder = `CASE pm.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END`
+
+// todoOrderBy maps a store.TodoListParams sort to an ORDER BY clause.
+func todoOrderBy(sort string) string {
+	switch sort {
+	case store.TodoSortPriority:
+		return `CASE t.priority WHEN 'high' THEN 0 WHEN 'medium' THEN 1 ELSE 2 END,
+			t.priority_rank NULLS LAST, t.created_at DESC`
+	case store.TodoSortPriorityRank:
+		return `t.priority_rank NULLS LAST, t.created_at DESC`
+	default:
+		return `t.created_at DESC`
+	}
+}
+
+// usernameOf returns the username for an optional user id, or "" when id is
+// nil.
+func (s *Store) usernameOf(ctx context.Context, id *int64) (string, error) {
+	if id == nil {
+		return "", nil
+	}
+	var username string
+	err := s.db.QueryRowContext(ctx, `SELECT username FROM users WHERE id = ?`, *id).Scan(&username)
+	return username, err
+}
@@ -2,9 +2,17 @@ package sqlite_test
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
 	"github.com/walidabualafia/bloom/internal/store/sqlite"
 )
 
@@ -81,6 +89,48 @@ func TestListUsers(t *testing.T) {
 	}
 }
 
+func TestUniqueViolationsAreConflicts(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	alice := &model.User{Username: "alice", Email: "alice@example.com", Password: "pw"}
+	if err := s.CreateUser(ctx, alice); err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	bob := &model.User{Username: "bob", Email: "bob@example.com", Password: "pw"}
+	s.CreateUser(ctx, bob)
+
+	err := s.CreateUser(ctx, &model.User{Username: "alice", Email: "other@example.com", Password: "pw"})
+	if !errors.Is(err, store.ErrConflict) {
+		t.Errorf("duplicate username: got %v, want ErrConflict", err)
+	}
+	bob.Email = alice.Email
+	if err := s.UpdateUser(ctx, bob, nil); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("update to taken email: got %v, want ErrConflict", err)
+	}
+
+	project := &model.Project{Name: "P", OwnerID: alice.ID}
+	s.CreateProject(ctx, project)
+	invite := func(token string) error {
+		return s.CreateProjectInvite(ctx, &model.ProjectInvite{ProjectID: project.ID, Email: "new@example.com", Role: "viewer", Token: token})
+	}
+	invite("t1")
+	if err := invite("t2"); !errors.Is(err, store.ErrConflict) {
+		t.Errorf("duplicate invite: got %v, want ErrConflict", err)
+	}
+
+	// Other failures must not be mistaken for conflicts.
+	err = s.CreateProjectInvite(ctx, &model.ProjectInvite{ProjectID: 9999, Email: "x@example.com", Role: "viewer", Token: "t3"})
+	if err == nil || errors.Is(err, store.ErrConflict) {
+		t.Errorf("invite to missing project: got %v, want a non-conflict error", err)
+	}
+	s.Close()
+	err = s.CreateUser(ctx, &model.User{Username: "carol", Email: "carol@example.com", Password: "pw"})
+	if err == nil || errors.Is(err, store.ErrConflict) {
+		t.Errorf("create user on closed store: got %v, want a non-conflict error", err)
+	}
+}
+
 func TestUpdateUser(t *testing.T) {
 	s := setupTestStore(t)
 	ctx := context.Background()
@@ -90,7 +140,7 @@ func TestUpdateUser(t *testing.T) {
 
 	user.Username = "alice2"
 	user.IsAdmin = true
-	if err := s.UpdateUser(ctx, user); err != nil {
+	if err := s.UpdateUser(ctx, user, nil); err != nil {
 		t.Fatalf("update user: %v", err)
 	}
 
@@ -110,7 +160,7 @@ func TestDeleteUser(t *testing.T) {
 	user := &model.User{Username: "alice", Email: "alice@example.com", Password: "pw"}
 	s.CreateUser(ctx, user)
 
-	if err := s.DeleteUser(ctx, user.ID); err != nil {
+	if err := s.DeleteUser(ctx, user.ID, nil); err != nil {
 		t.Fatalf("delete user: %v", err)
 	}
 
@@ -120,6 +170,142 @@ func TestDeleteUser(t *testing.T) {
 	}
 }
 
+func TestMissingRowsAreErrNotFound(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+
+	calls := map[string]func() error{
+		"GetUserByID":       func() error { _, err := s.GetUserByID(ctx, 9999); return err },
+		"GetUserByUsername": func() error { _, err := s.GetUserByUsername(ctx, "nobody"); return err },
+		"GetUserByEmail":    func() error { _, err := s.GetUserByEmail(ctx, "nobody@example.com"); return err },
+		"GetProject":        func() error { _, err := s.GetProject(ctx, 9999); return err },
+		"GetProjectOwner":   func() error { _, err := s.GetProjectOwner(ctx, 9999); return err },
+		"GetTodo":           func() error { _, err := s.GetTodo(ctx, 9999); return err },
+		"GetProjectMember":  func() error { _, err := s.GetProjectMember(ctx, project.ID, 9999); return err },
+		"GetMemberRole":     func() error { _, err := s.GetMemberRole(ctx, 9999, owner.ID); return err },
+		"GetWebhook":        func() error { _, err := s.GetWebhook(ctx, project.ID, 9999); return err },
+		"UpdateWebhook": func() error {
+			return s.UpdateWebhook(ctx, &model.Webhook{ID: 9999, ProjectID: project.ID, URL: "https://example.com", Events: []string{}})
+		},
+		"DeleteWebhook":       func() error { return s.DeleteWebhook(ctx, project.ID, 9999) },
+		"DeleteProjectInvite": func() error { return s.DeleteProjectInvite(ctx, project.ID, 9999) },
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, store.ErrNotFound) {
+			t.Errorf("%s: got %v, want ErrNotFound", name, err)
+		}
+	}
+}
+
+func TestGetProjectOwner(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	member := &model.User{Username: "member", Email: "member@example.com", Password: "pw"}
+	s.CreateUser(ctx, member)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	s.AddProjectMember(ctx, project.ID, member.ID, model.RoleEditor)
+
+	got, err := s.GetProjectOwner(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("get owner: %v", err)
+	}
+	if got.ID != owner.ID || got.Username != "owner" || got.Email != "owner@example.com" || got.Password != "pw" {
+		t.Errorf("owner = %+v, want %+v", got, owner)
+	}
+}
+
+func TestGetTodosByIDs(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	alice := &model.User{Username: "alice", Email: "alice@example.com", Password: "pw"}
+	s.CreateUser(ctx, alice)
+	bob := &model.User{Username: "bob", Email: "bob@example.com", Password: "pw"}
+	s.CreateUser(ctx, bob)
+	own := &model.Project{Name: "Own", OwnerID: alice.ID}
+	s.CreateProject(ctx, own)
+	shared := &model.Project{Name: "Shared", OwnerID: bob.ID}
+	s.CreateProject(ctx, shared)
+	s.AddProjectMember(ctx, shared.ID, alice.ID, model.RoleViewer)
+	private := &model.Project{Name: "Private", OwnerID: bob.ID}
+	s.CreateProject(ctx, private)
+
+	var ids []int64
+	for _, p := range []*model.Project{own, shared, private} {
+		todo := &model.Todo{ProjectID: p.ID, Title: p.Name, Status: model.StatusPending, Priority: model.PriorityMedium, CreatedBy: &p.OwnerID}
+		if err := s.CreateTodo(ctx, todo); err != nil {
+			t.Fatalf("create todo: %v", err)
+		}
+		ids = append(ids, todo.ID)
+	}
+	s.SetTodoAssignees(ctx, ids[0], []int64{alice.ID})
+
+	todos, err := s.GetTodosByIDs(ctx, []int64{ids[2], ids[1], ids[0], 9999}, alice.ID)
+	if err != nil {
+		t.Fatalf("get todos: %v", err)
+	}
+	if len(todos) != 2 || todos[0].ID != ids[0] || todos[1].ID != ids[1] {
+		t.Fatalf("got %+v, want the todos in Own and Shared", todos)
+	}
+	if len(todos[0].Assignees) != 1 || todos[0].Assignees[0].UserID != alice.ID {
+		t.Errorf("assignees = %+v, want alice", todos[0].Assignees)
+	}
+
+	todos, err = s.GetTodosByIDs(ctx, nil, alice.ID)
+	if err != nil || todos == nil || len(todos) != 0 {
+		t.Errorf("empty ids: got %v, %v; want an empty slice", todos, err)
+	}
+}
+
+func TestAdminAudit(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	admin := &model.User{Username: "admin", Email: "admin@example.com", Password: "pw", IsAdmin: true}
+	s.CreateUser(ctx, admin)
+	user := &model.User{Username: "alice", Email: "alice@example.com", Password: "pw"}
+	s.CreateUser(ctx, user)
+
+	user.Username = "alice2"
+	update := &model.AdminAuditEntry{ActorID: &admin.ID, Action: model.AuditUserUpdate, TargetID: user.ID,
+		Changes: []byte(`{"username":{"old":"alice","new":"alice2"}}`)}
+	if err := s.UpdateUser(ctx, user, update); err != nil {
+		t.Fatalf("update user: %v", err)
+	}
+	if update.ID == 0 || update.CreatedAt.IsZero() {
+		t.Errorf("audit entry not filled in: %+v", update)
+	}
+	del := &model.AdminAuditEntry{ActorID: &admin.ID, Action: model.AuditUserDelete, TargetID: user.ID,
+		Changes: []byte(`{"username":"alice2"}`)}
+	if err := s.DeleteUser(ctx, user.ID, del); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+
+	entries, err := s.ListAdminAudit(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("list audit: %v", err)
+	}
+	if len(entries) != 2 || entries[0].ID != del.ID || entries[1].ID != update.ID {
+		t.Fatalf("entries = %+v, want delete then update", entries)
+	}
+	if entries[0].ActorName != "admin" || entries[0].TargetID != user.ID || string(entries[0].Changes) != `{"username":"alice2"}` {
+		t.Errorf("delete entry = %+v", entries[0])
+	}
+	if page, _ := s.ListAdminAudit(ctx, 1, 1); len(page) != 1 || page[0].ID != update.ID {
+		t.Errorf("second page = %+v, want the update", page)
+	}
+
+}
+
 func TestProjectCRUD(t *testing.T) {
 	s := setupTestStore(t)
 	ctx := context.Background()
@@ -219,7 +405,7 @@ func TestTodoCRUD(t *testing.T) {
 	}
 
 	// List
-	todos, err := s.ListTodosByProject(ctx, project.ID)
+	todos, err := s.ListTodosByProject(ctx, project.ID, store.TodoListParams{})
 	if err != nil {
 		t.Fatalf("list todos: %v", err)
 	}
@@ -335,6 +521,7 @@ func TestGetStats(t *testing.T) {
 
 	s.CreateTodo(ctx, &model.Todo{ProjectID: project.ID, Title: "T1", Status: "pending", Priority: "low"})
 	s.CreateTodo(ctx, &model.Todo{ProjectID: project.ID, Title: "T2", Status: "completed", Priority: "medium"})
+	s.CreateTodo(ctx, &model.Todo{ProjectID: project.ID, Title: "T3", Status: "blocked", Priority: "medium"})
 
 	stats, err := s.GetStats(ctx)
 	if err != nil {
@@ -346,10 +533,812 @@ func TestGetStats(t *testing.T) {
 	if stats.TotalProjects != 1 {
 		t.Errorf("total_projects = %d, want 1", stats.TotalProjects)
 	}
-	if stats.TotalTodos != 2 {
-		t.Errorf("total_todos = %d, want 2", stats.TotalTodos)
+	if stats.TotalTodos != 3 {
+		t.Errorf("total_todos = %d, want 3", stats.TotalTodos)
 	}
 	if stats.CompletedTodos != 1 {
 		t.Errorf("completed_todos = %d, want 1", stats.CompletedTodos)
 	}
+	want := map[string]int{"pending": 1, "completed": 1, "blocked": 1}
+	if !maps.Equal(stats.TodosByStatus, want) {
+		t.Errorf("todos_by_status = %v, want %v", stats.TodosByStatus, want)
+	}
+}
+
+func TestListMembershipsByUser(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	alice := &model.User{Username: "alice", Email: "alice@example.com", Password: "pw"}
+	s.CreateUser(ctx, alice)
+	bob := &model.User{Username: "bob", Email: "bob@example.com", Password: "pw"}
+	s.CreateUser(ctx, bob)
+
+	owned := &model.Project{Name: "Owned", OwnerID: alice.ID}
+	s.CreateProject(ctx, owned)
+	shared := &model.Project{Name: "Shared", OwnerID: bob.ID}
+	s.CreateProject(ctx, shared)
+	s.AddProjectMember(ctx, shared.ID, alice.ID, "editor")
+
+	memberships, err := s.ListMembershipsByUser(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("list memberships: %v", err)
+	}
+	if len(memberships) != 2 {
+		t.Fatalf("got %d memberships, want 2", len(memberships))
+	}
+
+	roles := map[int64]string{}
+	for _, m := range memberships {
+		if m.UserID != alice.ID {
+			t.Errorf("user_id = %d, want %d", m.UserID, alice.ID)
+		}
+		roles[m.ProjectID] = m.Role
+	}
+	if roles[owned.ID] != "owner" {
+		t.Errorf("role on owned project = %q, want owner", roles[owned.ID])
+	}
+	if roles[shared.ID] != "editor" {
+		t.Errorf("role on shared project = %q, want editor", roles[shared.ID])
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	var users []*model.User
+	for _, name := range []string{"alice", "amy", "anna", "bob"} {
+		u := &model.User{Username: name, Email: name + "@test.io", Password: "pw"}
+		s.CreateUser(ctx, u)
+		users = append(users, u)
+	}
+	alice, amy := users[0], users[1]
+
+	project := &model.Project{Name: "P1", OwnerID: alice.ID}
+	s.CreateProject(ctx, project)
+	s.AddProjectMember(ctx, project.ID, amy.ID, "viewer")
+
+	// Paging
+	page, err := s.SearchUsers(ctx, store.UserSearchParams{Query: "a", Limit: 2, Offset: 1})
+	if err != nil {
+		t.Fatalf("search users: %v", err)
+	}
+	if len(page) != 2 || page[0].Username != "amy" || page[1].Username != "anna" {
+		t.Errorf("got %v, want [amy anna]", usernames(page))
+	}
+
+	// Excluding existing project members (owner included)
+	got, err := s.SearchUsers(ctx, store.UserSearchParams{Query: "a", ExcludeProjectID: project.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("search users: %v", err)
+	}
+	if len(got) != 1 || got[0].Username != "anna" {
+		t.Errorf("got %v, want [anna]", usernames(got))
+	}
+}
+
+func TestSearchUsersRanking(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	for _, name := range []string{"legal-team", "alice", "al", "bob", "alan", "coral"} {
+		s.CreateUser(ctx, &model.User{Username: name, Email: name + "@test.io", Password: "pw"})
+	}
+
+	got, err := s.SearchUsers(ctx, store.UserSearchParams{Query: "al", Limit: 10})
+	if err != nil {
+		t.Fatalf("search users: %v", err)
+	}
+	want := []string{"al", "alan", "alice", "coral", "legal-team"}
+	if !slices.Equal(usernames(got), want) {
+		t.Errorf("got %v, want %v", usernames(got), want)
+	}
+}
+
+func TestListUsersFiltered(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	for _, u := range []struct {
+		name  string
+		admin bool
+	}{{"alice", true}, {"amy", false}, {"anna", true}, {"bob", true}, {"carl", false}} {
+		s.CreateUser(ctx, &model.User{Username: u.name, Email: u.name + "@test.io", Password: "pw", IsAdmin: u.admin})
+	}
+	yes, no := true, false
+
+	tests := []struct {
+		name      string
+		filter    store.UserFilter
+		want      []string
+		wantTotal int
+	}{
+		{"no filter", store.UserFilter{Limit: 10}, []string{"alice", "amy", "anna", "bob", "carl"}, 5},
+		{"query", store.UserFilter{Query: "AN", Limit: 10}, []string{"anna"}, 1},
+		{"query matches email", store.UserFilter{Query: "test.io", Limit: 2}, []string{"alice", "amy"}, 5},
+		{"admins", store.UserFilter{IsAdmin: &yes, Limit: 10}, []string{"alice", "anna", "bob"}, 3},
+		{"non-admins", store.UserFilter{IsAdmin: &no, Limit: 10}, []string{"amy", "carl"}, 2},
+		{"query and admin", store.UserFilter{Query: "a", IsAdmin: &yes, Limit: 10}, []string{"alice", "anna"}, 2},
+		{"query, admin and page", store.UserFilter{Query: "a", IsAdmin: &yes, Limit: 1, Offset: 1}, []string{"anna"}, 2},
+		{"query and non-admin", store.UserFilter{Query: "a", IsAdmin: &no, Limit: 10}, []string{"amy", "carl"}, 2},
+	}
+	for _, tt := range tests {
+		users, total, err := s.ListUsersFiltered(ctx, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := usernames(users); !slices.Equal(got, tt.want) || total != tt.wantTotal {
+			t.Errorf("%s: got %v (total %d), want %v (total %d)", tt.name, got, total, tt.want, tt.wantTotal)
+		}
+	}
+}
+
+func TestListInactiveUsers(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	cutoff := time.Now().Add(-90 * 24 * time.Hour).Truncate(time.Second)
+	for _, u := range []struct {
+		name      string
+		admin     bool
+		lastLogin time.Time
+	}{
+		{"alice", false, cutoff.Add(-time.Second)},
+		{"bob", false, cutoff},
+		{"carl", false, cutoff.Add(time.Second)},
+		{"dave", false, time.Time{}}, // never logged in
+		{"erin", true, cutoff.Add(-time.Hour)},
+	} {
+		user := &model.User{Username: u.name, Email: u.name + "@test.io", Password: "pw", IsAdmin: u.admin}
+		if err := s.CreateUser(ctx, user); err != nil {
+			t.Fatal(err)
+		}
+		if !u.lastLogin.IsZero() {
+			if err := s.RecordLogin(ctx, user.ID, u.lastLogin); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	no := false
+
+	tests := []struct {
+		name      string
+		before    time.Time
+		filter    store.UserFilter
+		want      []string
+		wantTotal int
+	}{
+		{"before cutoff", cutoff, store.UserFilter{Limit: 10}, []string{"erin", "alice"}, 2},
+		{"without admins", cutoff, store.UserFilter{IsAdmin: &no, Limit: 10}, []string{"alice"}, 1},
+		{"just after cutoff", cutoff.Add(time.Second), store.UserFilter{Limit: 10}, []string{"erin", "alice", "bob"}, 3},
+		{"paged", cutoff.Add(2 * time.Second), store.UserFilter{Limit: 2, Offset: 1}, []string{"alice", "bob"}, 4},
+		// Users who never logged in count from when they were created.
+		{"future", time.Now().Add(time.Hour), store.UserFilter{IsAdmin: &no, Limit: 10}, []string{"alice", "bob", "carl", "dave"}, 4},
+	}
+	for _, tt := range tests {
+		users, total, err := s.ListInactiveUsers(ctx, tt.before, tt.filter)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := usernames(users); !slices.Equal(got, tt.want) || total != tt.wantTotal {
+			t.Errorf("%s: got %v (total %d), want %v (total %d)", tt.name, got, total, tt.want, tt.wantTotal)
+		}
+	}
+
+	user, err := s.GetUserByUsername(ctx, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.LastLoginAt == nil || !user.LastLoginAt.Equal(cutoff.Add(-time.Second)) {
+		t.Errorf("LastLoginAt = %v, want %v", user.LastLoginAt, cutoff.Add(-time.Second))
+	}
+}
+
+func usernames(users []model.User) []string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Username
+	}
+	return names
+}
+
+func TestListTodosSortedByPriorityRank(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P1", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+
+	rank := func(n int) *int { return &n }
+	s.CreateTodo(ctx, &model.Todo{ProjectID: project.ID, Title: "unranked", Status: "pending", Priority: "high"})
+	s.CreateTodo(ctx, &model.Todo{ProjectID: project.ID, Title: "second", Status: "pending", Priority: "low", PriorityRank: rank(20)})
+	s.CreateTodo(ctx, &model.Todo{ProjectID: project.ID, Title: "first", Status: "pending", Priority: "low", PriorityRank: rank(10)})
+
+	todos, err := s.ListTodosByProject(ctx, project.ID, store.TodoListParams{Sort: store.TodoSortPriorityRank})
+	if err != nil {
+		t.Fatalf("list todos: %v", err)
+	}
+	var titles []string
+	for _, td := range todos {
+		titles = append(titles, td.Title)
+	}
+	if len(titles) != 3 || titles[0] != "first" || titles[1] != "second" || titles[2] != "unranked" {
+		t.Errorf("order = %v, want [first second unranked]", titles)
+	}
+	if todos[0].PriorityRank == nil || *todos[0].PriorityRank != 10 {
+		t.Errorf("priority_rank = %v, want 10", todos[0].PriorityRank)
+	}
+
+	todos, _ = s.ListTodosByProject(ctx, project.ID, store.TodoListParams{Sort: store.TodoSortPriority})
+	if todos[0].Title != "unranked" || todos[1].Title != "first" {
+		t.Errorf("priority order starts with %q, %q; want unranked, first", todos[0].Title, todos[1].Title)
+	}
+}
+
+func TestCountTodosByPriority(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P1", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	other := &model.Project{Name: "P2", OwnerID: owner.ID}
+	s.CreateProject(ctx, other)
+
+	for _, p := range []string{"high", "high", "low"} {
+		s.CreateTodo(ctx, &model.Todo{ProjectID: project.ID, Title: p, Status: "pending", Priority: p})
+	}
+	s.CreateTodo(ctx, &model.Todo{ProjectID: other.ID, Title: "elsewhere", Status: "pending", Priority: "medium"})
+
+	counts, err := s.CountTodosByPriority(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("count: %v", err)
+	}
+	want := map[string]int{"low": 1, "medium": 0, "high": 2}
+	if !maps.Equal(counts, want) {
+		t.Errorf("counts = %v, want %v", counts, want)
+	}
+}
+
+func TestListsReturnEmptySlices(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	users, err := s.ListUsers(ctx)
+	if err != nil || users == nil {
+		t.Errorf("ListUsers on empty store = %#v, %v; want empty slice", users, err)
+	}
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	loner := &model.User{Username: "loner", Email: "loner@example.com", Password: "pw"}
+	s.CreateUser(ctx, loner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	todo := &model.Todo{ProjectID: project.ID, Title: "T", Status: "pending", Priority: "medium"}
+	s.CreateTodo(ctx, todo)
+	empty := &model.Project{Name: "Empty", OwnerID: owner.ID}
+	s.CreateProject(ctx, empty)
+
+	isAdmin := true
+	lists := map[string]func() (any, bool, error){
+		"SearchUsers": func() (any, bool, error) {
+			v, err := s.SearchUsers(ctx, store.UserSearchParams{Query: "nobody"})
+			return v, v != nil, err
+		},
+		"ListUsersFiltered": func() (any, bool, error) {
+			v, _, err := s.ListUsersFiltered(ctx, store.UserFilter{IsAdmin: &isAdmin})
+			return v, v != nil, err
+		},
+		"ListProjectsByUser": func() (any, bool, error) {
+			v, err := s.ListProjectsByUser(ctx, loner.ID)
+			return v, v != nil, err
+		},
+		"ListTemplatesByUser": func() (any, bool, error) {
+			v, err := s.ListTemplatesByUser(ctx, owner.ID)
+			return v, v != nil, err
+		},
+		"ListTodosByProject": func() (any, bool, error) {
+			v, err := s.ListTodosByProject(ctx, empty.ID, store.TodoListParams{})
+			return v, v != nil, err
+		},
+		"ListTodosByUser": func() (any, bool, error) {
+			v, _, err := s.ListTodosByUser(ctx, loner.ID, store.TodoListParams{}, 10, 0)
+			return v, v != nil, err
+		},
+		"ListTodosDueOn": func() (any, bool, error) {
+			v, err := s.ListTodosDueOn(ctx, owner.ID, time.Now())
+			return v, v != nil, err
+		},
+		"ListTodoHistory": func() (any, bool, error) {
+			v, err := s.ListTodoHistory(ctx, todo.ID)
+			return v, v != nil, err
+		},
+		"ListTodoAssignees": func() (any, bool, error) {
+			v, err := s.ListTodoAssignees(ctx, todo.ID)
+			return v, v != nil, err
+		},
+		"ListTodoDependencies": func() (any, bool, error) {
+			v, err := s.ListTodoDependencies(ctx, todo.ID)
+			return v, v != nil, err
+		},
+		"ListMembershipsByUser": func() (any, bool, error) {
+			v, err := s.ListMembershipsByUser(ctx, loner.ID)
+			return v, v != nil, err
+		},
+		"ListProjectInvites": func() (any, bool, error) {
+			v, err := s.ListProjectInvites(ctx, project.ID)
+			return v, v != nil, err
+		},
+		"ListWebhooks": func() (any, bool, error) {
+			v, err := s.ListWebhooks(ctx, project.ID)
+			return v, v != nil, err
+		},
+		"ListAdminAudit": func() (any, bool, error) {
+			v, err := s.ListAdminAudit(ctx, 10, 0)
+			return v, v != nil, err
+		},
+	}
+	for name, list := range lists {
+		v, ok, err := list()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if !ok {
+			t.Errorf("%s = %#v, want empty non-nil slice", name, v)
+		}
+	}
+}
+
+func TestDeleteProjects(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	var ids []int64
+	for _, name := range []string{"One", "Two", "Three"} {
+		p := &model.Project{Name: name, OwnerID: owner.ID}
+		if err := s.CreateProject(ctx, p); err != nil {
+			t.Fatalf("create project: %v", err)
+		}
+		ids = append(ids, p.ID)
+	}
+
+	audit := &model.AdminAuditEntry{ActorID: &owner.ID, Action: model.AuditProjectDelete, Changes: []byte(`{}`)}
+	deleted, err := s.DeleteProjects(ctx, []int64{ids[0], ids[2], 9999}, audit)
+	if err != nil {
+		t.Fatalf("delete projects: %v", err)
+	}
+	if len(deleted) != 2 || deleted[0] != ids[0] || deleted[1] != ids[2] {
+		t.Errorf("deleted = %v, want [%d %d]", deleted, ids[0], ids[2])
+	}
+	entries, err := s.ListAdminAudit(ctx, 10, 0)
+	if err != nil {
+		t.Fatalf("list audit: %v", err)
+	}
+	if len(entries) != 2 || entries[0].TargetID != ids[2] || entries[1].TargetID != ids[0] {
+		t.Errorf("audit entries = %+v, want one per deleted project", entries)
+	}
+
+	projects, _ := s.ListProjectsByUser(ctx, owner.ID)
+	if len(projects) != 1 || projects[0].ID != ids[1] {
+		t.Errorf("remaining projects = %+v, want only %d", projects, ids[1])
+	}
+}
+
+func TestListProjectMembersOrder(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "Shared", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+
+	for _, m := range []struct{ name, role string }{
+		{"zoe", model.RoleViewer},
+		{"mike", model.RoleEditor},
+		{"anna", model.RoleViewer},
+		{"bert", model.RoleEditor},
+	} {
+		u := &model.User{Username: m.name, Email: m.name + "@example.com", Password: "pw"}
+		s.CreateUser(ctx, u)
+		if err := s.AddProjectMember(ctx, project.ID, u.ID, m.role); err != nil {
+			t.Fatalf("add member %s: %v", m.name, err)
+		}
+	}
+
+	members, err := s.ListProjectMembers(ctx, project.ID)
+	if err != nil {
+		t.Fatalf("list members: %v", err)
+	}
+	var got []string
+	for _, m := range members {
+		got = append(got, m.Username+":"+m.Role)
+	}
+	want := []string{"bert:editor", "mike:editor", "anna:viewer", "zoe:viewer"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("members = %v, want %v", got, want)
+	}
+}
+
+func TestTodoHistoryDeletedWithTodo(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	todo := &model.Todo{ProjectID: project.ID, Title: "T", Status: model.StatusPending, Priority: model.PriorityMedium}
+	s.CreateTodo(ctx, todo)
+
+	old, updated := model.StatusPending, model.StatusCompleted
+	changes := []model.TodoChange{{TodoID: todo.ID, UserID: &owner.ID, Field: "status", OldValue: &old, NewValue: &updated}}
+	if err := s.CreateTodoChanges(ctx, changes); err != nil {
+		t.Fatalf("create changes: %v", err)
+	}
+	if changes[0].ID == 0 {
+		t.Error("expected change ID to be set")
+	}
+
+	history, err := s.ListTodoHistory(ctx, todo.ID)
+	if err != nil {
+		t.Fatalf("list history: %v", err)
+	}
+	if len(history) != 1 || history[0].Username != "owner" || *history[0].NewValue != model.StatusCompleted {
+		t.Fatalf("history = %+v", history)
+	}
+
+	if err := s.DeleteTodo(ctx, todo.ID); err != nil {
+		t.Fatalf("delete todo: %v", err)
+	}
+	history, _ = s.ListTodoHistory(ctx, todo.ID)
+	if len(history) != 0 {
+		t.Errorf("got %d history rows after delete, want 0", len(history))
+	}
+}
+
+func TestMigrateMovesLegacyAssignee(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bloom.db")
+	s, err := sqlite.New(path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	todo := &model.Todo{ProjectID: project.ID, Title: "T", Status: model.StatusPending, Priority: model.PriorityMedium}
+	s.CreateTodo(ctx, todo)
+
+	// Simulate a row written before todos could have several assignees.
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open raw: %v", err)
+	}
+	defer raw.Close()
+	if _, err := raw.ExecContext(ctx, `UPDATE todos SET assignee_id = ? WHERE id = ?`, owner.ID, todo.ID); err != nil {
+		t.Fatalf("set legacy assignee: %v", err)
+	}
+
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("re-migrate: %v", err)
+	}
+	assignees, err := s.ListTodoAssignees(ctx, todo.ID)
+	if err != nil {
+		t.Fatalf("list assignees: %v", err)
+	}
+	if len(assignees) != 1 || assignees[0].UserID != owner.ID || assignees[0].Username != "owner" {
+		t.Errorf("assignees = %+v, want owner", assignees)
+	}
+}
+
+func TestRepairTodos(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	plain := &model.Project{Name: "Plain", OwnerID: owner.ID}
+	s.CreateProject(ctx, plain)
+	custom := &model.Project{Name: "Custom", OwnerID: owner.ID, Statuses: model.StatusList{"pending", "review", "completed"}}
+	s.CreateProject(ctx, custom)
+
+	// The store does not validate, so drifted rows can be written directly.
+	todos := []*model.Todo{
+		{ProjectID: plain.ID, Title: "ok", Status: "in_progress", Priority: "high"},
+		{ProjectID: plain.ID, Title: "bad status", Status: "review", Priority: "low"},
+		{ProjectID: custom.ID, Title: "bad both", Status: "in_progress", Priority: "urgent"},
+		{ProjectID: custom.ID, Title: "custom ok", Status: "review", Priority: "medium"},
+	}
+	for _, td := range todos {
+		if err := s.CreateTodo(ctx, td); err != nil {
+			t.Fatalf("create todo: %v", err)
+		}
+	}
+
+	repair, err := s.RepairTodos(ctx)
+	if err != nil {
+		t.Fatalf("repair: %v", err)
+	}
+	if want := (store.TodoRepair{Todos: 2, Statuses: 2, Priorities: 1}); *repair != want {
+		t.Errorf("repair = %+v, want %+v", *repair, want)
+	}
+	for i, want := range []struct{ status, priority string }{
+		{"in_progress", "high"}, {"pending", "low"}, {"pending", "medium"}, {"review", "medium"},
+	} {
+		got, _ := s.GetTodo(ctx, todos[i].ID)
+		if got.Status != want.status || got.Priority != want.priority {
+			t.Errorf("%s: status/priority = %s/%s, want %s/%s", got.Title, got.Status, got.Priority, want.status, want.priority)
+		}
+	}
+
+	if repair, err = s.RepairTodos(ctx); err != nil || repair.Todos != 0 {
+		t.Errorf("second repair = %+v, %v; want nothing fixed", repair, err)
+	}
+}
+
+func TestClaimTodoReminders(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+
+	now := time.Now()
+	soon, later := now.Add(30*time.Minute), now.Add(3*time.Hour)
+	newTodo := func(title, status string, deadline *time.Time) *model.Todo {
+		todo := &model.Todo{ProjectID: project.ID, Title: title, Status: status, Priority: model.PriorityMedium, Deadline: deadline}
+		if err := s.CreateTodo(ctx, todo); err != nil {
+			t.Fatalf("create todo: %v", err)
+		}
+		return todo
+	}
+	due := newTodo("due", model.StatusPending, &soon)
+	newTodo("done", model.StatusCompleted, &soon)
+	laterTodo := newTodo("later", model.StatusPending, &later)
+	newTodo("no deadline", model.StatusPending, nil)
+
+	claimed, err := s.ClaimTodoReminders(ctx, now, time.Hour)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != due.ID {
+		t.Fatalf("claimed = %+v, want only %q", claimed, due.Title)
+	}
+	if claimed, _ = s.ClaimTodoReminders(ctx, now, time.Hour); len(claimed) != 0 {
+		t.Errorf("claimed %d todos twice", len(claimed))
+	}
+
+	// Moving the deadline makes the todo due for a reminder again.
+	sooner := now.Add(10 * time.Minute)
+	due.Deadline = &sooner
+	if err := s.UpdateTodo(ctx, due); err != nil {
+		t.Fatalf("update todo: %v", err)
+	}
+	if claimed, _ = s.ClaimTodoReminders(ctx, now, time.Hour); len(claimed) != 1 {
+		t.Errorf("got %d todos after deadline change, want 1", len(claimed))
+	}
+	// A per-todo offset overrides the default lead time.
+	offset := model.Duration(4 * time.Hour)
+	laterTodo.ReminderOffset = &offset
+	if err := s.UpdateTodo(ctx, laterTodo); err != nil {
+		t.Fatalf("update todo: %v", err)
+	}
+	claimed, err = s.ClaimTodoReminders(ctx, now, time.Hour)
+	if err != nil {
+		t.Fatalf("claim: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].ID != laterTodo.ID {
+		t.Fatalf("claimed = %+v, want only %q", claimed, laterTodo.Title)
+	}
+	if got := claimed[0].ReminderOffset; got == nil || *got != offset {
+		t.Errorf("reminder offset = %v, want %v", got, offset)
+	}
+}
+
+func TestTodoDependenciesDeletedWithTodo(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	var todos [3]*model.Todo
+	for i := range todos {
+		todos[i] = &model.Todo{ProjectID: project.ID, Title: "T", Status: model.StatusPending, Priority: model.PriorityMedium}
+		s.CreateTodo(ctx, todos[i])
+	}
+	a, b, c := todos[0].ID, todos[1].ID, todos[2].ID
+
+	if err := s.AddTodoDependency(ctx, a, b); err != nil {
+		t.Fatalf("add a->b: %v", err)
+	}
+	if err := s.AddTodoDependency(ctx, b, c); err != nil {
+		t.Fatalf("add b->c: %v", err)
+	}
+	if err := s.AddTodoDependency(ctx, c, a); !errors.Is(err, store.ErrDependencyCycle) {
+		t.Fatalf("add c->a: err = %v, want ErrDependencyCycle", err)
+	}
+	if n, _ := s.CountIncompleteDependencies(ctx, a); n != 1 {
+		t.Errorf("incomplete dependencies of a = %d, want 1", n)
+	}
+
+	if err := s.DeleteTodo(ctx, b); err != nil {
+		t.Fatalf("delete b: %v", err)
+	}
+	deps, err := s.ListTodoDependencies(ctx, a)
+	if err != nil {
+		t.Fatalf("list dependencies: %v", err)
+	}
+	if len(deps) != 0 {
+		t.Errorf("got %d dependencies after delete, want 0", len(deps))
+	}
+	// The b->c edge went with b, so c may now depend on a.
+	if err := s.AddTodoDependency(ctx, c, a); err != nil {
+		t.Errorf("add c->a after delete: %v", err)
+	}
+}
+
+func TestListCompletedBetween(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "bloom.db")
+	s, err := sqlite.New(path)
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	raw, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatalf("open raw: %v", err)
+	}
+	defer raw.Close()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	other := &model.Project{Name: "Other", OwnerID: owner.ID}
+	s.CreateProject(ctx, other)
+
+	from := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 7)
+	ids := map[string]int64{}
+	for _, c := range []struct {
+		title       string
+		projectID   int64
+		status      string
+		completedAt time.Time
+	}{
+		{"before", project.ID, model.StatusCompleted, from.Add(-time.Second)},
+		{"at from", project.ID, model.StatusCompleted, from},
+		{"last", project.ID, model.StatusCompleted, to.Add(-time.Second)},
+		{"middle", project.ID, model.StatusCompleted, from.Add(time.Hour)},
+		{"at to", project.ID, model.StatusCompleted, to},
+		{"other project", other.ID, model.StatusCompleted, from.Add(time.Hour)},
+		{"pending", project.ID, model.StatusPending, time.Time{}},
+	} {
+		todo := &model.Todo{ProjectID: c.projectID, Title: c.title, Status: c.status, Priority: model.PriorityMedium}
+		if err := s.CreateTodo(ctx, todo); err != nil {
+			t.Fatalf("create todo: %v", err)
+		}
+		ids[c.title] = todo.ID
+		if (todo.CompletedAt != nil) != (c.status == model.StatusCompleted) {
+			t.Errorf("%s: completed_at = %v after create", c.title, todo.CompletedAt)
+		}
+		if c.status == model.StatusCompleted {
+			_, err := raw.ExecContext(ctx, `UPDATE todos SET completed_at = ? WHERE id = ?`, c.completedAt.Format(time.RFC3339), todo.ID)
+			if err != nil {
+				t.Fatalf("set completed_at: %v", err)
+			}
+		}
+	}
+
+	// Editing a completed todo keeps its completion time.
+	middle, _ := s.GetTodo(ctx, ids["middle"])
+	middle.Description = "edited"
+	if err := s.UpdateTodo(ctx, middle); err != nil {
+		t.Fatalf("edit: %v", err)
+	}
+	if middle.CompletedAt == nil || !middle.CompletedAt.Equal(from.Add(time.Hour)) {
+		t.Errorf("completed_at after edit = %v, want %v", middle.CompletedAt, from.Add(time.Hour))
+	}
+
+	todos, err := s.ListCompletedBetween(ctx, project.ID, from, to)
+	if err != nil {
+		t.Fatalf("list completed: %v", err)
+	}
+	var titles []string
+	for _, td := range todos {
+		titles = append(titles, td.Title)
+	}
+	if want := []string{"at from", "middle", "last"}; !slices.Equal(titles, want) {
+		t.Errorf("completed = %v, want %v", titles, want)
+	}
+
+	todos, err = s.ListCompletedBetween(ctx, project.ID, to.AddDate(1, 0, 0), to.AddDate(2, 0, 0))
+	if err != nil || todos == nil || len(todos) != 0 {
+		t.Errorf("empty range = %v, %v; want an empty list", todos, err)
+	}
+}
+
+func TestUpdateTodoCompletedAt(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	todo := &model.Todo{ProjectID: project.ID, Title: "T", Status: model.StatusPending, Priority: model.PriorityMedium}
+	s.CreateTodo(ctx, todo)
+	if todo.CompletedAt != nil {
+		t.Fatalf("pending todo has completed_at %v", todo.CompletedAt)
+	}
+
+	todo.Status = model.StatusCompleted
+	if err := s.UpdateTodo(ctx, todo); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if todo.CompletedAt == nil {
+		t.Fatal("completed_at not set on completion")
+	}
+
+	todo.Status = model.StatusInProgress
+	if err := s.UpdateTodo(ctx, todo); err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	got, _ := s.GetTodo(ctx, todo.ID)
+	if todo.CompletedAt != nil || got.CompletedAt != nil {
+		t.Errorf("completed_at after reopen = %v / %v, want nil", todo.CompletedAt, got.CompletedAt)
+	}
+}
+
+func TestUpdateTodoWithAssignees(t *testing.T) {
+	s := setupTestStore(t)
+	ctx := context.Background()
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	bob := &model.User{Username: "bob", Email: "bob@example.com", Password: "pw"}
+	s.CreateUser(ctx, bob)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	todo := &model.Todo{ProjectID: project.ID, Title: "T", Status: model.StatusPending, Priority: model.PriorityMedium}
+	s.CreateTodo(ctx, todo)
+
+	todo.Title = "Renamed"
+	if err := s.UpdateTodoWithAssignees(ctx, todo, []int64{bob.ID}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if len(todo.Assignees) != 1 || todo.Assignees[0].Username != "bob" {
+		t.Errorf("assignees = %+v, want bob", todo.Assignees)
+	}
+
+	// A failed assignee write leaves the fields unsaved too.
+	todo.Title = "Lost"
+	if err := s.UpdateTodoWithAssignees(ctx, todo, []int64{owner.ID, 9999}); err == nil {
+		t.Fatal("update with a missing user succeeded")
+	}
+	got, _ := s.GetTodo(ctx, todo.ID)
+	if got.Title != "Renamed" || len(got.Assignees) != 1 || got.Assignees[0].UserID != bob.ID {
+		t.Errorf("after failed update: title %q, assignees %+v; want the previous state", got.Title, got.Assignees)
+	}
 }
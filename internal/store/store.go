@@ -2,57 +2,347 @@ package store
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/walidabualafia/bloom/internal/model"
 )
 
 // Store defines the interface for all database operations.
 // Both SQLite and PostgreSQL implementations satisfy this interface.
+// Methods that list rows return an empty, non-nil slice when nothing
+// matches, so results always encode as a JSON array.
 type Store interface {
 	// Users
+	// CreateUser inserts a user and, in the same transaction, converts any
+	// pending project invites for the user's email into memberships.
 	CreateUser(ctx context.Context, user *model.User) error
 	GetUserByID(ctx context.Context, id int64) (*model.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*model.User, error)
-	SearchUsers(ctx context.Context, query string, excludeID int64) ([]model.User, error)
+	GetUserByEmail(ctx context.Context, email string) (*model.User, error)
+	SearchUsers(ctx context.Context, params UserSearchParams) ([]model.User, error)
 	ListUsers(ctx context.Context) ([]model.User, error)
-	UpdateUser(ctx context.Context, user *model.User) error
-	DeleteUser(ctx context.Context, id int64) error
+	CountUsers(ctx context.Context) (int, error)
+	// ListUsersFiltered returns one page of the users matching filter,
+	// ordered by id, and how many users match in total.
+	ListUsersFiltered(ctx context.Context, filter UserFilter) ([]model.User, int, error)
+	// ListInactiveUsers is like ListUsersFiltered for the users who have not
+	// logged in since before, counting users who never logged in from when
+	// they were created. They are ordered by that time, oldest first.
+	ListInactiveUsers(ctx context.Context, before time.Time, filter UserFilter) ([]model.User, int, error)
+	// RecordLogin sets the user's LastLoginAt to at.
+	RecordLogin(ctx context.Context, userID int64, at time.Time) error
+	// UpdateUser and DeleteUser record audit, if non-nil, in the same
+	// transaction as the change, filling in its ID and timestamp.
+	UpdateUser(ctx context.Context, user *model.User, audit *model.AdminAuditEntry) error
+	DeleteUser(ctx context.Context, id int64, audit *model.AdminAuditEntry) error
 
 	// Projects
 	CreateProject(ctx context.Context, project *model.Project) error
 	GetProject(ctx context.Context, id int64) (*model.Project, error)
+	// GetProjectOwner returns the full user record of the project's owner,
+	// or ErrNotFound if the project does not exist.
+	GetProjectOwner(ctx context.Context, projectID int64) (*model.User, error)
+	ProjectExists(ctx context.Context, id int64) (bool, error)
+	// ListProjectsByUser returns the user's projects with Favorited set,
+	// favorites first. Within each group, projects in the user's saved order
+	// (see SetProjectOrder) come first, then the rest, most recently updated
+	// first.
 	ListProjectsByUser(ctx context.Context, userID int64) ([]model.Project, error)
+	// ListTemplatesByUser returns the template projects the user owns or is
+	// a member of.
+	ListTemplatesByUser(ctx context.Context, userID int64) ([]model.Project, error)
+	// CreateProjectFromTemplate inserts project and copies the template's
+	// todos into it in one transaction. Copies start pending and unassigned,
+	// with no deadline, and are credited to the new project's owner.
+	CreateProjectFromTemplate(ctx context.Context, templateID int64, project *model.Project) error
+	// ImportProjectTemplate inserts project and the template's todos in one
+	// transaction. The todos start pending and are credited to the project's
+	// owner.
+	ImportProjectTemplate(ctx context.Context, tmpl *model.ProjectTemplate, project *model.Project) error
+	// CountProjectsByOwner returns how many projects the user owns (not
+	// counting projects shared with them).
+	CountProjectsByOwner(ctx context.Context, ownerID int64) (int, error)
 	UpdateProject(ctx context.Context, project *model.Project) error
 	DeleteProject(ctx context.Context, id int64) error
+	// DeleteProjects deletes the given projects in one transaction and
+	// returns the ids that existed and were removed. Unless audit is nil, a
+	// copy of it targeting each removed project is recorded in the same
+	// transaction.
+	DeleteProjects(ctx context.Context, ids []int64, audit *model.AdminAuditEntry) ([]int64, error)
+	// ImportProjects creates each project with its todos and members in one
+	// transaction, setting the IDs and timestamps of the projects. Todos are
+	// credited to the project's owner and keep no assignees.
+	ImportProjects(ctx context.Context, imports []ProjectImport) error
+	// SetProjectFavorite stars or unstars a project for a user. Both are
+	// idempotent.
+	SetProjectFavorite(ctx context.Context, userID, projectID int64, favorite bool) error
+	// SetProjectOrder replaces the user's saved project order with
+	// projectIDs, first to last, in one transaction. IDs of projects the
+	// user cannot access are ignored.
+	SetProjectOrder(ctx context.Context, userID int64, projectIDs []int64) error
+	GetProjectStats(ctx context.Context, projectID int64) (*ProjectStats, error)
+	// CountTodosByPriority counts a project's todos per priority. Every
+	// known priority is present, with zero if no todo has it.
+	CountTodosByPriority(ctx context.Context, projectID int64) (map[string]int, error)
 
 	// Todos
 	CreateTodo(ctx context.Context, todo *model.Todo) error
 	GetTodo(ctx context.Context, id int64) (*model.Todo, error)
-	ListTodosByProject(ctx context.Context, projectID int64) ([]model.Todo, error)
+	// GetTodosByIDs returns, in one query, those of the todos with the
+	// given ids that are in a project the user can access, ordered by id.
+	// Missing and inaccessible ids are left out rather than reported.
+	GetTodosByIDs(ctx context.Context, ids []int64, userID int64) ([]model.Todo, error)
+	ListTodosByProject(ctx context.Context, projectID int64, params TodoListParams) ([]model.Todo, error)
+	// CountTodosByProject returns how many todos a project has, whatever
+	// their status.
+	CountTodosByProject(ctx context.Context, projectID int64) (int, error)
+	// ListTodosByUser returns one page of the todos matching params across
+	// every project the user can access, ordered by project id and then by
+	// params.Sort, and how many match in total.
+	ListTodosByUser(ctx context.Context, userID int64, params TodoListParams, limit, offset int) ([]model.Todo, int, error)
+	// ListTodosDueOn returns the incomplete todos, across every project the
+	// user can access, whose deadline falls on the calendar day starting at
+	// day. day must be midnight in the caller's time zone; the day ends at
+	// the next midnight in that zone. Results are ordered by deadline.
+	ListTodosDueOn(ctx context.Context, userID int64, day time.Time) ([]model.Todo, error)
+	// ListCompletedBetween returns the project's completed todos whose
+	// completed_at is at or after from and before to, ordered by completion
+	// time.
+	ListCompletedBetween(ctx context.Context, projectID int64, from, to time.Time) ([]model.Todo, error)
 	UpdateTodo(ctx context.Context, todo *model.Todo) error
+	// UpdateTodoWithAssignees is UpdateTodo that also replaces the todo's
+	// assignees with assigneeIDs, in the same transaction, and reloads
+	// todo.Assignees.
+	UpdateTodoWithAssignees(ctx context.Context, todo *model.Todo, assigneeIDs []int64) error
 	DeleteTodo(ctx context.Context, id int64) error
+	// ClaimTodoReminders marks as reminded, and returns, every incomplete,
+	// not yet reminded todo whose deadline is after at but no more than its
+	// reminder offset away; todos without an offset use defaultOffset. Each
+	// todo is claimed at most once, even across concurrent callers, until its
+	// deadline or offset changes.
+	ClaimTodoReminders(ctx context.Context, at time.Time, defaultOffset time.Duration) ([]model.Todo, error)
+	// ReleaseTodoReminder clears the mark ClaimTodoReminders set on a todo,
+	// so the next claim returns it again.
+	ReleaseTodoReminder(ctx context.Context, id int64) error
+	// DeleteCompletedTodos removes every completed todo in a project and
+	// returns how many were deleted.
+	DeleteCompletedTodos(ctx context.Context, projectID int64) (int64, error)
+	// CreateTodoChanges appends entries to a todo's history, filling in their
+	// IDs and timestamps. ListTodoHistory returns them oldest first.
+	CreateTodoChanges(ctx context.Context, changes []model.TodoChange) error
+	ListTodoHistory(ctx context.Context, todoID int64) ([]model.TodoChange, error)
+	// ListActivityForUser returns up to limit changes to todos in the
+	// projects the user can access now, newest first. A positive before is
+	// a cursor: only changes with a smaller ID are returned, so passing the
+	// last ID of one page fetches the next.
+	ListActivityForUser(ctx context.Context, userID int64, limit int, before int64) ([]model.Activity, error)
+	// SetTodoAssignees replaces a todo's assignees with userIDs.
+	// ListTodoAssignees returns them ordered by username.
+	SetTodoAssignees(ctx context.Context, todoID int64, userIDs []int64) error
+	ListTodoAssignees(ctx context.Context, todoID int64) ([]model.TodoAssignee, error)
+	// AddTodoDependency records that todoID depends on dependsOnID. It
+	// returns ErrDependencyCycle if dependsOnID already depends, directly or
+	// transitively, on todoID, or is todoID itself. Adding an existing
+	// dependency is a no-op. Dependencies are removed along with either todo.
+	AddTodoDependency(ctx context.Context, todoID, dependsOnID int64) error
+	RemoveTodoDependency(ctx context.Context, todoID, dependsOnID int64) error
+	// ListTodoDependencies returns the todos todoID directly depends on,
+	// oldest first.
+	ListTodoDependencies(ctx context.Context, todoID int64) ([]model.Todo, error)
+	// ListProjectDependencies returns every dependency between the
+	// project's todos, ordered by todo and then dependency id.
+	ListProjectDependencies(ctx context.Context, projectID int64) ([]model.TodoDependency, error)
+	// CountIncompleteDependencies returns how many of todoID's direct
+	// dependencies are not completed.
+	CountIncompleteDependencies(ctx context.Context, todoID int64) (int, error)
 
 	// Project Members
 	AddProjectMember(ctx context.Context, projectID, userID int64, role string) error
+	// RemoveProjectMember also drops the user's favorite on the project and
+	// its place in their project order.
 	RemoveProjectMember(ctx context.Context, projectID, userID int64) error
+	// CountProjectMembers returns how many members a project has, not
+	// counting its owner, plus its pending invites, since each invite
+	// becomes a membership when redeemed.
+	CountProjectMembers(ctx context.Context, projectID int64) (int, error)
+	// ListProjectMembers returns the project's members (excluding the owner)
+	// ordered by role, editors before viewers, then by username.
 	ListProjectMembers(ctx context.Context, projectID int64) ([]model.ProjectMember, error)
+	// GetProjectMember returns one user's membership, reporting the owner
+	// with role "owner". It returns ErrNotFound if the user has no access.
+	GetProjectMember(ctx context.Context, projectID, userID int64) (*model.ProjectMember, error)
+	// ListMembershipsByUser returns every project the user can access along with
+	// their role, including owned projects reported with role "owner".
+	ListMembershipsByUser(ctx context.Context, userID int64) ([]model.ProjectMember, error)
 	IsProjectMember(ctx context.Context, projectID, userID int64) (bool, error)
 	// GetMemberRole returns the user's role in a project: "owner", "editor", "viewer",
-	// or empty string if the user has no access.
+	// or empty string if the user has no access. It returns ErrNotFound if
+	// the project does not exist.
 	GetMemberRole(ctx context.Context, projectID, userID int64) (string, error)
+	// GetMemberRoles returns the user's role in each of projectIDs in one
+	// query. Projects the user cannot access, or that don't exist, are absent
+	// from the map.
+	GetMemberRoles(ctx context.Context, userID int64, projectIDs []int64) (map[int64]string, error)
+
+	// Project Invites
+	CreateProjectInvite(ctx context.Context, invite *model.ProjectInvite) error
+	ListProjectInvites(ctx context.Context, projectID int64) ([]model.ProjectInvite, error)
+	DeleteProjectInvite(ctx context.Context, projectID, inviteID int64) error
+
+	// Webhooks. GetWebhook, UpdateWebhook and DeleteWebhook return
+	// ErrNotFound if the webhook does not exist in the given project.
+	CreateWebhook(ctx context.Context, hook *model.Webhook) error
+	GetWebhook(ctx context.Context, projectID, webhookID int64) (*model.Webhook, error)
+	ListWebhooks(ctx context.Context, projectID int64) ([]model.Webhook, error)
+	UpdateWebhook(ctx context.Context, hook *model.Webhook) error
+	DeleteWebhook(ctx context.Context, projectID, webhookID int64) error
 
 	// Admin
 	GetStats(ctx context.Context) (*Stats, error)
+	// RepairTodos resets, in one transaction, every todo whose status is not
+	// allowed by its project's workflow to model.StatusPending and every
+	// todo with an unknown priority to model.PriorityMedium. Running it again
+	// finds nothing to fix.
+	RepairTodos(ctx context.Context) (*TodoRepair, error)
+	// CreateAdminAudit records an audit entry on its own, for admin actions
+	// that change nothing in the database, such as looking up a user's
+	// projects or toggling maintenance mode.
+	CreateAdminAudit(ctx context.Context, audit *model.AdminAuditEntry) error
+	// ListAdminAudit returns admin audit entries, newest first.
+	ListAdminAudit(ctx context.Context, limit, offset int) ([]model.AdminAuditEntry, error)
+
+	// Feature flags. ListFeatureFlags is ordered by key. SetFeatureFlag
+	// creates the flag or updates it, setting UpdatedAt.
+	ListFeatureFlags(ctx context.Context) ([]model.FeatureFlag, error)
+	SetFeatureFlag(ctx context.Context, flag *model.FeatureFlag) error
 
 	// Lifecycle
 	Migrate(ctx context.Context) error
+	// ResetData deletes every row from every table in one transaction,
+	// leaving the schema in place. It exists for demo mode and must never
+	// be called on real data.
+	ResetData(ctx context.Context) error
 	Close() error
 }
 
+// ProjectImport is one project for ImportProjects to create. The
+// ProjectID of its todos and members is filled in from the new project.
+type ProjectImport struct {
+	Project *model.Project
+	Todos   []model.Todo
+	Members []model.ProjectMember
+}
+
+// ErrNotFound is returned by the Get methods when the requested row does
+// not exist, and by the update and delete methods documented to report a
+// missing row.
+var ErrNotFound = errors.New("not found")
+
+// ErrConflict is returned, wrapping the driver's error, when a write
+// violates a unique constraint: CreateUser or UpdateUser with a taken
+// username or email, or CreateProjectInvite with an invite already pending
+// for the email. Other errors mean the write failed for another reason.
+var ErrConflict = errors.New("conflicts with an existing row")
+
+// ErrUnavailable is returned, wrapping the driver's error, when the
+// database cannot be reached, such as while it restarts. The request may
+// succeed if retried shortly.
+var ErrUnavailable = errors.New("database unavailable")
+
+// ErrDependencyCycle is returned by AddTodoDependency when the dependency
+// would make a todo depend on itself.
+var ErrDependencyCycle = errors.New("dependency would create a cycle")
+
+// UserSearchParams controls which users SearchUsers returns. Results are
+// ranked by username: an exact match first, then usernames starting with
+// Query, then the other matches, each group in alphabetical order.
+type UserSearchParams struct {
+	Query     string // matched against username and email
+	ExcludeID int64  // user to leave out, typically the caller
+	// ExcludeProjectID, when non-zero, leaves out the owner and members of
+	// that project.
+	ExcludeProjectID int64
+	Limit            int
+	Offset           int
+}
+
+// UserFilter controls which users ListUsersFiltered returns. Zero fields
+// don't filter.
+type UserFilter struct {
+	Query   string // matched against username and email, like SearchUsers
+	IsAdmin *bool
+	Limit   int
+	Offset  int
+}
+
+// Sort orders accepted by TodoListParams.Sort.
+const (
+	// TodoSortCreated lists the newest todos first. It is the default.
+	TodoSortCreated = "created_at"
+	// TodoSortPriority orders by priority level (high first), then by
+	// priority rank within each level.
+	TodoSortPriority = "priority"
+	// TodoSortPriorityRank orders by priority rank, unranked todos last.
+	TodoSortPriorityRank = "priority_rank"
+)
+
+// ValidTodoSort checks whether a sort order is supported. The empty string
+// selects the default.
+func ValidTodoSort(sort string) bool {
+	switch sort {
+	case "", TodoSortCreated, TodoSortPriority, TodoSortPriorityRank:
+		return true
+	}
+	return false
+}
+
+// TodoListParams controls how ListTodosByProject filters and orders todos.
+// Zero-valued filters are ignored.
+type TodoListParams struct {
+	Sort       string
+	Status     string
+	Priority   string
+	AssigneeID int64
+	Unassigned bool // only todos without an assignee; overrides AssigneeID
+	// Query keeps only todos whose title or description contains it,
+	// ignoring case.
+	Query string
+	// UpdatedSince, if set, keeps only todos updated strictly after it.
+	UpdatedSince *time.Time
+	// HideCompleted leaves out completed todos unless Status asks for a
+	// specific status.
+	HideCompleted bool
+}
+
+// ProjectStats summarizes a project's todos. Estimates are summed with
+// unestimated todos counting as zero; EstimatedTodos says how many todos
+// have an estimate at all.
+type ProjectStats struct {
+	TotalTodos        int            `json:"total_todos"`
+	CompletedTodos    int            `json:"completed_todos"`
+	TodosByStatus     map[string]int `json:"todos_by_status"`
+	TodosByPriority   map[string]int `json:"todos_by_priority"`
+	EstimatedTodos    int            `json:"estimated_todos"`
+	TotalEstimate     int            `json:"total_estimate"`
+	CompletedEstimate int            `json:"completed_estimate"`
+}
+
+// TodoRepair reports what RepairTodos changed. A todo with both a bad status
+// and a bad priority is counted once in Todos.
+type TodoRepair struct {
+	Todos      int `json:"todos_fixed"`
+	Statuses   int `json:"statuses_fixed"`
+	Priorities int `json:"priorities_fixed"`
+}
+
 // Stats holds system-wide statistics for the admin dashboard.
 type Stats struct {
 	TotalUsers    int `json:"total_users"`
 	TotalProjects int `json:"total_projects"`
 	TotalTodos    int `json:"total_todos"`
 	CompletedTodos int `json:"completed_todos"`
+	// TodosByStatus counts todos per status, including projects' custom
+	// statuses.
+	TodosByStatus map[string]int `json:"todos_by_status"`
 }
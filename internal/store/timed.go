@@ -0,0 +1,471 @@
+package store
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/model"
+)
+
+// Timed wraps a Store and records the duration of every call in the
+// request's QueryTimer, if one is attached to the context. Calls slower than
+// the configured threshold are counted and reported by SlowQueryStats.
+type Timed struct {
+	next          Store
+	slowThreshold time.Duration // 0 disables slow-call tracking
+
+	mu   sync.Mutex
+	slow SlowQueryStats
+}
+
+// SlowQueryStats summarizes store calls that exceeded the slow threshold.
+// Only method names are kept; SQL and arguments are never recorded.
+type SlowQueryStats struct {
+	Enabled      bool       `json:"enabled"`
+	ThresholdMS  int64      `json:"threshold_ms"`
+	Count        int64      `json:"count"`
+	LastMethod   string     `json:"last_method,omitempty"`
+	LastDuration float64    `json:"last_duration_ms,omitempty"`
+	LastOccurred *time.Time `json:"last_occurred_at,omitempty"`
+}
+
+// SlowQueryReporter is implemented by stores that track slow calls.
+type SlowQueryReporter interface {
+	SlowQueryStats() SlowQueryStats
+}
+
+// Compile-time check that Timed implements Store.
+var _ Store = (*Timed)(nil)
+
+// NewTimed returns a Store that times every call made to next. Calls taking
+// longer than slowThreshold are counted as slow; 0 disables that tracking.
+func NewTimed(next Store, slowThreshold time.Duration) *Timed {
+	return &Timed{
+		next:          next,
+		slowThreshold: slowThreshold,
+		slow: SlowQueryStats{
+			Enabled:     slowThreshold > 0,
+			ThresholdMS: slowThreshold.Milliseconds(),
+		},
+	}
+}
+
+// SlowQueryStats returns a snapshot of the slow-call counters.
+func (t *Timed) SlowQueryStats() SlowQueryStats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.slow
+}
+
+func (t *Timed) observe(ctx context.Context, method string, start time.Time) {
+	d := time.Since(start)
+	if qt := QueryTimerFrom(ctx); qt != nil {
+		qt.Add(d)
+	}
+	if t.slowThreshold > 0 && d > t.slowThreshold {
+		t.mu.Lock()
+		t.slow.Count++
+		t.slow.LastMethod = method
+		t.slow.LastDuration = float64(d) / float64(time.Millisecond)
+		now := time.Now().UTC()
+		t.slow.LastOccurred = &now
+		t.mu.Unlock()
+		log.Printf("slow store call: %s took %s", method, d.Round(time.Millisecond))
+	}
+}
+
+func (t *Timed) CreateUser(ctx context.Context, user *model.User) error {
+	defer t.observe(ctx, "CreateUser", time.Now())
+	return t.next.CreateUser(ctx, user)
+}
+
+func (t *Timed) GetUserByID(ctx context.Context, id int64) (*model.User, error) {
+	defer t.observe(ctx, "GetUserByID", time.Now())
+	return t.next.GetUserByID(ctx, id)
+}
+
+func (t *Timed) GetUserByUsername(ctx context.Context, username string) (*model.User, error) {
+	defer t.observe(ctx, "GetUserByUsername", time.Now())
+	return t.next.GetUserByUsername(ctx, username)
+}
+
+func (t *Timed) GetUserByEmail(ctx context.Context, email string) (*model.User, error) {
+	defer t.observe(ctx, "GetUserByEmail", time.Now())
+	return t.next.GetUserByEmail(ctx, email)
+}
+
+func (t *Timed) SearchUsers(ctx context.Context, params UserSearchParams) ([]model.User, error) {
+	defer t.observe(ctx, "SearchUsers", time.Now())
+	return t.next.SearchUsers(ctx, params)
+}
+
+func (t *Timed) ListUsers(ctx context.Context) ([]model.User, error) {
+	defer t.observe(ctx, "ListUsers", time.Now())
+	return t.next.ListUsers(ctx)
+}
+
+func (t *Timed) CountUsers(ctx context.Context) (int, error) {
+	defer t.observe(ctx, "CountUsers", time.Now())
+	return t.next.CountUsers(ctx)
+}
+
+func (t *Timed) ListUsersFiltered(ctx context.Context, filter UserFilter) ([]model.User, int, error) {
+	defer t.observe(ctx, "ListUsersFiltered", time.Now())
+	return t.next.ListUsersFiltered(ctx, filter)
+}
+
+func (t *Timed) ListInactiveUsers(ctx context.Context, before time.Time, filter UserFilter) ([]model.User, int, error) {
+	defer t.observe(ctx, "ListInactiveUsers", time.Now())
+	return t.next.ListInactiveUsers(ctx, before, filter)
+}
+
+func (t *Timed) RecordLogin(ctx context.Context, userID int64, at time.Time) error {
+	defer t.observe(ctx, "RecordLogin", time.Now())
+	return t.next.RecordLogin(ctx, userID, at)
+}
+
+func (t *Timed) UpdateUser(ctx context.Context, user *model.User, audit *model.AdminAuditEntry) error {
+	defer t.observe(ctx, "UpdateUser", time.Now())
+	return t.next.UpdateUser(ctx, user, audit)
+}
+
+func (t *Timed) DeleteUser(ctx context.Context, id int64, audit *model.AdminAuditEntry) error {
+	defer t.observe(ctx, "DeleteUser", time.Now())
+	return t.next.DeleteUser(ctx, id, audit)
+}
+
+func (t *Timed) CreateProject(ctx context.Context, project *model.Project) error {
+	defer t.observe(ctx, "CreateProject", time.Now())
+	return t.next.CreateProject(ctx, project)
+}
+
+func (t *Timed) GetProject(ctx context.Context, id int64) (*model.Project, error) {
+	defer t.observe(ctx, "GetProject", time.Now())
+	return t.next.GetProject(ctx, id)
+}
+
+func (t *Timed) GetProjectOwner(ctx context.Context, projectID int64) (*model.User, error) {
+	defer t.observe(ctx, "GetProjectOwner", time.Now())
+	return t.next.GetProjectOwner(ctx, projectID)
+}
+
+func (t *Timed) ListProjectsByUser(ctx context.Context, userID int64) ([]model.Project, error) {
+	defer t.observe(ctx, "ListProjectsByUser", time.Now())
+	return t.next.ListProjectsByUser(ctx, userID)
+}
+
+func (t *Timed) ListTemplatesByUser(ctx context.Context, userID int64) ([]model.Project, error) {
+	defer t.observe(ctx, "ListTemplatesByUser", time.Now())
+	return t.next.ListTemplatesByUser(ctx, userID)
+}
+
+func (t *Timed) CreateProjectFromTemplate(ctx context.Context, templateID int64, project *model.Project) error {
+	defer t.observe(ctx, "CreateProjectFromTemplate", time.Now())
+	return t.next.CreateProjectFromTemplate(ctx, templateID, project)
+}
+
+func (t *Timed) ImportProjectTemplate(ctx context.Context, tmpl *model.ProjectTemplate, project *model.Project) error {
+	defer t.observe(ctx, "ImportProjectTemplate", time.Now())
+	return t.next.ImportProjectTemplate(ctx, tmpl, project)
+}
+
+func (t *Timed) CountProjectsByOwner(ctx context.Context, ownerID int64) (int, error) {
+	defer t.observe(ctx, "CountProjectsByOwner", time.Now())
+	return t.next.CountProjectsByOwner(ctx, ownerID)
+}
+
+func (t *Timed) UpdateProject(ctx context.Context, project *model.Project) error {
+	defer t.observe(ctx, "UpdateProject", time.Now())
+	return t.next.UpdateProject(ctx, project)
+}
+
+func (t *Timed) DeleteProject(ctx context.Context, id int64) error {
+	defer t.observe(ctx, "DeleteProject", time.Now())
+	return t.next.DeleteProject(ctx, id)
+}
+
+func (t *Timed) DeleteProjects(ctx context.Context, ids []int64, audit *model.AdminAuditEntry) ([]int64, error) {
+	defer t.observe(ctx, "DeleteProjects", time.Now())
+	return t.next.DeleteProjects(ctx, ids, audit)
+}
+
+func (t *Timed) ImportProjects(ctx context.Context, imports []ProjectImport) error {
+	defer t.observe(ctx, "ImportProjects", time.Now())
+	return t.next.ImportProjects(ctx, imports)
+}
+
+func (t *Timed) SetProjectFavorite(ctx context.Context, userID, projectID int64, favorite bool) error {
+	defer t.observe(ctx, "SetProjectFavorite", time.Now())
+	return t.next.SetProjectFavorite(ctx, userID, projectID, favorite)
+}
+
+func (t *Timed) SetProjectOrder(ctx context.Context, userID int64, projectIDs []int64) error {
+	defer t.observe(ctx, "SetProjectOrder", time.Now())
+	return t.next.SetProjectOrder(ctx, userID, projectIDs)
+}
+
+func (t *Timed) GetProjectStats(ctx context.Context, projectID int64) (*ProjectStats, error) {
+	defer t.observe(ctx, "GetProjectStats", time.Now())
+	return t.next.GetProjectStats(ctx, projectID)
+}
+
+func (t *Timed) CountTodosByPriority(ctx context.Context, projectID int64) (map[string]int, error) {
+	defer t.observe(ctx, "CountTodosByPriority", time.Now())
+	return t.next.CountTodosByPriority(ctx, projectID)
+}
+
+func (t *Timed) CreateTodo(ctx context.Context, todo *model.Todo) error {
+	defer t.observe(ctx, "CreateTodo", time.Now())
+	return t.next.CreateTodo(ctx, todo)
+}
+
+func (t *Timed) GetTodo(ctx context.Context, id int64) (*model.Todo, error) {
+	defer t.observe(ctx, "GetTodo", time.Now())
+	return t.next.GetTodo(ctx, id)
+}
+
+func (t *Timed) GetTodosByIDs(ctx context.Context, ids []int64, userID int64) ([]model.Todo, error) {
+	defer t.observe(ctx, "GetTodosByIDs", time.Now())
+	return t.next.GetTodosByIDs(ctx, ids, userID)
+}
+
+func (t *Timed) ListTodosByProject(ctx context.Context, projectID int64, params TodoListParams) ([]model.Todo, error) {
+	defer t.observe(ctx, "ListTodosByProject", time.Now())
+	return t.next.ListTodosByProject(ctx, projectID, params)
+}
+
+func (t *Timed) ListCompletedBetween(ctx context.Context, projectID int64, from, to time.Time) ([]model.Todo, error) {
+	defer t.observe(ctx, "ListCompletedBetween", time.Now())
+	return t.next.ListCompletedBetween(ctx, projectID, from, to)
+}
+
+func (t *Timed) CountTodosByProject(ctx context.Context, projectID int64) (int, error) {
+	defer t.observe(ctx, "CountTodosByProject", time.Now())
+	return t.next.CountTodosByProject(ctx, projectID)
+}
+
+func (t *Timed) ListTodosByUser(ctx context.Context, userID int64, params TodoListParams, limit, offset int) ([]model.Todo, int, error) {
+	defer t.observe(ctx, "ListTodosByUser", time.Now())
+	return t.next.ListTodosByUser(ctx, userID, params, limit, offset)
+}
+
+func (t *Timed) ListTodosDueOn(ctx context.Context, userID int64, day time.Time) ([]model.Todo, error) {
+	defer t.observe(ctx, "ListTodosDueOn", time.Now())
+	return t.next.ListTodosDueOn(ctx, userID, day)
+}
+
+func (t *Timed) UpdateTodo(ctx context.Context, todo *model.Todo) error {
+	defer t.observe(ctx, "UpdateTodo", time.Now())
+	return t.next.UpdateTodo(ctx, todo)
+}
+
+func (t *Timed) UpdateTodoWithAssignees(ctx context.Context, todo *model.Todo, assigneeIDs []int64) error {
+	defer t.observe(ctx, "UpdateTodoWithAssignees", time.Now())
+	return t.next.UpdateTodoWithAssignees(ctx, todo, assigneeIDs)
+}
+
+func (t *Timed) ClaimTodoReminders(ctx context.Context, at time.Time, defaultOffset time.Duration) ([]model.Todo, error) {
+	defer t.observe(ctx, "ClaimTodoReminders", time.Now())
+	return t.next.ClaimTodoReminders(ctx, at, defaultOffset)
+}
+
+func (t *Timed) ReleaseTodoReminder(ctx context.Context, id int64) error {
+	defer t.observe(ctx, "ReleaseTodoReminder", time.Now())
+	return t.next.ReleaseTodoReminder(ctx, id)
+}
+
+func (t *Timed) DeleteTodo(ctx context.Context, id int64) error {
+	defer t.observe(ctx, "DeleteTodo", time.Now())
+	return t.next.DeleteTodo(ctx, id)
+}
+
+func (t *Timed) DeleteCompletedTodos(ctx context.Context, projectID int64) (int64, error) {
+	defer t.observe(ctx, "DeleteCompletedTodos", time.Now())
+	return t.next.DeleteCompletedTodos(ctx, projectID)
+}
+
+func (t *Timed) CreateTodoChanges(ctx context.Context, changes []model.TodoChange) error {
+	defer t.observe(ctx, "CreateTodoChanges", time.Now())
+	return t.next.CreateTodoChanges(ctx, changes)
+}
+
+func (t *Timed) ListActivityForUser(ctx context.Context, userID int64, limit int, before int64) ([]model.Activity, error) {
+	defer t.observe(ctx, "ListActivityForUser", time.Now())
+	return t.next.ListActivityForUser(ctx, userID, limit, before)
+}
+
+func (t *Timed) ListTodoHistory(ctx context.Context, todoID int64) ([]model.TodoChange, error) {
+	defer t.observe(ctx, "ListTodoHistory", time.Now())
+	return t.next.ListTodoHistory(ctx, todoID)
+}
+
+func (t *Timed) AddTodoDependency(ctx context.Context, todoID, dependsOnID int64) error {
+	defer t.observe(ctx, "AddTodoDependency", time.Now())
+	return t.next.AddTodoDependency(ctx, todoID, dependsOnID)
+}
+
+func (t *Timed) RemoveTodoDependency(ctx context.Context, todoID, dependsOnID int64) error {
+	defer t.observe(ctx, "RemoveTodoDependency", time.Now())
+	return t.next.RemoveTodoDependency(ctx, todoID, dependsOnID)
+}
+
+func (t *Timed) ListTodoDependencies(ctx context.Context, todoID int64) ([]model.Todo, error) {
+	defer t.observe(ctx, "ListTodoDependencies", time.Now())
+	return t.next.ListTodoDependencies(ctx, todoID)
+}
+
+func (t *Timed) ListProjectDependencies(ctx context.Context, projectID int64) ([]model.TodoDependency, error) {
+	defer t.observe(ctx, "ListProjectDependencies", time.Now())
+	return t.next.ListProjectDependencies(ctx, projectID)
+}
+
+func (t *Timed) CountIncompleteDependencies(ctx context.Context, todoID int64) (int, error) {
+	defer t.observe(ctx, "CountIncompleteDependencies", time.Now())
+	return t.next.CountIncompleteDependencies(ctx, todoID)
+}
+
+func (t *Timed) SetTodoAssignees(ctx context.Context, todoID int64, userIDs []int64) error {
+	defer t.observe(ctx, "SetTodoAssignees", time.Now())
+	return t.next.SetTodoAssignees(ctx, todoID, userIDs)
+}
+
+func (t *Timed) ListTodoAssignees(ctx context.Context, todoID int64) ([]model.TodoAssignee, error) {
+	defer t.observe(ctx, "ListTodoAssignees", time.Now())
+	return t.next.ListTodoAssignees(ctx, todoID)
+}
+
+func (t *Timed) AddProjectMember(ctx context.Context, projectID, userID int64, role string) error {
+	defer t.observe(ctx, "AddProjectMember", time.Now())
+	return t.next.AddProjectMember(ctx, projectID, userID, role)
+}
+
+func (t *Timed) RemoveProjectMember(ctx context.Context, projectID, userID int64) error {
+	defer t.observe(ctx, "RemoveProjectMember", time.Now())
+	return t.next.RemoveProjectMember(ctx, projectID, userID)
+}
+
+func (t *Timed) CountProjectMembers(ctx context.Context, projectID int64) (int, error) {
+	defer t.observe(ctx, "CountProjectMembers", time.Now())
+	return t.next.CountProjectMembers(ctx, projectID)
+}
+
+func (t *Timed) ListProjectMembers(ctx context.Context, projectID int64) ([]model.ProjectMember, error) {
+	defer t.observe(ctx, "ListProjectMembers", time.Now())
+	return t.next.ListProjectMembers(ctx, projectID)
+}
+
+func (t *Timed) GetProjectMember(ctx context.Context, projectID, userID int64) (*model.ProjectMember, error) {
+	defer t.observe(ctx, "GetProjectMember", time.Now())
+	return t.next.GetProjectMember(ctx, projectID, userID)
+}
+
+func (t *Timed) ListMembershipsByUser(ctx context.Context, userID int64) ([]model.ProjectMember, error) {
+	defer t.observe(ctx, "ListMembershipsByUser", time.Now())
+	return t.next.ListMembershipsByUser(ctx, userID)
+}
+
+func (t *Timed) ProjectExists(ctx context.Context, id int64) (bool, error) {
+	defer t.observe(ctx, "ProjectExists", time.Now())
+	return t.next.ProjectExists(ctx, id)
+}
+
+func (t *Timed) GetMemberRoles(ctx context.Context, userID int64, projectIDs []int64) (map[int64]string, error) {
+	defer t.observe(ctx, "GetMemberRoles", time.Now())
+	return t.next.GetMemberRoles(ctx, userID, projectIDs)
+}
+
+func (t *Timed) IsProjectMember(ctx context.Context, projectID, userID int64) (bool, error) {
+	defer t.observe(ctx, "IsProjectMember", time.Now())
+	return t.next.IsProjectMember(ctx, projectID, userID)
+}
+
+func (t *Timed) GetMemberRole(ctx context.Context, projectID, userID int64) (string, error) {
+	defer t.observe(ctx, "GetMemberRole", time.Now())
+	return t.next.GetMemberRole(ctx, projectID, userID)
+}
+
+func (t *Timed) CreateProjectInvite(ctx context.Context, invite *model.ProjectInvite) error {
+	defer t.observe(ctx, "CreateProjectInvite", time.Now())
+	return t.next.CreateProjectInvite(ctx, invite)
+}
+
+func (t *Timed) ListProjectInvites(ctx context.Context, projectID int64) ([]model.ProjectInvite, error) {
+	defer t.observe(ctx, "ListProjectInvites", time.Now())
+	return t.next.ListProjectInvites(ctx, projectID)
+}
+
+func (t *Timed) DeleteProjectInvite(ctx context.Context, projectID, inviteID int64) error {
+	defer t.observe(ctx, "DeleteProjectInvite", time.Now())
+	return t.next.DeleteProjectInvite(ctx, projectID, inviteID)
+}
+
+func (t *Timed) CreateWebhook(ctx context.Context, hook *model.Webhook) error {
+	defer t.observe(ctx, "CreateWebhook", time.Now())
+	return t.next.CreateWebhook(ctx, hook)
+}
+
+func (t *Timed) GetWebhook(ctx context.Context, projectID, webhookID int64) (*model.Webhook, error) {
+	defer t.observe(ctx, "GetWebhook", time.Now())
+	return t.next.GetWebhook(ctx, projectID, webhookID)
+}
+
+func (t *Timed) ListWebhooks(ctx context.Context, projectID int64) ([]model.Webhook, error) {
+	defer t.observe(ctx, "ListWebhooks", time.Now())
+	return t.next.ListWebhooks(ctx, projectID)
+}
+
+func (t *Timed) UpdateWebhook(ctx context.Context, hook *model.Webhook) error {
+	defer t.observe(ctx, "UpdateWebhook", time.Now())
+	return t.next.UpdateWebhook(ctx, hook)
+}
+
+func (t *Timed) DeleteWebhook(ctx context.Context, projectID, webhookID int64) error {
+	defer t.observe(ctx, "DeleteWebhook", time.Now())
+	return t.next.DeleteWebhook(ctx, projectID, webhookID)
+}
+
+func (t *Timed) GetStats(ctx context.Context) (*Stats, error) {
+	defer t.observe(ctx, "GetStats", time.Now())
+	return t.next.GetStats(ctx)
+}
+
+func (t *Timed) RepairTodos(ctx context.Context) (*TodoRepair, error) {
+	defer t.observe(ctx, "RepairTodos", time.Now())
+	return t.next.RepairTodos(ctx)
+}
+
+func (t *Timed) CreateAdminAudit(ctx context.Context, audit *model.AdminAuditEntry) error {
+	defer t.observe(ctx, "CreateAdminAudit", time.Now())
+	return t.next.CreateAdminAudit(ctx, audit)
+}
+
+func (t *Timed) ListAdminAudit(ctx context.Context, limit, offset int) ([]model.AdminAuditEntry, error) {
+	defer t.observe(ctx, "ListAdminAudit", time.Now())
+	return t.next.ListAdminAudit(ctx, limit, offset)
+}
+
+func (t *Timed) ListFeatureFlags(ctx context.Context) ([]model.FeatureFlag, error) {
+	defer t.observe(ctx, "ListFeatureFlags", time.Now())
+	return t.next.ListFeatureFlags(ctx)
+}
+
+func (t *Timed) SetFeatureFlag(ctx context.Context, flag *model.FeatureFlag) error {
+	defer t.observe(ctx, "SetFeatureFlag", time.Now())
+	return t.next.SetFeatureFlag(ctx, flag)
+}
+
+func (t *Timed) Migrate(ctx context.Context) error {
+	defer t.observe(ctx, "Migrate", time.Now())
+	return t.next.Migrate(ctx)
+}
+
+func (t *Timed) ResetData(ctx context.Context) error {
+	defer t.observe(ctx, "ResetData", time.Now())
+	return t.next.ResetData(ctx)
+}
+
+func (t *Timed) Close() error {
+	return t.next.Close()
+}
@@ -0,0 +1,46 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/store"
+	"github.com/walidabualafia/bloom/internal/store/sqlite"
+)
+
+func TestTimedRecordsQueries(t *testing.T) {
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	timed := store.NewTimed(s, time.Nanosecond)
+	if err := timed.Migrate(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	ctx, timer := store.WithQueryTimer(context.Background())
+	if _, err := timed.ListUsers(ctx); err != nil {
+		t.Fatalf("list users: %v", err)
+	}
+	if _, err := timed.GetStats(ctx); err != nil {
+		t.Fatalf("get stats: %v", err)
+	}
+
+	if timer.Count() != 2 {
+		t.Errorf("timer count = %d, want 2", timer.Count())
+	}
+	if timer.Total() <= 0 {
+		t.Error("expected positive total duration")
+	}
+
+	stats := timed.SlowQueryStats()
+	if !stats.Enabled || stats.Count < 2 {
+		t.Errorf("slow stats = %+v, want enabled with count >= 2", stats)
+	}
+	if stats.LastMethod != "GetStats" {
+		t.Errorf("last method = %q, want GetStats", stats.LastMethod)
+	}
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// QueryTimer accumulates the time spent in store calls during one request.
+// It is safe for concurrent use.
+type QueryTimer struct {
+	count int64
+	nanos int64
+}
+
+// Add records one store call of duration d.
+func (t *QueryTimer) Add(d time.Duration) {
+	atomic.AddInt64(&t.count, 1)
+	atomic.AddInt64(&t.nanos, int64(d))
+}
+
+// Count returns the number of store calls recorded.
+func (t *QueryTimer) Count() int64 {
+	return atomic.LoadInt64(&t.count)
+}
+
+// Total returns the cumulative duration of recorded store calls.
+func (t *QueryTimer) Total() time.Duration {
+	return time.Duration(atomic.LoadInt64(&t.nanos))
+}
+
+type queryTimerKey struct{}
+
+// WithQueryTimer returns a context carrying a fresh QueryTimer.
+func WithQueryTimer(ctx context.Context) (context.Context, *QueryTimer) {
+	t := &QueryTimer{}
+	return context.WithValue(ctx, queryTimerKey{}, t), t
+}
+
+// QueryTimerFrom returns the QueryTimer attached to ctx, or nil.
+func QueryTimerFrom(ctx context.Context) *QueryTimer {
+	t, _ := ctx.Value(queryTimerKey{}).(*QueryTimer)
+	return t
+}
@@ -0,0 +1,283 @@
+// Package webhook delivers todo events to the URLs projects have
+// registered, signing each request with the webhook's secret.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store"
+)
+
+// Request headers set on every delivery.
+const (
+	HeaderEvent     = "X-Bloom-Event"
+	HeaderSignature = "X-Bloom-Signature" // "sha256=" + hex HMAC-SHA256 of the body
+)
+
+// Payload is the JSON body of a delivery.
+type Payload struct {
+	Event      string      `json:"event"`
+	ProjectID  int64       `json:"project_id"`
+	Todo       *model.Todo `json:"todo"`
+	OccurredAt time.Time   `json:"occurred_at"`
+}
+
+// Options tunes delivery. Zero fields fall back to the defaults below.
+type Options struct {
+	Timeout     time.Duration // per attempt
+	MaxAttempts int
+	Backoff     time.Duration // before the second attempt, doubling after
+	Workers     int
+	QueueSize   int
+	// AllowPrivateNetworks lets deliveries reach addresses that
+	// model.WebhookAddressAllowed rejects, such as loopback. Only for tests
+	// and deployments whose webhook receivers are trusted.
+	AllowPrivateNetworks bool
+}
+
+const (
+	defaultTimeout     = 5 * time.Second
+	defaultMaxAttempts = 3
+	defaultBackoff     = time.Second
+	defaultWorkers     = 4
+	defaultQueueSize   = 1000
+)
+
+// Dispatcher sends events to subscribed webhooks in the background. Looking
+// up the subscribers is the only work done on the caller's goroutine;
+// deliveries are queued for a fixed pool of workers, and are dropped with a
+// log line if the queue is full, so slow endpoints never hold up a request.
+// Close stops it at shutdown.
+type Dispatcher struct {
+	store       store.Store
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	queue       chan delivery
+
+	// ctx is cancelled when Close gives up waiting, which aborts in-flight
+	// attempts and backoffs and drops what is still queued.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+	dropped atomic.Int64
+
+	mu     sync.RWMutex // guards closed and sending on queue
+	closed bool
+}
+
+type delivery struct {
+	hook  model.Webhook
+	event string
+	body  []byte
+}
+
+// New creates a Dispatcher and starts its workers, which run until Close.
+func New(s store.Store, opts Options) *Dispatcher {
+	if opts.Timeout <= 0 {
+		opts.Timeout = defaultTimeout
+	}
+	if opts.MaxAttempts < 1 {
+		opts.MaxAttempts = defaultMaxAttempts
+	}
+	if opts.Backoff <= 0 {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.Workers < 1 {
+		opts.Workers = defaultWorkers
+	}
+	if opts.QueueSize < 1 {
+		opts.QueueSize = defaultQueueSize
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !opts.AllowPrivateNetworks {
+		dialer := &net.Dialer{Timeout: opts.Timeout, Control: refusePrivate}
+		transport.DialContext = dialer.DialContext
+		// A proxy would dial on the webhook's behalf, past the check.
+		transport.Proxy = nil
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	d := &Dispatcher{
+		store: s,
+		client: &http.Client{
+			Timeout:   opts.Timeout,
+			Transport: transport,
+			// A redirect could bounce a delivery to an internal host; report
+			// it as a failed delivery instead.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		maxAttempts: opts.MaxAttempts,
+		backoff:     opts.Backoff,
+		queue:       make(chan delivery, opts.QueueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+	}
+	d.workers.Add(opts.Workers)
+	for range opts.Workers {
+		go d.work()
+	}
+	return d
+}
+
+// Close stops accepting events and waits for the workers to deliver what is
+// already queued, retries included. If ctx ends first, in-flight attempts
+// are aborted, the rest of the queue is dropped and an error saying how many
+// deliveries were lost is returned. Events dispatched after Close are
+// dropped.
+func (d *Dispatcher) Close(ctx context.Context) error {
+	d.mu.Lock()
+	if !d.closed {
+		d.closed = true
+		close(d.queue)
+	}
+	d.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		d.workers.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		d.cancel()
+		return nil
+	case <-ctx.Done():
+		d.cancel()
+		<-done
+		return fmt.Errorf("webhooks: dropped %d queued deliveries: %w", d.dropped.Load(), ctx.Err())
+	}
+}
+
+// Dispatch queues event for every webhook of the todo's project that
+// subscribes to it.
+func (d *Dispatcher) Dispatch(ctx context.Context, event string, todo *model.Todo) {
+	hooks, err := d.store.ListWebhooks(ctx, todo.ProjectID)
+	if err != nil {
+		log.Printf("webhooks: list for project %d: %v", todo.ProjectID, err)
+		return
+	}
+	var body []byte
+	for _, hook := range hooks {
+		if !hook.Subscribes(event) {
+			continue
+		}
+		if body == nil {
+			payload := Payload{Event: event, ProjectID: todo.ProjectID, Todo: todo, OccurredAt: time.Now().UTC()}
+			if body, err = json.Marshal(payload); err != nil {
+				log.Printf("webhooks: encode %s for todo %d: %v", event, todo.ID, err)
+				return
+			}
+		}
+		d.enqueue(delivery{hook: hook, event: event, body: body})
+	}
+}
+
+func (d *Dispatcher) enqueue(job delivery) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.closed {
+		log.Printf("webhooks: shutting down, dropping %s for webhook %d", job.event, job.hook.ID)
+		return
+	}
+	select {
+	case d.queue <- job:
+	default:
+		log.Printf("webhooks: queue full, dropping %s for webhook %d", job.event, job.hook.ID)
+	}
+}
+
+func (d *Dispatcher) work() {
+	defer d.workers.Done()
+	for job := range d.queue {
+		if d.ctx.Err() != nil {
+			d.dropped.Add(1)
+			continue
+		}
+		if err := d.deliver(d.ctx, job); err != nil {
+			log.Printf("webhooks: deliver %s to webhook %d (%s): %v", job.event, job.hook.ID, job.hook.URL, err)
+		}
+	}
+}
+
+// deliver POSTs the job, retrying with exponential backoff until it gets a
+// 2xx response, runs out of attempts or ctx is cancelled.
+func (d *Dispatcher) deliver(ctx context.Context, job delivery) error {
+	var err error
+	wait := d.backoff
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		if attempt > 1 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return fmt.Errorf("stopped after %d attempts: %w", attempt-1, err)
+			}
+			wait *= 2
+		}
+		if err = d.post(ctx, job); err == nil {
+			return nil
+		}
+	}
+	return fmt.Errorf("giving up after %d attempts: %w", d.maxAttempts, err)
+}
+
+func (d *Dispatcher) post(ctx context.Context, job delivery) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.hook.URL, bytes.NewReader(job.body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "bloom-webhooks")
+	req.Header.Set(HeaderEvent, job.event)
+	req.Header.Set(HeaderSignature, Sign(job.hook.Secret, job.body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10)) //nolint:errcheck // drain so the connection can be reused
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// refusePrivate is a net.Dialer Control function that refuses addresses
+// webhooks may not reach. It runs on the resolved address, so a host name
+// that resolves, or is later rebound, to an internal address is caught too.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || !model.WebhookAddressAllowed(ip) {
+		return fmt.Errorf("webhook address %s is not allowed", host)
+	}
+	return nil
+}
+
+// Sign returns the X-Bloom-Signature value for body: "sha256=" followed by
+// the hex HMAC-SHA256 of body keyed with secret.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
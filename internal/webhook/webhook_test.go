@@ -0,0 +1,224 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/walidabualafia/bloom/internal/model"
+	"github.com/walidabualafia/bloom/internal/store/sqlite"
+	"github.com/walidabualafia/bloom/internal/webhook"
+)
+
+// setupTodo returns a store holding one todo in a project.
+func setupTodo(t *testing.T) (*sqlite.Store, *model.Todo) {
+	t.Helper()
+	s, err := sqlite.New(":memory:")
+	if err != nil {
+		t.Fatalf("open store: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	ctx := context.Background()
+	if err := s.Migrate(ctx); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	owner := &model.User{Username: "owner", Email: "owner@example.com", Password: "pw"}
+	s.CreateUser(ctx, owner)
+	project := &model.Project{Name: "P", OwnerID: owner.ID}
+	s.CreateProject(ctx, project)
+	todo := &model.Todo{ProjectID: project.ID, Title: "T", Status: model.StatusPending, Priority: model.PriorityMedium}
+	s.CreateTodo(ctx, todo)
+	return s, todo
+}
+
+func TestDispatchSignsAndRetries(t *testing.T) {
+	s, todo := setupTodo(t)
+	ctx := context.Background()
+
+	type received struct {
+		event, signature string
+		body             []byte
+	}
+	got := make(chan received, 10)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // first attempt fails
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		got <- received{r.Header.Get(webhook.HeaderEvent), r.Header.Get(webhook.HeaderSignature), body}
+	}))
+	t.Cleanup(srv.Close)
+
+	hook := &model.Webhook{ProjectID: todo.ProjectID, URL: srv.URL, Secret: "s3cret", Events: []string{model.EventTodoCreated}}
+	if err := s.CreateWebhook(ctx, hook); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+
+	d := webhook.New(s, webhook.Options{Backoff: time.Millisecond, AllowPrivateNetworks: true})
+	d.Dispatch(ctx, model.EventTodoDeleted, todo) // not subscribed
+	d.Dispatch(ctx, model.EventTodoCreated, todo)
+
+	select {
+	case r := <-got:
+		if r.event != model.EventTodoCreated {
+			t.Errorf("event = %q, want %q", r.event, model.EventTodoCreated)
+		}
+		if want := webhook.Sign("s3cret", r.body); r.signature != want {
+			t.Errorf("signature = %q, want %q", r.signature, want)
+		}
+		var payload webhook.Payload
+		if err := json.Unmarshal(r.body, &payload); err != nil {
+			t.Fatalf("decode payload: %v", err)
+		}
+		if payload.Event != model.EventTodoCreated || payload.ProjectID != todo.ProjectID || payload.Todo.ID != todo.ID {
+			t.Errorf("payload = %+v", payload)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("no delivery")
+	}
+	select {
+	case r := <-got:
+		t.Errorf("unexpected delivery of %q", r.event)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if n := calls.Load(); n != 2 {
+		t.Errorf("server called %d times, want 2", n)
+	}
+}
+
+func TestDispatchRefusesPrivateAddresses(t *testing.T) {
+	s, todo := setupTodo(t)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	t.Cleanup(internal.Close)
+
+	// The store does not validate URLs, standing in for a host name that
+	// resolves to loopback.
+	hook := &model.Webhook{ProjectID: todo.ProjectID, URL: internal.URL, Secret: "s", Events: []string{model.EventTodoCreated}}
+	if err := s.CreateWebhook(ctx, hook); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+
+	d := webhook.New(s, webhook.Options{Backoff: time.Millisecond, MaxAttempts: 2})
+	d.Dispatch(ctx, model.EventTodoCreated, todo)
+	time.Sleep(100 * time.Millisecond)
+	if n := calls.Load(); n != 0 {
+		t.Errorf("loopback receiver called %d times, want 0", n)
+	}
+}
+
+func TestDispatchDoesNotFollowRedirects(t *testing.T) {
+	s, todo := setupTodo(t)
+	ctx := context.Background()
+
+	var targetCalls, redirects atomic.Int32
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		targetCalls.Add(1)
+	}))
+	t.Cleanup(target.Close)
+	redirector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		redirects.Add(1)
+		http.Redirect(w, r, target.URL, http.StatusTemporaryRedirect)
+	}))
+	t.Cleanup(redirector.Close)
+
+	hook := &model.Webhook{ProjectID: todo.ProjectID, URL: redirector.URL, Secret: "s", Events: []string{model.EventTodoCreated}}
+	if err := s.CreateWebhook(ctx, hook); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+
+	// Both servers are on loopback, so private networks must be allowed to
+	// reach the redirector at all.
+	d := webhook.New(s, webhook.Options{Backoff: time.Millisecond, MaxAttempts: 2, AllowPrivateNetworks: true})
+	d.Dispatch(ctx, model.EventTodoCreated, todo)
+	time.Sleep(100 * time.Millisecond)
+	if n := redirects.Load(); n != 2 {
+		t.Errorf("redirector called %d times, want 2 (a redirect is a failed attempt)", n)
+	}
+	if n := targetCalls.Load(); n != 0 {
+		t.Errorf("redirect target called %d times, want 0", n)
+	}
+}
+
+func TestCloseDrainsQueue(t *testing.T) {
+	s, todo := setupTodo(t)
+	ctx := context.Background()
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1)%2 == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable) // every first attempt fails
+		}
+	}))
+	t.Cleanup(srv.Close)
+	hook := &model.Webhook{ProjectID: todo.ProjectID, URL: srv.URL, Secret: "s", Events: []string{model.EventTodoCreated}}
+	if err := s.CreateWebhook(ctx, hook); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+
+	d := webhook.New(s, webhook.Options{Backoff: 20 * time.Millisecond, Workers: 1, AllowPrivateNetworks: true})
+	d.Dispatch(ctx, model.EventTodoCreated, todo)
+	d.Dispatch(ctx, model.EventTodoCreated, todo)
+	if err := d.Close(ctx); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if n := calls.Load(); n != 4 {
+		t.Errorf("receiver called %d times by Close, want 4 (two deliveries, each retried once)", n)
+	}
+
+	d.Dispatch(ctx, model.EventTodoCreated, todo) // dropped, not a panic
+	if err := d.Close(ctx); err != nil {
+		t.Errorf("second close: %v", err)
+	}
+}
+
+func TestCloseGivesUpWithContext(t *testing.T) {
+	s, todo := setupTodo(t)
+	ctx := context.Background()
+
+	called := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called <- struct{}{}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(srv.Close)
+	hook := &model.Webhook{ProjectID: todo.ProjectID, URL: srv.URL, Secret: "s", Events: []string{model.EventTodoCreated}}
+	if err := s.CreateWebhook(ctx, hook); err != nil {
+		t.Fatalf("create webhook: %v", err)
+	}
+
+	// The only worker is stuck in a long backoff with one more delivery
+	// queued behind it.
+	d := webhook.New(s, webhook.Options{Backoff: time.Hour, Workers: 1, AllowPrivateNetworks: true})
+	d.Dispatch(ctx, model.EventTodoCreated, todo)
+	d.Dispatch(ctx, model.EventTodoCreated, todo)
+	<-called
+
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := d.Close(closeCtx)
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "dropped 1 ") {
+		t.Errorf("close = %v, want a deadline error reporting 1 dropped delivery", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("close took %s, want it to stop at the deadline", elapsed)
+	}
+	if n := len(called); n != 0 {
+		t.Errorf("receiver called %d more times after the first attempt, want 0", n)
+	}
+}
@@ -2,7 +2,7 @@ import { useState, useEffect, useRef } from 'react';
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import { api } from '@/services/api';
 import { X, UserPlus, Trash2 } from 'lucide-react';
-import type { ProjectMember, User } from '@/types';
+import type { ProjectMember, PublicUser } from '@/types';
 
 interface ShareProjectModalProps {
   projectId: number;
@@ -15,7 +15,7 @@ export default function ShareProjectModal({
 }: ShareProjectModalProps) {
   const queryClient = useQueryClient();
   const [search, setSearch] = useState('');
-  const [selectedUser, setSelectedUser] = useState<User | null>(null);
+  const [selectedUser, setSelectedUser] = useState<PublicUser | null>(null);
   const [role, setRole] = useState<'viewer' | 'editor'>('viewer');
   const [error, setError] = useState('');
   const [showDropdown, setShowDropdown] = useState(false);
@@ -23,7 +23,7 @@ export default function ShareProjectModal({
   const dropdownRef = useRef<HTMLDivElement>(null);
 
   // Search users as the user types (debounced via staleTime)
-  const { data: searchResults = [] } = useQuery<User[]>({
+  const { data: searchResults = [] } = useQuery<PublicUser[]>({
     queryKey: ['user-search', search],
     queryFn: () => api.searchUsers(search),
     enabled: search.length >= 1 && !selectedUser,
@@ -153,14 +153,9 @@ export default function ShareProjectModal({
                             {u.username.charAt(0).toUpperCase()}
                           </span>
                         </div>
-                        <div className="min-w-0">
-                          <p className="font-medium text-gray-900 dark:text-white truncate">
-                            {u.username}
-                          </p>
-                          <p className="text-xs text-gray-500 dark:text-gray-400 truncate">
-                            {u.email}
-                          </p>
-                        </div>
+                        <p className="min-w-0 font-medium text-gray-900 dark:text-white truncate">
+                          {u.username}
+                        </p>
                       </button>
                     ))
                   )}
@@ -22,6 +22,10 @@ export default function TodoModal({
   const [deadline, setDeadline] = useState(
     initial?.deadline ? initial.deadline.slice(0, 10) : ''
   );
+  // Empty means "not estimated", which is different from an estimate of 0.
+  const [estimate, setEstimate] = useState(
+    initial?.estimate != null ? String(initial.estimate) : ''
+  );
   const isEdit = !!initial;
 
   const handleSubmit = (e: React.FormEvent) => {
@@ -32,6 +36,7 @@ export default function TodoModal({
       status,
       priority,
       deadline: deadline ? new Date(deadline).toISOString() : undefined,
+      estimate: estimate === '' ? null : Number(estimate),
     });
   };
 
@@ -111,16 +116,33 @@ export default function TodoModal({
             </div>
           </div>
 
-          <div>
-            <label className="block text-sm font-medium text-gray-700 dark:text-gray-300">
-              Deadline
-            </label>
-            <input
-              type="date"
-              value={deadline}
-              onChange={(e) => setDeadline(e.target.value)}
-              className="mt-1 block w-full rounded-lg border border-gray-300 px-3 py-2 shadow-sm focus:border-bloom-500 focus:outline-none focus:ring-1 focus:ring-bloom-500 dark:border-gray-700 dark:bg-gray-800 dark:text-white"
-            />
+          <div className="grid grid-cols-2 gap-4">
+            <div>
+              <label className="block text-sm font-medium text-gray-700 dark:text-gray-300">
+                Deadline
+              </label>
+              <input
+                type="date"
+                value={deadline}
+                onChange={(e) => setDeadline(e.target.value)}
+                className="mt-1 block w-full rounded-lg border border-gray-300 px-3 py-2 shadow-sm focus:border-bloom-500 focus:outline-none focus:ring-1 focus:ring-bloom-500 dark:border-gray-700 dark:bg-gray-800 dark:text-white"
+              />
+            </div>
+
+            <div>
+              <label className="block text-sm font-medium text-gray-700 dark:text-gray-300">
+                Estimate (points)
+              </label>
+              <input
+                type="number"
+                min={0}
+                step={1}
+                value={estimate}
+                onChange={(e) => setEstimate(e.target.value)}
+                className="mt-1 block w-full rounded-lg border border-gray-300 px-3 py-2 shadow-sm focus:border-bloom-500 focus:outline-none focus:ring-1 focus:ring-bloom-500 dark:border-gray-700 dark:bg-gray-800 dark:text-white"
+                placeholder="Not estimated"
+              />
+            </div>
           </div>
 
           <div className="flex justify-end gap-3">
@@ -8,7 +8,7 @@ import {
 } from 'react';
 import { useQueryClient } from '@tanstack/react-query';
 import { api } from '@/services/api';
-import type { User } from '@/types';
+import type { AuthResponse, User } from '@/types';
 
 interface AuthContextType {
   user: User | null;
@@ -31,46 +31,48 @@ export function AuthProvider({ children }: { children: ReactNode }) {
   );
   const [isLoading, setIsLoading] = useState(true);
 
+  // Restore the session once on load, from the stored token or, when the
+  // server uses cookie sessions, from the HttpOnly cookie.
   useEffect(() => {
-    if (token) {
-      api.setToken(token);
-      api
-        .me()
-        .then(setUser)
-        .catch(() => {
-          // Token expired or invalid
-          localStorage.removeItem(TOKEN_KEY);
-          setToken(null);
-          api.setToken(null);
-        })
-        .finally(() => setIsLoading(false));
-    } else {
-      setIsLoading(false);
-    }
-  }, [token]);
+    const stored = localStorage.getItem(TOKEN_KEY);
+    api.setToken(stored);
+    api
+      .me()
+      .then(setUser)
+      .catch(() => {
+        // Token expired or invalid, or no session cookie
+        localStorage.removeItem(TOKEN_KEY);
+        setToken(null);
+        api.setToken(null);
+      })
+      .finally(() => setIsLoading(false));
+  }, []);
 
-  const login = useCallback(async (username: string, password: string) => {
-    const res = await api.login(username, password);
-    localStorage.setItem(TOKEN_KEY, res.token);
+  const startSession = useCallback((res: AuthResponse) => {
+    // With a cookie session the token never needs to touch storage that
+    // scripts can read; keep it in memory only.
+    if (!res.cookie_session) {
+      localStorage.setItem(TOKEN_KEY, res.token);
+    }
     api.setToken(res.token);
     queryClient.clear();
     setToken(res.token);
     setUser(res.user);
   }, [queryClient]);
 
+  const login = useCallback(async (username: string, password: string) => {
+    startSession(await api.login(username, password));
+  }, [startSession]);
+
   const register = useCallback(
     async (username: string, email: string, password: string) => {
-      const res = await api.register(username, email, password);
-      localStorage.setItem(TOKEN_KEY, res.token);
-      api.setToken(res.token);
-      queryClient.clear();
-      setToken(res.token);
-      setUser(res.user);
+      startSession(await api.register(username, email, password));
     },
-    [queryClient]
+    [startSession]
   );
 
   const logout = useCallback(() => {
+    api.logout().catch(() => {});
     localStorage.removeItem(TOKEN_KEY);
     api.setToken(null);
     queryClient.clear();
@@ -16,7 +16,7 @@ export default function AdminPage() {
 
   const { data: users = [] } = useQuery<User[]>({
     queryKey: ['admin-users'],
-    queryFn: () => api.listUsers(),
+    queryFn: () => api.listUsers({ limit: 50 }),
   });
 
   const toggleAdmin = useMutation({
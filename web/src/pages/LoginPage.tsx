@@ -1,6 +1,8 @@
 import { useState } from 'react';
 import { Link, Navigate } from 'react-router-dom';
+import { useQuery } from '@tanstack/react-query';
 import { useAuth } from '@/hooks/useAuth';
+import { api } from '@/services/api';
 import BloomLogo from '@/components/BloomLogo';
 
 export default function LoginPage() {
@@ -9,6 +11,7 @@ export default function LoginPage() {
   const [password, setPassword] = useState('');
   const [error, setError] = useState('');
   const [loading, setLoading] = useState(false);
+  const { data: meta } = useQuery({ queryKey: ['meta'], queryFn: () => api.meta() });
 
   if (user) return <Navigate to="/" replace />;
 
@@ -84,12 +87,14 @@ export default function LoginPage() {
           </button>
         </form>
 
-        <p className="mt-6 text-center text-sm text-gray-500 dark:text-gray-400">
-          Don&apos;t have an account?{' '}
-          <Link to="/register" className="font-medium text-bloom-600 hover:text-bloom-500">
-            Create one
-          </Link>
-        </p>
+        {meta?.features.registration_enabled !== false && (
+          <p className="mt-6 text-center text-sm text-gray-500 dark:text-gray-400">
+            Don&apos;t have an account?{' '}
+            <Link to="/register" className="font-medium text-bloom-600 hover:text-bloom-500">
+              Create one
+            </Link>
+          </p>
+        )}
       </div>
     </div>
   );
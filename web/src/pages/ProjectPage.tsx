@@ -287,6 +287,9 @@ export default function ProjectPage() {
                 </div>
 
                 <div className="flex items-center gap-2 shrink-0">
+                  {todo.estimate != null && (
+                    <span className="text-xs text-gray-400">{todo.estimate} pts</span>
+                  )}
                   {todo.deadline && (
                     <span className="text-xs text-gray-400">
                       {new Date(todo.deadline).toLocaleDateString()}
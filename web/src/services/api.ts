@@ -1,13 +1,39 @@
 import type {
+  Activity,
+  AdminAuditEntry,
   AuthResponse,
+  ImportResult,
   Project,
+  ProjectImport,
   ProjectMember,
+  ProjectStats,
+  ProjectTemplate,
+  PublicUser,
+  ServerMeta,
   Stats,
   Todo,
+  TodoDependency,
+  TodoGroup,
+  TodoRepair,
+  TokenStatus,
   User,
+  Webhook,
 } from '@/types';
 
-const API_BASE = (import.meta.env.VITE_API_URL as string) || '/api';
+const API_BASE = (import.meta.env.VITE_API_URL as string) || '/api/v1';
+
+// ApiError is thrown for error responses. Branch on code, which is stable;
+// message is meant for people and may change.
+export class ApiError extends Error {
+  constructor(
+    message: string,
+    readonly status: number,
+    readonly code: string
+  ) {
+    super(message);
+    this.name = 'ApiError';
+  }
+}
 
 class ApiClient {
   private token: string | null = null;
@@ -22,6 +48,8 @@ class ApiClient {
   ): Promise<T> {
     const headers: Record<string, string> = {
       'Content-Type': 'application/json',
+      // Required by the server for cookie-authenticated writes (CSRF guard).
+      'X-Requested-With': 'XMLHttpRequest',
       ...((options.headers as Record<string, string>) || {}),
     };
 
@@ -36,7 +64,11 @@ class ApiClient {
 
     if (!res.ok) {
       const body = await res.json().catch(() => ({ error: 'Unknown error' }));
-      throw new Error(body.error || `Request failed with status ${res.status}`);
+      throw new ApiError(
+        body.error || `Request failed with status ${res.status}`,
+        res.status,
+        body.code || 'UNKNOWN'
+      );
     }
 
     if (res.status === 204) {
@@ -65,6 +97,25 @@ class ApiClient {
     return this.request('/auth/me');
   }
 
+  async updateMe(data: { timezone?: string; locale?: string }): Promise<User> {
+    return this.request('/auth/me', {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async validateToken(): Promise<TokenStatus> {
+    return this.request('/auth/validate');
+  }
+
+  async logout(): Promise<void> {
+    return this.request('/auth/logout', { method: 'POST' });
+  }
+
+  async meta(): Promise<ServerMeta> {
+    return this.request('/meta');
+  }
+
   // Projects
   async listProjects(): Promise<Project[]> {
     return this.request('/projects');
@@ -96,6 +147,42 @@ class ApiClient {
     return this.request(`/projects/${projectId}/role`);
   }
 
+  async getProjectStats(projectId: number): Promise<ProjectStats> {
+    return this.request(`/projects/${projectId}/stats`);
+  }
+
+  async getProjectRoles(projectIds: number[]): Promise<Record<string, string>> {
+    return this.request('/projects/roles', {
+      method: 'POST',
+      body: JSON.stringify({ ids: projectIds }),
+    });
+  }
+
+  async reorderProjects(projectIds: number[]): Promise<void> {
+    return this.request('/projects/reorder', {
+      method: 'POST',
+      body: JSON.stringify({ ids: projectIds }),
+    });
+  }
+
+  async exportProjectTemplate(projectId: number): Promise<ProjectTemplate> {
+    return this.request(`/projects/${projectId}/export-template`, { method: 'POST' });
+  }
+
+  async importProjectTemplate(template: ProjectTemplate): Promise<Project> {
+    return this.request('/templates/import', {
+      method: 'POST',
+      body: JSON.stringify(template),
+    });
+  }
+
+  async importProjects(bundle: ProjectImport): Promise<ImportResult> {
+    return this.request('/projects/import', {
+      method: 'POST',
+      body: JSON.stringify(bundle),
+    });
+  }
+
   // Project Members
   async listMembers(projectId: number): Promise<ProjectMember[]> {
     return this.request(`/projects/${projectId}/members`);
@@ -114,11 +201,74 @@ class ApiClient {
     });
   }
 
+  // Webhooks
+  async listWebhooks(projectId: number): Promise<Webhook[]> {
+    return this.request(`/projects/${projectId}/webhooks`);
+  }
+
+  async createWebhook(
+    projectId: number,
+    data: Pick<Webhook, 'url' | 'events'> & { secret?: string },
+  ): Promise<Webhook> {
+    return this.request(`/projects/${projectId}/webhooks`, {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async updateWebhook(
+    projectId: number,
+    webhookId: number,
+    data: Partial<Pick<Webhook, 'url' | 'events' | 'secret'>>,
+  ): Promise<Webhook> {
+    return this.request(`/projects/${projectId}/webhooks/${webhookId}`, {
+      method: 'PUT',
+      body: JSON.stringify(data),
+    });
+  }
+
+  async deleteWebhook(projectId: number, webhookId: number): Promise<void> {
+    return this.request(`/projects/${projectId}/webhooks/${webhookId}`, { method: 'DELETE' });
+  }
+
   // Todos
   async listTodos(projectId: number): Promise<Todo[]> {
     return this.request(`/projects/${projectId}/todos`);
   }
 
+  // from is inclusive and to exclusive; both are RFC3339 timestamps.
+  async listCompletedTodos(projectId: number, from: string, to: string): Promise<Todo[]> {
+    const query = new URLSearchParams({ from, to });
+    return this.request(`/projects/${projectId}/todos/completed?${query}`);
+  }
+
+  async listMyTodos(
+    params: {
+      status?: string;
+      priority?: Todo['priority'];
+      sort?: string;
+      limit?: number;
+      offset?: number;
+    } = {},
+  ): Promise<TodoGroup[]> {
+    const query = new URLSearchParams();
+    for (const [key, value] of Object.entries(params)) {
+      if (value !== undefined && value !== '') query.set(key, String(value));
+    }
+    const qs = query.toString();
+    return this.request(`/todos${qs ? `?${qs}` : ''}`);
+  }
+
+  // A full page means there may be more: pass the last entry's id as before.
+  async listActivity(params: { limit?: number; before?: number } = {}): Promise<Activity[]> {
+    const query = new URLSearchParams();
+    for (const [key, value] of Object.entries(params)) {
+      if (value !== undefined) query.set(key, String(value));
+    }
+    const qs = query.toString();
+    return this.request(`/activity${qs ? `?${qs}` : ''}`);
+  }
+
   async createTodo(projectId: number, data: Partial<Todo>): Promise<Todo> {
     return this.request(`/projects/${projectId}/todos`, {
       method: 'POST',
@@ -137,8 +287,24 @@ class ApiClient {
     return this.request(`/todos/${id}`, { method: 'DELETE' });
   }
 
+  // Todo dependencies
+  async listTodoDependencies(id: number): Promise<Todo[]> {
+    return this.request(`/todos/${id}/dependencies`);
+  }
+
+  async addTodoDependency(id: number, dependsOnId: number): Promise<TodoDependency> {
+    return this.request(`/todos/${id}/dependencies`, {
+      method: 'POST',
+      body: JSON.stringify({ depends_on_id: dependsOnId }),
+    });
+  }
+
+  async removeTodoDependency(id: number, dependsOnId: number): Promise<void> {
+    return this.request(`/todos/${id}/dependencies/${dependsOnId}`, { method: 'DELETE' });
+  }
+
   // User search
-  async searchUsers(query: string): Promise<User[]> {
+  async searchUsers(query: string): Promise<PublicUser[]> {
     return this.request(`/users/search?q=${encodeURIComponent(query)}`);
   }
 
@@ -147,8 +313,42 @@ class ApiClient {
     return this.request('/admin/stats');
   }
 
-  async listUsers(): Promise<User[]> {
-    return this.request('/admin/users');
+  async repairTodos(): Promise<TodoRepair> {
+    return this.request('/admin/repair/todos', { method: 'POST' });
+  }
+
+  async listUsers(
+    params: { q?: string; is_admin?: boolean; limit?: number; offset?: number } = {},
+  ): Promise<User[]> {
+    const query = new URLSearchParams();
+    for (const [key, value] of Object.entries(params)) {
+      if (value !== undefined && value !== '') query.set(key, String(value));
+    }
+    const qs = query.toString();
+    return this.request(`/admin/users${qs ? `?${qs}` : ''}`);
+  }
+
+  async listInactiveUsers(
+    params: { days?: number; include_admins?: boolean; limit?: number; offset?: number } = {},
+  ): Promise<User[]> {
+    const query = new URLSearchParams();
+    for (const [key, value] of Object.entries(params)) {
+      if (value !== undefined) query.set(key, String(value));
+    }
+    const qs = query.toString();
+    return this.request(`/admin/users/inactive${qs ? `?${qs}` : ''}`);
+  }
+
+  async createUser(data: {
+    username: string;
+    email: string;
+    password: string;
+    is_admin?: boolean;
+  }): Promise<User> {
+    return this.request('/admin/users', {
+      method: 'POST',
+      body: JSON.stringify(data),
+    });
   }
 
   async updateUser(id: number, data: Partial<User>): Promise<User> {
@@ -161,6 +361,10 @@ class ApiClient {
   async deleteUser(id: number): Promise<void> {
     return this.request(`/admin/users/${id}`, { method: 'DELETE' });
   }
+
+  async listAdminAudit(limit = 50, offset = 0): Promise<AdminAuditEntry[]> {
+    return this.request(`/admin/audit?limit=${limit}&offset=${offset}`);
+  }
 }
 
 export const api = new ApiClient();
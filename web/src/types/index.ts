@@ -3,14 +3,51 @@ export interface User {
   username: string;
   email: string;
   is_admin: boolean;
+  timezone: string;
+  locale: string;
+  last_login_at: string | null;
   created_at: string;
   updated_at: string;
 }
 
+export interface TodoChange {
+  id: number;
+  todo_id: number;
+  user_id: number | null;
+  username?: string;
+  field: string;
+  old_value: string | null;
+  new_value: string | null;
+  created_at: string;
+}
+
+export interface Activity extends TodoChange {
+  project_id: number;
+  todo_title: string;
+}
+
+export interface TodoDependency {
+  todo_id: number;
+  depends_on_id: number;
+}
+
+export interface PublicUser {
+  id: number;
+  username: string;
+}
+
 export interface Project {
   id: number;
   name: string;
-  description: string;
+  description: string; // Markdown
+  description_html?: string; // sanitized, only with ?render=html
+  color: string;
+  is_template: boolean;
+  statuses: string[] | null; // custom workflow; null means pending/in_progress/completed
+  hide_completed: boolean; // todo list leaves out completed todos unless asked
+  default_member_role: 'viewer' | 'editor'; // role of members added without one
+  end_date: string | null; // no todo may be due after it
+  favorited: boolean;
   owner_id: number;
   owner_name?: string;
   created_at: string;
@@ -21,14 +58,34 @@ export interface Todo {
   id: number;
   project_id: number;
   title: string;
-  description: string;
-  status: 'pending' | 'in_progress' | 'completed';
+  description: string; // Markdown
+  description_html?: string; // sanitized, only with ?render=html
+  // Projects with a custom workflow may use other statuses.
+  status: 'pending' | 'in_progress' | 'completed' | (string & {});
   priority: 'low' | 'medium' | 'high';
-  deadline?: string;
+  priority_rank: number | null;
+  deadline: string | null;
+  reminder_offset: string | null; // Go duration, e.g. "24h0m0s"
+  estimate: number | null; // effort points; null when not estimated
+  completed_at: string | null; // null unless status is completed
+  created_by: number | null;
+  created_by_name?: string;
+  assignees: TodoAssignee[];
   created_at: string;
   updated_at: string;
 }
 
+// One project's todos in GET /todos.
+export interface TodoGroup {
+  project: Project;
+  todos: Todo[];
+}
+
+export interface TodoAssignee {
+  user_id: number;
+  username: string;
+}
+
 export interface ProjectMember {
   project_id: number;
   user_id: number;
@@ -36,18 +93,113 @@ export interface ProjectMember {
   role: 'viewer' | 'editor';
 }
 
+export type WebhookEvent = 'todo.created' | 'todo.updated' | 'todo.completed' | 'todo.deleted';
+
+export interface Webhook {
+  id: number;
+  project_id: number;
+  url: string;
+  secret?: string; // only returned when the webhook is created
+  events: WebhookEvent[];
+  created_at: string;
+}
+
+export interface AdminAuditEntry {
+  id: number;
+  actor_id: number | null;
+  actor_name?: string;
+  action: 'user.update' | 'user.delete';
+  target_id: number;
+  changes: unknown;
+  created_at: string;
+}
+
+// Shareable project outline; see "Shareable templates" in the README.
+export interface ProjectTemplate {
+  version: number;
+  name: string;
+  color: string;
+  todos: {
+    title: string;
+    priority: Todo['priority'];
+    priority_rank: number | null;
+  }[];
+}
+
+// Body of POST /projects/import: a data export, optionally with members.
+export interface ProjectImport {
+  version: number;
+  projects: Partial<Project>[];
+  todos: Partial<Todo>[];
+  members?: { project_id: number; email: string; role: 'viewer' | 'editor' }[];
+}
+
+export interface ImportResult {
+  projects: Project[];
+  todos_created: number;
+  members_added: number;
+  skipped: { kind: 'todo' | 'member'; ref: string; reason: string }[];
+}
+
+// Public server information from GET /meta.
+export interface ServerMeta {
+  server_time: string;
+  maintenance: boolean;
+  features: {
+    registration_enabled: boolean;
+    cookie_auth: boolean;
+    block_incomplete_dependencies: boolean;
+    reminders: boolean;
+    max_projects_per_user: number; // 0 means unlimited
+    max_project_members: number; // 0 means unlimited
+    max_todos_per_project: number; // 0 means unlimited
+    todo_max_title_length: number;
+    todo_max_description_length: number;
+    max_page_size: number;
+    demo_mode: boolean;
+  };
+  flags: Record<string, boolean>; // public feature flags
+}
+
+// Totals count todos without an estimate as zero.
+export interface ProjectStats {
+  total_todos: number;
+  completed_todos: number;
+  todos_by_status: Record<string, number>;
+  todos_by_priority: Record<Todo['priority'], number>;
+  estimated_todos: number;
+  total_estimate: number;
+  completed_estimate: number;
+}
+
 export interface Stats {
   total_users: number;
   total_projects: number;
   total_todos: number;
   completed_todos: number;
+  todos_by_status: Record<string, number>;
+}
+
+export interface TodoRepair {
+  todos_fixed: number;
+  statuses_fixed: number;
+  priorities_fixed: number;
 }
 
 export interface AuthResponse {
   token: string;
   user: User;
+  // Set when the server also holds the session in an HttpOnly cookie.
+  cookie_session?: boolean;
+}
+
+export interface TokenStatus {
+  valid: boolean;
+  user_id: number;
+  expires_at: string | null;
 }
 
 export interface ApiError {
   error: string;
+  errors?: Record<string, string>; // per-field messages when validation fails
 }